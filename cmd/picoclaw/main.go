@@ -8,35 +8,47 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"embed"
 	"fmt"
 	"io"
 	"io/fs"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/chzyer/readline"
 	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/approval"
+	"github.com/sipeed/picoclaw/pkg/archive"
 	"github.com/sipeed/picoclaw/pkg/auth"
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/channels"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/crypt"
 	"github.com/sipeed/picoclaw/pkg/devices"
+	"github.com/sipeed/picoclaw/pkg/egress"
 	"github.com/sipeed/picoclaw/pkg/health"
 	"github.com/sipeed/picoclaw/pkg/heartbeat"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/migrate"
 	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/retention"
+	"github.com/sipeed/picoclaw/pkg/session"
 	"github.com/sipeed/picoclaw/pkg/skills"
 	"github.com/sipeed/picoclaw/pkg/state"
 	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/usage"
+	"github.com/sipeed/picoclaw/pkg/vault"
 	"github.com/sipeed/picoclaw/pkg/voice"
 )
 
@@ -140,6 +152,20 @@ func main() {
 		migrateCmd()
 	case "auth":
 		authCmd()
+	case "audit":
+		auditCmd()
+	case "encrypt":
+		cryptCmd(true)
+	case "decrypt":
+		cryptCmd(false)
+	case "privacy":
+		privacyCmd()
+	case "export":
+		exportCmd()
+	case "import":
+		importCmd()
+	case "secret":
+		secretCmd()
 	case "cron":
 		cronCmd()
 	case "skills":
@@ -208,6 +234,13 @@ func printHelp() {
 	fmt.Println("  onboard     Initialize picoclaw configuration and workspace")
 	fmt.Println("  agent       Interact with the agent directly")
 	fmt.Println("  auth        Manage authentication (login, logout, status)")
+	fmt.Println("  audit       Review outbound network connections")
+	fmt.Println("  encrypt     Encrypt sessions, memory and credentials at rest")
+	fmt.Println("  decrypt     Turn encrypted sessions, memory and credentials back into plaintext")
+	fmt.Println("  privacy     Apply data retention policies (purge)")
+	fmt.Println("  export      Export sessions, memory, cron jobs and skills to an archive")
+	fmt.Println("  import      Restore an archive made by export")
+	fmt.Println("  secret      Manage secrets tools can use without the model seeing them")
 	fmt.Println("  gateway     Start picoclaw gateway")
 	fmt.Println("  status      Show picoclaw status")
 	fmt.Println("  cron        Manage scheduled tasks")
@@ -418,6 +451,12 @@ func agentCmd() {
 		})
 
 	if message != "" {
+		reader := bufio.NewReader(os.Stdin)
+		agentLoop.Approvals().SetPrompt(approvalPrompt(func(prompt string) (string, error) {
+			fmt.Print(prompt)
+			return reader.ReadString('\n')
+		}))
+
 		ctx := context.Background()
 		response, err := agentLoop.ProcessDirect(ctx, message, sessionKey)
 		if err != nil {
@@ -450,6 +489,12 @@ func interactiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
 	}
 	defer rl.Close()
 
+	agentLoop.Approvals().SetPrompt(approvalPrompt(func(question string) (string, error) {
+		rl.SetPrompt(question)
+		defer rl.SetPrompt(prompt)
+		return rl.Readline()
+	}))
+
 	for {
 		line, err := rl.Readline()
 		if err != nil {
@@ -471,19 +516,20 @@ func interactiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
 			return
 		}
 
-		ctx := context.Background()
-		response, err := agentLoop.ProcessDirect(ctx, input, sessionKey)
-		if err != nil {
+		if err := streamTurn(agentLoop, input, sessionKey); err != nil {
 			fmt.Printf("Error: %v\n", err)
 			continue
 		}
-
-		fmt.Printf("\n%s %s\n\n", logo, response)
 	}
 }
 
 func simpleInteractiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
 	reader := bufio.NewReader(os.Stdin)
+	agentLoop.Approvals().SetPrompt(approvalPrompt(func(prompt string) (string, error) {
+		fmt.Print(prompt)
+		return reader.ReadString('\n')
+	}))
+
 	for {
 		fmt.Print(fmt.Sprintf("%s You: ", logo))
 		line, err := reader.ReadString('\n')
@@ -506,15 +552,53 @@ func simpleInteractiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
 			return
 		}
 
-		ctx := context.Background()
-		response, err := agentLoop.ProcessDirect(ctx, input, sessionKey)
-		if err != nil {
+		if err := streamTurn(agentLoop, input, sessionKey); err != nil {
 			fmt.Printf("Error: %v\n", err)
 			continue
 		}
+	}
+}
+
+// approvalPrompt asks at the terminal about tool calls that need approval.
+// The turn is waiting on the call, so nothing else reads stdin meanwhile.
+// Anything but a yes denies the call.
+func approvalPrompt(readLine func(prompt string) (string, error)) approval.PromptFunc {
+	return func(ctx context.Context, question string) (bool, error) {
+		fmt.Printf("\n%s\n", question)
+		line, err := readLine("Allow it? [y/N] ")
+		if err != nil && err != io.EOF {
+			return false, err
+		}
+		allowed, _ := approval.ParseAnswer(line)
+		return allowed, nil
+	}
+}
 
+// streamTurn runs one interactive turn, printing tokens as the provider
+// streams them. Providers without streaming support print the whole reply
+// once it is complete.
+func streamTurn(agentLoop *agent.AgentLoop, input, sessionKey string) error {
+	streamed := false
+	response, err := agentLoop.ProcessDirectStream(context.Background(), input, sessionKey, func(delta string) {
+		if !streamed {
+			fmt.Printf("\n%s ", logo)
+			streamed = true
+		}
+		fmt.Print(delta)
+	})
+	if err != nil {
+		if streamed {
+			fmt.Println()
+		}
+		return err
+	}
+
+	if streamed {
+		fmt.Print("\n\n")
+	} else {
 		fmt.Printf("\n%s %s\n\n", logo, response)
 	}
+	return nil
 }
 
 func gatewayCmd() {
@@ -562,7 +646,7 @@ func gatewayCmd() {
 		})
 
 	// Setup cron tool and service
-	cronService := setupCronTool(agentLoop, msgBus, cfg.WorkspacePath(), cfg.Agents.Defaults.RestrictToWorkspace)
+	cronService := setupCronTool(agentLoop, msgBus, cfg.WorkspacePath())
 
 	heartbeatService := heartbeat.NewHeartbeatService(
 		cfg.WorkspacePath(),
@@ -647,6 +731,17 @@ func gatewayCmd() {
 	}
 	fmt.Println("✓ Heartbeat service started")
 
+	retentionPolicy, err := retention.PolicyFromConfig(cfg.Privacy.Retention)
+	if err != nil {
+		fmt.Printf("Error in retention policy: %v\n", err)
+		os.Exit(1)
+	}
+	janitor := retention.NewJanitor(retentionPolicy, cfg.WorkspacePath(), agentLoop.Sessions(), cronService)
+	if !retentionPolicy.Empty() {
+		janitor.Start()
+		fmt.Println("✓ Retention janitor started")
+	}
+
 	stateManager := state.NewManager(cfg.WorkspacePath())
 	deviceService := devices.NewService(devices.Config{
 		Enabled:    cfg.Devices.Enabled,
@@ -664,12 +759,13 @@ func gatewayCmd() {
 	}
 
 	healthServer := health.NewServer(cfg.Gateway.Host, cfg.Gateway.Port)
+	healthServer.RegisterMetric("agent_queue", func() interface{} { return agentLoop.QueueStats() })
 	go func() {
 		if err := healthServer.Start(); err != nil && err != http.ErrServerClosed {
 			logger.ErrorCF("health", "Health server error", map[string]interface{}{"error": err.Error()})
 		}
 	}()
-	fmt.Printf("✓ Health endpoints available at http://%s:%d/health and /ready\n", cfg.Gateway.Host, cfg.Gateway.Port)
+	fmt.Printf("✓ Health endpoints available at http://%s:%d/health, /ready and /metrics\n", cfg.Gateway.Host, cfg.Gateway.Port)
 
 	go agentLoop.Run(ctx)
 
@@ -682,6 +778,7 @@ func gatewayCmd() {
 	healthServer.Stop(context.Background())
 	deviceService.Stop()
 	heartbeatService.Stop()
+	janitor.Stop()
 	cronService.Stop()
 	agentLoop.Stop()
 	channelManager.StopAll(ctx)
@@ -746,6 +843,7 @@ func statusCmd() {
 		} else {
 			fmt.Println("vLLM/Local: not set")
 		}
+		printSecretStatus(cfg)
 
 		store, _ := auth.LoadStore()
 		if store != nil && len(store.Credentials) > 0 {
@@ -760,6 +858,669 @@ func statusCmd() {
 				fmt.Printf("  %s (%s): %s\n", provider, cred.AuthMethod, status)
 			}
 		}
+
+		printUsageStatus(usage.NewTracker(workspace, cfg.Usage))
+		printPrivacyStatus(cfg, workspace)
+	}
+}
+
+// printSecretStatus lists the secrets loaded from file:, env: or cmd:
+// references and why any of them couldn't be resolved.
+func printSecretStatus(cfg *config.Config) {
+	refs := cfg.SecretRefs()
+	if len(refs) == 0 {
+		return
+	}
+
+	unresolved := 0
+	for _, r := range refs {
+		if r.Err != nil {
+			unresolved++
+		}
+	}
+	fmt.Printf("\nSecret references: %d resolved, %d unresolved\n", len(refs)-unresolved, unresolved)
+	for _, r := range refs {
+		if r.Err != nil {
+			fmt.Printf("  %s: ✗ %s (%v)\n", r.Path, r.Ref, r.Err)
+		} else {
+			fmt.Printf("  %s: ✓ %s\n", r.Path, r.Ref)
+		}
+	}
+}
+
+func printUsageStatus(tracker *usage.Tracker) {
+	today := tracker.Today()
+	limits := tracker.Limits()
+
+	fmt.Println("\nUsage:")
+	fmt.Printf("  Today: %s\n", today.All)
+	fmt.Printf("  Today (cloud): %s\n", today.Cloud)
+	if limits.DailyTokenLimit > 0 {
+		fmt.Printf("  Daily token cap: %d/%d\n", today.Cloud.TotalTokens, limits.DailyTokenLimit)
+	}
+	if limits.DailyCostLimit > 0 {
+		fmt.Printf("  Daily cost cap: $%.2f/$%.2f\n", today.Cloud.CostUSD, limits.DailyCostLimit)
+	}
+
+	printUsageTotals("Provider", tracker.Providers())
+	printUsageTotals("Model", tracker.Models())
+}
+
+func printUsageTotals(label string, totals map[string]usage.Totals) {
+	names := make([]string, 0, len(totals))
+	for name := range totals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  %s %s: %s\n", label, name, totals[name])
+	}
+}
+
+func printPrivacyStatus(cfg *config.Config, workspace string) {
+	fmt.Println("\nPrivacy:")
+	if cfg.Privacy.LocalOnly {
+		fmt.Println("  Local-only: ✓ (connections outside this machine and the LAN are blocked)")
+	} else {
+		fmt.Println("  Local-only: off")
+	}
+	if crypt.Enabled() {
+		fmt.Println("  Encryption at rest: ✓")
+	} else {
+		fmt.Println("  Encryption at rest: off")
+	}
+
+	stats, err := egress.LoadStats(workspace)
+	if err != nil {
+		fmt.Printf("  Blocked connections: unknown (%v)\n", err)
+		return
+	}
+	if stats.Blocked == 0 {
+		return
+	}
+	fmt.Printf("  Blocked connections: %d\n", stats.Blocked)
+	recent := stats.Recent
+	if len(recent) > 5 {
+		recent = recent[len(recent)-5:]
+	}
+	for _, a := range recent {
+		fmt.Printf("    %s %s -> %s\n", a.Time.Local().Format("2006-01-02 15:04"), a.Component, a.Host)
+	}
+}
+
+func auditCmd() {
+	if len(os.Args) < 3 {
+		auditHelp()
+		return
+	}
+
+	switch os.Args[2] {
+	case "network":
+		auditNetworkCmd()
+	default:
+		fmt.Printf("Unknown audit command: %s\n", os.Args[2])
+		auditHelp()
+	}
+}
+
+func auditHelp() {
+	fmt.Println("\nAudit commands:")
+	fmt.Println("  network     Summarise outbound connections by destination host")
+	fmt.Println()
+	fmt.Println("Network options:")
+	fmt.Println("  --since <when>       Only entries newer than a duration (24h) or date (2006-01-02)")
+	fmt.Println("  --session <key>      Only entries made for one session")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  picoclaw audit network")
+	fmt.Println("  picoclaw audit network --since 24h")
+	fmt.Println("  picoclaw audit network --session telegram:123456")
+}
+
+func auditNetworkCmd() {
+	var since time.Time
+	sessionKey := ""
+
+	args := os.Args[3:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--since":
+			if i+1 < len(args) {
+				t, err := parseSince(args[i+1])
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					return
+				}
+				since = t
+				i++
+			}
+		case "--session":
+			if i+1 < len(args) {
+				sessionKey = args[i+1]
+				i++
+			}
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	path := egress.AuditPath(cfg.WorkspacePath())
+	entries, err := egress.ReadAudit(path, since)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	if sessionKey != "" {
+		filtered := entries[:0]
+		for _, e := range entries {
+			if e.SessionKey == sessionKey {
+				filtered = append(filtered, e)
+			}
+		}
+		entries = filtered
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No outbound connections recorded.")
+		return
+	}
+
+	var sent, received int64
+	for _, e := range entries {
+		sent += e.BytesSent
+		received += e.BytesReceived
+	}
+	fmt.Printf("%d connections, %s sent, %s received (%s)\n\n",
+		len(entries), formatBytes(sent), formatBytes(received), path)
+
+	fmt.Printf("%-36s %6s %8s %10s %10s  %s\n", "HOST", "COUNT", "BLOCKED", "SENT", "RECEIVED", "COMPONENTS")
+	for _, h := range egress.Summarize(entries) {
+		host := h.Host
+		if host == "" {
+			host = "(unknown)"
+		}
+		fmt.Printf("%-36s %6d %8d %10s %10s  %s\n",
+			host, h.Requests, h.Blocked, formatBytes(h.BytesSent), formatBytes(h.BytesReceived),
+			strings.Join(h.Components, ","))
+	}
+}
+
+// parseSince accepts a duration back from now ("24h") or a local date.
+func parseSince(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q (use a duration like 24h or a date like 2006-01-02)", s)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGT"[exp])
+}
+
+func privacyCmd() {
+	if len(os.Args) < 3 {
+		privacyHelp()
+		return
+	}
+
+	switch os.Args[2] {
+	case "purge":
+		privacyPurgeCmd()
+	default:
+		fmt.Printf("Unknown privacy command: %s\n", os.Args[2])
+		privacyHelp()
+	}
+}
+
+func privacyHelp() {
+	fmt.Println("\nPrivacy commands:")
+	fmt.Println("  purge       Delete data older than privacy.retention allows")
+	fmt.Println()
+	fmt.Println("Purge options:")
+	fmt.Println("  --dry-run            List what would be deleted without deleting it")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  picoclaw privacy purge --dry-run")
+	fmt.Println("  picoclaw privacy purge")
+}
+
+func privacyPurgeCmd() {
+	dryRun := false
+	for _, arg := range os.Args[3:] {
+		if arg == "--dry-run" || arg == "-n" {
+			dryRun = true
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	policy, err := retention.PolicyFromConfig(cfg.Privacy.Retention)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if policy.Empty() {
+		fmt.Println("No retention policy set (privacy.retention); nothing is purged.")
+		return
+	}
+
+	// A running gateway holds sessions and cron jobs in memory and would
+	// write back what is deleted here
+	if !dryRun && gatewayRunning(cfg) {
+		fmt.Println("The gateway is running and purges expired data itself every hour.")
+		fmt.Println("Stop it first to purge now, or use --dry-run to see what would be deleted.")
+		os.Exit(1)
+	}
+
+	workspace := cfg.WorkspacePath()
+	janitor := retention.NewJanitor(policy, workspace,
+		session.NewSessionManager(filepath.Join(workspace, "sessions")),
+		cron.NewCronService(filepath.Join(workspace, "cron", "jobs.json"), nil))
+	janitor.AddSessions(session.NewSessionManager(webUISessionsPath()))
+
+	items, err := janitor.Plan(time.Now())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(items) == 0 {
+		fmt.Println("Nothing to purge.")
+		return
+	}
+
+	if dryRun {
+		fmt.Printf("Would delete %d items:\n", len(items))
+		printPurgeItems(items)
+		return
+	}
+
+	removed, err := janitor.Purge(items)
+	fmt.Printf("Deleted %d items:\n", len(removed))
+	printPurgeItems(removed)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// gatewayRunning reports whether a gateway answers on the configured health
+// endpoint.
+func gatewayRunning(cfg *config.Config) bool {
+	host := cfg.Gateway.Host
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	client := &http.Client{Timeout: time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://%s/health", net.JoinHostPort(host, strconv.Itoa(cfg.Gateway.Port))))
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
+
+// webUISessionsPath is where the web UI keeps its sessions.
+func webUISessionsPath() string {
+	return filepath.Join(filepath.Dir(getConfigPath()), "sessions")
+}
+
+func printPurgeItems(items []retention.Item) {
+	for _, item := range items {
+		where := item.Name
+		if item.Path != "" && filepath.Base(item.Path) != item.Name {
+			where = fmt.Sprintf("%s (%s)", item.Name, item.Path)
+		} else if item.Path != "" {
+			where = item.Path
+		}
+		fmt.Printf("  %-12s %s  %s\n", item.Class, item.Time.Local().Format("2006-01-02 15:04"), where)
+	}
+}
+
+// exportCmd writes the user's data to a single archive. With encryption
+// enabled the archive is sealed as a whole, so it opens on another board
+// with the same passphrase or key file.
+func exportCmd() {
+	out := fmt.Sprintf("picoclaw-export-%s.tar.gz", time.Now().Format("20060102-150405"))
+	for _, arg := range os.Args[2:] {
+		switch arg {
+		case "-h", "--help":
+			exportHelp()
+			return
+		default:
+			out = arg
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	var buf bytes.Buffer
+	m, err := archive.Export(&buf, archive.Source{
+		Workspace:     cfg.WorkspacePath(),
+		WebUISessions: webUISessionsPath(),
+		Config:        cfg,
+		Version:       formatVersion(),
+	})
+	if err != nil {
+		fmt.Printf("Error exporting: %v\n", err)
+		os.Exit(1)
+	}
+	data, err := crypt.Seal(buf.Bytes())
+	if err != nil {
+		fmt.Printf("Error encrypting archive: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(out, data, 0600); err != nil {
+		fmt.Printf("Error writing %s: %v\n", out, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ Exported %d sessions, %d cron jobs, %d skills and %d files to %s\n",
+		m.Sessions, m.CronJobs, len(m.Skills), len(m.Files), out)
+	if crypt.Enabled() {
+		fmt.Println("  The archive is encrypted with your passphrase or key file.")
+	}
+	if len(m.RedactedConfig) > 0 {
+		fmt.Printf("  Removed %d secrets from the config: %s\n", len(m.RedactedConfig), strings.Join(m.RedactedConfig, ", "))
+	}
+}
+
+func exportHelp() {
+	fmt.Println("\nExport:")
+	fmt.Println("  picoclaw export [file]   Write sessions, memory, cron jobs, state, skills")
+	fmt.Println("                           and the config (without secrets) to one archive")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  picoclaw export")
+	fmt.Println("  picoclaw export /mnt/usb/picoclaw.tar.gz")
+}
+
+// importCmd restores an archive made by exportCmd into this workspace.
+func importCmd() {
+	if len(os.Args) < 3 || os.Args[2] == "-h" || os.Args[2] == "--help" {
+		importHelp()
+		return
+	}
+	path := os.Args[2]
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	if data, err = crypt.Open(data); err != nil {
+		fmt.Printf("Error decrypting %s: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	m, res, err := archive.Import(bytes.NewReader(data), archive.Target{
+		Workspace:     cfg.WorkspacePath(),
+		WebUISessions: webUISessionsPath(),
+		ConfigDir:     filepath.Dir(getConfigPath()),
+	})
+	if m != nil {
+		fmt.Printf("Archive from picoclaw %s, created %s\n", m.Version, m.Created.Local().Format("2006-01-02 15:04"))
+	}
+	if res != nil {
+		printImportList("Added", res.Added)
+		printImportList("Merged", res.Merged)
+		printImportList("Skipped", res.Skipped)
+	}
+	if err != nil {
+		fmt.Printf("Error importing: %v\n", err)
+		os.Exit(1)
+	}
+	if m != nil && len(m.RedactedConfig) > 0 {
+		fmt.Printf("\nThe exported config had its secrets removed; set them again: %s\n", strings.Join(m.RedactedConfig, ", "))
+	}
+}
+
+func importHelp() {
+	fmt.Println("\nImport:")
+	fmt.Println("  picoclaw import <file>   Restore an archive made by picoclaw export")
+	fmt.Println()
+	fmt.Println("Local data is kept: sessions with a clashing key are merged into the local")
+	fmt.Println("history, memory files are merged, and existing cron jobs, skills, state")
+	fmt.Println("and config are left alone (the config goes to config.imported.json).")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  picoclaw import picoclaw-export-20260101-120000.tar.gz")
+}
+
+func printImportList(label string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Printf("%s (%d):\n", label, len(items))
+	for _, item := range items {
+		fmt.Printf("  %s\n", item)
+	}
+}
+
+// cryptCmd migrates the files privacy.encryption covers to or from their
+// encrypted form. Files already in the target form are left alone, so it
+// can be rerun safely on a mixed workspace.
+func cryptCmd(encrypt bool) {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if !crypt.HasKey() {
+		fmt.Printf("Error: no key. Set %s or privacy.encryption.key_file.\n", crypt.PassphraseEnv)
+		os.Exit(1)
+	}
+
+	files := atRestFiles(cfg)
+	n, err := crypt.Migrate(files, encrypt)
+	verb := "Encrypted"
+	if !encrypt {
+		verb = "Decrypted"
+	}
+	fmt.Printf("%s %d of %d files\n", verb, n, len(files))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !encrypt {
+		// Nothing is sealed with the key any more, so another may be set up
+		os.Remove(crypt.CheckPath(encryptionSaltPath()))
+	}
+
+	enabled := cfg.Privacy.Encryption.Enabled
+	if encrypt && !enabled {
+		fmt.Println("Set privacy.encryption.enabled to true so new data is encrypted too.")
+	} else if !encrypt && enabled {
+		fmt.Println("Set privacy.encryption.enabled to false, or new data will be encrypted again.")
+	}
+}
+
+// atRestFiles lists the files privacy.encryption covers: session histories
+// (including the web UI's), memory notes, OAuth credentials, the secret
+// vault and the cassette.
+func atRestFiles(cfg *config.Config) []string {
+	workspace := cfg.WorkspacePath()
+	var files []string
+	for _, dir := range []string{
+		filepath.Join(workspace, "sessions"),
+		webUISessionsPath(),
+	} {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+		files = append(files, matches...)
+	}
+	filepath.WalkDir(filepath.Join(workspace, "memory"), func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && strings.HasSuffix(path, ".md") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	// Cached embeddings give away the text they were made from
+	filepath.WalkDir(filepath.Join(workspace, "embeddings"), func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() && !strings.HasSuffix(path, ".tmp") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return append(files, auth.StorePath(), vault.DefaultPath(), cfg.CassettePath())
+}
+
+func secretCmd() {
+	if len(os.Args) < 3 {
+		secretHelp()
+		return
+	}
+
+	// Load the config first so the vault is encrypted when encryption is on
+	if _, err := loadConfig(); err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	v := vault.New(vault.DefaultPath())
+
+	switch os.Args[2] {
+	case "set":
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: picoclaw secret set <NAME> [--host <HOST>]... [--command <REGEX>]...")
+			return
+		}
+		var scope vault.Scope
+		args := os.Args[4:]
+		for i := 0; i < len(args); i++ {
+			switch args[i] {
+			case "--host":
+				if i+1 < len(args) {
+					scope.Hosts = append(scope.Hosts, args[i+1])
+					i++
+				}
+			case "--command":
+				if i+1 < len(args) {
+					scope.Commands = append(scope.Commands, args[i+1])
+					i++
+				}
+			}
+		}
+		secretSetCmd(v, os.Args[3], scope)
+	case "list":
+		names, err := v.Names()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(names) == 0 {
+			fmt.Println("No secrets stored.")
+			return
+		}
+		for _, name := range names {
+			fmt.Printf("  %-24s %s\n", name, vault.Placeholder(name))
+			scope := v.Scope(name)
+			for _, host := range scope.Hosts {
+				fmt.Printf("    host:    %s\n", host)
+			}
+			for _, command := range scope.Commands {
+				fmt.Printf("    command: %s\n", command)
+			}
+			if scope.Empty() {
+				fmt.Println("    (no scope, so it can't be used)")
+			}
+		}
+	case "remove", "rm":
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: picoclaw secret remove <NAME>")
+			return
+		}
+		removed, err := v.Delete(os.Args[3])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !removed {
+			fmt.Printf("No secret named %s\n", os.Args[3])
+			return
+		}
+		fmt.Printf("✓ Removed %s\n", os.Args[3])
+	default:
+		fmt.Printf("Unknown secret command: %s\n", os.Args[2])
+		secretHelp()
+	}
+}
+
+func secretHelp() {
+	fmt.Println("\nSecret commands:")
+	fmt.Println("  set <NAME>       Store a secret, read from the terminal or stdin")
+	fmt.Println("  list             List stored secrets (names and scopes)")
+	fmt.Println("  remove <NAME>    Delete a secret")
+	fmt.Println()
+	fmt.Println("Set options:")
+	fmt.Println("  --host <HOST>       A host web_fetch may send it to (*.example.com for subdomains)")
+	fmt.Println("  --command <REGEX>   A pattern exec commands that use it must match as a whole")
+	fmt.Println()
+	fmt.Println("The agent refers to a secret as {{secret:NAME}} in exec commands and")
+	fmt.Println("web_fetch URLs or headers. The value is filled in when the tool runs and")
+	fmt.Println("masked out of its output. A secret is refused for hosts and commands")
+	fmt.Println("its options don't list.")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  picoclaw secret set GITHUB_TOKEN --host api.github.com --command 'gh .*'")
+	fmt.Println("  echo \"$TOKEN\" | picoclaw secret set GITHUB_TOKEN --host api.github.com")
+	fmt.Println("  picoclaw secret list")
+}
+
+// secretSetCmd reads the value without echoing it when stdin is a terminal,
+// so it stays out of the shell history and off the screen.
+func secretSetCmd(v *vault.Vault, name string, scope vault.Scope) {
+	var value string
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		b, err := readline.Password(fmt.Sprintf("Value for %s: ", name))
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		value = string(b)
+	} else {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		value = line
+	}
+
+	if err := v.Set(name, strings.TrimSpace(value), scope); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Saved %s. The agent can use it as %s\n", name, vault.Placeholder(name))
+	if scope.Empty() {
+		fmt.Println("  It has no --host or --command, so every use will be refused.")
 	}
 }
 
@@ -987,14 +1748,14 @@ func getConfigPath() string {
 	return filepath.Join(home, ".picoclaw", "config.json")
 }
 
-func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace string, restrict bool) *cron.CronService {
+func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace string) *cron.CronService {
 	cronStorePath := filepath.Join(workspace, "cron", "jobs.json")
 
 	// Create cron service
 	cronService := cron.NewCronService(cronStorePath, nil)
 
 	// Create and register CronTool
-	cronTool := tools.NewCronTool(cronService, agentLoop, msgBus, workspace, restrict)
+	cronTool := tools.NewCronTool(cronService, agentLoop, msgBus)
 	agentLoop.RegisterTool(cronTool)
 
 	// Set the onJob handler
@@ -1007,7 +1768,19 @@ func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace
 }
 
 func loadConfig() (*config.Config, error) {
-	return config.LoadConfig(getConfigPath())
+	cfg, err := config.LoadConfig(getConfigPath())
+	if err != nil {
+		return nil, err
+	}
+	egress.Configure(cfg.Privacy.LocalOnly, cfg.WorkspacePath())
+	if err := crypt.Setup(cfg.Privacy.Encryption.Enabled, cfg.EncryptionKeyFile(), encryptionSaltPath()); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func encryptionSaltPath() string {
+	return filepath.Join(filepath.Dir(getConfigPath()), "encryption.salt")
 }
 
 func cronCmd() {
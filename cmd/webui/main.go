@@ -16,6 +16,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -24,7 +25,10 @@ import (
 	"github.com/sipeed/picoclaw/pkg/agent"
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/crypt"
+	"github.com/sipeed/picoclaw/pkg/egress"
 	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/retention"
 	"github.com/sipeed/picoclaw/pkg/session"
 )
 
@@ -39,9 +43,32 @@ var upgrader = websocket.Upgrader{
 
 // ChatMessage represents a chat message
 type ChatMessage struct {
-	Role      string `json:"role"`
-	Content   string `json:"content"`
-	Timestamp int64  `json:"timestamp"`
+	Role      string   `json:"role"`
+	Content   string   `json:"content"`
+	Images    []string `json:"images,omitempty"` // data URLs, sent to vision models only
+	Reasoning string   `json:"reasoning,omitempty"`
+	Timestamp int64    `json:"timestamp"`
+}
+
+// toProviderMessage converts a client message, attaching its images as
+// content parts. Images that fail to decode are dropped.
+func toProviderMessage(msg ChatMessage) providers.Message {
+	pm := providers.Message{
+		Role:    msg.Role,
+		Content: msg.Content,
+	}
+	for _, img := range msg.Images {
+		part, err := providers.ImagePartFromDataURL(img)
+		if err != nil {
+			log.Printf("Dropping attached image: %v", err)
+			continue
+		}
+		if len(pm.Parts) == 0 {
+			pm.Parts = append(pm.Parts, providers.TextPart(msg.Content))
+		}
+		pm.Parts = append(pm.Parts, part)
+	}
+	return pm
 }
 
 // ChatRequest represents a chat request from the client
@@ -51,13 +78,16 @@ type ChatRequest struct {
 	Model        string        `json:"model"`
 	SystemPrompt string        `json:"systemPrompt"`
 	SessionKey   string        `json:"sessionKey"`
+	Incognito    bool          `json:"incognito"` // keep this tab's chat in memory only
 }
 
 // ChatResponse represents a streaming chunk response
 type ChatResponse struct {
-	Content string `json:"content"`
-	Done    bool   `json:"done"`
-	Error   string `json:"error,omitempty"`
+	Content   string `json:"content"`
+	Reasoning string `json:"reasoning,omitempty"` // shown only when the user asks for it
+	Done      bool   `json:"done"`
+	Stopped   bool   `json:"stopped,omitempty"` // the user stopped the reply
+	Error     string `json:"error,omitempty"`
 }
 
 // ModelsResponse lists available models
@@ -89,6 +119,41 @@ func (p *ProviderWrapper) Chat(ctx context.Context, messages []providers.Message
 }
 
 func (p *ProviderWrapper) StreamChat(ctx context.Context, req *StreamChatRequest) (<-chan StreamChunk, error) {
+	if sp, ok := p.provider.(providers.StreamingProvider); ok {
+		events, err := sp.ChatStream(ctx, req.Messages, nil, req.Model, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		chunkChan := make(chan StreamChunk, 16)
+		go func() {
+			defer close(chunkChan)
+			for ev := range events {
+				var chunk StreamChunk
+				switch {
+				case ev.Err != nil:
+					chunk = StreamChunk{Error: ev.Err}
+				case ev.Done:
+					chunk = StreamChunk{Done: true}
+				case ev.Content != "" || ev.Reasoning != "":
+					chunk = StreamChunk{Content: ev.Content, Reasoning: ev.Reasoning}
+				default:
+					continue
+				}
+
+				select {
+				case chunkChan <- chunk:
+				case <-ctx.Done():
+					return
+				}
+				if chunk.Error != nil || chunk.Done {
+					return
+				}
+			}
+		}()
+		return chunkChan, nil
+	}
+
 	// For non-streaming providers, simulate streaming
 	chunkChan := make(chan StreamChunk, 1)
 	go func() {
@@ -103,8 +168,9 @@ func (p *ProviderWrapper) StreamChat(ctx context.Context, req *StreamChatRequest
 
 		// Send the full response as one chunk
 		chunkChan <- StreamChunk{
-			Content: resp.Content,
-			Done:    true,
+			Content:   resp.Content,
+			Reasoning: resp.Reasoning,
+			Done:      true,
 		}
 	}()
 	return chunkChan, nil
@@ -129,9 +195,10 @@ type StreamChatRequest struct {
 
 // StreamChunk represents a chunk of streamed response
 type StreamChunk struct {
-	Content string
-	Done    bool
-	Error   error
+	Content   string
+	Reasoning string
+	Done      bool
+	Error     error
 }
 
 var (
@@ -144,6 +211,18 @@ var (
 	sessionStoragePath string
 )
 
+// incognitoSessions holds the chats of incognito tabs. It has no storage
+// path, so nothing in it is ever written to disk.
+var incognitoSessions = session.NewSessionManager("")
+
+// sessionsFor returns the session store a chat request belongs to.
+func sessionsFor(req ChatRequest) *session.SessionManager {
+	if req.Incognito {
+		return incognitoSessions
+	}
+	return sessions
+}
+
 func main() {
 	// Default port
 	port := "8080"
@@ -160,6 +239,11 @@ func main() {
 		log.Printf("Warning: Could not load config: %v", err)
 		cfg = config.DefaultConfig()
 	}
+	egress.Configure(cfg.Privacy.LocalOnly, cfg.WorkspacePath())
+	saltPath := filepath.Join(home, ".picoclaw", "encryption.salt")
+	if err := crypt.Setup(cfg.Privacy.Encryption.Enabled, cfg.EncryptionKeyFile(), saltPath); err != nil {
+		log.Fatalf("Encryption: %v", err)
+	}
 
 	// Initialize message bus
 	msgBus = bus.NewMessageBus()
@@ -169,6 +253,13 @@ func main() {
 	sessions = session.NewSessionManager(sessionStoragePath)
 	log.Printf("Session storage: %s", sessionStoragePath)
 
+	// The gateway's janitor doesn't know this store, so expire it here
+	if policy, err := retention.PolicyFromConfig(cfg.Privacy.Retention); err != nil {
+		log.Printf("Retention policy: %v", err)
+	} else if policy.Sessions > 0 {
+		retention.NewJanitor(retention.Policy{Sessions: policy.Sessions}, "", sessions, nil).Start()
+	}
+
 	// Initialize providers
 	initializeProviders()
 
@@ -183,6 +274,7 @@ func main() {
 	// Setup HTTP routes
 	http.HandleFunc("/", handleIndex)
 	http.HandleFunc("/api/chat", handleChat)
+	http.HandleFunc("/api/chat/stop", handleStop)
 	http.HandleFunc("/api/models", handleModels)
 	http.HandleFunc("/api/sessions", handleSessions)
 	http.HandleFunc("/api/sessions/", handleSessionDetail)
@@ -339,16 +431,14 @@ func handleChat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Load session history
-	history := sessions.GetHistory(sessionKey)
+	store := sessionsFor(req)
+	history := store.GetHistory(sessionKey)
 
 	// Convert new messages and append to history
 	for _, msg := range req.Messages {
-		history = append(history, providers.Message{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
+		history = append(history, toProviderMessage(msg))
 		// Save to session
-		sessions.AddMessage(sessionKey, msg.Role, msg.Content)
+		store.AddMessage(sessionKey, msg.Role, msg.Content)
 	}
 
 	// Add system prompt if provided
@@ -381,7 +471,8 @@ func handleChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := r.Context()
+	ctx, done := startChat(r.Context(), sessionKey)
+	defer done()
 	chunkChan, err := provider.StreamChat(ctx, chatReq)
 	if err != nil {
 		fmt.Fprintf(w, "data: {\"error\": \"%s\"}\n\n", err.Error())
@@ -389,17 +480,21 @@ func handleChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var fullResponse string
+	var fullResponse, fullReasoning string
 	for chunk := range chunkChan {
 		if chunk.Error != nil {
+			if ctx.Err() != nil {
+				break
+			}
 			fmt.Fprintf(w, "data: {\"error\": \"%s\"}\n\n", chunk.Error.Error())
 			flusher.Flush()
 			break
 		}
 
 		response := ChatResponse{
-			Content: chunk.Content,
-			Done:    chunk.Done,
+			Content:   chunk.Content,
+			Reasoning: chunk.Reasoning,
+			Done:      chunk.Done,
 		}
 
 		data, _ := json.Marshal(response)
@@ -407,17 +502,88 @@ func handleChat(w http.ResponseWriter, r *http.Request) {
 		flusher.Flush()
 
 		fullResponse += chunk.Content
+		fullReasoning += chunk.Reasoning
 
 		if chunk.Done {
 			// Save assistant response to session
 			if fullResponse != "" {
-				sessions.AddMessage(sessionKey, "assistant", fullResponse)
-				// Persist session
-				_ = sessions.Save(sessionKey)
+				saveAssistantMessage(store, sessionKey, fullResponse, fullReasoning)
+				// Persist session (a no-op for incognito tabs)
+				_ = store.Save(sessionKey)
 			}
-			break
+			return
 		}
 	}
+
+	if ctx.Err() != nil {
+		saveStopped(store, sessionKey, fullResponse, fullReasoning)
+		data, _ := json.Marshal(ChatResponse{Done: true, Stopped: true})
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		flusher.Flush()
+	}
+}
+
+// activeChat is a reply being generated, which the stop button can cancel.
+type activeChat struct {
+	cancel context.CancelFunc
+}
+
+// activeChats maps session keys to the reply in progress for them.
+var activeChats sync.Map
+
+// startChat makes the reply for sessionKey stoppable through /api/chat/stop.
+// The returned function must be called when the reply ends.
+func startChat(ctx context.Context, sessionKey string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	chat := &activeChat{cancel: cancel}
+	activeChats.Store(sessionKey, chat)
+	return ctx, func() {
+		activeChats.CompareAndDelete(sessionKey, chat)
+		cancel()
+	}
+}
+
+// handleStop cancels the reply in progress for a session. The provider
+// request is aborted and what was generated so far is kept.
+func handleStop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		SessionKey string `json:"sessionKey"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.SessionKey == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	v, stopped := activeChats.LoadAndDelete(req.SessionKey)
+	if stopped {
+		v.(*activeChat).cancel()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"stopped": stopped})
+}
+
+// saveStopped records a reply the user stopped, so the conversation reads
+// sensibly when it is reloaded or continued.
+func saveStopped(store *session.SessionManager, sessionKey, content, reasoning string) {
+	content = strings.TrimSpace(content + "\n\n⏹️ Stopped.")
+	saveAssistantMessage(store, sessionKey, content, reasoning)
+	_ = store.Save(sessionKey)
+}
+
+// saveAssistantMessage records a streamed reply, keeping the model's
+// reasoning beside the answer rather than in it.
+func saveAssistantMessage(store *session.SessionManager, sessionKey, content, reasoning string) {
+	store.AddFullMessage(sessionKey, providers.Message{
+		Role:      "assistant",
+		Content:   content,
+		Reasoning: strings.TrimSpace(reasoning),
+	})
 }
 
 func handleModels(w http.ResponseWriter, r *http.Request) {
@@ -483,7 +649,7 @@ func handleSessions(w http.ResponseWriter, r *http.Request) {
 
 		// Read session file to get info
 		sessionPath := filepath.Join(sessionStoragePath, entry.Name())
-		data, err := os.ReadFile(sessionPath)
+		data, err := crypt.ReadFile(sessionPath)
 		if err != nil {
 			continue
 		}
@@ -537,8 +703,9 @@ func handleSessionDetail(w http.ResponseWriter, r *http.Request) {
 		var messages []ChatMessage
 		for _, msg := range history {
 			messages = append(messages, ChatMessage{
-				Role:    msg.Role,
-				Content: msg.Content,
+				Role:      msg.Role,
+				Content:   msg.Content,
+				Reasoning: msg.Reasoning,
 			})
 		}
 
@@ -590,16 +757,14 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Load session history
-		history := sessions.GetHistory(sessionKey)
+		store := sessionsFor(req)
+		history := store.GetHistory(sessionKey)
 
 		// Convert new messages and append to history
 		for _, msg := range req.Messages {
-			history = append(history, providers.Message{
-				Role:    msg.Role,
-				Content: msg.Content,
-			})
+			history = append(history, toProviderMessage(msg))
 			// Save to session
-			sessions.AddMessage(sessionKey, msg.Role, msg.Content)
+			store.AddMessage(sessionKey, msg.Role, msg.Content)
 		}
 
 		if req.SystemPrompt != "" {
@@ -618,40 +783,54 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			Model:    model,
 		}
 
-		ctx := context.Background()
+		ctx, done := startChat(context.Background(), sessionKey)
 		chunkChan, err := provider.StreamChat(ctx, chatReq)
 		if err != nil {
+			done()
 			conn.WriteJSON(ChatResponse{Error: err.Error()})
 			continue
 		}
 
-		var fullResponse string
+		var fullResponse, fullReasoning string
+		finished := false
 		for chunk := range chunkChan {
 			if chunk.Error != nil {
+				if ctx.Err() != nil {
+					break
+				}
 				conn.WriteJSON(ChatResponse{Error: chunk.Error.Error()})
+				finished = true
 				break
 			}
 
 			if err := conn.WriteJSON(ChatResponse{
-				Content: chunk.Content,
-				Done:    chunk.Done,
+				Content:   chunk.Content,
+				Reasoning: chunk.Reasoning,
+				Done:      chunk.Done,
 			}); err != nil {
 				log.Printf("WebSocket write error: %v", err)
 				break
 			}
 
 			fullResponse += chunk.Content
+			fullReasoning += chunk.Reasoning
 
 			if chunk.Done {
 				// Save assistant response to session
 				if fullResponse != "" {
-					sessions.AddMessage(sessionKey, "assistant", fullResponse)
-					// Persist session
-					_ = sessions.Save(sessionKey)
+					saveAssistantMessage(store, sessionKey, fullResponse, fullReasoning)
+					// Persist session (a no-op for incognito tabs)
+					_ = store.Save(sessionKey)
 				}
+				finished = true
 				break
 			}
 		}
+		if !finished && ctx.Err() != nil {
+			saveStopped(store, sessionKey, fullResponse, fullReasoning)
+			conn.WriteJSON(ChatResponse{Done: true, Stopped: true})
+		}
+		done()
 	}
 
 	log.Println("WebSocket client disconnected")
@@ -2,6 +2,7 @@
 class PicoClawApp {
     constructor() {
         this.messages = [];
+        this.pendingImages = [];
         this.isStreaming = false;
         this.abortController = null;
         this.sessionKey = localStorage.getItem('picoclaw_session') || 'webui:' + Date.now();
@@ -21,6 +22,8 @@ class PicoClawApp {
             provider: document.getElementById('provider'),
             model: document.getElementById('model'),
             streaming: document.getElementById('streaming'),
+            showReasoning: document.getElementById('showReasoning'),
+            incognito: document.getElementById('incognito'),
             clearBtn: document.getElementById('clearBtn'),
             statusText: document.getElementById('statusText'),
             providerStatus: document.getElementById('providerStatus'),
@@ -30,12 +33,22 @@ class PicoClawApp {
             sessionsPanel: document.getElementById('sessionsPanel'),
             sessionsList: document.getElementById('sessionsList'),
             newChatBtn: document.getElementById('newChatBtn'),
-            closeSessionsBtn: document.getElementById('closeSessionsBtn')
+            closeSessionsBtn: document.getElementById('closeSessionsBtn'),
+            attachBtn: document.getElementById('attachBtn'),
+            imageInput: document.getElementById('imageInput'),
+            attachments: document.getElementById('attachments')
         };
     }
 
     attachEventListeners() {
-        this.elements.sendBtn.addEventListener('click', () => this.sendMessage());
+        // The send button turns into a stop button while a reply is generated
+        this.elements.sendBtn.addEventListener('click', () => {
+            if (this.isStreaming) {
+                this.stopResponse();
+            } else {
+                this.sendMessage();
+            }
+        });
         this.elements.userInput.addEventListener('keydown', (e) => {
             if (e.key === 'Enter' && !e.shiftKey) {
                 e.preventDefault();
@@ -47,6 +60,24 @@ class PicoClawApp {
         this.elements.systemPromptBtn.addEventListener('click', () => {
             this.elements.systemPrompt.classList.toggle('show');
         });
+        this.elements.showReasoning.checked = localStorage.getItem('picoclaw_show_reasoning') === 'true';
+        this.applyReasoningVisibility();
+        this.elements.showReasoning.addEventListener('change', () => {
+            localStorage.setItem('picoclaw_show_reasoning', this.elements.showReasoning.checked);
+            this.applyReasoningVisibility();
+        });
+        // Incognito is per tab: sessionStorage isn't shared with other tabs
+        this.elements.incognito.checked = sessionStorage.getItem('picoclaw_incognito') === 'true';
+        this.elements.incognito.addEventListener('change', () => {
+            sessionStorage.setItem('picoclaw_incognito', this.elements.incognito.checked);
+            // Don't mix a saved conversation with an unsaved one
+            this.startNewChat();
+            this.updateStatus(this.elements.incognito.checked
+                ? 'Incognito: this chat is not saved'
+                : 'Incognito off: new messages are saved');
+        });
+        this.elements.attachBtn.addEventListener('click', () => this.elements.imageInput.click());
+        this.elements.imageInput.addEventListener('change', () => this.attachImages());
         
         // Session management
         this.elements.sessionsBtn.addEventListener('click', () => this.toggleSessionsPanel());
@@ -168,7 +199,7 @@ class PicoClawApp {
             // Clear and reload chat display
             this.elements.chat.innerHTML = '';
             this.messages.forEach(msg => {
-                this.addMessage(msg.role, msg.content, false);
+                this.addMessage(msg.role, msg.content, false, msg.reasoning);
             });
             
             this.toggleSessionsPanel();
@@ -233,12 +264,37 @@ class PicoClawApp {
         }
     }
 
+    async attachImages() {
+        const files = Array.from(this.elements.imageInput.files);
+        for (const file of files) {
+            const dataURL = await new Promise((resolve, reject) => {
+                const reader = new FileReader();
+                reader.onload = () => resolve(reader.result);
+                reader.onerror = () => reject(reader.error);
+                reader.readAsDataURL(file);
+            });
+            this.pendingImages.push(dataURL);
+        }
+        this.elements.imageInput.value = '';
+        this.renderAttachments();
+    }
+
+    renderAttachments() {
+        const count = this.pendingImages.length;
+        this.elements.attachments.textContent = count ? `${count} image${count > 1 ? 's' : ''} attached` : '';
+    }
+
     async sendMessage() {
         const content = this.elements.userInput.value.trim();
-        if (!content || this.isStreaming) return;
+        if ((!content && this.pendingImages.length === 0) || this.isStreaming) return;
+
+        // Images go with this message only; they aren't kept in the history
+        const images = this.pendingImages;
+        this.pendingImages = [];
+        this.renderAttachments();
 
         // Add user message
-        this.addMessage('user', content);
+        this.addMessage('user', images.length ? `${content}\n\n📎 ${images.length} image(s)` : content);
         this.elements.userInput.value = '';
         this.elements.userInput.style.height = 'auto';
 
@@ -249,6 +305,7 @@ class PicoClawApp {
 
         this.isStreaming = true;
         this.abortController = new AbortController();
+        this.elements.sendBtn.textContent = 'Stop';
         
         // Add assistant message placeholder
         const assistantMsgDiv = this.addMessage('assistant', '');
@@ -261,9 +318,9 @@ class PicoClawApp {
             const useStreaming = this.elements.streaming.checked;
             
             if (useStreaming) {
-                await this.streamResponse(provider, model, content, systemPrompt, contentDiv, typingIndicator);
+                await this.streamResponse(provider, model, content, images, systemPrompt, contentDiv, typingIndicator);
             } else {
-                await this.nonStreamResponse(provider, model, content, systemPrompt, contentDiv, typingIndicator);
+                await this.nonStreamResponse(provider, model, content, images, systemPrompt, contentDiv, typingIndicator);
             }
             
             // Refresh sessions list after message
@@ -273,11 +330,27 @@ class PicoClawApp {
         } finally {
             this.isStreaming = false;
             this.abortController = null;
+            this.elements.sendBtn.textContent = 'Send';
             this.updateStatus('Ready');
         }
     }
 
-    async streamResponse(provider, model, content, systemPrompt, contentDiv, typingIndicator) {
+    async stopResponse() {
+        this.updateStatus('Stopping...');
+        try {
+            await fetch('/api/chat/stop', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({ sessionKey: this.sessionKey })
+            });
+        } catch (error) {
+            console.error('Error stopping response:', error);
+        }
+    }
+
+    async streamResponse(provider, model, content, images, systemPrompt, contentDiv, typingIndicator) {
         this.updateStatus('Streaming response...');
         
         const response = await fetch('/api/chat', {
@@ -286,11 +359,12 @@ class PicoClawApp {
                 'Content-Type': 'application/json',
             },
             body: JSON.stringify({
-                messages: [...this.messages, { role: 'user', content }],
+                messages: [...this.messages, { role: 'user', content, images }],
                 provider,
                 model,
                 systemPrompt,
-                sessionKey: this.sessionKey
+                sessionKey: this.sessionKey,
+                incognito: this.elements.incognito.checked
             }),
             signal: this.abortController.signal
         });
@@ -308,6 +382,7 @@ class PicoClawApp {
         const decoder = new TextDecoder();
         let buffer = '';
         let fullResponse = '';
+        let fullReasoning = '';
 
         while (true) {
             const { done, value } = await reader.read();
@@ -326,12 +401,23 @@ class PicoClawApp {
                             throw new Error(data.error);
                         }
                         
+                        if (data.reasoning) {
+                            fullReasoning += data.reasoning;
+                            this.setReasoning(contentDiv, fullReasoning);
+                        }
+
                         if (data.content) {
                             fullResponse += data.content;
                             contentDiv.innerHTML = this.formatMarkdown(fullResponse);
                             this.scrollToBottom();
                         }
                         
+                        if (data.stopped) {
+                            fullResponse = (fullResponse + '\n\n⏹️ Stopped.').trim();
+                            contentDiv.innerHTML = this.formatMarkdown(fullResponse);
+                            this.scrollToBottom();
+                        }
+
                         if (data.done) {
                             this.messages.push({ role: 'assistant', content: fullResponse });
                         }
@@ -347,7 +433,7 @@ class PicoClawApp {
         }
     }
 
-    async nonStreamResponse(provider, model, content, systemPrompt, contentDiv, typingIndicator) {
+    async nonStreamResponse(provider, model, content, images, systemPrompt, contentDiv, typingIndicator) {
         this.updateStatus('Generating response...');
         
         // For non-streaming, we'll collect all chunks and display at once
@@ -357,11 +443,12 @@ class PicoClawApp {
                 'Content-Type': 'application/json',
             },
             body: JSON.stringify({
-                messages: [...this.messages, { role: 'user', content }],
+                messages: [...this.messages, { role: 'user', content, images }],
                 provider,
                 model,
                 systemPrompt,
-                sessionKey: this.sessionKey
+                sessionKey: this.sessionKey,
+                incognito: this.elements.incognito.checked
             })
         });
 
@@ -373,6 +460,7 @@ class PicoClawApp {
         const decoder = new TextDecoder();
         let buffer = '';
         let fullResponse = '';
+        let fullReasoning = '';
 
         while ( true) {
             const { done, value } = await reader.read();
@@ -394,12 +482,21 @@ class PicoClawApp {
                         if (data.content) {
                             fullResponse += data.content;
                         }
+
+                        if (data.reasoning) {
+                            fullReasoning += data.reasoning;
+                        }
+
+                        if (data.stopped) {
+                            fullResponse = (fullResponse + '\n\n⏹️ Stopped.').trim();
+                        }
                         
                         if (data.done) {
                             if (typingIndicator) {
                                 typingIndicator.remove();
                             }
                             contentDiv.innerHTML = this.formatMarkdown(fullResponse);
+                            this.setReasoning(contentDiv, fullReasoning);
                             this.scrollToBottom();
                             this.messages.push({ role: 'assistant', content: fullResponse });
                         }
@@ -415,7 +512,7 @@ class PicoClawApp {
         }
     }
 
-    addMessage(role, content, scroll = true) {
+    addMessage(role, content, scroll = true, reasoning = '') {
         // Remove welcome message if exists
         const welcome = this.elements.chat.querySelector('.welcome-message');
         if (welcome) {
@@ -434,7 +531,16 @@ class PicoClawApp {
         iconDiv.textContent = role === 'user' ? '👤' : '🦞';
         
         messageDiv.appendChild(iconDiv);
-        messageDiv.appendChild(contentDiv);
+        if (role === 'assistant') {
+            // Reasoning sits above the answer in the same column
+            const bodyDiv = document.createElement('div');
+            bodyDiv.className = 'message-body';
+            bodyDiv.appendChild(contentDiv);
+            messageDiv.appendChild(bodyDiv);
+            this.setReasoning(contentDiv, reasoning);
+        } else {
+            messageDiv.appendChild(contentDiv);
+        }
         this.elements.chat.appendChild(messageDiv);
         
         if (scroll) {
@@ -444,6 +550,30 @@ class PicoClawApp {
         return messageDiv;
     }
 
+    // setReasoning shows the model's thinking in a collapsed block before
+    // the answer. The blocks are only visible with "Show reasoning" on.
+    setReasoning(contentDiv, reasoning) {
+        if (!reasoning) return;
+
+        let details = contentDiv.parentElement.querySelector('.message-reasoning');
+        if (!details) {
+            details = document.createElement('details');
+            details.className = 'message-reasoning';
+            const summary = document.createElement('summary');
+            summary.textContent = 'Reasoning';
+            const body = document.createElement('div');
+            body.className = 'reasoning-text';
+            details.appendChild(summary);
+            details.appendChild(body);
+            contentDiv.parentElement.insertBefore(details, contentDiv);
+        }
+        details.querySelector('.reasoning-text').textContent = reasoning.trim();
+    }
+
+    applyReasoningVisibility() {
+        this.elements.chat.classList.toggle('show-reasoning', this.elements.showReasoning.checked);
+    }
+
     showTypingIndicator(container) {
         const indicator = document.createElement('div');
         indicator.className = 'typing-indicator';
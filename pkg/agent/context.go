@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,6 +13,7 @@ import (
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/skills"
 	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
 type ContextBuilder struct {
@@ -48,13 +50,21 @@ func (cb *ContextBuilder) SetToolsRegistry(registry *tools.ToolRegistry) {
 	cb.tools = registry
 }
 
-func (cb *ContextBuilder) getIdentity() string {
+type withoutToolsKey struct{}
+
+// withoutTools marks ctx so that the system prompt built for it lists no
+// tools, for models that can't call them.
+func withoutTools(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withoutToolsKey{}, true)
+}
+
+func (cb *ContextBuilder) getIdentity(ctx context.Context) string {
 	now := time.Now().Format("2006-01-02 15:04 (Monday)")
 	workspacePath, _ := filepath.Abs(filepath.Join(cb.workspace))
 	runtime := fmt.Sprintf("%s %s, Go %s", runtime.GOOS, runtime.GOARCH, runtime.Version())
 
 	// Build tools section dynamically
-	toolsSection := cb.buildToolsSection()
+	toolsSection := cb.buildToolsSection(ctx)
 
 	return fmt.Sprintf(`# picoclaw 🦞
 
@@ -84,12 +94,13 @@ Your workspace is at: %s
 		now, runtime, workspacePath, workspacePath, workspacePath, workspacePath, toolsSection, workspacePath)
 }
 
-func (cb *ContextBuilder) buildToolsSection() string {
-	if cb.tools == nil {
+// buildToolsSection lists the tools the caller in ctx may use.
+func (cb *ContextBuilder) buildToolsSection(ctx context.Context) string {
+	if cb.tools == nil || ctx.Value(withoutToolsKey{}) != nil {
 		return ""
 	}
 
-	summaries := cb.tools.GetSummaries()
+	summaries := cb.tools.GetSummaries(ctx)
 	if len(summaries) == 0 {
 		return ""
 	}
@@ -106,11 +117,11 @@ func (cb *ContextBuilder) buildToolsSection() string {
 	return sb.String()
 }
 
-func (cb *ContextBuilder) BuildSystemPrompt() string {
+func (cb *ContextBuilder) BuildSystemPrompt(ctx context.Context) string {
 	parts := []string{}
 
 	// Core identity section
-	parts = append(parts, cb.getIdentity())
+	parts = append(parts, cb.getIdentity(ctx))
 
 	// Bootstrap files
 	bootstrapContent := cb.LoadBootstrapFiles()
@@ -157,10 +168,10 @@ func (cb *ContextBuilder) LoadBootstrapFiles() string {
 	return result
 }
 
-func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary string, currentMessage string, media []string, channel, chatID string) []providers.Message {
+func (cb *ContextBuilder) BuildMessages(ctx context.Context, history []providers.Message, summary string, currentMessage string, media []string, channel, chatID string) []providers.Message {
 	messages := []providers.Message{}
 
-	systemPrompt := cb.BuildSystemPrompt()
+	systemPrompt := cb.BuildSystemPrompt(ctx)
 
 	// Add Current Session info if provided
 	if channel != "" && chatID != "" {
@@ -207,14 +218,52 @@ func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary str
 
 	messages = append(messages, history...)
 
-	messages = append(messages, providers.Message{
+	userMessage := providers.Message{
 		Role:    "user",
 		Content: currentMessage,
-	})
+	}
+	if images := loadImageParts(media); len(images) > 0 {
+		userMessage.Parts = append([]providers.ContentPart{providers.TextPart(currentMessage)}, images...)
+	}
+	messages = append(messages, userMessage)
 
 	return messages
 }
 
+// loadImageParts turns the image attachments of an inbound message into
+// content parts. Media may be local paths or URLs; anything that isn't a
+// readable image is skipped.
+func loadImageParts(media []string) []providers.ContentPart {
+	var parts []providers.ContentPart
+	for _, m := range media {
+		path := m
+		if strings.HasPrefix(m, "http://") || strings.HasPrefix(m, "https://") {
+			if !utils.IsImageFile(strings.SplitN(m, "?", 2)[0], "") {
+				continue
+			}
+			path = utils.DownloadFile(m, filepath.Base(strings.SplitN(m, "?", 2)[0]), utils.DownloadOptions{
+				LoggerPrefix: "agent",
+			})
+			if path == "" {
+				continue
+			}
+			defer os.Remove(path)
+		}
+
+		part, err := providers.ImagePartFromFile(path)
+		if err != nil {
+			logger.DebugCF("agent", "Skipping media attachment",
+				map[string]interface{}{
+					"media": m,
+					"error": err.Error(),
+				})
+			continue
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
 func (cb *ContextBuilder) AddToolResult(messages []providers.Message, toolCallID, toolName, result string) []providers.Message {
 	messages = append(messages, providers.Message{
 		Role:       "tool",
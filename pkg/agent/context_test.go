@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildMessages_AttachesImages(t *testing.T) {
+	workspace := t.TempDir()
+	cb := NewContextBuilder(workspace)
+
+	png, _ := base64.StdEncoding.DecodeString("iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAQAAAC1HAwCAAAAC0lEQVR42mNkYAAAAAYAAjCB0C8AAAAASUVORK5CYII=")
+	photo := filepath.Join(workspace, "photo.jpg")
+	os.WriteFile(photo, png, 0644)
+	voice := filepath.Join(workspace, "voice.ogg")
+	os.WriteFile(voice, []byte("OggS not an image"), 0644)
+
+	messages := cb.BuildMessages(context.Background(), nil, "", "Describe this", []string{photo, voice}, "telegram", "42")
+	user := messages[len(messages)-1]
+
+	if user.Content != "Describe this" {
+		t.Errorf("Content = %q, want text kept for non-vision providers", user.Content)
+	}
+	if len(user.Parts) != 2 {
+		t.Fatalf("len(Parts) = %d, want text + 1 image", len(user.Parts))
+	}
+	if user.Parts[1].Type != "image" || user.Parts[1].MimeType != "image/png" {
+		t.Errorf("Parts[1] = %s/%s, want image/png", user.Parts[1].Type, user.Parts[1].MimeType)
+	}
+
+	plain := cb.BuildMessages(context.Background(), nil, "", "Hello", nil, "telegram", "42")
+	if len(plain[len(plain)-1].Parts) != 0 {
+		t.Error("message without media should have no parts")
+	}
+}
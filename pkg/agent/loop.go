@@ -9,6 +9,7 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -18,46 +19,90 @@ import (
 	"time"
 	"unicode/utf8"
 
+	"github.com/sipeed/picoclaw/pkg/approval"
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/channels"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/constants"
+	"github.com/sipeed/picoclaw/pkg/egress"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/session"
 	"github.com/sipeed/picoclaw/pkg/state"
+	"github.com/sipeed/picoclaw/pkg/toolpolicy"
 	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/usage"
 	"github.com/sipeed/picoclaw/pkg/utils"
+	"github.com/sipeed/picoclaw/pkg/vault"
 )
 
 type AgentLoop struct {
 	bus            *bus.MessageBus
 	provider       providers.LLMProvider
+	providerName   string // Configured provider; a fallback chain reports the backend that answered
 	workspace      string
-	model          string
-	contextWindow  int // Maximum context window size in tokens
+	modelMu        sync.RWMutex
+	model          string // Guarded by modelMu; /switch changes it while sessions run
+	contextWindow  int    // Context window override from config; 0 = ask the provider
+	modelInfoMu    sync.Mutex
+	modelInfo      map[string]modelInfoEntry // Keyed by model; guarded by modelInfoMu
 	maxIterations  int
+	maxParallel    int // Tool calls of one response run at once
 	sessions       *session.SessionManager
 	state          *state.Manager
+	usage          *usage.Tracker
 	contextBuilder *ContextBuilder
 	tools          *tools.ToolRegistry
+	approvals      *approval.Approvals
 	running        atomic.Bool
 	summarizing    sync.Map // Tracks which sessions are currently being summarized
 	channelManager *channels.Manager
+
+	incognitoSessions *session.SessionManager // In-memory only, never saved
+	incognitoChannels map[string]bool         // From privacy.incognito_channels
+
+	// Inbound messages are processed by one worker per session, at most
+	// len(slots) sessions at a time (see queue.go)
+	workersMu sync.Mutex
+	workers   map[string]*sessionQueue
+	slots     chan struct{}
+	queued    atomic.Int64
+	active    atomic.Int64
+
+	runs sync.Map // Session key → *activeRun, for /stop (see stop.go)
 }
 
 // processOptions configures how a message is processed
 type processOptions struct {
-	SessionKey      string // Session identifier for history/context
-	Channel         string // Target channel for tool execution
-	ChatID          string // Target chat ID for tool execution
-	UserMessage     string // User message content (may include prefix)
-	DefaultResponse string // Response when LLM returns empty
-	EnableSummary   bool   // Whether to trigger summarization
-	SendResponse    bool   // Whether to send response via bus
-	NoHistory       bool   // If true, don't load session history (for heartbeat)
+	SessionKey      string             // Session identifier for history/context
+	Channel         string             // Target channel for tool execution
+	ChatID          string             // Target chat ID for tool execution
+	SenderID        string             // Sender of the message, for tool policies
+	Group           string             // Server or group of the chat, for tool policies
+	UserMessage     string             // User message content (may include prefix)
+	Media           []string           // Attachments of the user message (local paths or URLs)
+	DefaultResponse string             // Response when LLM returns empty
+	EnableSummary   bool               // Whether to trigger summarization
+	SendResponse    bool               // Whether to send response via bus
+	NoHistory       bool               // If true, don't load session history (for heartbeat)
+	OnDelta         func(delta string) // Receives content tokens as they stream (nil = no streaming)
+	Incognito       bool               // Keep the conversation in memory and its content out of logs
+}
+
+// incognitoSessionKey stands in for the session key of incognito
+// conversations in usage records and the network audit log.
+const incognitoSessionKey = "incognito"
+
+// modelInfoEntry is what the provider said about a model, and when.
+type modelInfoEntry struct {
+	info providers.ModelInfo
+	at   time.Time
 }
 
+// modelInfoRetry is how long capabilities guessed after a failed query of
+// the provider are used before it is asked again.
+const modelInfoRetry = time.Minute
+
 // createToolRegistry creates a tool registry with common tools.
 // This is shared between main agent and subagents.
 func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msgBus *bus.MessageBus) *tools.ToolRegistry {
@@ -70,8 +115,11 @@ func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msg
 	registry.Register(tools.NewEditFileTool(workspace, restrict))
 	registry.Register(tools.NewAppendFileTool(workspace, restrict))
 
-	// Shell execution
-	registry.Register(tools.NewExecTool(workspace, restrict))
+	// Shell execution and web_fetch can use secrets from the vault
+	secrets := vault.New(vault.DefaultPath())
+	execTool := tools.NewExecTool(workspace, restrict)
+	execTool.SetVault(secrets)
+	registry.Register(execTool)
 
 	if searchTool := tools.NewWebSearchTool(tools.WebSearchToolOptions{
 		BraveAPIKey:          cfg.Tools.Web.Brave.APIKey,
@@ -82,7 +130,9 @@ func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msg
 	}); searchTool != nil {
 		registry.Register(searchTool)
 	}
-	registry.Register(tools.NewWebFetchTool(50000))
+	fetchTool := tools.NewWebFetchTool(50000)
+	fetchTool.SetVault(secrets)
+	registry.Register(fetchTool)
 
 	// Hardware tools (I2C, SPI) - Linux only, returns error on other platforms
 	registry.Register(tools.NewI2CTool())
@@ -110,12 +160,32 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 
 	restrict := cfg.Agents.Defaults.RestrictToWorkspace
 
+	// Track token usage and stop cloud calls once a daily cap is reached.
+	// Wrap before handing the provider to subagents so they are capped too.
+	usageTracker := usage.NewTracker(workspace, cfg.Usage)
+	provider = providers.WithCloudBudget(provider, usageTracker)
+
 	// Create tool registry for main agent
 	toolsRegistry := createToolRegistry(workspace, restrict, cfg, msgBus)
 
 	// Create subagent manager with its own tool registry
 	subagentManager := tools.NewSubagentManager(provider, cfg.Agents.Defaults.Model, workspace, msgBus)
+	subagentManager.SetContextWindow(cfg.Agents.Defaults.ContextWindow)
 	subagentTools := createToolRegistry(workspace, restrict, cfg, msgBus)
+
+	// Calls chosen in tools.approval wait for the user, including those
+	// made by subagents
+	approvals := approval.New(cfg.Tools.Approval, msgBus)
+	if approvals.Enabled() {
+		toolsRegistry.SetApprover(approvals)
+		subagentTools.SetApprover(approvals)
+	}
+	// The limits of tools.policies apply to subagents as well
+	if len(cfg.Tools.Policies) > 0 {
+		policies := toolpolicy.New(cfg.Tools.Policies)
+		toolsRegistry.SetPolicy(policies)
+		subagentTools.SetPolicy(policies)
+	}
 	// Subagent doesn't need spawn/subagent tools to avoid recursion
 	subagentManager.SetTools(subagentTools)
 
@@ -136,21 +206,50 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 	contextBuilder := NewContextBuilder(workspace)
 	contextBuilder.SetToolsRegistry(toolsRegistry)
 
+	maxConcurrent := cfg.Agents.Defaults.MaxConcurrentSessions
+	if maxConcurrent <= 0 {
+		maxConcurrent = defaultMaxConcurrentSessions
+	}
+
+	maxParallel := cfg.Agents.Defaults.MaxParallelTools
+	if maxParallel <= 0 {
+		maxParallel = defaultMaxParallelTools
+	}
+
+	incognitoChannels := make(map[string]bool)
+	for _, ch := range cfg.Privacy.IncognitoChannels {
+		incognitoChannels[ch] = true
+		msgBus.SetIncognitoChannel(ch)
+	}
+
 	return &AgentLoop{
 		bus:            msgBus,
 		provider:       provider,
 		workspace:      workspace,
 		model:          cfg.Agents.Defaults.Model,
-		contextWindow:  cfg.Agents.Defaults.MaxTokens, // Restore context window for summarization
+		contextWindow:  cfg.Agents.Defaults.ContextWindow,
+		providerName:   cfg.Agents.Defaults.Provider,
 		maxIterations:  cfg.Agents.Defaults.MaxToolIterations,
+		maxParallel:    maxParallel,
 		sessions:       sessionsManager,
 		state:          stateManager,
+		usage:          usageTracker,
 		contextBuilder: contextBuilder,
 		tools:          toolsRegistry,
+		approvals:      approvals,
 		summarizing:    sync.Map{},
+
+		incognitoSessions: session.NewSessionManager(""),
+		incognitoChannels: incognitoChannels,
+
+		workers: make(map[string]*sessionQueue),
+		slots:   make(chan struct{}, maxConcurrent),
 	}
 }
 
+// Run consumes inbound messages until ctx is done or Stop is called. Each
+// message is handed to its session's worker, so one long conversation
+// doesn't hold up the others.
 func (al *AgentLoop) Run(ctx context.Context) error {
 	al.running.Store(true)
 
@@ -163,36 +262,32 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 			if !ok {
 				continue
 			}
-
-			response, err := al.processMessage(ctx, msg)
-			if err != nil {
-				response = fmt.Sprintf("Error processing message: %v", err)
-			}
-
-			if response != "" {
-				// Check if the message tool already sent a response during this round.
-				// If so, skip publishing to avoid duplicate messages to the user.
-				alreadySent := false
-				if tool, ok := al.tools.Get("message"); ok {
-					if mt, ok := tool.(*tools.MessageTool); ok {
-						alreadySent = mt.HasSentInRound()
-					}
-				}
-
-				if !alreadySent {
-					al.bus.PublishOutbound(bus.OutboundMessage{
-						Channel: msg.Channel,
-						ChatID:  msg.ChatID,
-						Content: response,
-					})
-				}
-			}
+			al.dispatch(ctx, msg)
 		}
 	}
 
 	return nil
 }
 
+// handleInbound processes one message from the bus and publishes the reply.
+func (al *AgentLoop) handleInbound(ctx context.Context, msg bus.InboundMessage) {
+	ctx, round := tools.WithRound(ctx)
+	response, err := al.processMessage(ctx, msg)
+	if err != nil {
+		response = fmt.Sprintf("Error processing message: %v", err)
+	}
+
+	// Skip the reply if the message tool already sent one during this round,
+	// to avoid duplicate messages to the user
+	if response != "" && !round.MessageSent() {
+		al.bus.PublishOutbound(bus.OutboundMessage{
+			Channel: msg.Channel,
+			ChatID:  msg.ChatID,
+			Content: response,
+		})
+	}
+}
+
 func (al *AgentLoop) Stop() {
 	al.running.Store(false)
 }
@@ -201,6 +296,20 @@ func (al *AgentLoop) RegisterTool(tool tools.Tool) {
 	al.tools.Register(tool)
 }
 
+// ExecuteTool runs a tool outside a conversation, such as the command of a
+// scheduled job, for channel and chatID. The call needs the same approval
+// and passes the same policies as one the model makes there.
+func (al *AgentLoop) ExecuteTool(ctx context.Context, name string, args map[string]interface{}, channel, chatID string) *tools.ToolResult {
+	return al.tools.ExecuteWithContext(ctx, name, args, channel, chatID, nil)
+}
+
+// Approvals returns the policy for tool calls that need the user's
+// approval, for callers that can ask the user directly (see
+// approval.Approvals.SetPrompt).
+func (al *AgentLoop) Approvals() *approval.Approvals {
+	return al.approvals
+}
+
 func (al *AgentLoop) SetChannelManager(cm *channels.Manager) {
 	al.channelManager = cm
 }
@@ -217,22 +326,61 @@ func (al *AgentLoop) RecordLastChatID(chatID string) error {
 	return al.state.SetLastChatID(chatID)
 }
 
+// CLISenderID is the sender of messages typed into `picoclaw agent`, for
+// tools.policies on the cli channel.
+const CLISenderID = "local"
+
+// ProcessDirect processes a message typed into the CLI. It is from the
+// sender of the caller in ctx, or from CLISenderID if there is none.
 func (al *AgentLoop) ProcessDirect(ctx context.Context, content, sessionKey string) (string, error) {
+	if tools.CallerFrom(ctx).SenderID == "" {
+		ctx = tools.WithCaller(ctx, tools.Caller{Channel: "cli", ChatID: "direct", SenderID: CLISenderID})
+	}
 	return al.ProcessDirectWithChannel(ctx, content, sessionKey, "cli", "direct")
 }
 
+// ProcessDirectWithChannel processes content for channel and chatID, as
+// scheduled jobs do. The message is from the sender of the caller in ctx
+// (see tools.WithCaller), or from "cron" if there is none.
 func (al *AgentLoop) ProcessDirectWithChannel(ctx context.Context, content, sessionKey, channel, chatID string) (string, error) {
+	caller := tools.CallerFrom(ctx)
+	senderID := caller.SenderID
+	if senderID == "" {
+		senderID = "cron"
+	}
 	msg := bus.InboundMessage{
 		Channel:    channel,
-		SenderID:   "cron",
+		SenderID:   senderID,
 		ChatID:     chatID,
 		Content:    content,
 		SessionKey: sessionKey,
+		Metadata:   map[string]string{"guild_id": caller.Group},
 	}
 
 	return al.processMessage(ctx, msg)
 }
 
+// ProcessDirectStream is like ProcessDirect but forwards response tokens to
+// onDelta as they are generated, when the provider supports streaming.
+// The full final response is still returned.
+func (al *AgentLoop) ProcessDirectStream(ctx context.Context, content, sessionKey string, onDelta func(delta string)) (string, error) {
+	caller := tools.CallerFrom(ctx)
+	senderID := caller.SenderID
+	if senderID == "" {
+		senderID = CLISenderID
+	}
+	msg := bus.InboundMessage{
+		Channel:    "cli",
+		SenderID:   senderID,
+		ChatID:     "direct",
+		Content:    content,
+		SessionKey: sessionKey,
+		Metadata:   map[string]string{"guild_id": caller.Group},
+	}
+
+	return al.processMessageStream(ctx, msg, onDelta)
+}
+
 // ProcessHeartbeat processes a heartbeat request without session history.
 // Each heartbeat is independent and doesn't accumulate context.
 func (al *AgentLoop) ProcessHeartbeat(ctx context.Context, content, channel, chatID string) (string, error) {
@@ -249,9 +397,17 @@ func (al *AgentLoop) ProcessHeartbeat(ctx context.Context, content, channel, cha
 }
 
 func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage) (string, error) {
+	return al.processMessageStream(ctx, msg, nil)
+}
+
+func (al *AgentLoop) processMessageStream(ctx context.Context, msg bus.InboundMessage, onDelta func(delta string)) (string, error) {
+	incognito := al.isIncognito(msg.SessionKey, msg.Channel)
+
 	// Add message preview to log (show full content for error messages)
 	var logContent string
-	if strings.Contains(msg.Content, "Error:") || strings.Contains(msg.Content, "error") {
+	if incognito {
+		logContent = "(incognito)"
+	} else if strings.Contains(msg.Content, "Error:") || strings.Contains(msg.Content, "error") {
 		logContent = msg.Content // Full content for errors
 	} else {
 		logContent = utils.Truncate(msg.Content, 80)
@@ -269,6 +425,9 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 		return al.processSystemMessage(ctx, msg)
 	}
 
+	// Downloaded attachments are ours to clean up once the message is handled
+	defer utils.ReleaseMedia(msg.Media)
+
 	// Check for commands
 	if response, handled := al.handleCommand(ctx, msg); handled {
 		return response, nil
@@ -279,10 +438,15 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 		SessionKey:      msg.SessionKey,
 		Channel:         msg.Channel,
 		ChatID:          msg.ChatID,
+		SenderID:        msg.SenderID,
+		Group:           msg.Metadata["guild_id"],
 		UserMessage:     msg.Content,
+		Media:           msg.Media,
 		DefaultResponse: "I've completed processing but have no response to give.",
-		EnableSummary:   true,
+		EnableSummary:   !incognito,
 		SendResponse:    false,
+		OnDelta:         onDelta,
+		Incognito:       incognito,
 	})
 }
 
@@ -341,8 +505,30 @@ func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMe
 // runAgentLoop is the core message processing logic.
 // It handles context building, LLM calls, tool execution, and response handling.
 func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (string, error) {
-	// 0. Record last channel for heartbeat notifications (skip internal channels)
-	if opts.Channel != "" && opts.ChatID != "" {
+	// Tag outbound traffic (LLM calls, web tools) with the session for the network audit log
+	if opts.Incognito {
+		ctx = egress.WithSession(ctx, incognitoSessionKey)
+		ctx = tools.WithIncognito(ctx)
+	} else {
+		ctx = egress.WithSession(ctx, opts.SessionKey)
+	}
+	sessions := al.sessionsFor(opts)
+
+	// Tool policies are checked against the sender of the message
+	ctx = tools.WithCaller(ctx, tools.Caller{
+		Channel:  opts.Channel,
+		ChatID:   opts.ChatID,
+		SenderID: opts.SenderID,
+		Group:    opts.Group,
+	})
+
+	// The run can be stopped with /stop until it returns
+	ctx, done := al.startRun(ctx, opts.SessionKey)
+	defer done()
+
+	// 0. Record last channel for heartbeat notifications (skip internal channels
+	// and incognito conversations)
+	if opts.Channel != "" && opts.ChatID != "" && !opts.Incognito {
 		// Don't record internal channels (cli, system, subagent)
 		if !constants.IsInternalChannel(opts.Channel) {
 			channelKey := fmt.Sprintf("%s:%s", opts.Channel, opts.ChatID)
@@ -352,52 +538,77 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 		}
 	}
 
-	// 1. Update tool contexts
-	al.updateToolContexts(opts.Channel, opts.ChatID)
-
-	// 2. Build messages (skip history for heartbeat)
+	// 1. Build messages (skip history for heartbeat)
 	var history []providers.Message
 	var summary string
 	if !opts.NoHistory {
-		history = al.sessions.GetHistory(opts.SessionKey)
-		summary = al.sessions.GetSummary(opts.SessionKey)
+		history = sessions.GetHistory(opts.SessionKey)
+		summary = sessions.GetSummary(opts.SessionKey)
+	}
+	caps := al.capabilities()
+	if !caps.Tools {
+		// Don't advertise tools in the system prompt that can't be called
+		ctx = withoutTools(ctx)
+	}
+	media := opts.Media
+	if len(media) > 0 && !caps.Vision {
+		logger.InfoCF("agent", "Model has no vision support, not attaching images",
+			map[string]interface{}{
+				"model": al.currentModel(),
+				"media": len(media),
+			})
+		media = nil
 	}
 	messages := al.contextBuilder.BuildMessages(
+		ctx,
 		history,
 		summary,
 		opts.UserMessage,
-		nil,
+		media,
 		opts.Channel,
 		opts.ChatID,
 	)
 
-	// 3. Save user message to session
-	al.sessions.AddMessage(opts.SessionKey, "user", opts.UserMessage)
+	// 2. Save user message to session
+	sessions.AddMessage(opts.SessionKey, "user", opts.UserMessage)
 
-	// 4. Run LLM iteration loop
-	finalContent, iteration, err := al.runLLMIteration(ctx, messages, opts)
+	// 3. Run LLM iteration loop
+	finalContent, reasoning, iteration, err := al.runLLMIteration(ctx, messages, opts)
 	if err != nil {
-		return "", err
+		if !wasStopped(ctx) {
+			return "", err
+		}
+		// Close the turn so the next message continues from a coherent history
+		logger.InfoCF("agent", "Run stopped by user",
+			map[string]interface{}{
+				"session_key": opts.SessionKey,
+				"iteration":   iteration,
+			})
+		finalContent, reasoning = stoppedResponse, ""
 	}
 
 	// If last tool had ForUser content and we already sent it, we might not need to send final response
 	// This is controlled by the tool's Silent flag and ForUser content
 
-	// 5. Handle empty response
+	// 4. Handle empty response
 	if finalContent == "" {
 		finalContent = opts.DefaultResponse
 	}
 
-	// 6. Save final assistant message to session
-	al.sessions.AddMessage(opts.SessionKey, "assistant", finalContent)
-	al.sessions.Save(opts.SessionKey)
+	// 5. Save final assistant message to session
+	sessions.AddFullMessage(opts.SessionKey, providers.Message{
+		Role:      "assistant",
+		Content:   finalContent,
+		Reasoning: reasoning,
+	})
+	sessions.Save(opts.SessionKey)
 
-	// 7. Optional: summarization
+	// 6. Optional: summarization
 	if opts.EnableSummary {
 		al.maybeSummarize(opts.SessionKey, opts.Channel, opts.ChatID)
 	}
 
-	// 8. Optional: send response via bus
+	// 7. Optional: send response via bus
 	if opts.SendResponse {
 		al.bus.PublishOutbound(bus.OutboundMessage{
 			Channel: opts.Channel,
@@ -406,8 +617,11 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 		})
 	}
 
-	// 9. Log response
+	// 8. Log response
 	responsePreview := utils.Truncate(finalContent, 120)
+	if opts.Incognito {
+		responsePreview = "(incognito)"
+	}
 	logger.InfoCF("agent", fmt.Sprintf("Response: %s", responsePreview),
 		map[string]interface{}{
 			"session_key":  opts.SessionKey,
@@ -419,12 +633,17 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 }
 
 // runLLMIteration executes the LLM call loop with tool handling.
-// Returns the final content, iteration count, and any error.
-func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.Message, opts processOptions) (string, int, error) {
+// Returns the final content, the model's reasoning for it, iteration count,
+// and any error.
+func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.Message, opts processOptions) (string, string, int, error) {
 	iteration := 0
-	var finalContent string
+	var finalContent, finalReasoning string
+	sessions := al.sessionsFor(opts)
 
 	for iteration < al.maxIterations {
+		if err := ctx.Err(); err != nil {
+			return "", "", iteration, err
+		}
 		iteration++
 
 		logger.DebugCF("agent", "LLM iteration",
@@ -433,14 +652,17 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 				"max":       al.maxIterations,
 			})
 
-		// Build tool definitions
-		providerToolDefs := al.tools.ToProviderDefs()
+		// Build tool definitions, unless the model can't call them
+		var providerToolDefs []providers.ToolDefinition
+		if al.capabilities().Tools {
+			providerToolDefs = al.tools.ToProviderDefs(ctx)
+		}
 
 		// Log LLM request details
 		logger.DebugCF("agent", "LLM request",
 			map[string]interface{}{
 				"iteration":         iteration,
-				"model":             al.model,
+				"model":             al.currentModel(),
 				"messages_count":    len(messages),
 				"tools_count":       len(providerToolDefs),
 				"max_tokens":        8192,
@@ -449,36 +671,53 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 			})
 
 		// Log full messages (detailed)
-		logger.DebugCF("agent", "Full LLM request",
-			map[string]interface{}{
-				"iteration":     iteration,
-				"messages_json": formatMessagesForLog(messages),
-				"tools_json":    formatToolsForLog(providerToolDefs),
-			})
+		if !opts.Incognito {
+			logger.DebugCF("agent", "Full LLM request",
+				map[string]interface{}{
+					"iteration":     iteration,
+					"messages_json": formatMessagesForLog(messages),
+					"tools_json":    formatToolsForLog(providerToolDefs),
+				})
+		}
 
 		var response *providers.LLMResponse
 		var err error
 
-		// Retry loop for context/token errors
+		// Retry loop: compress on context overflow, back off on rate limits
+		// and transient failures, give up on anything else
 		maxRetries := 2
 		for retry := 0; retry <= maxRetries; retry++ {
-			response, err = al.provider.Chat(ctx, messages, providerToolDefs, al.model, map[string]interface{}{
-				"max_tokens":  8192,
-				"temperature": 0.7,
-			})
+			response, err = al.callLLM(ctx, messages, providerToolDefs, opts)
 
 			if err == nil {
 				break // Success
 			}
+			// Providers without status codes, such as the CLI ones, only
+			// have the message to go by
+			err = providers.Classify(err)
+
+			if retry >= maxRetries {
+				break
+			}
 
-			errMsg := strings.ToLower(err.Error())
-			// Check for context window errors (provider specific, but usually contain "token" or "invalid")
-			isContextError := strings.Contains(errMsg, "token") ||
-				strings.Contains(errMsg, "context") ||
-				strings.Contains(errMsg, "invalidparameter") ||
-				strings.Contains(errMsg, "length")
+			// Throttled or flaky backend: wait and try again. Failing over to
+			// another backend already happened inside the provider, if any.
+			if errors.Is(err, providers.ErrRateLimited) || errors.Is(err, providers.ErrTransient) {
+				delay := retryDelay(err, retry)
+				logger.WarnCF("agent", "LLM call failed, backing off before retry", map[string]interface{}{
+					"error": err.Error(),
+					"retry": retry,
+					"delay": delay.String(),
+				})
+				select {
+				case <-time.After(delay):
+					continue
+				case <-ctx.Done():
+					return "", "", iteration, ctx.Err()
+				}
+			}
 
-			if isContextError && retry < maxRetries {
+			if errors.Is(err, providers.ErrContextOverflow) {
 				logger.WarnCF("agent", "Context window error detected, attempting compression", map[string]interface{}{
 					"error": err.Error(),
 					"retry": retry,
@@ -494,16 +733,17 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 				}
 
 				// Force compression
-				al.forceCompression(opts.SessionKey)
+				al.forceCompression(sessions, opts.SessionKey)
 
 				// Rebuild messages with compressed history
 				// Note: We need to reload history from session manager because forceCompression changed it
-				newHistory := al.sessions.GetHistory(opts.SessionKey)
-				newSummary := al.sessions.GetSummary(opts.SessionKey)
+				newHistory := sessions.GetHistory(opts.SessionKey)
+				newSummary := sessions.GetSummary(opts.SessionKey)
 
 				// Re-create messages for the next attempt
 				// We keep the current user message (opts.UserMessage) effectively
 				messages = al.contextBuilder.BuildMessages(
+					ctx,
 					newHistory,
 					newSummary,
 					opts.UserMessage,
@@ -547,8 +787,8 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 				// If we rebuild from Session, we need to know if "currentMessage" should be appended or is already in history.
 
 				// In runAgentLoop:
-				// 3. sessions.AddMessage(userMsg)
-				// 4. runLLMIteration(..., UserMessage)
+				// 2. sessions.AddMessage(userMsg)
+				// 3. runLLMIteration(..., UserMessage)
 
 				// So History contains the user message.
 				// BuildMessages typically appends the user message as a *new* pending message.
@@ -567,6 +807,7 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 				// because the "current message" is already saved in history (step 3).
 
 				messages = al.contextBuilder.BuildMessages(
+					ctx,
 					newHistory,
 					newSummary,
 					"", // Empty because history already contains the relevant messages
@@ -582,18 +823,28 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 			break
 		}
 
+		if err != nil && ctx.Err() != nil {
+			return "", "", iteration, ctx.Err()
+		}
 		if err != nil {
 			logger.ErrorCF("agent", "LLM call failed",
 				map[string]interface{}{
 					"iteration": iteration,
 					"error":     err.Error(),
 				})
-			return "", iteration, fmt.Errorf("LLM call failed after retries: %w", err)
+			switch {
+			case errors.Is(err, providers.ErrAuth):
+				return "", "", iteration, fmt.Errorf("LLM provider rejected the credentials, check the API key or run picoclaw auth login: %w", err)
+			case errors.Is(err, providers.ErrBudgetExceeded):
+				return "", "", iteration, fmt.Errorf("LLM call blocked: %w", err)
+			}
+			return "", "", iteration, fmt.Errorf("LLM call failed after retries: %w", err)
 		}
 
 		// Check if no tool calls - we're done
 		if len(response.ToolCalls) == 0 {
 			finalContent = response.Content
+			finalReasoning = response.Reasoning
 			logger.InfoCF("agent", "LLM response without tool calls (direct answer)",
 				map[string]interface{}{
 					"iteration":     iteration,
@@ -616,8 +867,9 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 
 		// Build assistant message with tool calls
 		assistantMsg := providers.Message{
-			Role:    "assistant",
-			Content: response.Content,
+			Role:      "assistant",
+			Content:   response.Content,
+			Reasoning: response.Reasoning,
 		}
 		for _, tc := range response.ToolCalls {
 			argumentsJSON, _ := json.Marshal(tc.Arguments)
@@ -633,36 +885,12 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 		messages = append(messages, assistantMsg)
 
 		// Save assistant message with tool calls to session
-		al.sessions.AddFullMessage(opts.SessionKey, assistantMsg)
+		sessions.AddFullMessage(opts.SessionKey, assistantMsg)
 
-		// Execute tool calls
-		for _, tc := range response.ToolCalls {
-			// Log tool call with arguments preview
-			argsJSON, _ := json.Marshal(tc.Arguments)
-			argsPreview := utils.Truncate(string(argsJSON), 200)
-			logger.InfoCF("agent", fmt.Sprintf("Tool call: %s(%s)", tc.Name, argsPreview),
-				map[string]interface{}{
-					"tool":      tc.Name,
-					"iteration": iteration,
-				})
-
-			// Create async callback for tools that implement AsyncTool
-			// NOTE: Following openclaw's design, async tools do NOT send results directly to users.
-			// Instead, they notify the agent via PublishInbound, and the agent decides
-			// whether to forward the result to the user (in processSystemMessage).
-			asyncCallback := func(callbackCtx context.Context, result *tools.ToolResult) {
-				// Log the async completion but don't send directly to user
-				// The agent will handle user notification via processSystemMessage
-				if !result.Silent && result.ForUser != "" {
-					logger.InfoCF("agent", "Async tool completed, agent will handle notification",
-						map[string]interface{}{
-							"tool":        tc.Name,
-							"content_len": len(result.ForUser),
-						})
-				}
-			}
-
-			toolResult := al.tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID, asyncCallback)
+		// Execute tool calls, independent ones in parallel (see toolcalls.go)
+		results := al.runToolCalls(ctx, response.ToolCalls, opts, iteration)
+		for i, tc := range response.ToolCalls {
+			toolResult := results[i]
 
 			// Send ForUser content to user immediately if not Silent
 			if !toolResult.Silent && toolResult.ForUser != "" && opts.SendResponse {
@@ -692,38 +920,170 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 			messages = append(messages, toolResultMsg)
 
 			// Save tool result message to session
-			al.sessions.AddFullMessage(opts.SessionKey, toolResultMsg)
+			sessions.AddFullMessage(opts.SessionKey, toolResultMsg)
 		}
 	}
 
-	return finalContent, iteration, nil
+	if err := ctx.Err(); err != nil && finalContent == "" {
+		return "", "", iteration, err
+	}
+	return finalContent, finalReasoning, iteration, nil
 }
 
-// updateToolContexts updates the context for tools that need channel/chatID info.
-func (al *AgentLoop) updateToolContexts(channel, chatID string) {
-	// Use ContextualTool interface instead of type assertions
-	if tool, ok := al.tools.Get("message"); ok {
-		if mt, ok := tool.(tools.ContextualTool); ok {
-			mt.SetContext(channel, chatID)
+// callLLM sends one request to the provider. When the caller wants streaming
+// and the provider supports it, content tokens are forwarded to opts.OnDelta
+// as they arrive; otherwise this is a plain Chat call.
+func (al *AgentLoop) callLLM(ctx context.Context, messages []providers.Message, toolDefs []providers.ToolDefinition, opts processOptions) (*providers.LLMResponse, error) {
+	options := al.llmOptions(8192, 0.7)
+
+	sp, ok := al.provider.(providers.StreamingProvider)
+	if !ok || opts.OnDelta == nil {
+		resp, err := al.provider.Chat(ctx, messages, toolDefs, al.currentModel(), options)
+		if err != nil {
+			return nil, err
 		}
+		al.recordUsage(opts, resp)
+		return resp, nil
 	}
-	if tool, ok := al.tools.Get("spawn"); ok {
-		if st, ok := tool.(tools.ContextualTool); ok {
-			st.SetContext(channel, chatID)
-		}
+
+	events, err := sp.ChatStream(ctx, messages, toolDefs, al.currentModel(), options)
+	if err != nil {
+		return nil, err
 	}
-	if tool, ok := al.tools.Get("subagent"); ok {
-		if st, ok := tool.(tools.ContextualTool); ok {
-			st.SetContext(channel, chatID)
+
+	for ev := range events {
+		if ev.Err != nil {
+			return nil, ev.Err
+		}
+		if ev.Content != "" {
+			opts.OnDelta(ev.Content)
 		}
+		if ev.Done {
+			al.recordUsage(opts, ev.Response)
+			return ev.Response, nil
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("stream ended without a final response")
+}
+
+// retryBaseDelay is the first backoff delay after a rate limit or transient
+// error; it doubles with each retry.
+var retryBaseDelay = 2 * time.Second
+
+// retryDelay honours a Retry-After from the backend when it is reasonable,
+// otherwise backs off exponentially.
+func retryDelay(err error, retry int) time.Duration {
+	if d := providers.RetryAfter(err); d > 0 && d <= time.Minute {
+		return d
+	}
+	return retryBaseDelay << retry
+}
+
+// recordUsage adds the tokens of one LLM call to the session's usage.
+func (al *AgentLoop) recordUsage(opts processOptions, resp *providers.LLMResponse) {
+	if resp == nil || resp.Usage == nil {
+		return
+	}
+	sessionKey := opts.SessionKey
+	if opts.Incognito {
+		sessionKey = incognitoSessionKey
+	}
+	// After a failover the response names the backend that answered
+	provider, model := resp.Provider, resp.Model
+	if provider == "" {
+		provider = al.providerName
+	}
+	if model == "" {
+		model = al.currentModel()
+	}
+	if err := al.usage.Record(sessionKey, opts.Channel, provider, model, resp.Usage); err != nil {
+		logger.WarnCF("agent", "Failed to record usage",
+			map[string]interface{}{
+				"session_key": opts.SessionKey,
+				"error":       err.Error(),
+			})
+	}
+}
+
+func (al *AgentLoop) currentModel() string {
+	al.modelMu.RLock()
+	defer al.modelMu.RUnlock()
+	return al.model
+}
+
+func (al *AgentLoop) setModel(model string) {
+	al.modelMu.Lock()
+	al.model = model
+	al.modelMu.Unlock()
+}
+
+// capabilities returns what the current model supports. The provider is
+// asked on first use of each model rather than at startup so that an
+// unreachable backend doesn't delay boot. Answers are cached per model;
+// guesses made after a failed query are replaced once modelInfoRetry has
+// passed.
+func (al *AgentLoop) capabilities() providers.ModelInfo {
+	model := al.currentModel()
+
+	al.modelInfoMu.Lock()
+	entry, ok := al.modelInfo[model]
+	al.modelInfoMu.Unlock()
+	// A failed query, e.g. while Ollama is still starting, is retried
+	// rather than kept for good
+	if ok && (!entry.info.Guessed || time.Since(entry.at) < modelInfoRetry) {
+		return entry.info
+	}
+
+	// Not under the lock: the query can take a while, and other sessions
+	// shouldn't wait for it
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	info := providers.GetModelInfo(ctx, al.provider, model)
+	if al.contextWindow > 0 {
+		info.ContextWindow = al.contextWindow
 	}
+
+	al.modelInfoMu.Lock()
+	if al.modelInfo == nil {
+		al.modelInfo = make(map[string]modelInfoEntry)
+	}
+	al.modelInfo[model] = modelInfoEntry{info: info, at: time.Now()}
+	al.modelInfoMu.Unlock()
+
+	logger.InfoCF("agent", "Model capabilities",
+		map[string]interface{}{
+			"model":          model,
+			"context_window": info.ContextWindow,
+			"tools":          info.Tools,
+			"vision":         info.Vision,
+			"guessed":        info.Guessed,
+		})
+	return info
+}
+
+// llmOptions are the options of a request to the model. A configured
+// context window is passed on, so that local servers such as Ollama load
+// the model with it.
+func (al *AgentLoop) llmOptions(maxTokens int, temperature float64) map[string]interface{} {
+	options := map[string]interface{}{
+		"max_tokens":  maxTokens,
+		"temperature": temperature,
+	}
+	if al.contextWindow > 0 {
+		options["context_window"] = al.contextWindow
+	}
+	return options
 }
 
 // maybeSummarize triggers summarization if the session history exceeds thresholds.
 func (al *AgentLoop) maybeSummarize(sessionKey, channel, chatID string) {
 	newHistory := al.sessions.GetHistory(sessionKey)
 	tokenEstimate := al.estimateTokens(newHistory)
-	threshold := al.contextWindow * 75 / 100
+	threshold := al.capabilities().ContextWindow * 75 / 100
 
 	if len(newHistory) > 20 || tokenEstimate > threshold {
 		if _, loading := al.summarizing.LoadOrStore(sessionKey, true); !loading {
@@ -745,8 +1105,8 @@ func (al *AgentLoop) maybeSummarize(sessionKey, channel, chatID string) {
 
 // forceCompression aggressively reduces context when the limit is hit.
 // It drops the oldest 50% of messages (keeping system prompt and last user message).
-func (al *AgentLoop) forceCompression(sessionKey string) {
-	history := al.sessions.GetHistory(sessionKey)
+func (al *AgentLoop) forceCompression(sessions *session.SessionManager, sessionKey string) {
+	history := sessions.GetHistory(sessionKey)
 	if len(history) <= 4 {
 		return
 	}
@@ -793,8 +1153,8 @@ func (al *AgentLoop) forceCompression(sessionKey string) {
 	newHistory = append(newHistory, history[len(history)-1]) // Last message
 
 	// Update session
-	al.sessions.SetHistory(sessionKey, newHistory)
-	al.sessions.Save(sessionKey)
+	sessions.SetHistory(sessionKey, newHistory)
+	sessions.Save(sessionKey)
 
 	logger.WarnCF("agent", "Forced compression executed", map[string]interface{}{
 		"session_key":  sessionKey,
@@ -803,6 +1163,27 @@ func (al *AgentLoop) forceCompression(sessionKey string) {
 	})
 }
 
+// Sessions returns the session store, for services such as the retention
+// janitor that prune it while the agent runs.
+func (al *AgentLoop) Sessions() *session.SessionManager {
+	return al.sessions
+}
+
+// isIncognito reports whether a conversation must stay off disk, because
+// its channel is in privacy.incognito_channels or it was switched with
+// /incognito on.
+func (al *AgentLoop) isIncognito(sessionKey, channel string) bool {
+	return al.bus.IsIncognito(sessionKey, channel)
+}
+
+// sessionsFor returns the session store a conversation is kept in.
+func (al *AgentLoop) sessionsFor(opts processOptions) *session.SessionManager {
+	if opts.Incognito {
+		return al.incognitoSessions
+	}
+	return al.sessions
+}
+
 // GetStartupInfo returns information about loaded tools and skills for logging.
 func (al *AgentLoop) GetStartupInfo() map[string]interface{} {
 	info := make(map[string]interface{})
@@ -846,6 +1227,11 @@ func formatMessagesForLog(messages []providers.Message) string {
 		if msg.ToolCallID != "" {
 			result += fmt.Sprintf("  ToolCallID: %s\n", msg.ToolCallID)
 		}
+		for _, part := range msg.Parts {
+			if part.Type == "image" {
+				result += fmt.Sprintf("  Image: %s, %d bytes\n", part.MimeType, len(part.Data))
+			}
+		}
 		result += "\n"
 	}
 	result += "]"
@@ -873,7 +1259,7 @@ func formatToolsForLog(tools []providers.ToolDefinition) string {
 
 // summarizeSession summarizes the conversation history for a session.
 func (al *AgentLoop) summarizeSession(sessionKey string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	ctx, cancel := context.WithTimeout(egress.WithSession(context.Background(), sessionKey), 120*time.Second)
 	defer cancel()
 
 	history := al.sessions.GetHistory(sessionKey)
@@ -888,7 +1274,7 @@ func (al *AgentLoop) summarizeSession(sessionKey string) {
 
 	// Oversized Message Guard
 	// Skip messages larger than 50% of context window to prevent summarizer overflow
-	maxMessageTokens := al.contextWindow / 2
+	maxMessageTokens := al.capabilities().ContextWindow / 2
 	validMessages := make([]providers.Message, 0)
 	omitted := false
 
@@ -922,10 +1308,7 @@ func (al *AgentLoop) summarizeSession(sessionKey string) {
 
 		// Merge them
 		mergePrompt := fmt.Sprintf("Merge these two conversation summaries into one cohesive summary:\n\n1: %s\n\n2: %s", s1, s2)
-		resp, err := al.provider.Chat(ctx, []providers.Message{{Role: "user", Content: mergePrompt}}, nil, al.model, map[string]interface{}{
-			"max_tokens":  1024,
-			"temperature": 0.3,
-		})
+		resp, err := al.provider.Chat(ctx, []providers.Message{{Role: "user", Content: mergePrompt}}, nil, al.currentModel(), al.llmOptions(1024, 0.3))
 		if err == nil {
 			finalSummary = resp.Content
 		} else {
@@ -957,10 +1340,7 @@ func (al *AgentLoop) summarizeBatch(ctx context.Context, batch []providers.Messa
 		prompt += fmt.Sprintf("%s: %s\n", m.Role, m.Content)
 	}
 
-	response, err := al.provider.Chat(ctx, []providers.Message{{Role: "user", Content: prompt}}, nil, al.model, map[string]interface{}{
-		"max_tokens":  1024,
-		"temperature": 0.3,
-	})
+	response, err := al.provider.Chat(ctx, []providers.Message{{Role: "user", Content: prompt}}, nil, al.currentModel(), al.llmOptions(1024, 0.3))
 	if err != nil {
 		return "", err
 	}
@@ -1000,13 +1380,22 @@ func (al *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage)
 		}
 		switch args[0] {
 		case "model":
-			return fmt.Sprintf("Current model: %s", al.model), true
+			return fmt.Sprintf("Current model: %s", al.currentModel()), true
 		case "channel":
 			return fmt.Sprintf("Current channel: %s", msg.Channel), true
 		default:
 			return fmt.Sprintf("Unknown show target: %s", args[0]), true
 		}
 
+	case "/usage":
+		return al.formatUsage(msg.SessionKey), true
+
+	case "/stop":
+		return al.stopCommand(msg), true
+
+	case "/incognito":
+		return al.switchIncognito(msg, args), true
+
 	case "/list":
 		if len(args) < 1 {
 			return "Usage: /list [models|channels]", true
@@ -1037,8 +1426,8 @@ func (al *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage)
 
 		switch target {
 		case "model":
-			oldModel := al.model
-			al.model = value
+			oldModel := al.currentModel()
+			al.setModel(value)
 			return fmt.Sprintf("Switched model from %s to %s", oldModel, value), true
 		case "channel":
 			// This changes the 'default' channel for some operations, or effectively redirects output?
@@ -1061,3 +1450,48 @@ func (al *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage)
 
 	return "", false
 }
+
+// switchIncognito handles /incognito. Turning it off discards the
+// conversation held in memory.
+func (al *AgentLoop) switchIncognito(msg bus.InboundMessage, args []string) string {
+	if len(args) < 1 {
+		state := "off"
+		if al.isIncognito(msg.SessionKey, msg.Channel) {
+			state = "on"
+		}
+		return fmt.Sprintf("Incognito is %s for this chat. Usage: /incognito [on|off]", state)
+	}
+
+	switch args[0] {
+	case "on":
+		al.bus.SetIncognito(msg.SessionKey, true)
+		return "🕶️ Incognito on: nothing in this chat is saved, and memory is read-only, until /incognito off."
+	case "off":
+		if al.incognitoChannels[msg.Channel] {
+			return fmt.Sprintf("Incognito is always on for %s (privacy.incognito_channels).", msg.Channel)
+		}
+		al.bus.SetIncognito(msg.SessionKey, false)
+		al.incognitoSessions.Delete(msg.SessionKey)
+		return "Incognito off: the incognito conversation was discarded and new messages are saved again."
+	default:
+		return "Usage: /incognito [on|off]"
+	}
+}
+
+// formatUsage describes the token usage of a session and of today.
+func (al *AgentLoop) formatUsage(sessionKey string) string {
+	today := al.usage.Today()
+	limits := al.usage.Limits()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "This session: %s\n", al.usage.Session(sessionKey))
+	fmt.Fprintf(&sb, "Today: %s\n", today.All)
+	fmt.Fprintf(&sb, "Today (cloud): %s", today.Cloud)
+	if limits.DailyTokenLimit > 0 {
+		fmt.Fprintf(&sb, "\nDaily token cap: %d/%d", today.Cloud.TotalTokens, limits.DailyTokenLimit)
+	}
+	if limits.DailyCostLimit > 0 {
+		fmt.Fprintf(&sb, "\nDaily cost cap: $%.2f/$%.2f", today.Cloud.CostUSD, limits.DailyCostLimit)
+	}
+	return sb.String()
+}
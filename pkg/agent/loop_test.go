@@ -2,9 +2,11 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -201,7 +203,7 @@ func TestToolRegistry_ToolRegistration(t *testing.T) {
 	}
 }
 
-// TestToolContext_Updates verifies tool context is updated with channel/chatID
+// TestToolContext_Updates verifies tools see the channel/chatID of the message
 func TestToolContext_Updates(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "agent-test-*")
 	if err != nil {
@@ -221,15 +223,18 @@ func TestToolContext_Updates(t *testing.T) {
 	}
 
 	msgBus := bus.NewMessageBus()
-	provider := &simpleMockProvider{response: "OK"}
-	_ = NewAgentLoop(cfg, msgBus, provider)
+	provider := &toolThenAnswerProvider{tool: "mock_contextual"}
+	al := NewAgentLoop(cfg, msgBus, provider)
 
-	// Verify that ContextualTool interface is defined and can be implemented
-	// This test validates the interface contract exists
 	ctxTool := &mockContextualTool{}
+	al.RegisterTool(ctxTool)
 
-	// Verify the tool implements the interface correctly
-	var _ tools.ContextualTool = ctxTool
+	if _, err := al.ProcessDirectWithChannel(context.Background(), "run the tool", "s", "telegram", "chat-42"); err != nil {
+		t.Fatalf("ProcessDirectWithChannel() error: %v", err)
+	}
+	if ctxTool.lastChannel != "telegram" || ctxTool.lastChatID != "chat-42" {
+		t.Errorf("tool context = %s/%s, want telegram/chat-42", ctxTool.lastChannel, ctxTool.lastChatID)
+	}
 }
 
 // TestToolRegistry_GetDefinitions verifies tool definitions can be retrieved
@@ -396,7 +401,7 @@ func (m *mockCustomTool) Execute(ctx context.Context, args map[string]interface{
 	return tools.SilentResult("Custom tool executed")
 }
 
-// mockContextualTool tracks context updates
+// mockContextualTool records the tool context it was called with
 type mockContextualTool struct {
 	lastChannel string
 	lastChatID  string
@@ -418,14 +423,10 @@ func (m *mockContextualTool) Parameters() map[string]interface{} {
 }
 
 func (m *mockContextualTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	m.lastChannel, m.lastChatID = tools.ToolContext(ctx)
 	return tools.SilentResult("Contextual tool executed")
 }
 
-func (m *mockContextualTool) SetContext(channel, chatID string) {
-	m.lastChannel = channel
-	m.lastChatID = chatID
-}
-
 // testHelper executes a message and returns the response
 type testHelper struct {
 	al *AgentLoop
@@ -573,7 +574,8 @@ func TestAgentLoop_ContextExhaustionRetry(t *testing.T) {
 
 	msgBus := bus.NewMessageBus()
 
-	// Create a provider that fails once with a context error
+	// Create a provider that fails once with a context error. It is a
+	// plain error, as the CLI providers return, not a classified one.
 	contextErr := fmt.Errorf("InvalidParameter: Total tokens of image and text exceed max message tokens")
 	provider := &failFirstMockProvider{
 		failures:    1,
@@ -624,3 +626,483 @@ func TestAgentLoop_ContextExhaustionRetry(t *testing.T) {
 		t.Errorf("Expected history to be compressed (len < 8), got %d", len(finalHistory))
 	}
 }
+
+// TestAgentLoop_ErrorKinds verifies the retry strategy chosen for each provider error kind
+func TestAgentLoop_ErrorKinds(t *testing.T) {
+	oldDelay := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = oldDelay }()
+
+	tests := []struct {
+		name      string
+		err       error
+		wantCalls int
+		wantOK    bool
+	}{
+		{
+			name:      "auth fails immediately",
+			err:       &providers.ProviderError{Kind: providers.ErrAuth, StatusCode: 401, Err: fmt.Errorf("invalid token")},
+			wantCalls: 1,
+		},
+		{
+			name:      "rate limit backs off and retries",
+			err:       &providers.ProviderError{Kind: providers.ErrRateLimited, StatusCode: 429, Err: fmt.Errorf("slow down")},
+			wantCalls: 2,
+			wantOK:    true,
+		},
+		{
+			name:      "transient error retries",
+			err:       &providers.ProviderError{Kind: providers.ErrTransient, Err: fmt.Errorf("connection reset")},
+			wantCalls: 2,
+			wantOK:    true,
+		},
+		{
+			name:      "unclassified error fails immediately",
+			err:       fmt.Errorf("max tokens must be positive"),
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Agents: config.AgentsConfig{
+					Defaults: config.AgentDefaults{
+						Workspace:         t.TempDir(),
+						Model:             "test-model",
+						MaxTokens:         4096,
+						MaxToolIterations: 10,
+					},
+				},
+			}
+			provider := &failFirstMockProvider{failures: 1, failError: tt.err, successResp: "ok"}
+			al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+
+			_, err := al.ProcessDirectWithChannel(context.Background(), "hello", "test-session", "test", "test-chat")
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("error = %v, want success %v", err, tt.wantOK)
+			}
+			if provider.currentCall != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", provider.currentCall, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// capabilityMockProvider reports fixed model capabilities and records the
+// tools it was offered.
+type capabilityMockProvider struct {
+	info      providers.ModelInfo
+	err       error
+	queries   int
+	toolCount int
+	system    string
+}
+
+func (m *capabilityMockProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	m.toolCount = len(tools)
+	if len(messages) > 0 {
+		m.system = messages[0].Content
+	}
+	return &providers.LLMResponse{Content: "ok"}, nil
+}
+
+func (m *capabilityMockProvider) GetDefaultModel() string {
+	return "capability-model"
+}
+
+func (m *capabilityMockProvider) ModelInfo(ctx context.Context, model string) (*providers.ModelInfo, error) {
+	m.queries++
+	if m.err != nil {
+		return nil, m.err
+	}
+	info := m.info
+	return &info, nil
+}
+
+func TestAgentLoop_ModelCapabilities(t *testing.T) {
+	newLoop := func(provider providers.LLMProvider, contextWindow int) *AgentLoop {
+		cfg := &config.Config{
+			Agents: config.AgentsConfig{
+				Defaults: config.AgentDefaults{
+					Workspace:         t.TempDir(),
+					Model:             "test-model",
+					MaxTokens:         4096,
+					MaxToolIterations: 10,
+					ContextWindow:     contextWindow,
+				},
+			},
+		}
+		return NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	}
+
+	withoutTools := &capabilityMockProvider{info: providers.ModelInfo{ContextWindow: 4096}}
+	al := newLoop(withoutTools, 0)
+	if _, err := al.ProcessDirectWithChannel(context.Background(), "hello", "s", "test", "chat"); err != nil {
+		t.Fatalf("ProcessDirect() error: %v", err)
+	}
+	if withoutTools.toolCount != 0 {
+		t.Errorf("tools offered = %d, want 0 for a model without tool support", withoutTools.toolCount)
+	}
+	if strings.Contains(withoutTools.system, "Available Tools") {
+		t.Error("system prompt lists tools the model can't call")
+	}
+	if got := al.capabilities().ContextWindow; got != 4096 {
+		t.Errorf("ContextWindow = %d, want 4096 from the provider", got)
+	}
+
+	withTools := &capabilityMockProvider{info: providers.ModelInfo{ContextWindow: 131072, Tools: true}}
+	al = newLoop(withTools, 16384)
+	if _, err := al.ProcessDirectWithChannel(context.Background(), "hello", "s", "test", "chat"); err != nil {
+		t.Fatalf("ProcessDirect() error: %v", err)
+	}
+	if withTools.toolCount == 0 {
+		t.Error("expected tools to be offered to a tool-capable model")
+	}
+	if !strings.Contains(withTools.system, "Available Tools") {
+		t.Error("system prompt should list the tools of a tool-capable model")
+	}
+	if got := al.capabilities().ContextWindow; got != 16384 {
+		t.Errorf("ContextWindow = %d, want config override 16384", got)
+	}
+}
+
+func TestAgentLoop_ModelCapabilitiesRetry(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	provider := &capabilityMockProvider{err: errors.New("connection refused")}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+
+	if info := al.capabilities(); !info.Guessed {
+		t.Fatalf("capabilities() = %+v, want guessed after a failed query", info)
+	}
+	al.capabilities()
+	if provider.queries != 1 {
+		t.Errorf("provider asked %d times, want the guess reused for a while", provider.queries)
+	}
+
+	provider.err = nil
+	provider.info = providers.ModelInfo{ContextWindow: 8192, Tools: true}
+	expire := func() {
+		al.modelInfoMu.Lock()
+		defer al.modelInfoMu.Unlock()
+		for model, entry := range al.modelInfo {
+			entry.at = time.Now().Add(-modelInfoRetry)
+			al.modelInfo[model] = entry
+		}
+	}
+	expire()
+	if got := al.capabilities().ContextWindow; got != 8192 {
+		t.Errorf("ContextWindow = %d, want 8192 once the provider answers", got)
+	}
+	expire()
+	al.capabilities()
+	if provider.queries != 2 {
+		t.Errorf("provider asked %d times, want an answer kept", provider.queries)
+	}
+
+	// Another model is asked about on its own
+	provider.info = providers.ModelInfo{ContextWindow: 32768}
+	al.setModel("other-model")
+	if got := al.capabilities().ContextWindow; got != 32768 || provider.queries != 3 {
+		t.Errorf("ContextWindow after a switch = %d (%d queries), want 32768 from a new query", got, provider.queries)
+	}
+}
+
+func TestAgentLoop_ToolPolicies(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Tools: config.ToolsConfig{
+			Policies: []config.ToolPolicy{
+				{Channel: "discord", Groups: config.FlexibleStringSlice{"guild-9"}, Allow: config.FlexibleStringSlice{"web_fetch"}},
+				{Channel: "cli", Senders: config.FlexibleStringSlice{CLISenderID}, Allow: config.FlexibleStringSlice{"web_fetch"}},
+			},
+		},
+	}
+	provider := &capabilityMockProvider{info: providers.ModelInfo{ContextWindow: 131072, Tools: true}}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+
+	send := func(guild string) int {
+		t.Helper()
+		_, err := al.processMessage(context.Background(), bus.InboundMessage{
+			Channel:    "discord",
+			SenderID:   "friend",
+			ChatID:     "chan-1",
+			Content:    "hello",
+			SessionKey: "discord:chan-1",
+			Metadata:   map[string]string{"guild_id": guild},
+		})
+		if err != nil {
+			t.Fatalf("processMessage() error: %v", err)
+		}
+		return provider.toolCount
+	}
+
+	if got := send("guild-9"); got != 1 {
+		t.Errorf("tools offered in the restricted server = %d, want 1", got)
+	}
+	if got := send("guild-1"); got != 0 {
+		t.Errorf("tools offered in a server no policy lists = %d, want none", got)
+	}
+
+	// Scheduled jobs run with the caller that created them
+	ctx := tools.WithCaller(context.Background(), tools.Caller{
+		Channel:  "discord",
+		ChatID:   "chan-1",
+		SenderID: "friend",
+		Group:    "guild-9",
+	})
+	if _, err := al.ProcessDirectWithChannel(ctx, "hello", "cron-job", "discord", "chan-1"); err != nil {
+		t.Fatalf("ProcessDirectWithChannel() error: %v", err)
+	}
+	if provider.toolCount != 1 {
+		t.Errorf("tools offered to a job from the restricted server = %d, want 1", provider.toolCount)
+	}
+
+	// The CLI user can be named in a policy
+	if _, err := al.ProcessDirect(context.Background(), "hello", "cli:direct"); err != nil || provider.toolCount != 1 {
+		t.Errorf("ProcessDirect() offered %d tools (%v), want the cli policy's 1", provider.toolCount, err)
+	}
+	provider.toolCount = 0
+	if _, err := al.ProcessDirectStream(context.Background(), "hello", "cli:direct", func(string) {}); err != nil || provider.toolCount != 1 {
+		t.Errorf("ProcessDirectStream() offered %d tools (%v), want the cli policy's 1", provider.toolCount, err)
+	}
+}
+
+// toolThenAnswerProvider calls mock_custom once, then answers.
+type toolThenAnswerProvider struct {
+	calls int
+	tool  string // Defaults to mock_custom
+}
+
+func (m *toolThenAnswerProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	m.calls++
+	if m.calls == 1 {
+		name := m.tool
+		if name == "" {
+			name = "mock_custom"
+		}
+		return &providers.LLMResponse{
+			ToolCalls: []providers.ToolCall{
+				{ID: "call_1", Name: name, Arguments: map[string]interface{}{}},
+			},
+			FinishReason: "tool_calls",
+		}, nil
+	}
+	return &providers.LLMResponse{Content: "The tool ran.", FinishReason: "stop"}, nil
+}
+
+func (m *toolThenAnswerProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func TestAgentLoop_RecordAndReplay(t *testing.T) {
+	cassette := filepath.Join(t.TempDir(), "conversation.json")
+
+	run := func(provider providers.LLMProvider) string {
+		cfg := &config.Config{
+			Agents: config.AgentsConfig{
+				Defaults: config.AgentDefaults{
+					Workspace:         t.TempDir(),
+					Model:             "test-model",
+					MaxTokens:         4096,
+					MaxToolIterations: 10,
+				},
+			},
+		}
+		al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+		al.RegisterTool(&mockCustomTool{})
+		resp, err := al.ProcessDirectWithChannel(context.Background(), "run the tool", "s", "test", "chat")
+		if err != nil {
+			t.Fatalf("ProcessDirect() error: %v", err)
+		}
+		return resp
+	}
+
+	live := &toolThenAnswerProvider{}
+	recorded := run(providers.NewRecordingProvider(live, cassette))
+	if live.calls != 2 {
+		t.Fatalf("live provider calls = %d, want 2", live.calls)
+	}
+
+	replay, err := providers.NewReplayProvider(cassette)
+	if err != nil {
+		t.Fatalf("NewReplayProvider() error: %v", err)
+	}
+	if replayed := run(replay); replayed != recorded {
+		t.Errorf("replayed response = %q, want %q", replayed, recorded)
+	}
+}
+
+// reasoningMockProvider answers with separate reasoning and records the
+// messages it was sent.
+type reasoningMockProvider struct {
+	seen []providers.Message
+}
+
+func (m *reasoningMockProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	m.seen = messages
+	return &providers.LLMResponse{Content: "Paris", Reasoning: "The capital of France."}, nil
+}
+
+func (m *reasoningMockProvider) GetDefaultModel() string {
+	return "reasoning-model"
+}
+
+func TestAgentLoop_ReasoningKeptInSession(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	provider := &reasoningMockProvider{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+
+	reply, err := al.ProcessDirectWithChannel(context.Background(), "capital of France?", "s", "test", "chat")
+	if err != nil {
+		t.Fatalf("ProcessDirect() error: %v", err)
+	}
+	if reply != "Paris" {
+		t.Errorf("reply = %q, want %q", reply, "Paris")
+	}
+
+	history := al.sessions.GetHistory("s")
+	last := history[len(history)-1]
+	if last.Role != "assistant" || last.Content != "Paris" || last.Reasoning != "The capital of France." {
+		t.Errorf("stored message = %+v, want answer and reasoning kept apart", last)
+	}
+
+	// The next turn sends the history back; reasoning must not leak into content
+	if _, err := al.ProcessDirectWithChannel(context.Background(), "and Spain?", "s", "test", "chat"); err != nil {
+		t.Fatalf("ProcessDirect() error: %v", err)
+	}
+	for _, m := range provider.seen {
+		if strings.Contains(m.Content, "The capital of France.") {
+			t.Errorf("reasoning leaked into %s message content: %q", m.Role, m.Content)
+		}
+	}
+}
+
+// memoryWriterProvider asks to write MEMORY.md once, then answers, and
+// records the messages it was sent.
+type memoryWriterProvider struct {
+	calls int
+	seen  []providers.Message
+}
+
+func (m *memoryWriterProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	m.calls++
+	m.seen = messages
+	if m.calls%2 == 1 {
+		return &providers.LLMResponse{
+			ToolCalls: []providers.ToolCall{{
+				ID:        "call_1",
+				Name:      "write_file",
+				Arguments: map[string]interface{}{"path": "memory/MEMORY.md", "content": "secret plans"},
+			}},
+		}, nil
+	}
+	return &providers.LLMResponse{Content: "Noted."}, nil
+}
+
+func (m *memoryWriterProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func TestAgentLoop_Incognito(t *testing.T) {
+	workspace := t.TempDir()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         workspace,
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	provider := &memoryWriterProvider{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	ctx := context.Background()
+
+	if reply, _ := al.ProcessDirectWithChannel(ctx, "/incognito on", "telegram:1", "telegram", "1"); !strings.Contains(reply, "Incognito on") {
+		t.Fatalf("/incognito on reply = %q", reply)
+	}
+	if _, err := al.ProcessDirectWithChannel(ctx, "remember my plans", "telegram:1", "telegram", "1"); err != nil {
+		t.Fatalf("ProcessDirect() error: %v", err)
+	}
+
+	// The memory write was refused and the model was told why
+	if _, err := os.Stat(filepath.Join(workspace, "memory", "MEMORY.md")); !os.IsNotExist(err) {
+		t.Error("MEMORY.md was written while incognito")
+	}
+	if last := provider.seen[len(provider.seen)-1]; last.Role != "tool" || !strings.Contains(last.Content, "incognito") {
+		t.Errorf("tool result = %+v, want an incognito refusal", last)
+	}
+
+	// Nothing reached the disk, but the conversation is kept in memory
+	if files, _ := filepath.Glob(filepath.Join(workspace, "sessions", "*.json")); len(files) != 0 {
+		t.Errorf("session files written while incognito: %v", files)
+	}
+	if ch := al.state.GetLastChannel(); ch != "" {
+		t.Errorf("last channel = %q, want none recorded", ch)
+	}
+	if h := al.incognitoSessions.GetHistory("telegram:1"); len(h) == 0 {
+		t.Error("incognito conversation not kept in memory")
+	}
+
+	// Turning it off discards the conversation and saves again
+	al.ProcessDirectWithChannel(ctx, "/incognito off", "telegram:1", "telegram", "1")
+	if h := al.incognitoSessions.GetHistory("telegram:1"); len(h) != 0 {
+		t.Error("incognito conversation kept after /incognito off")
+	}
+	al.ProcessDirectWithChannel(ctx, "remember my plans", "telegram:1", "telegram", "1")
+	if _, err := os.Stat(filepath.Join(workspace, "memory", "MEMORY.md")); err != nil {
+		t.Error("MEMORY.md not written after /incognito off")
+	}
+	if h := al.sessions.GetHistory("telegram:1"); len(h) == 0 {
+		t.Error("conversation not saved after /incognito off")
+	}
+}
+
+func TestAgentLoop_IncognitoChannel(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Privacy: config.PrivacyConfig{IncognitoChannels: []string{"discord"}},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+
+	if !al.isIncognito("discord:9", "discord") || al.isIncognito("telegram:9", "telegram") {
+		t.Error("privacy.incognito_channels not applied per channel")
+	}
+	if reply, _ := al.ProcessDirectWithChannel(context.Background(), "/incognito off", "discord:9", "discord", "9"); !strings.Contains(reply, "always on") {
+		t.Errorf("/incognito off on an incognito channel = %q", reply)
+	}
+}
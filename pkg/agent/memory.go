@@ -11,11 +11,14 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/crypt"
 )
 
 // MemoryStore manages persistent memory for the agent.
 // - Long-term memory: memory/MEMORY.md
 // - Daily notes: memory/YYYYMM/YYYYMMDD.md
+// Files are encrypted at rest when privacy.encryption is enabled.
 type MemoryStore struct {
 	workspace  string
 	memoryDir  string
@@ -30,6 +33,7 @@ func NewMemoryStore(workspace string) *MemoryStore {
 
 	// Ensure memory directory exists
 	os.MkdirAll(memoryDir, 0755)
+	crypt.Protect(memoryDir)
 
 	return &MemoryStore{
 		workspace:  workspace,
@@ -49,7 +53,7 @@ func (ms *MemoryStore) getTodayFile() string {
 // ReadLongTerm reads the long-term memory (MEMORY.md).
 // Returns empty string if the file doesn't exist.
 func (ms *MemoryStore) ReadLongTerm() string {
-	if data, err := os.ReadFile(ms.memoryFile); err == nil {
+	if data, err := crypt.ReadFile(ms.memoryFile); err == nil {
 		return string(data)
 	}
 	return ""
@@ -57,14 +61,14 @@ func (ms *MemoryStore) ReadLongTerm() string {
 
 // WriteLongTerm writes content to the long-term memory file (MEMORY.md).
 func (ms *MemoryStore) WriteLongTerm(content string) error {
-	return os.WriteFile(ms.memoryFile, []byte(content), 0644)
+	return crypt.WriteFile(ms.memoryFile, []byte(content), 0644)
 }
 
 // ReadToday reads today's daily note.
 // Returns empty string if the file doesn't exist.
 func (ms *MemoryStore) ReadToday() string {
 	todayFile := ms.getTodayFile()
-	if data, err := os.ReadFile(todayFile); err == nil {
+	if data, err := crypt.ReadFile(todayFile); err == nil {
 		return string(data)
 	}
 	return ""
@@ -80,8 +84,12 @@ func (ms *MemoryStore) AppendToday(content string) error {
 	os.MkdirAll(monthDir, 0755)
 
 	var existingContent string
-	if data, err := os.ReadFile(todayFile); err == nil {
+	data, err := crypt.ReadFile(todayFile)
+	if err == nil {
 		existingContent = string(data)
+	} else if !os.IsNotExist(err) {
+		// Don't overwrite a note we can't decrypt
+		return err
 	}
 
 	var newContent string
@@ -94,7 +102,7 @@ func (ms *MemoryStore) AppendToday(content string) error {
 		newContent = existingContent + "\n" + content
 	}
 
-	return os.WriteFile(todayFile, []byte(newContent), 0644)
+	return crypt.WriteFile(todayFile, []byte(newContent), 0644)
 }
 
 // GetRecentDailyNotes returns daily notes from the last N days.
@@ -108,7 +116,7 @@ func (ms *MemoryStore) GetRecentDailyNotes(days int) string {
 		monthDir := dateStr[:6]            // YYYYMM
 		filePath := filepath.Join(ms.memoryDir, monthDir, dateStr+".md")
 
-		if data, err := os.ReadFile(filePath); err == nil {
+		if data, err := crypt.ReadFile(filePath); err == nil {
 			notes = append(notes, string(data))
 		}
 	}
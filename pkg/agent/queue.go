@@ -0,0 +1,137 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package agent
+
+import (
+	"context"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// defaultMaxConcurrentSessions applies when
+// agents.defaults.max_concurrent_sessions is not set.
+const defaultMaxConcurrentSessions = 4
+
+// QueueStats is a snapshot of the inbound message queue.
+type QueueStats struct {
+	Queued   int `json:"queued"`   // Messages waiting for their session or a free slot
+	Active   int `json:"active"`   // Messages being processed
+	Sessions int `json:"sessions"` // Sessions with queued or active messages
+	Limit    int `json:"limit"`    // Sessions processed at once
+}
+
+// sessionQueue holds the messages of one session waiting for its worker.
+type sessionQueue struct {
+	pending []bus.InboundMessage
+}
+
+// queueKey is what messages are ordered by: the session, or the chat for
+// messages without one (such as subagent reports).
+func queueKey(msg bus.InboundMessage) string {
+	if msg.SessionKey != "" {
+		return msg.SessionKey
+	}
+	return msg.Channel + ":" + msg.ChatID
+}
+
+// dispatch queues msg for its session and starts the session's worker if
+// it isn't running. Messages of one session are processed in order; those
+// of different sessions run in parallel, up to the slot limit. /stop and
+// answers to approval requests skip the queue.
+func (al *AgentLoop) dispatch(ctx context.Context, msg bus.InboundMessage) {
+	if isStopCommand(msg) {
+		if reply := al.stopCommand(msg); reply != "" {
+			al.bus.PublishOutbound(bus.OutboundMessage{
+				Channel: msg.Channel,
+				ChatID:  msg.ChatID,
+				Content: reply,
+			})
+		}
+		return
+	}
+	// A yes or no for a tool call waiting for approval goes to that call;
+	// queued, it would wait behind the run that is waiting for it
+	if al.approvals.Answer(msg.Channel, msg.ChatID, msg.SenderID, msg.Content) {
+		return
+	}
+	// A button press that wasn't taken as an answer, such as one by another
+	// member of a group, is not a message from them
+	if msg.Metadata["button"] == "true" {
+		logger.DebugCF("agent", "Ignoring button press that isn't an answer",
+			map[string]interface{}{
+				"channel":   msg.Channel,
+				"chat_id":   msg.ChatID,
+				"sender_id": msg.SenderID,
+			})
+		return
+	}
+
+	key := queueKey(msg)
+
+	al.workersMu.Lock()
+	q, running := al.workers[key]
+	if !running {
+		q = &sessionQueue{}
+		al.workers[key] = q
+	}
+	q.pending = append(q.pending, msg)
+	al.queued.Add(1)
+	al.workersMu.Unlock()
+
+	if !running {
+		go al.runSession(ctx, key, q)
+	}
+}
+
+// runSession processes the queued messages of one session until none are
+// left.
+func (al *AgentLoop) runSession(ctx context.Context, key string, q *sessionQueue) {
+	for {
+		al.workersMu.Lock()
+		if len(q.pending) == 0 {
+			delete(al.workers, key)
+			al.workersMu.Unlock()
+			return
+		}
+		msg := q.pending[0]
+		q.pending = q.pending[1:]
+		al.workersMu.Unlock()
+
+		select {
+		case al.slots <- struct{}{}:
+		case <-ctx.Done():
+			al.workersMu.Lock()
+			dropped := len(q.pending) + 1
+			q.pending = nil
+			delete(al.workers, key)
+			al.workersMu.Unlock()
+			al.queued.Add(-int64(dropped))
+			logger.WarnCF("agent", "Dropped queued messages on shutdown",
+				map[string]interface{}{"session_key": key, "count": dropped})
+			return
+		}
+
+		al.queued.Add(-1)
+		al.active.Add(1)
+		al.handleInbound(ctx, msg)
+		al.active.Add(-1)
+		<-al.slots
+	}
+}
+
+// QueueStats reports how many inbound messages are waiting and running.
+func (al *AgentLoop) QueueStats() QueueStats {
+	al.workersMu.Lock()
+	sessions := len(al.workers)
+	al.workersMu.Unlock()
+	return QueueStats{
+		Queued:   int(al.queued.Load()),
+		Active:   int(al.active.Load()),
+		Sessions: sessions,
+		Limit:    cap(al.slots),
+	}
+}
@@ -0,0 +1,210 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// blockingProvider holds every request until release is closed. Message
+// contents are "<session>-<n>".
+type blockingProvider struct {
+	started chan string
+	release chan struct{}
+
+	mu       sync.Mutex
+	inFlight map[string]bool
+	overlap  []string
+}
+
+func (m *blockingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	content := messages[len(messages)-1].Content
+	session, _, _ := strings.Cut(content, "-")
+
+	m.mu.Lock()
+	if m.inFlight[session] {
+		m.overlap = append(m.overlap, content)
+	}
+	m.inFlight[session] = true
+	m.mu.Unlock()
+
+	m.started <- content
+	select {
+	case <-m.release:
+	case <-ctx.Done():
+	}
+
+	m.mu.Lock()
+	m.inFlight[session] = false
+	m.mu.Unlock()
+	return &providers.LLMResponse{Content: "reply to " + content, FinishReason: "stop"}, nil
+}
+
+func (m *blockingProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func TestAgentLoop_ConcurrentSessions(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:             t.TempDir(),
+				Model:                 "test-model",
+				MaxTokens:             4096,
+				MaxToolIterations:     10,
+				MaxConcurrentSessions: 2,
+			},
+		},
+	}
+	provider := &blockingProvider{
+		started:  make(chan string, 10),
+		release:  make(chan struct{}),
+		inFlight: make(map[string]bool),
+	}
+	msgBus := bus.NewMessageBus()
+	al := NewAgentLoop(cfg, msgBus, provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go al.Run(ctx)
+
+	for _, content := range []string{"a-1", "a-2", "b-1", "c-1"} {
+		session, _, _ := strings.Cut(content, "-")
+		msgBus.PublishInbound(bus.InboundMessage{
+			Channel:    "test",
+			SenderID:   "user",
+			ChatID:     session,
+			Content:    content,
+			SessionKey: "test:" + session,
+		})
+	}
+
+	var order []string
+	waitStarted := func() string {
+		select {
+		case content := <-provider.started:
+			order = append(order, content)
+			return content
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timed out waiting for a request; started so far: %v", order)
+			return ""
+		}
+	}
+
+	// Two sessions run at once, and a-2 waits for a-1 even with a slot free
+	first, second := waitStarted(), waitStarted()
+	if first == "a-2" || second == "a-2" {
+		t.Fatalf("a-2 started before a-1 finished: %v", order)
+	}
+	want := QueueStats{Queued: 2, Active: 2, Sessions: 3, Limit: 2}
+	deadline := time.Now().Add(3 * time.Second)
+	for al.QueueStats() != want && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := al.QueueStats(); got != want {
+		t.Errorf("QueueStats() = %+v, want %+v", got, want)
+	}
+
+	close(provider.release)
+	waitStarted()
+	waitStarted()
+
+	replies := make(map[string]bool)
+	for len(replies) < 4 {
+		outCtx, outCancel := context.WithTimeout(ctx, 3*time.Second)
+		msg, ok := msgBus.SubscribeOutbound(outCtx)
+		outCancel()
+		if !ok {
+			t.Fatalf("timed out waiting for replies; got %v", replies)
+		}
+		replies[msg.Content] = true
+	}
+	for _, content := range []string{"a-1", "a-2", "b-1", "c-1"} {
+		if !replies["reply to "+content] {
+			t.Errorf("missing reply to %s; got %v", content, replies)
+		}
+	}
+
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if len(provider.overlap) > 0 {
+		t.Errorf("messages of one session overlapped: %v", provider.overlap)
+	}
+}
+
+func TestAgentLoop_ApprovalAnswer(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Tools: config.ToolsConfig{
+			Approval: config.ApprovalConfig{
+				Tools:          config.FlexibleStringSlice{"mock_custom"},
+				TimeoutSeconds: 10,
+			},
+		},
+	}
+	msgBus := bus.NewMessageBus()
+	al := NewAgentLoop(cfg, msgBus, &toolThenAnswerProvider{})
+	al.RegisterTool(&mockCustomTool{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go al.Run(ctx)
+
+	send := func(content string) {
+		msgBus.PublishInbound(bus.InboundMessage{
+			Channel:    "test",
+			SenderID:   "user",
+			ChatID:     "chat",
+			Content:    content,
+			SessionKey: "test:chat",
+		})
+	}
+	reply := func() bus.OutboundMessage {
+		outCtx, outCancel := context.WithTimeout(ctx, 3*time.Second)
+		defer outCancel()
+		msg, ok := msgBus.SubscribeOutbound(outCtx)
+		if !ok {
+			t.Fatal("timed out waiting for a reply")
+		}
+		return msg
+	}
+
+	send("run it")
+	if req := reply(); len(req.Buttons) == 0 || !strings.Contains(req.Content, "mock_custom") {
+		t.Fatalf("expected an approval request, got %+v", req)
+	}
+
+	// Another member of the chat can't answer, and their button press is
+	// not taken as a message either
+	msgBus.PublishInbound(bus.InboundMessage{
+		Channel:    "test",
+		SenderID:   "other",
+		ChatID:     "chat",
+		Content:    "yes",
+		SessionKey: "test:chat",
+		Metadata:   map[string]string{"button": "true"},
+	})
+
+	// The answer isn't queued behind the run waiting for it
+	send("yes")
+	if got := reply().Content; got != "The tool ran." {
+		t.Errorf("reply = %q, want %q", got, "The tool ran.")
+	}
+	history := al.sessions.GetHistory("test:chat")
+	if len(history) != 4 || history[2].Content != "Custom tool executed" {
+		t.Errorf("tool should have run after approval, history: %+v", history)
+	}
+}
@@ -0,0 +1,71 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// errStopped is the cancellation cause of a run stopped with /stop.
+var errStopped = errors.New("stopped by user")
+
+// stoppedResponse is recorded as the assistant's reply to a stopped run.
+const stoppedResponse = "⏹️ Stopped. Tell me if you want me to continue."
+
+// activeRun is the agent run in progress for a session.
+type activeRun struct {
+	cancel context.CancelCauseFunc
+}
+
+// startRun makes the run for sessionKey stoppable with StopSession. The
+// returned function must be called when the run ends.
+func (al *AgentLoop) startRun(ctx context.Context, sessionKey string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	run := &activeRun{cancel: cancel}
+	al.runs.Store(sessionKey, run)
+	return ctx, func() {
+		al.runs.CompareAndDelete(sessionKey, run)
+		cancel(nil)
+	}
+}
+
+// StopSession cancels the run in progress for sessionKey: the pending LLM
+// request is aborted and running commands are killed. It reports whether
+// there was a run to stop.
+func (al *AgentLoop) StopSession(sessionKey string) bool {
+	v, ok := al.runs.LoadAndDelete(sessionKey)
+	if !ok {
+		return false
+	}
+	v.(*activeRun).cancel(errStopped)
+	logger.InfoCF("agent", "Stopping run", map[string]interface{}{"session_key": sessionKey})
+	return true
+}
+
+// wasStopped reports whether ctx was cancelled by StopSession.
+func wasStopped(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errStopped)
+}
+
+// isStopCommand reports whether msg is /stop. It is handled as soon as it
+// arrives rather than queued behind the run it is meant to stop.
+func isStopCommand(msg bus.InboundMessage) bool {
+	return msg.Channel != "system" && strings.TrimSpace(msg.Content) == "/stop"
+}
+
+// stopCommand handles /stop for the session of msg.
+func (al *AgentLoop) stopCommand(msg bus.InboundMessage) string {
+	if al.StopSession(msg.SessionKey) {
+		// The stopped run replies itself
+		return ""
+	}
+	return "Nothing to stop."
+}
@@ -0,0 +1,194 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// twoBlockingCallsProvider asks for two calls of mock_blocking, then answers.
+type twoBlockingCallsProvider struct {
+	calls atomic.Int32
+}
+
+func (m *twoBlockingCallsProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	if m.calls.Add(1) == 1 {
+		return &providers.LLMResponse{
+			ToolCalls: []providers.ToolCall{
+				{ID: "call_1", Name: "mock_blocking", Arguments: map[string]interface{}{}},
+				{ID: "call_2", Name: "mock_blocking", Arguments: map[string]interface{}{}},
+			},
+			FinishReason: "tool_calls",
+		}, nil
+	}
+	return &providers.LLMResponse{Content: "All done.", FinishReason: "stop"}, nil
+}
+
+func (m *twoBlockingCallsProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+// mockBlockingTool runs until its context is cancelled. Its calls run one
+// at a time.
+type mockBlockingTool struct {
+	started chan struct{}
+}
+
+func (m *mockBlockingTool) Name() string {
+	return "mock_blocking"
+}
+
+func (m *mockBlockingTool) Description() string {
+	return "Mock tool that runs until cancelled"
+}
+
+func (m *mockBlockingTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+func (m *mockBlockingTool) SerialKey(args map[string]interface{}) string {
+	return "blocking"
+}
+
+func (m *mockBlockingTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	m.started <- struct{}{}
+	<-ctx.Done()
+	return tools.ErrorResult("interrupted")
+}
+
+func newStopTestLoop(t *testing.T) (*AgentLoop, *twoBlockingCallsProvider, *mockBlockingTool) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	provider := &twoBlockingCallsProvider{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	tool := &mockBlockingTool{started: make(chan struct{}, 2)}
+	al.RegisterTool(tool)
+	return al, provider, tool
+}
+
+func waitToolStarted(t *testing.T, tool *mockBlockingTool) {
+	t.Helper()
+	select {
+	case <-tool.started:
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for the tool to start")
+	}
+}
+
+func TestAgentLoop_StopSession(t *testing.T) {
+	al, provider, tool := newStopTestLoop(t)
+
+	if al.StopSession("s") {
+		t.Error("StopSession() = true with nothing running")
+	}
+
+	type result struct {
+		resp string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := al.ProcessDirectWithChannel(context.Background(), "run it", "s", "test", "chat")
+		done <- result{resp, err}
+	}()
+
+	waitToolStarted(t, tool)
+	if !al.StopSession("s") {
+		t.Fatal("StopSession() = false during a run")
+	}
+
+	var res result
+	select {
+	case res = <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("run did not end after StopSession")
+	}
+	if res.err != nil {
+		t.Fatalf("ProcessDirectWithChannel() error: %v", res.err)
+	}
+	if res.resp != stoppedResponse {
+		t.Errorf("response = %q, want %q", res.resp, stoppedResponse)
+	}
+	if n := provider.calls.Load(); n != 1 {
+		t.Errorf("provider calls = %d, want 1", n)
+	}
+
+	// Every tool call has a result and the turn ends with the stop notice
+	history := al.sessions.GetHistory("s")
+	wantRoles := []string{"user", "assistant", "tool", "tool", "assistant"}
+	if len(history) != len(wantRoles) {
+		t.Fatalf("history has %d messages, want %d: %+v", len(history), len(wantRoles), history)
+	}
+	for i, role := range wantRoles {
+		if history[i].Role != role {
+			t.Errorf("history[%d].Role = %q, want %q", i, history[i].Role, role)
+		}
+	}
+	if history[2].Content != "interrupted" {
+		t.Errorf("running call result = %q, want %q", history[2].Content, "interrupted")
+	}
+	if history[3].ToolCallID != "call_2" || !strings.HasPrefix(history[3].Content, "Not run") {
+		t.Errorf("skipped call result = %+v", history[3])
+	}
+	if history[4].Content != stoppedResponse {
+		t.Errorf("last message = %q, want %q", history[4].Content, stoppedResponse)
+	}
+}
+
+func TestAgentLoop_StopCommand(t *testing.T) {
+	al, _, tool := newStopTestLoop(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go al.Run(ctx)
+
+	send := func(content string) {
+		al.bus.PublishInbound(bus.InboundMessage{
+			Channel:    "test",
+			SenderID:   "user",
+			ChatID:     "chat",
+			Content:    content,
+			SessionKey: "test:chat",
+		})
+	}
+	reply := func() string {
+		outCtx, outCancel := context.WithTimeout(ctx, 3*time.Second)
+		defer outCancel()
+		msg, ok := al.bus.SubscribeOutbound(outCtx)
+		if !ok {
+			t.Fatal("timed out waiting for a reply")
+		}
+		return msg.Content
+	}
+
+	send("/stop")
+	if got := reply(); got != "Nothing to stop." {
+		t.Errorf("reply to idle /stop = %q", got)
+	}
+
+	// /stop isn't queued behind the run it stops
+	send("run it")
+	waitToolStarted(t, tool)
+	send("/stop")
+	if got := reply(); got != stoppedResponse {
+		t.Errorf("reply = %q, want %q", got, stoppedResponse)
+	}
+}
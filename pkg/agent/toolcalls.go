@@ -0,0 +1,118 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// defaultMaxParallelTools applies when agents.defaults.max_parallel_tools
+// is not set.
+const defaultMaxParallelTools = 4
+
+// runToolCalls executes the tool calls of one LLM response and returns their
+// results in the order of calls. Calls run in parallel, at most
+// al.maxParallel at a time, except that calls with the same serial key (see
+// tools.SerialTool) run one after another in the order requested. Commands
+// act as a barrier for file calls: with a command among the calls, file
+// reads and changes run in order with it.
+func (al *AgentLoop) runToolCalls(ctx context.Context, calls []providers.ToolCall, opts processOptions, iteration int) []*tools.ToolResult {
+	results := make([]*tools.ToolResult, len(calls))
+
+	keys := make([]string, len(calls))
+	hasExec := false
+	for i, tc := range calls {
+		keys[i] = al.tools.SerialKey(tc.Name, tc.Arguments)
+		hasExec = hasExec || keys[i] == tools.ExecSerialKey
+	}
+
+	// Each lane is a list of calls that run in order; lanes run in parallel
+	var lanes [][]int
+	laneOf := make(map[string]int)
+	for i, key := range keys {
+		if hasExec && strings.HasPrefix(key, tools.FileSerialPrefix) {
+			key = tools.ExecSerialKey
+		}
+		if key != "" {
+			if l, ok := laneOf[key]; ok {
+				lanes[l] = append(lanes[l], i)
+				continue
+			}
+			laneOf[key] = len(lanes)
+		}
+		lanes = append(lanes, []int{i})
+	}
+
+	if len(lanes) == 1 || al.maxParallel <= 1 {
+		for i, tc := range calls {
+			results[i] = al.runToolCall(ctx, tc, opts, iteration)
+		}
+		return results
+	}
+
+	slots := make(chan struct{}, al.maxParallel)
+	var wg sync.WaitGroup
+	for _, lane := range lanes {
+		wg.Add(1)
+		go func(lane []int) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			for _, i := range lane {
+				results[i] = al.runToolCall(ctx, calls[i], opts, iteration)
+			}
+		}(lane)
+	}
+	wg.Wait()
+	return results
+}
+
+// runToolCall executes one tool call.
+func (al *AgentLoop) runToolCall(ctx context.Context, tc providers.ToolCall, opts processOptions, iteration int) *tools.ToolResult {
+	// Log tool call with arguments preview
+	argsPreview := "…"
+	if !opts.Incognito {
+		argsJSON, _ := json.Marshal(tc.Arguments)
+		argsPreview = utils.Truncate(string(argsJSON), 200)
+	}
+	logger.InfoCF("agent", fmt.Sprintf("Tool call: %s(%s)", tc.Name, argsPreview),
+		map[string]interface{}{
+			"tool":      tc.Name,
+			"iteration": iteration,
+		})
+
+	// Create async callback for tools that implement AsyncTool
+	// NOTE: Following openclaw's design, async tools do NOT send results directly to users.
+	// Instead, they notify the agent via PublishInbound, and the agent decides
+	// whether to forward the result to the user (in processSystemMessage).
+	asyncCallback := func(callbackCtx context.Context, result *tools.ToolResult) {
+		// Log the async completion but don't send directly to user
+		// The agent will handle user notification via processSystemMessage
+		if !result.Silent && result.ForUser != "" {
+			logger.InfoCF("agent", "Async tool completed, agent will handle notification",
+				map[string]interface{}{
+					"tool":        tc.Name,
+					"content_len": len(result.ForUser),
+				})
+		}
+	}
+
+	// Once the run is stopped, the remaining calls still get a result:
+	// the provider rejects tool calls left without one
+	if ctx.Err() != nil {
+		return tools.ErrorResult("Not run: the user stopped the run")
+	}
+	return al.tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID, asyncCallback)
+}
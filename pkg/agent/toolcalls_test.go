@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// mockSlowTool takes a while and records how many of its calls overlapped.
+// Calls with a "key" argument are serialized by it.
+type mockSlowTool struct {
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+func (m *mockSlowTool) Name() string {
+	return "mock_slow"
+}
+
+func (m *mockSlowTool) Description() string {
+	return "Mock tool that takes a while"
+}
+
+func (m *mockSlowTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+func (m *mockSlowTool) SerialKey(args map[string]interface{}) string {
+	key, _ := args["key"].(string)
+	return key
+}
+
+func (m *mockSlowTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	m.mu.Lock()
+	m.running++
+	if m.running > m.peak {
+		m.peak = m.running
+	}
+	m.mu.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+
+	m.mu.Lock()
+	m.running--
+	m.mu.Unlock()
+	return tools.NewToolResult(fmt.Sprint(args["id"]))
+}
+
+func TestAgentLoop_RunToolCalls(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxParallel int
+		keys        []string
+		wantPeak    int
+	}{
+		{"independent calls run together", 4, []string{"", "", ""}, 3},
+		{"cap limits parallel calls", 2, []string{"", "", "", ""}, 2},
+		{"same key runs one at a time", 4, []string{"a", "a", "a"}, 1},
+		{"different keys run together", 4, []string{"a", "b", "a", "b"}, 2},
+		{"max 1 runs in order", 1, []string{"", "", ""}, 1},
+		{"different files run together", 4, []string{"file:a", "file:b"}, 2},
+		{"a command is a barrier for files", 4, []string{"file:a", "exec", "file:b"}, 1},
+		{"a command leaves other calls alone", 4, []string{"exec", "", "file:a"}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Agents: config.AgentsConfig{
+					Defaults: config.AgentDefaults{
+						Workspace:         t.TempDir(),
+						Model:             "test-model",
+						MaxTokens:         4096,
+						MaxToolIterations: 10,
+						MaxParallelTools:  tt.maxParallel,
+					},
+				},
+			}
+			al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+			tool := &mockSlowTool{}
+			al.RegisterTool(tool)
+
+			calls := make([]providers.ToolCall, len(tt.keys))
+			for i, key := range tt.keys {
+				calls[i] = providers.ToolCall{
+					ID:        fmt.Sprintf("call_%d", i),
+					Name:      "mock_slow",
+					Arguments: map[string]interface{}{"id": fmt.Sprint(i), "key": key},
+				}
+			}
+
+			results := al.runToolCalls(context.Background(), calls, processOptions{SessionKey: "s"}, 1)
+
+			// Results come back in the order of calls, whatever order they ran in
+			for i, r := range results {
+				if r.ForLLM != fmt.Sprint(i) {
+					t.Errorf("results[%d] = %q, want %q", i, r.ForLLM, fmt.Sprint(i))
+				}
+			}
+			if tool.peak != tt.wantPeak {
+				t.Errorf("peak parallel calls = %d, want %d", tool.peak, tt.wantPeak)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package approval holds back tool calls the user has marked as dangerous
+// until they approve them. The request is sent to the chat the call came
+// from, with Allow/Deny buttons on channels that have them, and the user
+// whose message led to the call answers yes or no. A request nobody answers
+// expires and the call is refused.
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/constants"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// defaultTimeout applies when tools.approval.timeout_seconds is not set.
+const defaultTimeout = 5 * time.Minute
+
+var (
+	// ErrDenied means the user answered no.
+	ErrDenied = errors.New("the user denied it")
+	// ErrExpired means nobody answered before the request timed out.
+	ErrExpired = errors.New("the approval request expired without an answer")
+	// ErrNoOneToAsk means the call didn't come from a chat, as with
+	// subagent reports, so there was nobody to ask.
+	ErrNoOneToAsk = errors.New("it needs approval and there is no chat to ask in")
+)
+
+// PromptFunc asks the local user directly and returns their answer. It is
+// used for the cli channel, whose replies don't go through the bus.
+type PromptFunc func(ctx context.Context, question string) (bool, error)
+
+// Approvals decides which tool calls need approval and waits for the
+// answers. It implements tools.Approver. A nil *Approvals approves
+// everything.
+type Approvals struct {
+	tools    map[string]bool
+	patterns map[string][]*regexp.Regexp
+	timeout  time.Duration
+	bus      *bus.MessageBus
+
+	mu      sync.Mutex
+	prompt  PromptFunc
+	pending map[string]*request      // Chat → the request open there
+	turns   map[string]chan struct{} // Chat → held while a request is open there
+}
+
+// request is an open approval request.
+type request struct {
+	sender string    // Who may answer; anyone in the chat if empty
+	answer chan bool // Where the answer goes
+}
+
+// New returns the approval policy of cfg. Requests are sent through
+// msgBus. A pattern that doesn't compile is logged and its tool then needs
+// approval for every call, rather than for none.
+func New(cfg config.ApprovalConfig, msgBus *bus.MessageBus) *Approvals {
+	a := &Approvals{
+		tools:    make(map[string]bool),
+		patterns: make(map[string][]*regexp.Regexp),
+		timeout:  defaultTimeout,
+		bus:      msgBus,
+		pending:  make(map[string]*request),
+		turns:    make(map[string]chan struct{}),
+	}
+	if cfg.TimeoutSeconds > 0 {
+		a.timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+	for _, tool := range cfg.Tools {
+		a.tools[tool] = true
+	}
+	for tool, exprs := range cfg.Patterns {
+		for _, expr := range exprs {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				logger.ErrorCF("approval", "Invalid approval pattern, every call of the tool will need approval",
+					map[string]interface{}{
+						"tool":    tool,
+						"pattern": expr,
+						"error":   err.Error(),
+					})
+				a.tools[tool] = true
+				continue
+			}
+			a.patterns[tool] = append(a.patterns[tool], re)
+		}
+	}
+	return a
+}
+
+// Enabled reports whether any call can need approval.
+func (a *Approvals) Enabled() bool {
+	return a != nil && (len(a.tools) > 0 || len(a.patterns) > 0)
+}
+
+// SetPrompt sets how calls from the cli channel are approved. Without it
+// they are refused.
+func (a *Approvals) SetPrompt(fn PromptFunc) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.prompt = fn
+}
+
+// Requires reports whether a call of tool with args needs approval: the tool
+// is listed, or one of its patterns matches one of the string arguments.
+func (a *Approvals) Requires(tool string, args map[string]interface{}) bool {
+	if a == nil {
+		return false
+	}
+	if a.tools[tool] {
+		return true
+	}
+	for _, re := range a.patterns[tool] {
+		for _, v := range args {
+			if s, ok := v.(string); ok && re.MatchString(s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Approve asks the user of channel/chatID about the call if it needs
+// approval, and returns nil once they allow it. Only the sender of the
+// caller in ctx (see tools.WithCaller) may answer, when there is one. It
+// returns an error, which the model is shown, when they deny it, don't
+// answer in time or can't be asked, or when ctx ends first.
+func (a *Approvals) Approve(ctx context.Context, tool string, args map[string]interface{}, channel, chatID string) error {
+	if !a.Requires(tool, args) {
+		return nil
+	}
+
+	a.mu.Lock()
+	prompt := a.prompt
+	a.mu.Unlock()
+
+	question := describe(tool, args)
+	var allowed bool
+	var err error
+	switch {
+	case channel == "cli" && prompt != nil:
+		promptCtx, cancel := context.WithTimeout(ctx, a.timeout)
+		allowed, err = prompt(promptCtx, question)
+		cancel()
+	case channel == "cli" || channel == "" || chatID == "" || constants.IsInternalChannel(channel):
+		err = ErrNoOneToAsk
+	default:
+		allowed, err = a.ask(ctx, channel, chatID, tools.CallerFrom(ctx).SenderID, tool, question)
+	}
+
+	if err == nil && !allowed {
+		err = ErrDenied
+	}
+	logger.InfoCF("approval", "Approval decided",
+		map[string]interface{}{
+			"tool":    tool,
+			"channel": channel,
+			"chat_id": chatID,
+			"allowed": err == nil,
+		})
+	if err != nil {
+		return fmt.Errorf("%s was not run: %w", tool, err)
+	}
+	return nil
+}
+
+// ask sends the request to the chat and waits for sender's answer. Requests
+// in one chat are asked one at a time, so an answer can't go to the wrong
+// one.
+func (a *Approvals) ask(ctx context.Context, channel, chatID, sender, tool, question string) (bool, error) {
+	key := channel + ":" + chatID
+
+	turn := a.turnFor(key)
+	select {
+	case turn <- struct{}{}:
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+	defer func() { <-turn }()
+
+	answer := make(chan bool, 1)
+	a.mu.Lock()
+	a.pending[key] = &request{sender: sender, answer: answer}
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		delete(a.pending, key)
+		a.mu.Unlock()
+	}()
+
+	a.bus.PublishOutbound(bus.OutboundMessage{
+		Channel: channel,
+		ChatID:  chatID,
+		Content: fmt.Sprintf("%s\n\nReply *yes* to allow or *no* to deny. The request expires in %s.",
+			question, a.timeout),
+		Buttons: []bus.Button{
+			{Label: "✅ Allow", Data: "yes"},
+			{Label: "❌ Deny", Data: "no"},
+		},
+	})
+
+	timer := time.NewTimer(a.timeout)
+	defer timer.Stop()
+
+	select {
+	case allowed := <-answer:
+		return allowed, nil
+	case <-timer.C:
+		a.bus.PublishOutbound(bus.OutboundMessage{
+			Channel: channel,
+			ChatID:  chatID,
+			Content: fmt.Sprintf("⌛ The approval request for %s expired, so it was not run.", tool),
+		})
+		return false, ErrExpired
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+func (a *Approvals) turnFor(key string) chan struct{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	turn, ok := a.turns[key]
+	if !ok {
+		turn = make(chan struct{}, 1)
+		a.turns[key] = turn
+	}
+	return turn
+}
+
+// Answer passes content on as the answer to the request open in the chat,
+// if there is one, senderID may answer it and content is a yes or a no. It
+// reports whether content was taken as an answer; otherwise it is an
+// ordinary message.
+func (a *Approvals) Answer(channel, chatID, senderID, content string) bool {
+	if a == nil {
+		return false
+	}
+	allowed, ok := ParseAnswer(content)
+	if !ok {
+		return false
+	}
+
+	key := channel + ":" + chatID
+	a.mu.Lock()
+	req, pending := a.pending[key]
+	if !pending || (req.sender != "" && req.sender != senderID) {
+		a.mu.Unlock()
+		return false
+	}
+	delete(a.pending, key)
+	a.mu.Unlock()
+
+	req.answer <- allowed
+	return true
+}
+
+// ParseAnswer reads a reply to an approval request. ok is false when the
+// reply is neither a yes nor a no.
+func ParseAnswer(content string) (allowed, ok bool) {
+	switch strings.ToLower(strings.Trim(strings.TrimSpace(content), ".!")) {
+	case "yes", "y", "allow", "approve", "ok":
+		return true, true
+	case "no", "n", "deny", "refuse":
+		return false, true
+	}
+	return false, false
+}
+
+// describe words the request for a call.
+func describe(tool string, args map[string]interface{}) string {
+	// Shell commands read better as they are than as JSON
+	var details string
+	if command, ok := args["command"].(string); ok {
+		details = command
+		if dir, _ := args["working_dir"].(string); dir != "" {
+			details += "\n(in " + dir + ")"
+		}
+	} else {
+		b, _ := json.MarshalIndent(args, "", "  ")
+		details = string(b)
+	}
+	return fmt.Sprintf("⚠️ Approval needed: the agent wants to run %s\n```\n%s\n```",
+		tool, utils.Truncate(details, 1000))
+}
@@ -0,0 +1,162 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+func TestApprovals_Requires(t *testing.T) {
+	a := New(config.ApprovalConfig{
+		Tools: config.FlexibleStringSlice{"write_file"},
+		Patterns: map[string][]string{
+			"exec":      {`\brm\s+-rf\b`, `^sudo `},
+			"edit_file": {`(`},
+		},
+	}, bus.NewMessageBus())
+
+	tests := []struct {
+		name string
+		tool string
+		args map[string]interface{}
+		want bool
+	}{
+		{"listed tool", "write_file", map[string]interface{}{"path": "a.txt"}, true},
+		{"unlisted tool", "read_file", map[string]interface{}{"path": "a.txt"}, false},
+		{"pattern matches", "exec", map[string]interface{}{"command": "rm -rf /tmp/x"}, true},
+		{"second pattern matches", "exec", map[string]interface{}{"command": "sudo reboot"}, true},
+		{"no pattern matches", "exec", map[string]interface{}{"command": "ls -la"}, false},
+		{"invalid pattern needs approval always", "edit_file", map[string]interface{}{"path": "a.txt"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.Requires(tt.tool, tt.args); got != tt.want {
+				t.Errorf("Requires(%q, %v) = %v, want %v", tt.tool, tt.args, got, tt.want)
+			}
+		})
+	}
+
+	var none *Approvals
+	if none.Enabled() || none.Requires("write_file", nil) {
+		t.Error("nil Approvals should approve everything")
+	}
+	if New(config.ApprovalConfig{}, nil).Enabled() {
+		t.Error("empty config should not be enabled")
+	}
+}
+
+func TestApprovals_Approve(t *testing.T) {
+	tests := []struct {
+		name    string
+		answer  string
+		wantErr error
+	}{
+		{"allowed", "yes", nil},
+		{"denied", "No.", ErrDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgBus := bus.NewMessageBus()
+			a := New(config.ApprovalConfig{Tools: config.FlexibleStringSlice{"exec"}}, msgBus)
+
+			done := make(chan error, 1)
+			go func() {
+				ctx := tools.WithCaller(context.Background(), tools.Caller{Channel: "telegram", ChatID: "42", SenderID: "7"})
+				done <- a.Approve(ctx, "exec",
+					map[string]interface{}{"command": "make deploy"}, "telegram", "42")
+			}()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			req, ok := msgBus.SubscribeOutbound(ctx)
+			if !ok {
+				t.Fatal("no approval request was sent")
+			}
+			if req.Channel != "telegram" || req.ChatID != "42" {
+				t.Errorf("request sent to %s:%s, want telegram:42", req.Channel, req.ChatID)
+			}
+			if !strings.Contains(req.Content, "make deploy") {
+				t.Errorf("request should show the command, got %q", req.Content)
+			}
+			if len(req.Buttons) != 2 || req.Buttons[0].Data != "yes" || req.Buttons[1].Data != "no" {
+				t.Errorf("expected Allow/Deny buttons, got %+v", req.Buttons)
+			}
+
+			if a.Answer("telegram", "99", "7", tt.answer) {
+				t.Error("an answer from another chat should not be taken")
+			}
+			if a.Answer("telegram", "42", "8", tt.answer) {
+				t.Error("an answer from another member of the chat should not be taken")
+			}
+			if a.Answer("telegram", "42", "7", "what is this?") {
+				t.Error("a message that is not a yes or no should not be taken")
+			}
+			if !a.Answer("telegram", "42", "7", tt.answer) {
+				t.Fatal("answer was not taken")
+			}
+
+			err := <-done
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("Approve() = %v, want %v", err, tt.wantErr)
+			}
+			if a.Answer("telegram", "42", "7", "yes") {
+				t.Error("an answer after the request closed should not be taken")
+			}
+		})
+	}
+}
+
+func TestApprovals_Approve_Expired(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	a := New(config.ApprovalConfig{Tools: config.FlexibleStringSlice{"exec"}}, msgBus)
+	a.timeout = 50 * time.Millisecond
+
+	err := a.Approve(context.Background(), "exec", nil, "discord", "7")
+	if !errors.Is(err, ErrExpired) {
+		t.Fatalf("Approve() = %v, want %v", err, ErrExpired)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msgBus.SubscribeOutbound(ctx) // The request
+	notice, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok || !strings.Contains(notice.Content, "expired") {
+		t.Errorf("expected an expiry notice, got %q", notice.Content)
+	}
+}
+
+func TestApprovals_Approve_NoOneToAsk(t *testing.T) {
+	a := New(config.ApprovalConfig{Tools: config.FlexibleStringSlice{"exec"}}, bus.NewMessageBus())
+
+	for _, channel := range []string{"system", "", "cli"} {
+		err := a.Approve(context.Background(), "exec", nil, channel, "direct")
+		if !errors.Is(err, ErrNoOneToAsk) {
+			t.Errorf("Approve() from %q = %v, want it refused", channel, err)
+		}
+	}
+}
+
+func TestApprovals_Approve_Prompt(t *testing.T) {
+	a := New(config.ApprovalConfig{Tools: config.FlexibleStringSlice{"exec"}}, bus.NewMessageBus())
+
+	var asked string
+	a.SetPrompt(func(ctx context.Context, question string) (bool, error) {
+		asked = question
+		return true, nil
+	})
+
+	err := a.Approve(context.Background(), "exec",
+		map[string]interface{}{"command": "ls", "working_dir": "/tmp"}, "cli", "direct")
+	if err != nil {
+		t.Fatalf("Approve() = %v, want nil", err)
+	}
+	if !strings.Contains(asked, "ls\n(in /tmp)") {
+		t.Errorf("prompt should show the command and its directory, got %q", asked)
+	}
+}
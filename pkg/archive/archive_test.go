@@ -0,0 +1,194 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+func TestExportImport_RoundTrip(t *testing.T) {
+	src := t.TempDir()
+	sessions := session.NewSessionManager(filepath.Join(src, "sessions"))
+	sessions.AddMessage("telegram:1", "user", "hello from the old board")
+	sessions.SetSummary("telegram:1", "greeting")
+	sessions.Save("telegram:1")
+	sessions.AddMessage("discord:2", "user", "same everywhere")
+	sessions.Save("discord:2")
+	webSessions := session.NewSessionManager(filepath.Join(src, "webui"))
+	webSessions.AddMessage("web:default", "user", "typed in the browser")
+	webSessions.Save("web:default")
+
+	writeFile(t, filepath.Join(src, "memory", "MEMORY.md"), "Likes tea.\n")
+	writeFile(t, filepath.Join(src, "memory", "202601", "20260115.md"), "Went hiking.\n")
+	writeFile(t, filepath.Join(src, "state", "state.json"), `{"last_channel":"telegram"}`)
+	writeFile(t, filepath.Join(src, "skills", "weather", "SKILL.md"), "# weather\n")
+
+	every := int64(time.Hour / time.Millisecond)
+	cs := cron.NewCronService(filepath.Join(src, "cron", "jobs.json"), nil)
+	job, _ := cs.AddJob("hourly", cron.CronSchedule{Kind: "every", EveryMS: &every}, "check", false, "", "")
+
+	cfg := config.DefaultConfig()
+	cfg.Providers.OpenAI.APIKey = "sk-secret"
+	cfg.Channels.Telegram.Token = "123:abc"
+
+	var buf bytes.Buffer
+	m, err := Export(&buf, Source{Workspace: src, WebUISessions: filepath.Join(src, "webui"), Config: cfg, Version: "test"})
+	if err != nil {
+		t.Fatalf("Export() error: %v", err)
+	}
+	if m.Sessions != 3 || m.CronJobs != 1 || len(m.Skills) != 1 {
+		t.Errorf("manifest = %+v", m)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("sk-secret")) {
+		t.Error("archive contains an API key")
+	}
+
+	// The target board already has its own data
+	dst := t.TempDir()
+	local := session.NewSessionManager(filepath.Join(dst, "sessions"))
+	local.AddMessage("telegram:1", "user", "a different chat")
+	local.Save("telegram:1")
+	// Newer than the exported session, so its messages come last
+	time.Sleep(10 * time.Millisecond)
+	local.AddMessage("telegram:1", "assistant", "noted")
+	local.Save("telegram:1")
+	local.AddMessage("discord:2", "user", "same everywhere")
+	local.Save("discord:2")
+	writeFile(t, filepath.Join(dst, "memory", "MEMORY.md"), "Lives in Berlin.\n")
+	writeFile(t, filepath.Join(dst, "config.json"), "{}")
+
+	_, res, err := Import(bytes.NewReader(buf.Bytes()), Target{Workspace: dst, WebUISessions: filepath.Join(dst, "webui"), ConfigDir: dst})
+	if err != nil {
+		t.Fatalf("Import() error: %v", err)
+	}
+
+	got := session.NewSessionManager(filepath.Join(dst, "sessions"))
+	h := got.GetHistory("telegram:1")
+	if len(h) != 3 || h[0].Content != "hello from the old board" || h[1].Content != "a different chat" || h[2].Content != "noted" {
+		t.Errorf("merged session = %+v, want the imported messages before the newer local ones", h)
+	}
+	if s := got.GetSummary("telegram:1"); s != "greeting" {
+		t.Errorf("merged summary = %q", s)
+	}
+	if h := session.NewSessionManager(filepath.Join(dst, "webui")).GetHistory("web:default"); len(h) != 1 || h[0].Content != "typed in the browser" {
+		t.Errorf("web UI session = %+v", h)
+	}
+	if len(res.Merged) < 1 || res.Merged[0] != "session telegram:1" || !strings.Contains(strings.Join(res.Skipped, "\n"), "discord:2") {
+		t.Errorf("result = %+v", res)
+	}
+
+	memory, _ := os.ReadFile(filepath.Join(dst, "memory", "MEMORY.md"))
+	if !strings.Contains(string(memory), "Lives in Berlin.") || !strings.Contains(string(memory), "Likes tea.") {
+		t.Errorf("MEMORY.md = %q, want both versions merged", memory)
+	}
+	if note, _ := os.ReadFile(filepath.Join(dst, "memory", "202601", "20260115.md")); string(note) != "Went hiking.\n" {
+		t.Errorf("daily note = %q", note)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "skills", "weather", "SKILL.md")); err != nil {
+		t.Error("skill not restored")
+	}
+	if _, err := os.Stat(filepath.Join(dst, "state", "state.json")); err != nil {
+		t.Error("state not restored")
+	}
+	jobs := cron.NewCronService(filepath.Join(dst, "cron", "jobs.json"), nil).ListJobs(true)
+	if len(jobs) != 1 || jobs[0].ID != job.ID {
+		t.Errorf("jobs = %+v", jobs)
+	}
+	if raw, _ := os.ReadFile(filepath.Join(dst, "config.json")); string(raw) != "{}" {
+		t.Error("local config was overwritten")
+	}
+	if _, err := os.Stat(filepath.Join(dst, "config.imported.json")); err != nil {
+		t.Error("imported config not written beside the local one")
+	}
+
+	// Importing the same archive again changes nothing
+	_, res, err = Import(bytes.NewReader(buf.Bytes()), Target{Workspace: dst, WebUISessions: filepath.Join(dst, "webui")})
+	if err != nil || len(res.Added)+len(res.Merged) != 0 {
+		t.Errorf("second Import() = %+v, %v; want everything skipped", res, err)
+	}
+}
+
+func TestImport_RejectsUnsafeArchives(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"path traversal", map[string]string{"manifest.json": `{"format":1}`, "memory/../../evil": "x"}},
+		{"unknown file", map[string]string{"manifest.json": `{"format":1}`, "bin/sh": "x"}},
+		{"no manifest", map[string]string{"memory/MEMORY.md": "x"}},
+		{"unlisted file", map[string]string{"manifest.json": `{"format":1}`, "memory/MEMORY.md": "x"}},
+		{"bad checksum", map[string]string{
+			"manifest.json":    `{"format":1,"files":[{"path":"memory/MEMORY.md","sha256":"00"}]}`,
+			"memory/MEMORY.md": "x",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := t.TempDir()
+			if _, _, err := Import(bytes.NewReader(makeArchive(t, tt.files)), Target{Workspace: dst}); err == nil {
+				t.Fatal("Import() accepted the archive")
+			}
+			if entries, _ := os.ReadDir(dst); len(entries) != 0 {
+				t.Errorf("Import() wrote %d entries before failing", len(entries))
+			}
+		})
+	}
+}
+
+func TestRedactConfig(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Providers.Anthropic.APIKey = "sk-ant"
+	cfg.Channels.Slack.BotToken = "xoxb"
+	cfg.Channels.Feishu.AppSecret = "s3cret"
+	cfg.Tools.Web.Brave.APIKey = "BSA-key"
+
+	data, redacted, err := redactConfig(cfg)
+	if err != nil {
+		t.Fatalf("redactConfig() error: %v", err)
+	}
+	for _, secret := range []string{"sk-ant", "xoxb", "s3cret", "BSA-key"} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("redacted config still contains %q", secret)
+		}
+	}
+	want := []string{"channels.feishu.app_secret", "channels.slack.bot_token", "providers.anthropic.api_key", "tools.web.brave.api_key"}
+	if strings.Join(redacted, ",") != strings.Join(want, ",") {
+		t.Errorf("redacted = %v, want %v", redacted, want)
+	}
+	if !bytes.Contains(data, []byte(`"workspace"`)) {
+		t.Error("non-secret settings were dropped")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func makeArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
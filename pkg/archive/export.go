@@ -0,0 +1,266 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package archive exports a user's personal data into a single tar.gz and
+// imports it on another board. The archive holds a manifest.json listing
+// every file with its SHA-256, the sessions of the agent and the web UI
+// (with their summaries), memory,
+// cron jobs, workspace state, installed skills and a copy of the config
+// with its secrets blanked out.
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/crypt"
+)
+
+// FormatVersion is the archive layout written by Export.
+const FormatVersion = 1
+
+const manifestName = "manifest.json"
+
+// Where the sessions of the agent and of the web UI go in an archive.
+const (
+	sessionsPrefix      = "sessions/"
+	webUISessionsPrefix = "webui/sessions/"
+)
+
+// Manifest describes an archive.
+type Manifest struct {
+	Format         int         `json:"format"`
+	Version        string      `json:"picoclaw_version,omitempty"`
+	Created        time.Time   `json:"created"`
+	Sessions       int         `json:"sessions"`
+	CronJobs       int         `json:"cron_jobs"`
+	Skills         []string    `json:"skills,omitempty"`
+	RedactedConfig []string    `json:"redacted_config,omitempty"` // config fields blanked out
+	Files          []FileEntry `json:"files"`
+}
+
+// FileEntry is one file in an archive.
+type FileEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Source is what Export reads.
+type Source struct {
+	Workspace     string
+	WebUISessions string // directory of the web UI's sessions, if any
+	Config        *config.Config
+	Version       string
+}
+
+// Export writes the archive to w. Encrypted workspace files are stored
+// decrypted; callers that care seal the whole archive.
+func Export(w io.Writer, src Source) (*Manifest, error) {
+	m := &Manifest{
+		Format:  FormatVersion,
+		Version: src.Version,
+		Created: time.Now().UTC(),
+	}
+	files := make(map[string][]byte)
+
+	// Sessions, summaries included
+	sessionDirs := map[string]string{sessionsPrefix: filepath.Join(src.Workspace, "sessions")}
+	if src.WebUISessions != "" {
+		sessionDirs[webUISessionsPrefix] = src.WebUISessions
+	}
+	for prefix, dir := range sessionDirs {
+		sessionFiles, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+		for _, p := range sessionFiles {
+			data, err := crypt.ReadFile(p)
+			if err != nil {
+				return nil, err
+			}
+			files[prefix+filepath.Base(p)] = data
+			m.Sessions++
+		}
+	}
+
+	// MEMORY.md and daily notes
+	memoryDir := filepath.Join(src.Workspace, "memory")
+	err := filepath.WalkDir(memoryDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(p, ".md") {
+			return nil
+		}
+		data, err := crypt.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src.Workspace, p)
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Cron jobs and workspace state
+	cronPath := filepath.Join(src.Workspace, "cron", "jobs.json")
+	if data, err := os.ReadFile(cronPath); err == nil {
+		files["cron/jobs.json"] = data
+		var store cron.CronStore
+		if json.Unmarshal(data, &store) == nil {
+			m.CronJobs = len(store.Jobs)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(src.Workspace, "state", "state.json")); err == nil {
+		files["state/state.json"] = data
+	}
+
+	// Installed skills
+	skillsDir := filepath.Join(src.Workspace, "skills")
+	entries, _ := os.ReadDir(skillsDir)
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		m.Skills = append(m.Skills, e.Name())
+		err := filepath.WalkDir(filepath.Join(skillsDir, e.Name()), func(p string, d os.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(src.Workspace, p)
+			files[filepath.ToSlash(rel)] = data
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if src.Config != nil {
+		data, redacted, err := redactConfig(src.Config)
+		if err != nil {
+			return nil, err
+		}
+		files["config.json"] = data
+		m.RedactedConfig = redacted
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sum := sha256.Sum256(files[name])
+		m.Files = append(m.Files, FileEntry{Path: name, Size: int64(len(files[name])), SHA256: hex.EncodeToString(sum[:])})
+	}
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if err := writeEntry(tw, manifestName, manifest, m.Created); err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if err := writeEntry(tw, name, files[name], m.Created); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func writeEntry(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	hdr := &tar.Header{
+		Name:    path.Clean(name),
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// redactConfig returns the config as JSON with every credential emptied,
+// and the dotted paths of the fields it emptied.
+func redactConfig(cfg *config.Config) ([]byte, []string, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	var tree map[string]interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, nil, err
+	}
+
+	var redacted []string
+	var walk func(prefix string, v interface{})
+	walk = func(prefix string, v interface{}) {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			for k, child := range node {
+				p := k
+				if prefix != "" {
+					p = prefix + "." + k
+				}
+				if s, ok := child.(string); ok && s != "" && isSecretField(k) {
+					node[k] = ""
+					redacted = append(redacted, p)
+					continue
+				}
+				walk(p, child)
+			}
+		case []interface{}:
+			for i, child := range node {
+				walk(fmt.Sprintf("%s[%d]", prefix, i), child)
+			}
+		}
+	}
+	walk("", tree)
+	sort.Strings(redacted)
+
+	out, err := json.MarshalIndent(tree, "", "  ")
+	return out, redacted, err
+}
+
+func isSecretField(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasSuffix(name, "key") ||
+		strings.Contains(name, "token") ||
+		strings.Contains(name, "secret") ||
+		strings.Contains(name, "password")
+}
@@ -0,0 +1,336 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/crypt"
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+// maxArchiveSize bounds how much of an archive Import reads into memory.
+const maxArchiveSize = 512 << 20
+
+// memorySeparator goes between local and imported memory that both have
+// content of their own.
+const memorySeparator = "\n\n---\n\n"
+
+// Target is where Import restores to.
+type Target struct {
+	Workspace     string
+	WebUISessions string // directory of the web UI's sessions; their sessions are skipped if empty
+	ConfigDir     string // directory of config.json
+}
+
+// Result reports what Import did, one line per item.
+type Result struct {
+	Added   []string
+	Merged  []string
+	Skipped []string
+}
+
+// Import restores an archive written by Export. Every file is checked
+// against the manifest before anything is written. Existing data wins:
+// sessions whose key is taken are merged into the local history (see
+// session.SessionManager.Import), memory files are merged,
+// and cron jobs, skills and state that already exist are left alone. The
+// config is written as config.json only if there isn't one, and as
+// config.imported.json otherwise; its secrets have to be filled in again.
+func Import(r io.Reader, dst Target) (*Manifest, *Result, error) {
+	m, files, err := readArchive(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res := &Result{}
+	if err := importSessions(files, sessionsPrefix, filepath.Join(dst.Workspace, "sessions"), "session", res); err != nil {
+		return m, res, err
+	}
+	if err := importSessions(files, webUISessionsPrefix, dst.WebUISessions, "web UI session", res); err != nil {
+		return m, res, err
+	}
+	if err := importMemory(files, dst.Workspace, res); err != nil {
+		return m, res, err
+	}
+	if data, ok := files["cron/jobs.json"]; ok {
+		if err := importCron(data, dst.Workspace, res); err != nil {
+			return m, res, err
+		}
+	}
+	if data, ok := files["state/state.json"]; ok {
+		if err := writeIfMissing(filepath.Join(dst.Workspace, "state", "state.json"), data, "state/state.json", res); err != nil {
+			return m, res, err
+		}
+	}
+	if err := importSkills(files, dst.Workspace, res); err != nil {
+		return m, res, err
+	}
+	if data, ok := files["config.json"]; ok && dst.ConfigDir != "" {
+		target := filepath.Join(dst.ConfigDir, "config.json")
+		if _, err := os.Stat(target); err == nil {
+			target = filepath.Join(dst.ConfigDir, "config.imported.json")
+		}
+		if err := os.MkdirAll(dst.ConfigDir, 0755); err != nil {
+			return m, res, err
+		}
+		if err := os.WriteFile(target, data, 0600); err != nil {
+			return m, res, err
+		}
+		res.Added = append(res.Added, "config -> "+target)
+	}
+	return m, res, nil
+}
+
+// readArchive reads the whole archive and verifies it against its manifest.
+func readArchive(r io.Reader) (*Manifest, map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a picoclaw export: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(io.LimitReader(gz, maxArchiveSize))
+	files := make(map[string][]byte)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil, nil, fmt.Errorf("archive entry %q is not a regular file", hdr.Name)
+		}
+		if err := checkPath(hdr.Name); err != nil {
+			return nil, nil, err
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading %s: %w", hdr.Name, err)
+		}
+		files[hdr.Name] = data
+	}
+
+	raw, ok := files[manifestName]
+	if !ok {
+		return nil, nil, errors.New("not a picoclaw export: no manifest.json")
+	}
+	delete(files, manifestName)
+	var m Manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if m.Format != FormatVersion {
+		return nil, nil, fmt.Errorf("unsupported export format %d", m.Format)
+	}
+
+	if len(m.Files) != len(files) {
+		return nil, nil, fmt.Errorf("archive has %d files, manifest lists %d", len(files), len(m.Files))
+	}
+	for _, f := range m.Files {
+		data, ok := files[f.Path]
+		if !ok {
+			return nil, nil, fmt.Errorf("%s is in the manifest but not in the archive", f.Path)
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != f.SHA256 {
+			return nil, nil, fmt.Errorf("%s: checksum mismatch", f.Path)
+		}
+	}
+	return &m, files, nil
+}
+
+// checkPath rejects entries that would land outside the places Import
+// writes to.
+func checkPath(name string) error {
+	if name == "" || path.IsAbs(name) || strings.Contains(name, `\`) || path.Clean(name) != name {
+		return fmt.Errorf("unsafe path %q in archive", name)
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return fmt.Errorf("unsafe path %q in archive", name)
+		}
+	}
+	switch {
+	case name == manifestName, name == "config.json",
+		name == "cron/jobs.json", name == "state/state.json",
+		strings.HasPrefix(name, sessionsPrefix),
+		strings.HasPrefix(name, webUISessionsPrefix),
+		strings.HasPrefix(name, "memory/"),
+		strings.HasPrefix(name, "skills/"):
+		return nil
+	}
+	return fmt.Errorf("unexpected file %q in archive", name)
+}
+
+// importSessions imports the sessions under prefix into the store in dir.
+// label names them in the result.
+func importSessions(files map[string][]byte, prefix, dir, label string, res *Result) error {
+	names := sortedNames(files, prefix)
+	if len(names) == 0 {
+		return nil
+	}
+	if dir == "" {
+		res.Skipped = append(res.Skipped, fmt.Sprintf("%d %s files (no store to import them into)", len(names), label))
+		return nil
+	}
+
+	sm := session.NewSessionManager(dir)
+	for _, name := range names {
+		var s session.Session
+		if err := json.Unmarshal(files[name], &s); err != nil || s.Key == "" {
+			res.Skipped = append(res.Skipped, label+" file "+path.Base(name)+" (invalid)")
+			continue
+		}
+		result := sm.Import(s)
+		if result == session.ImportSkipped {
+			res.Skipped = append(res.Skipped, label+" "+s.Key+" (already present)")
+			continue
+		}
+		if err := sm.Save(s.Key); err != nil {
+			return fmt.Errorf("saving %s %s: %w", label, s.Key, err)
+		}
+		if result == session.ImportMerged {
+			res.Merged = append(res.Merged, label+" "+s.Key)
+		} else {
+			res.Added = append(res.Added, label+" "+s.Key)
+		}
+	}
+	return nil
+}
+
+func importMemory(files map[string][]byte, workspace string, res *Result) error {
+	for _, name := range sortedNames(files, "memory/") {
+		data := files[name]
+		target := filepath.Join(workspace, filepath.FromSlash(name))
+		local, err := crypt.ReadFile(target)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		imported := string(data)
+		current := string(local)
+		var merged string
+		switch {
+		case strings.TrimSpace(current) == "":
+			merged = imported
+			res.Added = append(res.Added, name)
+		case strings.Contains(current, imported):
+			res.Skipped = append(res.Skipped, name+" (already present)")
+			continue
+		case strings.HasPrefix(imported, current):
+			merged = imported
+			res.Merged = append(res.Merged, name)
+		default:
+			merged = strings.TrimRight(current, "\n") + memorySeparator + imported
+			res.Merged = append(res.Merged, name)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := crypt.WriteFile(target, []byte(merged), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func importCron(data []byte, workspace string, res *Result) error {
+	var store cron.CronStore
+	if err := json.Unmarshal(data, &store); err != nil {
+		return fmt.Errorf("cron/jobs.json: %w", err)
+	}
+	cs := cron.NewCronService(filepath.Join(workspace, "cron", "jobs.json"), nil)
+	for _, job := range store.Jobs {
+		added, err := cs.ImportJob(job)
+		if err != nil {
+			return err
+		}
+		label := fmt.Sprintf("cron job %s (%s)", job.ID, job.Name)
+		if added {
+			res.Added = append(res.Added, label)
+		} else {
+			res.Skipped = append(res.Skipped, label+" (already present)")
+		}
+	}
+	return nil
+}
+
+// importSkills copies each skill whose directory doesn't exist yet.
+func importSkills(files map[string][]byte, workspace string, res *Result) error {
+	skip := make(map[string]bool)
+	for _, name := range sortedNames(files, "skills/") {
+		data := files[name]
+		rest := strings.TrimPrefix(name, "skills/")
+		skill, _, _ := strings.Cut(rest, "/")
+		dir := filepath.Join(workspace, "skills", skill)
+
+		if _, seen := skip[skill]; !seen {
+			_, err := os.Stat(dir)
+			skip[skill] = err == nil
+			if skip[skill] {
+				res.Skipped = append(res.Skipped, "skill "+skill+" (already installed)")
+			} else {
+				res.Added = append(res.Added, "skill "+skill)
+			}
+		}
+		if skip[skill] {
+			continue
+		}
+
+		target := filepath.Join(workspace, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedNames returns the archive paths starting with prefix, in order.
+func sortedNames(files map[string][]byte, prefix string) []string {
+	var names []string
+	for name := range files {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeIfMissing(target string, data []byte, label string, res *Result) error {
+	if _, err := os.Stat(target); err == nil {
+		res.Skipped = append(res.Skipped, label+" (already present)")
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(target, data, 0644); err != nil {
+		return err
+	}
+	res.Added = append(res.Added, label)
+	return nil
+}
@@ -1,10 +1,14 @@
 package auth
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/crypt"
 )
 
 func TestAuthCredentialIsExpired(t *testing.T) {
@@ -187,3 +191,37 @@ func TestLoadStoreEmpty(t *testing.T) {
 		t.Errorf("expected empty credentials, got %d", len(store.Credentials))
 	}
 }
+
+func TestStoreEncrypted(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+
+	keyFile := filepath.Join(tmpDir, "at-rest.key")
+	os.WriteFile(keyFile, []byte("0123456789abcdef0123456789abcdef"), 0600)
+	key, err := crypt.LoadKey("", keyFile, "")
+	if err != nil {
+		t.Fatalf("LoadKey() error: %v", err)
+	}
+	crypt.Configure(key, true)
+	defer crypt.Configure(nil, false)
+
+	cred := &AuthCredential{RefreshToken: "refresh-secret", Provider: "openai", AuthMethod: "oauth"}
+	if err := SetCredential("openai", cred); err != nil {
+		t.Fatalf("SetCredential() error: %v", err)
+	}
+
+	raw, _ := os.ReadFile(StorePath())
+	if strings.Contains(string(raw), "refresh-secret") {
+		t.Fatal("refresh token is readable on disk")
+	}
+
+	loaded, err := GetCredential("openai")
+	if err != nil || loaded == nil || loaded.RefreshToken != "refresh-secret" {
+		t.Fatalf("GetCredential() = %+v, %v", loaded, err)
+	}
+
+	crypt.Configure(nil, false)
+	if _, err := LoadStore(); !errors.Is(err, crypt.ErrNoKey) {
+		t.Errorf("LoadStore() without key error = %v, want ErrNoKey", err)
+	}
+}
@@ -11,6 +11,9 @@ type MessageBus struct {
 	handlers map[string]MessageHandler
 	closed   bool
 	mu       sync.RWMutex
+
+	incognito         sync.Map // Session keys of incognito conversations
+	incognitoChannels sync.Map // Channels all of whose chats are incognito
 }
 
 func NewMessageBus() *MessageBus {
@@ -70,6 +73,32 @@ func (mb *MessageBus) GetHandler(channel string) (MessageHandler, bool) {
 	return handler, ok
 }
 
+// SetIncognito switches incognito on or off for the conversation with
+// sessionKey. Channels check it to keep the content of incognito chats out
+// of their logs.
+func (mb *MessageBus) SetIncognito(sessionKey string, on bool) {
+	if on {
+		mb.incognito.Store(sessionKey, true)
+	} else {
+		mb.incognito.Delete(sessionKey)
+	}
+}
+
+// SetIncognitoChannel makes every chat of channel incognito.
+func (mb *MessageBus) SetIncognitoChannel(channel string) {
+	mb.incognitoChannels.Store(channel, true)
+}
+
+// IsIncognito reports whether the conversation with sessionKey on channel
+// is incognito.
+func (mb *MessageBus) IsIncognito(sessionKey, channel string) bool {
+	if _, on := mb.incognitoChannels.Load(channel); on {
+		return true
+	}
+	_, on := mb.incognito.Load(sessionKey)
+	return on
+}
+
 func (mb *MessageBus) Close() {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
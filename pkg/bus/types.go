@@ -11,9 +11,18 @@ type InboundMessage struct {
 }
 
 type OutboundMessage struct {
-	Channel string `json:"channel"`
-	ChatID  string `json:"chat_id"`
-	Content string `json:"content"`
+	Channel string   `json:"channel"`
+	ChatID  string   `json:"chat_id"`
+	Content string   `json:"content"`
+	Buttons []Button `json:"buttons,omitempty"` // Shown by channels that support them
+}
+
+// Button is a quick reply offered with an outbound message. Pressing it
+// sends Data back as if the user had typed it, so Content should also tell
+// users of channels without buttons what to reply.
+type Button struct {
+	Label string `json:"label"`
+	Data  string `json:"data"`
 }
 
 type MessageHandler func(InboundMessage) error
@@ -3,9 +3,15 @@ package channels
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/egress"
+	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
 type Channel interface {
@@ -87,8 +93,7 @@ func (c *BaseChannel) HandleMessage(senderID, chatID, content string, media []st
 		return
 	}
 
-	// Build session key: channel:chatID
-	sessionKey := fmt.Sprintf("%s:%s", c.name, chatID)
+	sessionKey := c.sessionKey(chatID)
 
 	msg := bus.InboundMessage{
 		Channel:    c.name,
@@ -103,6 +108,31 @@ func (c *BaseChannel) HandleMessage(senderID, chatID, content string, media []st
 	c.bus.PublishInbound(msg)
 }
 
+// sessionKey builds the session key of a chat: channel:chatID
+func (c *BaseChannel) sessionKey(chatID string) string {
+	return fmt.Sprintf("%s:%s", c.name, chatID)
+}
+
+// preview shortens message content for a log entry. The content of
+// incognito chats is left out.
+func (c *BaseChannel) preview(chatID, content string, maxLen int) string {
+	if c.bus.IsIncognito(c.sessionKey(chatID), c.name) {
+		return "(incognito)"
+	}
+	return utils.Truncate(content, maxLen)
+}
+
 func (c *BaseChannel) setRunning(running bool) {
 	c.running = running
 }
+
+// wsDialer returns a WebSocket dialer like websocket.DefaultDialer whose
+// connections go through egress, so they respect local-only mode and are
+// written to the network audit log.
+func wsDialer(component string, handshakeTimeout time.Duration) *websocket.Dialer {
+	return &websocket.Dialer{
+		Proxy:            egress.Proxy(http.ProxyFromEnvironment),
+		HandshakeTimeout: handshakeTimeout,
+		NetDialContext:   egress.DialContext(component),
+	}
+}
@@ -1,6 +1,10 @@
 package channels
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
 
 func TestBaseChannelIsAllowed(t *testing.T) {
 	tests := []struct {
@@ -50,3 +54,24 @@ func TestBaseChannelIsAllowed(t *testing.T) {
 		})
 	}
 }
+
+func TestBaseChannelPreview_HidesIncognito(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	ch := NewBaseChannel("telegram", nil, msgBus, nil)
+
+	if got := ch.preview("42", "see you at the clinic", 50); got != "see you at the clinic" {
+		t.Errorf("preview() = %q, want the content", got)
+	}
+	msgBus.SetIncognito("telegram:42", true)
+	if got := ch.preview("42", "see you at the clinic", 50); got != "(incognito)" {
+		t.Errorf("preview() of an incognito chat = %q", got)
+	}
+	if got := ch.preview("7", "hello", 50); got != "hello" {
+		t.Errorf("preview() of another chat = %q, want the content", got)
+	}
+
+	msgBus.SetIncognitoChannel("discord")
+	if got := NewBaseChannel("discord", nil, msgBus, nil).preview("1", "hello", 50); got != "(incognito)" {
+		t.Errorf("preview() on an incognito channel = %q", got)
+	}
+}
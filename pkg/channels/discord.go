@@ -10,6 +10,7 @@ import (
 	"github.com/bwmarrin/discordgo"
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/egress"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/utils"
 	"github.com/sipeed/picoclaw/pkg/voice"
@@ -33,6 +34,8 @@ func NewDiscordChannel(cfg config.DiscordConfig, bus *bus.MessageBus) (*DiscordC
 	if err != nil {
 		return nil, fmt.Errorf("failed to create discord session: %w", err)
 	}
+	session.Client = egress.NewClient("discord", 20*time.Second)
+	session.Dialer = wsDialer("discord", 45*time.Second)
 
 	base := NewBaseChannel("discord", cfg, bus, cfg.AllowFrom)
 
@@ -61,6 +64,7 @@ func (c *DiscordChannel) Start(ctx context.Context) error {
 
 	c.ctx = ctx
 	c.session.AddHandler(c.handleMessage)
+	c.session.AddHandler(c.handleInteraction)
 
 	if err := c.session.Open(); err != nil {
 		return fmt.Errorf("failed to open discord session: %w", err)
@@ -108,8 +112,13 @@ func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 
 	chunks := splitMessage(msg.Content, 1500) // Discord has a limit of 2000 characters per message, leave 500 for natural split e.g. code blocks
 
-	for _, chunk := range chunks {
-		if err := c.sendChunk(ctx, channelID, chunk); err != nil {
+	for i, chunk := range chunks {
+		// Buttons go under the last chunk
+		var components []discordgo.MessageComponent
+		if i == len(chunks)-1 {
+			components = messageButtons(msg.Buttons)
+		}
+		if err := c.sendChunk(ctx, channelID, chunk, components); err != nil {
 			return err
 		}
 	}
@@ -117,6 +126,23 @@ func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 	return nil
 }
 
+// messageButtons renders buttons as one action row, or returns nil when
+// there are none.
+func messageButtons(buttons []bus.Button) []discordgo.MessageComponent {
+	if len(buttons) == 0 {
+		return nil
+	}
+	row := discordgo.ActionsRow{}
+	for _, b := range buttons {
+		row.Components = append(row.Components, discordgo.Button{
+			Label:    b.Label,
+			Style:    discordgo.SecondaryButton,
+			CustomID: b.Data,
+		})
+	}
+	return []discordgo.MessageComponent{row}
+}
+
 // splitMessage splits long messages into chunks, preserving code block integrity
 // Uses natural boundaries (newlines, spaces) and extends messages slightly to avoid breaking code blocks
 func splitMessage(content string, limit int) []string {
@@ -243,14 +269,22 @@ func findLastSpace(s string, searchWindow int) int {
 	return -1
 }
 
-func (c *DiscordChannel) sendChunk(ctx context.Context, channelID, content string) error {
+func (c *DiscordChannel) sendChunk(ctx context.Context, channelID, content string, components []discordgo.MessageComponent) error {
 	// 使用传入的 ctx 进行超时控制
 	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
 	defer cancel()
 
 	done := make(chan error, 1)
 	go func() {
-		_, err := c.session.ChannelMessageSend(channelID, content)
+		var err error
+		if len(components) > 0 {
+			_, err = c.session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+				Content:    content,
+				Components: components,
+			})
+		} else {
+			_, err = c.session.ChannelMessageSend(channelID, content)
+		}
 		done <- err
 	}()
 
@@ -373,7 +407,7 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 	logger.DebugCF("discord", "Received message", map[string]any{
 		"sender_name": senderName,
 		"sender_id":   senderID,
-		"preview":     utils.Truncate(content, 50),
+		"preview":     c.preview(m.ChannelID, content, 50),
 	})
 
 	metadata := map[string]string{
@@ -389,6 +423,56 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 	c.HandleMessage(senderID, m.ChannelID, content, mediaPaths, metadata)
 }
 
+// handleInteraction handles a press of a message button: the button's data
+// is passed on as a message from the user who pressed it, and the buttons
+// are removed so they can't be pressed twice.
+func (c *DiscordChannel) handleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i == nil || i.Type != discordgo.InteractionMessageComponent {
+		return
+	}
+
+	user := i.User
+	if i.Member != nil {
+		user = i.Member.User
+	}
+	if user == nil {
+		return
+	}
+	if !c.IsAllowed(user.ID) {
+		logger.DebugCF("discord", "Button press rejected by allowlist", map[string]any{
+			"user_id": user.ID,
+		})
+		return
+	}
+
+	response := &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate}
+	if i.Message != nil {
+		response = &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    i.Message.Content,
+				Components: []discordgo.MessageComponent{},
+			},
+		}
+	}
+	if err := s.InteractionRespond(i.Interaction, response); err != nil {
+		logger.DebugCF("discord", "Failed to acknowledge button press", map[string]any{
+			"error": err.Error(),
+		})
+	}
+
+	metadata := map[string]string{
+		"user_id":    user.ID,
+		"username":   user.Username,
+		"guild_id":   i.GuildID,
+		"channel_id": i.ChannelID,
+		"is_dm":      fmt.Sprintf("%t", i.GuildID == ""),
+		"button":     "true",
+	}
+
+	c.HandleMessage(user.ID, i.ChannelID, i.MessageComponentData().CustomID, nil, metadata)
+}
+
 func (c *DiscordChannel) downloadAttachment(url, filename string) string {
 	return utils.DownloadFile(url, filename, utils.DownloadOptions{
 		LoggerPrefix: "discord",
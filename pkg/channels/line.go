@@ -17,6 +17,7 @@ import (
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/egress"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/utils"
 )
@@ -124,7 +125,7 @@ func (c *LINEChannel) fetchBotInfo() error {
 	}
 	req.Header.Set("Authorization", "Bearer "+c.config.ChannelAccessToken)
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := egress.NewClient("line", 10*time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
@@ -330,7 +331,7 @@ func (c *LINEChannel) processEvent(event lineEvent) {
 	case "image":
 		localPath := c.downloadContent(msg.ID, "image.jpg")
 		if localPath != "" {
-			localFiles = append(localFiles, localPath)
+			// Released by the agent after it has read the image
 			mediaPaths = append(mediaPaths, localPath)
 			content = "[image]"
 		}
@@ -371,7 +372,7 @@ func (c *LINEChannel) processEvent(event lineEvent) {
 		"chat_id":      chatID,
 		"message_type": msg.Type,
 		"is_group":     isGroup,
-		"preview":      utils.Truncate(content, 50),
+		"preview":      c.preview(chatID, content, 50),
 	})
 
 	// Show typing/loading indicator (requires user ID, not group ID)
@@ -571,7 +572,7 @@ func (c *LINEChannel) callAPI(ctx context.Context, endpoint string, payload inte
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+c.config.ChannelAccessToken)
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := egress.NewClient("line", 30*time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("API request failed: %w", err)
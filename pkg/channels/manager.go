@@ -10,10 +10,12 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/constants"
+	"github.com/sipeed/picoclaw/pkg/egress"
 	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
@@ -46,7 +48,7 @@ func NewManager(cfg *config.Config, messageBus *bus.MessageBus) (*Manager, error
 func (m *Manager) initChannels() error {
 	logger.InfoC("channels", "Initializing channel manager")
 
-	if m.config.Channels.Telegram.Enabled && m.config.Channels.Telegram.Token != "" {
+	if m.config.Channels.Telegram.Enabled && m.config.Channels.Telegram.Token != "" && m.allowChannel("telegram", "") {
 		logger.DebugC("channels", "Attempting to initialize Telegram channel")
 		telegram, err := NewTelegramChannel(m.config, m.bus)
 		if err != nil {
@@ -59,7 +61,7 @@ func (m *Manager) initChannels() error {
 		}
 	}
 
-	if m.config.Channels.WhatsApp.Enabled && m.config.Channels.WhatsApp.BridgeURL != "" {
+	if m.config.Channels.WhatsApp.Enabled && m.config.Channels.WhatsApp.BridgeURL != "" && m.allowChannel("whatsapp", m.config.Channels.WhatsApp.BridgeURL) {
 		logger.DebugC("channels", "Attempting to initialize WhatsApp channel")
 		whatsapp, err := NewWhatsAppChannel(m.config.Channels.WhatsApp, m.bus)
 		if err != nil {
@@ -72,7 +74,7 @@ func (m *Manager) initChannels() error {
 		}
 	}
 
-	if m.config.Channels.Feishu.Enabled {
+	if m.config.Channels.Feishu.Enabled && m.allowChannel("feishu", "") {
 		logger.DebugC("channels", "Attempting to initialize Feishu channel")
 		feishu, err := NewFeishuChannel(m.config.Channels.Feishu, m.bus)
 		if err != nil {
@@ -85,7 +87,7 @@ func (m *Manager) initChannels() error {
 		}
 	}
 
-	if m.config.Channels.Discord.Enabled && m.config.Channels.Discord.Token != "" {
+	if m.config.Channels.Discord.Enabled && m.config.Channels.Discord.Token != "" && m.allowChannel("discord", "") {
 		logger.DebugC("channels", "Attempting to initialize Discord channel")
 		discord, err := NewDiscordChannel(m.config.Channels.Discord, m.bus)
 		if err != nil {
@@ -111,7 +113,7 @@ func (m *Manager) initChannels() error {
 		}
 	}
 
-	if m.config.Channels.QQ.Enabled {
+	if m.config.Channels.QQ.Enabled && m.allowChannel("qq", "") {
 		logger.DebugC("channels", "Attempting to initialize QQ channel")
 		qq, err := NewQQChannel(m.config.Channels.QQ, m.bus)
 		if err != nil {
@@ -124,7 +126,7 @@ func (m *Manager) initChannels() error {
 		}
 	}
 
-	if m.config.Channels.DingTalk.Enabled && m.config.Channels.DingTalk.ClientID != "" {
+	if m.config.Channels.DingTalk.Enabled && m.config.Channels.DingTalk.ClientID != "" && m.allowChannel("dingtalk", "") {
 		logger.DebugC("channels", "Attempting to initialize DingTalk channel")
 		dingtalk, err := NewDingTalkChannel(m.config.Channels.DingTalk, m.bus)
 		if err != nil {
@@ -137,7 +139,7 @@ func (m *Manager) initChannels() error {
 		}
 	}
 
-	if m.config.Channels.Slack.Enabled && m.config.Channels.Slack.BotToken != "" {
+	if m.config.Channels.Slack.Enabled && m.config.Channels.Slack.BotToken != "" && m.allowChannel("slack", "") {
 		logger.DebugC("channels", "Attempting to initialize Slack channel")
 		slackCh, err := NewSlackChannel(m.config.Channels.Slack, m.bus)
 		if err != nil {
@@ -150,7 +152,7 @@ func (m *Manager) initChannels() error {
 		}
 	}
 
-	if m.config.Channels.LINE.Enabled && m.config.Channels.LINE.ChannelAccessToken != "" {
+	if m.config.Channels.LINE.Enabled && m.config.Channels.LINE.ChannelAccessToken != "" && m.allowChannel("line", "") {
 		logger.DebugC("channels", "Attempting to initialize LINE channel")
 		line, err := NewLINEChannel(m.config.Channels.LINE, m.bus)
 		if err != nil {
@@ -163,7 +165,7 @@ func (m *Manager) initChannels() error {
 		}
 	}
 
-	if m.config.Channels.OneBot.Enabled && m.config.Channels.OneBot.WSUrl != "" {
+	if m.config.Channels.OneBot.Enabled && m.config.Channels.OneBot.WSUrl != "" && m.allowChannel("onebot", m.config.Channels.OneBot.WSUrl) {
 		logger.DebugC("channels", "Attempting to initialize OneBot channel")
 		onebot, err := NewOneBotChannel(m.config.Channels.OneBot, m.bus)
 		if err != nil {
@@ -183,6 +185,29 @@ func (m *Manager) initChannels() error {
 	return nil
 }
 
+// allowChannel applies privacy.local_only at startup. Channels that only
+// work through a vendor's servers are refused; bridge channels (WhatsApp,
+// OneBot) are allowed when endpoint is on this machine or the local network.
+// MaixCam is always local and isn't checked.
+func (m *Manager) allowChannel(name, endpoint string) bool {
+	if !m.config.Privacy.LocalOnly {
+		return true
+	}
+	if endpoint != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if egress.IsLocalURL(ctx, endpoint) {
+			return true
+		}
+	}
+	logger.ErrorCF("channels", "Channel not enabled: it needs a cloud endpoint and privacy.local_only is set",
+		map[string]interface{}{
+			"channel":  name,
+			"endpoint": endpoint,
+		})
+	return false
+}
+
 func (m *Manager) StartAll(ctx context.Context) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
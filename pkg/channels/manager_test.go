@@ -0,0 +1,27 @@
+package channels
+
+import (
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestManagerAllowChannel_LocalOnly(t *testing.T) {
+	cfg := config.DefaultConfig()
+	m := &Manager{config: cfg}
+
+	if !m.allowChannel("slack", "") {
+		t.Error("channels should be allowed when local_only is off")
+	}
+
+	cfg.Privacy.LocalOnly = true
+	if m.allowChannel("slack", "") {
+		t.Error("slack needs the cloud and should be refused")
+	}
+	if !m.allowChannel("onebot", "ws://127.0.0.1:3001") {
+		t.Error("a OneBot bridge on this machine should be allowed")
+	}
+	if m.allowChannel("whatsapp", "ws://8.8.8.8:3001") {
+		t.Error("a bridge on a public address should be refused")
+	}
+}
@@ -139,8 +139,7 @@ func (c *OneBotChannel) Start(ctx context.Context) error {
 }
 
 func (c *OneBotChannel) connect() error {
-	dialer := websocket.DefaultDialer
-	dialer.HandshakeTimeout = 10 * time.Second
+	dialer := wsDialer("onebot", 10*time.Second)
 
 	header := make(map[string][]string)
 	if c.config.AccessToken != "" {
@@ -14,6 +14,7 @@ import (
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/egress"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/utils"
 	"github.com/sipeed/picoclaw/pkg/voice"
@@ -44,9 +45,10 @@ func NewSlackChannel(cfg config.SlackConfig, messageBus *bus.MessageBus) (*Slack
 	api := slack.New(
 		cfg.BotToken,
 		slack.OptionAppLevelToken(cfg.AppToken),
+		slack.OptionHTTPClient(egress.NewClient("slack", 0)),
 	)
 
-	socketClient := socketmode.New(api)
+	socketClient := socketmode.New(api, socketmode.OptionDialer(wsDialer("slack", 45*time.Second)))
 
 	base := NewBaseChannel("slack", cfg, messageBus, cfg.AllowFrom)
 
@@ -250,7 +252,10 @@ func (c *SlackChannel) handleMessageEvent(ev *slackevents.MessageEvent) {
 			if localPath == "" {
 				continue
 			}
-			localFiles = append(localFiles, localPath)
+			// Images are released by the agent after it has read them
+			if !utils.IsImageFile(file.Name, file.Mimetype) {
+				localFiles = append(localFiles, localPath)
+			}
 			mediaPaths = append(mediaPaths, localPath)
 
 			if utils.IsAudioFile(file.Name, file.Mimetype) && c.transcriber != nil && c.transcriber.IsAvailable() {
@@ -284,7 +289,7 @@ func (c *SlackChannel) handleMessageEvent(ev *slackevents.MessageEvent) {
 	logger.DebugCF("slack", "Received message", map[string]interface{}{
 		"sender_id":  senderID,
 		"chat_id":    chatID,
-		"preview":    utils.Truncate(content, 50),
+		"preview":    c.preview(chatID, content, 50),
 		"has_thread": threadTS != "",
 	})
 
@@ -378,7 +383,7 @@ func (c *SlackChannel) handleSlashCommand(event socketmode.Event) {
 	logger.DebugCF("slack", "Slash command received", map[string]interface{}{
 		"sender_id": senderID,
 		"command":   cmd.Command,
-		"text":      utils.Truncate(content, 50),
+		"text":      c.preview(chatID, content, 50),
 	})
 
 	c.HandleMessage(senderID, chatID, content, nil, metadata)
@@ -12,7 +12,6 @@ import (
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
-	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
 type WhatsAppChannel struct {
@@ -38,8 +37,7 @@ func NewWhatsAppChannel(cfg config.WhatsAppConfig, bus *bus.MessageBus) (*WhatsA
 func (c *WhatsAppChannel) Start(ctx context.Context) error {
 	log.Printf("Starting WhatsApp channel connecting to %s...", c.url)
 
-	dialer := websocket.DefaultDialer
-	dialer.HandshakeTimeout = 10 * time.Second
+	dialer := wsDialer("whatsapp", 10*time.Second)
 
 	conn, _, err := dialer.Dial(c.url, nil)
 	if err != nil {
@@ -178,7 +176,7 @@ func (c *WhatsAppChannel) handleIncomingMessage(msg map[string]interface{}) {
 		metadata["user_name"] = userName
 	}
 
-	log.Printf("WhatsApp message from %s: %s...", senderID, utils.Truncate(content, 50))
+	log.Printf("WhatsApp message from %s: %s...", senderID, c.preview(chatID, content, 50))
 
 	c.HandleMessage(senderID, chatID, content, mediaPaths, metadata)
 }
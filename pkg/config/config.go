@@ -44,14 +44,19 @@ func (f *FlexibleStringSlice) UnmarshalJSON(data []byte) error {
 }
 
 type Config struct {
-	Agents    AgentsConfig    `json:"agents"`
-	Channels  ChannelsConfig  `json:"channels"`
-	Providers ProvidersConfig `json:"providers"`
-	Gateway   GatewayConfig   `json:"gateway"`
-	Tools     ToolsConfig     `json:"tools"`
-	Heartbeat HeartbeatConfig `json:"heartbeat"`
-	Devices   DevicesConfig   `json:"devices"`
-	mu        sync.RWMutex
+	Agents     AgentsConfig     `json:"agents"`
+	Channels   ChannelsConfig   `json:"channels"`
+	Providers  ProvidersConfig  `json:"providers"`
+	Gateway    GatewayConfig    `json:"gateway"`
+	Tools      ToolsConfig      `json:"tools"`
+	Heartbeat  HeartbeatConfig  `json:"heartbeat"`
+	Devices    DevicesConfig    `json:"devices"`
+	Usage      UsageConfig      `json:"usage"`
+	Embeddings EmbeddingsConfig `json:"embeddings"`
+	Cassette   CassetteConfig   `json:"cassette"`
+	Privacy    PrivacyConfig    `json:"privacy"`
+	mu         sync.RWMutex
+	secrets    []SecretRef // secret fields loaded from references
 }
 
 type AgentsConfig struct {
@@ -66,6 +71,26 @@ type AgentDefaults struct {
 	MaxTokens           int     `json:"max_tokens" env:"PICOCLAW_AGENTS_DEFAULTS_MAX_TOKENS"`
 	Temperature         float64 `json:"temperature" env:"PICOCLAW_AGENTS_DEFAULTS_TEMPERATURE"`
 	MaxToolIterations   int     `json:"max_tool_iterations" env:"PICOCLAW_AGENTS_DEFAULTS_MAX_TOOL_ITERATIONS"`
+	// MaxConcurrentSessions is how many conversations the gateway works on
+	// at once. Messages of one conversation are always handled in order.
+	MaxConcurrentSessions int `json:"max_concurrent_sessions,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_MAX_CONCURRENT_SESSIONS"`
+	// MaxParallelTools is how many tool calls from one model response run at
+	// once. 1 runs them one after another.
+	MaxParallelTools int `json:"max_parallel_tools,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_MAX_PARALLEL_TOOLS"`
+	// ContextWindow overrides the model's context size in tokens as
+	// reported by the provider. 0 means detect it.
+	ContextWindow int `json:"context_window,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_CONTEXT_WINDOW"`
+	// Fallbacks are tried in order when the primary provider is unreachable,
+	// returns a 5xx or is rate limited.
+	Fallbacks []FallbackConfig `json:"fallbacks,omitempty"`
+}
+
+// FallbackConfig names a backup provider. An empty Model reuses the model
+// requested of the primary provider.
+type FallbackConfig struct {
+	Provider        string `json:"provider"`
+	Model           string `json:"model,omitempty"`
+	CooldownSeconds int    `json:"cooldown_seconds,omitempty"`
 }
 
 type ChannelsConfig struct {
@@ -89,7 +114,7 @@ type WhatsAppConfig struct {
 
 type TelegramConfig struct {
 	Enabled   bool                `json:"enabled" env:"PICOCLAW_CHANNELS_TELEGRAM_ENABLED"`
-	Token     string              `json:"token" env:"PICOCLAW_CHANNELS_TELEGRAM_TOKEN"`
+	Token     string              `json:"token" env:"PICOCLAW_CHANNELS_TELEGRAM_TOKEN" secret:"true"`
 	Proxy     string              `json:"proxy" env:"PICOCLAW_CHANNELS_TELEGRAM_PROXY"`
 	AllowFrom FlexibleStringSlice `json:"allow_from" env:"PICOCLAW_CHANNELS_TELEGRAM_ALLOW_FROM"`
 }
@@ -97,15 +122,15 @@ type TelegramConfig struct {
 type FeishuConfig struct {
 	Enabled           bool                `json:"enabled" env:"PICOCLAW_CHANNELS_FEISHU_ENABLED"`
 	AppID             string              `json:"app_id" env:"PICOCLAW_CHANNELS_FEISHU_APP_ID"`
-	AppSecret         string              `json:"app_secret" env:"PICOCLAW_CHANNELS_FEISHU_APP_SECRET"`
-	EncryptKey        string              `json:"encrypt_key" env:"PICOCLAW_CHANNELS_FEISHU_ENCRYPT_KEY"`
-	VerificationToken string              `json:"verification_token" env:"PICOCLAW_CHANNELS_FEISHU_VERIFICATION_TOKEN"`
+	AppSecret         string              `json:"app_secret" env:"PICOCLAW_CHANNELS_FEISHU_APP_SECRET" secret:"true"`
+	EncryptKey        string              `json:"encrypt_key" env:"PICOCLAW_CHANNELS_FEISHU_ENCRYPT_KEY" secret:"true"`
+	VerificationToken string              `json:"verification_token" env:"PICOCLAW_CHANNELS_FEISHU_VERIFICATION_TOKEN" secret:"true"`
 	AllowFrom         FlexibleStringSlice `json:"allow_from" env:"PICOCLAW_CHANNELS_FEISHU_ALLOW_FROM"`
 }
 
 type DiscordConfig struct {
 	Enabled   bool                `json:"enabled" env:"PICOCLAW_CHANNELS_DISCORD_ENABLED"`
-	Token     string              `json:"token" env:"PICOCLAW_CHANNELS_DISCORD_TOKEN"`
+	Token     string              `json:"token" env:"PICOCLAW_CHANNELS_DISCORD_TOKEN" secret:"true"`
 	AllowFrom FlexibleStringSlice `json:"allow_from" env:"PICOCLAW_CHANNELS_DISCORD_ALLOW_FROM"`
 }
 
@@ -119,28 +144,28 @@ type MaixCamConfig struct {
 type QQConfig struct {
 	Enabled   bool                `json:"enabled" env:"PICOCLAW_CHANNELS_QQ_ENABLED"`
 	AppID     string              `json:"app_id" env:"PICOCLAW_CHANNELS_QQ_APP_ID"`
-	AppSecret string              `json:"app_secret" env:"PICOCLAW_CHANNELS_QQ_APP_SECRET"`
+	AppSecret string              `json:"app_secret" env:"PICOCLAW_CHANNELS_QQ_APP_SECRET" secret:"true"`
 	AllowFrom FlexibleStringSlice `json:"allow_from" env:"PICOCLAW_CHANNELS_QQ_ALLOW_FROM"`
 }
 
 type DingTalkConfig struct {
 	Enabled      bool                `json:"enabled" env:"PICOCLAW_CHANNELS_DINGTALK_ENABLED"`
 	ClientID     string              `json:"client_id" env:"PICOCLAW_CHANNELS_DINGTALK_CLIENT_ID"`
-	ClientSecret string              `json:"client_secret" env:"PICOCLAW_CHANNELS_DINGTALK_CLIENT_SECRET"`
+	ClientSecret string              `json:"client_secret" env:"PICOCLAW_CHANNELS_DINGTALK_CLIENT_SECRET" secret:"true"`
 	AllowFrom    FlexibleStringSlice `json:"allow_from" env:"PICOCLAW_CHANNELS_DINGTALK_ALLOW_FROM"`
 }
 
 type SlackConfig struct {
 	Enabled   bool                `json:"enabled" env:"PICOCLAW_CHANNELS_SLACK_ENABLED"`
-	BotToken  string              `json:"bot_token" env:"PICOCLAW_CHANNELS_SLACK_BOT_TOKEN"`
-	AppToken  string              `json:"app_token" env:"PICOCLAW_CHANNELS_SLACK_APP_TOKEN"`
+	BotToken  string              `json:"bot_token" env:"PICOCLAW_CHANNELS_SLACK_BOT_TOKEN" secret:"true"`
+	AppToken  string              `json:"app_token" env:"PICOCLAW_CHANNELS_SLACK_APP_TOKEN" secret:"true"`
 	AllowFrom FlexibleStringSlice `json:"allow_from" env:"PICOCLAW_CHANNELS_SLACK_ALLOW_FROM"`
 }
 
 type LINEConfig struct {
 	Enabled            bool                `json:"enabled" env:"PICOCLAW_CHANNELS_LINE_ENABLED"`
-	ChannelSecret      string              `json:"channel_secret" env:"PICOCLAW_CHANNELS_LINE_CHANNEL_SECRET"`
-	ChannelAccessToken string              `json:"channel_access_token" env:"PICOCLAW_CHANNELS_LINE_CHANNEL_ACCESS_TOKEN"`
+	ChannelSecret      string              `json:"channel_secret" env:"PICOCLAW_CHANNELS_LINE_CHANNEL_SECRET" secret:"true"`
+	ChannelAccessToken string              `json:"channel_access_token" env:"PICOCLAW_CHANNELS_LINE_CHANNEL_ACCESS_TOKEN" secret:"true"`
 	WebhookHost        string              `json:"webhook_host" env:"PICOCLAW_CHANNELS_LINE_WEBHOOK_HOST"`
 	WebhookPort        int                 `json:"webhook_port" env:"PICOCLAW_CHANNELS_LINE_WEBHOOK_PORT"`
 	WebhookPath        string              `json:"webhook_path" env:"PICOCLAW_CHANNELS_LINE_WEBHOOK_PATH"`
@@ -150,7 +175,7 @@ type LINEConfig struct {
 type OneBotConfig struct {
 	Enabled            bool                `json:"enabled" env:"PICOCLAW_CHANNELS_ONEBOT_ENABLED"`
 	WSUrl              string              `json:"ws_url" env:"PICOCLAW_CHANNELS_ONEBOT_WS_URL"`
-	AccessToken        string              `json:"access_token" env:"PICOCLAW_CHANNELS_ONEBOT_ACCESS_TOKEN"`
+	AccessToken        string              `json:"access_token" env:"PICOCLAW_CHANNELS_ONEBOT_ACCESS_TOKEN" secret:"true"`
 	ReconnectInterval  int                 `json:"reconnect_interval" env:"PICOCLAW_CHANNELS_ONEBOT_RECONNECT_INTERVAL"`
 	GroupTriggerPrefix []string            `json:"group_trigger_prefix" env:"PICOCLAW_CHANNELS_ONEBOT_GROUP_TRIGGER_PREFIX"`
 	AllowFrom          FlexibleStringSlice `json:"allow_from" env:"PICOCLAW_CHANNELS_ONEBOT_ALLOW_FROM"`
@@ -166,6 +191,72 @@ type DevicesConfig struct {
 	MonitorUSB bool `json:"monitor_usb" env:"PICOCLAW_DEVICES_MONITOR_USB"`
 }
 
+// UsageConfig sets daily caps on cloud LLM usage. Zero disables a cap.
+// Calls to local providers are counted but never blocked.
+type UsageConfig struct {
+	DailyTokenLimit int                   `json:"daily_token_limit" env:"PICOCLAW_USAGE_DAILY_TOKEN_LIMIT"`
+	DailyCostLimit  float64               `json:"daily_cost_limit" env:"PICOCLAW_USAGE_DAILY_COST_LIMIT"` // USD
+	Prices          map[string]ModelPrice `json:"prices,omitempty"`                                       // keyed by model name
+}
+
+// ModelPrice is the cost of a model in USD per million tokens.
+type ModelPrice struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// EmbeddingsConfig selects the backend used to embed text for semantic
+// search. Provider is "ollama" (the default), "openai" or "vllm"; the
+// connection settings come from the matching entry in providers.
+type EmbeddingsConfig struct {
+	Provider  string `json:"provider" env:"PICOCLAW_EMBEDDINGS_PROVIDER"`
+	Model     string `json:"model" env:"PICOCLAW_EMBEDDINGS_MODEL"`
+	BatchSize int    `json:"batch_size,omitempty" env:"PICOCLAW_EMBEDDINGS_BATCH_SIZE"`
+}
+
+// CassetteConfig records LLM traffic to a file or replays it from one
+// instead of calling a provider. Mode is "record", "replay" or empty (off).
+// Path defaults to cassettes/cassette.json in the workspace.
+type CassetteConfig struct {
+	Mode string `json:"mode,omitempty" env:"PICOCLAW_CASSETTE_MODE"`
+	Path string `json:"path,omitempty" env:"PICOCLAW_CASSETTE_PATH"`
+}
+
+// PrivacyConfig controls what leaves the machine and what can be read from
+// its disk. LocalOnly refuses every connection outside loopback and private
+// networks. RedactPatterns are extra regular expressions, on top of the
+// built-in email, phone, IBAN and API key rules, whose matches are hidden
+// from providers that have redact set. Encryption protects personal data at
+// rest and Retention limits how long it is kept. Conversations on
+// IncognitoChannels are never written to disk.
+type PrivacyConfig struct {
+	LocalOnly         bool             `json:"local_only" env:"PICOCLAW_PRIVACY_LOCAL_ONLY"`
+	RedactPatterns    []string         `json:"redact_patterns,omitempty"`
+	Encryption        EncryptionConfig `json:"encryption"`
+	Retention         RetentionConfig  `json:"retention"`
+	IncognitoChannels []string         `json:"incognito_channels,omitempty"`
+}
+
+// EncryptionConfig encrypts sessions, memory and OAuth credentials at rest.
+// The key is read from KeyFile or derived from the PICOCLAW_PASSPHRASE
+// environment variable; the passphrase itself is never stored in the config.
+type EncryptionConfig struct {
+	Enabled bool   `json:"enabled" env:"PICOCLAW_PRIVACY_ENCRYPTION_ENABLED"`
+	KeyFile string `json:"key_file,omitempty" env:"PICOCLAW_PRIVACY_ENCRYPTION_KEY_FILE"`
+}
+
+// RetentionConfig deletes personal data once it is older than an age such
+// as "30d" or "24h". Sessions and Cron cover chat histories (Cron the ones
+// cron jobs run in, plus finished one-time jobs), Media covers downloaded
+// attachments and DailyNotes the memory/YYYYMM notes. An empty value keeps
+// that data forever.
+type RetentionConfig struct {
+	Sessions   string `json:"sessions,omitempty" env:"PICOCLAW_PRIVACY_RETENTION_SESSIONS"`
+	Media      string `json:"media,omitempty" env:"PICOCLAW_PRIVACY_RETENTION_MEDIA"`
+	DailyNotes string `json:"daily_notes,omitempty" env:"PICOCLAW_PRIVACY_RETENTION_DAILY_NOTES"`
+	Cron       string `json:"cron,omitempty" env:"PICOCLAW_PRIVACY_RETENTION_CRON"`
+}
+
 type ProvidersConfig struct {
 	Anthropic     ProviderConfig `json:"anthropic"`
 	OpenAI        ProviderConfig `json:"openai"`
@@ -183,11 +274,14 @@ type ProvidersConfig struct {
 }
 
 type ProviderConfig struct {
-	APIKey      string `json:"api_key" env:"PICOCLAW_PROVIDERS_{{.Name}}_API_KEY"`
+	APIKey      string `json:"api_key" env:"PICOCLAW_PROVIDERS_{{.Name}}_API_KEY" secret:"true"`
 	APIBase     string `json:"api_base" env:"PICOCLAW_PROVIDERS_{{.Name}}_API_BASE"`
 	Proxy       string `json:"proxy,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_PROXY"`
 	AuthMethod  string `json:"auth_method,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_AUTH_METHOD"`
 	ConnectMode string `json:"connect_mode,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_CONNECT_MODE"` //only for Github Copilot, `stdio` or `grpc`
+	// Redact replaces personal data and secrets in prompts with placeholders
+	// before they are sent to this provider.
+	Redact bool `json:"redact,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_REDACT"`
 }
 
 type GatewayConfig struct {
@@ -197,7 +291,7 @@ type GatewayConfig struct {
 
 type BraveConfig struct {
 	Enabled    bool   `json:"enabled" env:"PICOCLAW_TOOLS_WEB_BRAVE_ENABLED"`
-	APIKey     string `json:"api_key" env:"PICOCLAW_TOOLS_WEB_BRAVE_API_KEY"`
+	APIKey     string `json:"api_key" env:"PICOCLAW_TOOLS_WEB_BRAVE_API_KEY" secret:"true"`
 	MaxResults int    `json:"max_results" env:"PICOCLAW_TOOLS_WEB_BRAVE_MAX_RESULTS"`
 }
 
@@ -211,21 +305,60 @@ type WebToolsConfig struct {
 	DuckDuckGo DuckDuckGoConfig `json:"duckduckgo"`
 }
 
+// ApprovalConfig selects tool calls that wait for the user to approve them
+// in the chat they came from.
+type ApprovalConfig struct {
+	// Tools lists tools every call of which needs approval
+	Tools FlexibleStringSlice `json:"tools" env:"PICOCLAW_TOOLS_APPROVAL_TOOLS"`
+	// Patterns maps a tool name to regular expressions; a call needs approval
+	// when one of them matches any of its string arguments
+	Patterns map[string][]string `json:"patterns,omitempty"`
+	// TimeoutSeconds is how long a request waits for an answer before the
+	// call is refused
+	TimeoutSeconds int `json:"timeout_seconds" env:"PICOCLAW_TOOLS_APPROVAL_TIMEOUT_SECONDS"`
+}
+
+// ToolPolicy limits the tools available to the messages it matches. Empty
+// match fields match anything.
+type ToolPolicy struct {
+	// Channel is the channel name the policy applies to
+	Channel string `json:"channel,omitempty"`
+	// Senders lists sender IDs or usernames
+	Senders FlexibleStringSlice `json:"senders,omitempty"`
+	// Groups lists chat IDs, or Discord server (guild) IDs
+	Groups FlexibleStringSlice `json:"groups,omitempty"`
+	// Allow lists the tools that may be used; empty or "*" allows all
+	Allow FlexibleStringSlice `json:"allow,omitempty"`
+	// Deny lists tools that may not be used, even if allowed above; "*"
+	// denies all
+	Deny FlexibleStringSlice `json:"deny,omitempty"`
+	// Args maps a tool name to its arguments and a regular expression each
+	// one's value must match for the call to run
+	Args map[string]map[string]string `json:"args,omitempty"`
+}
+
 type ToolsConfig struct {
-	Web WebToolsConfig `json:"web"`
+	Web      WebToolsConfig `json:"web"`
+	Approval ApprovalConfig `json:"approval"`
+	// Policies are checked in order and the first that matches a message
+	// applies. Messages no policy matches may use no tool on a channel some
+	// policy names, and every tool elsewhere.
+	Policies []ToolPolicy `json:"policies,omitempty"`
 }
 
 func DefaultConfig() *Config {
 	return &Config{
 		Agents: AgentsConfig{
 			Defaults: AgentDefaults{
-				Workspace:           "~/.picoclaw/workspace",
-				RestrictToWorkspace: true,
-				Provider:            "",
-				Model:               "glm-4.7",
-				MaxTokens:           8192,
-				Temperature:         0.7,
-				MaxToolIterations:   20,
+				Workspace:             "~/.picoclaw/workspace",
+				RestrictToWorkspace:   true,
+				Provider:              "",
+				Model:                 "glm-4.7",
+				MaxTokens:             8192,
+				Temperature:           0.7,
+				MaxToolIterations:     20,
+				MaxConcurrentSessions: 4,
+				MaxParallelTools:      4,
 			},
 		},
 		Channels: ChannelsConfig{
@@ -322,6 +455,10 @@ func DefaultConfig() *Config {
 					MaxResults: 5,
 				},
 			},
+			Approval: ApprovalConfig{
+				Tools:          FlexibleStringSlice{},
+				TimeoutSeconds: 300,
+			},
 		},
 		Heartbeat: HeartbeatConfig{
 			Enabled:  true,
@@ -353,14 +490,20 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, err
 	}
 
+	cfg.resolveSecrets()
+
 	return cfg, nil
 }
 
+// SaveConfig writes cfg to path. Secrets loaded from a reference are
+// written as the reference, not the value.
 func SaveConfig(path string, cfg *Config) error {
-	cfg.mu.RLock()
-	defer cfg.mu.RUnlock()
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
 
+	restore := cfg.unresolveSecrets()
 	data, err := json.MarshalIndent(cfg, "", "  ")
+	restore()
 	if err != nil {
 		return err
 	}
@@ -379,6 +522,29 @@ func (c *Config) WorkspacePath() string {
 	return expandHome(c.Agents.Defaults.Workspace)
 }
 
+// CassettePath returns the cassette file, defaulting to
+// cassettes/cassette.json in the workspace.
+func (c *Config) CassettePath() string {
+	c.mu.RLock()
+	path := c.Cassette.Path
+	c.mu.RUnlock()
+	if path == "" {
+		return filepath.Join(c.WorkspacePath(), "cassettes", "cassette.json")
+	}
+	return expandHome(path)
+}
+
+// EncryptionKeyFile returns privacy.encryption.key_file with ~ expanded,
+// or "" when no key file is configured.
+func (c *Config) EncryptionKeyFile() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.Privacy.Encryption.KeyFile == "" {
+		return ""
+	}
+	return expandHome(c.Privacy.Encryption.KeyFile)
+}
+
 func (c *Config) GetAPIKey() string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
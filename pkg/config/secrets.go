@@ -0,0 +1,181 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Secret fields (tagged secret:"true") may hold a reference instead of the
+// value itself. LoadConfig resolves references and SaveConfig writes them
+// back as they were, so the value never lands in config.json.
+//
+//	file:/run/secrets/openrouter   contents of the file, trimmed
+//	env:MY_KEY                     value of an environment variable
+//	cmd:pass show picoclaw/tg      first-line-trimmed output of a shell command
+const (
+	secretFilePrefix = "file:"
+	secretEnvPrefix  = "env:"
+	secretCmdPrefix  = "cmd:"
+)
+
+// secretCmdTimeout bounds how long a cmd: reference may run.
+var secretCmdTimeout = 10 * time.Second
+
+// SecretRef is a secret field that was set to a reference.
+type SecretRef struct {
+	Path string // JSON path of the field, e.g. providers.openrouter.api_key
+	Ref  string // the reference as written in the config
+	Err  error  // why it couldn't be resolved; nil when it was
+
+	value string // what it resolved to
+}
+
+// isSecretRef reports whether s is a secret reference rather than a value.
+func isSecretRef(s string) bool {
+	return strings.HasPrefix(s, secretFilePrefix) ||
+		strings.HasPrefix(s, secretEnvPrefix) ||
+		strings.HasPrefix(s, secretCmdPrefix)
+}
+
+// SecretRefs returns the secret references in the config, resolved or not,
+// sorted by path.
+func (c *Config) SecretRefs() []SecretRef {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	refs := make([]SecretRef, len(c.secrets))
+	copy(refs, c.secrets)
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Path < refs[j].Path })
+	return refs
+}
+
+// resolveSecrets replaces every secret reference with the value it points
+// to. A reference that can't be resolved leaves the field empty, so it is
+// never sent anywhere as if it were a key.
+func (c *Config) resolveSecrets() {
+	c.secrets = nil
+	walkSecrets(reflect.ValueOf(c).Elem(), "", func(path string, field reflect.Value) {
+		ref := field.String()
+		if !isSecretRef(ref) {
+			return
+		}
+		value, err := resolveSecret(ref)
+		if err != nil {
+			value = ""
+		}
+		field.SetString(value)
+		c.secrets = append(c.secrets, SecretRef{Path: path, Ref: ref, Err: err, value: value})
+	})
+}
+
+// unresolveSecrets puts the references back in place of their values and
+// returns a function that undoes it. Fields changed since loading keep
+// their new value.
+func (c *Config) unresolveSecrets() (restore func()) {
+	if len(c.secrets) == 0 {
+		return func() {}
+	}
+	byPath := make(map[string]SecretRef, len(c.secrets))
+	for _, s := range c.secrets {
+		byPath[s.Path] = s
+	}
+
+	var undo []func()
+	walkSecrets(reflect.ValueOf(c).Elem(), "", func(path string, field reflect.Value) {
+		s, ok := byPath[path]
+		if !ok || field.String() != s.value {
+			return
+		}
+		field.SetString(s.Ref)
+		undo = append(undo, func() { field.SetString(s.value) })
+	})
+	return func() {
+		for _, u := range undo {
+			u()
+		}
+	}
+}
+
+// walkSecrets calls fn for every string field tagged secret:"true" under v.
+func walkSecrets(v reflect.Value, prefix string, fn func(path string, field reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			name = f.Name
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		field := v.Field(i)
+		switch {
+		case field.Kind() == reflect.Struct:
+			walkSecrets(field, name, fn)
+		case field.Kind() == reflect.String && f.Tag.Get("secret") == "true":
+			fn(name, field)
+		}
+	}
+}
+
+func resolveSecret(ref string) (string, error) {
+	var value string
+	switch {
+	case strings.HasPrefix(ref, secretFilePrefix):
+		path := expandHome(strings.TrimPrefix(ref, secretFilePrefix))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		value = string(data)
+
+	case strings.HasPrefix(ref, secretEnvPrefix):
+		name := strings.TrimPrefix(ref, secretEnvPrefix)
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		value = v
+
+	case strings.HasPrefix(ref, secretCmdPrefix):
+		command := strings.TrimPrefix(ref, secretCmdPrefix)
+		ctx, cancel := context.WithTimeout(context.Background(), secretCmdTimeout)
+		defer cancel()
+
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", command)
+		} else {
+			cmd = exec.CommandContext(ctx, "sh", "-c", command)
+		}
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return "", fmt.Errorf("%w: %s", err, msg)
+			}
+			return "", err
+		}
+		// pass and similar tools print the secret on the first line
+		value, _, _ = strings.Cut(string(out), "\n")
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", errors.New("resolved to an empty value")
+	}
+	return value, nil
+}
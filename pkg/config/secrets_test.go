@@ -0,0 +1,77 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig_SecretRefs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cmd: references run through sh")
+	}
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "openrouter")
+	os.WriteFile(keyFile, []byte("sk-or-from-file\n"), 0600)
+	t.Setenv("PICOCLAW_TEST_TG_TOKEN", "123:from-env")
+
+	path := filepath.Join(dir, "config.json")
+	os.WriteFile(path, []byte(`{
+  "providers": {
+    "openrouter": {"api_key": "file:`+keyFile+`"},
+    "anthropic": {"api_key": "env:PICOCLAW_TEST_UNSET_KEY"},
+    "openai": {"api_key": "sk-inline"}
+  },
+  "channels": {"telegram": {"token": "env:PICOCLAW_TEST_TG_TOKEN"}},
+  "tools": {"web": {"brave": {"api_key": "cmd:printf 'brave-%d\\nsecond line' 2"}}}
+}`), 0600)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	for name, tt := range map[string]struct{ got, want string }{
+		"file": {cfg.Providers.OpenRouter.APIKey, "sk-or-from-file"},
+		"env":  {cfg.Channels.Telegram.Token, "123:from-env"},
+		"cmd":  {cfg.Tools.Web.Brave.APIKey, "brave-2"},
+		"miss": {cfg.Providers.Anthropic.APIKey, ""},
+		"raw":  {cfg.Providers.OpenAI.APIKey, "sk-inline"},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s reference resolved to %q, want %q", name, tt.got, tt.want)
+		}
+	}
+
+	refs := cfg.SecretRefs()
+	if len(refs) != 4 {
+		t.Fatalf("SecretRefs() = %+v, want 4", refs)
+	}
+	for _, r := range refs {
+		if (r.Err != nil) != (r.Path == "providers.anthropic.api_key") {
+			t.Errorf("%s: err = %v", r.Path, r.Err)
+		}
+	}
+
+	// Saving writes the references back, never the values
+	cfg.Channels.Telegram.Token = "456:changed"
+	out := filepath.Join(dir, "saved.json")
+	if err := SaveConfig(out, cfg); err != nil {
+		t.Fatalf("SaveConfig() error: %v", err)
+	}
+	data, _ := os.ReadFile(out)
+	for _, want := range []string{"file:" + keyFile, "env:PICOCLAW_TEST_UNSET_KEY", "cmd:printf", "sk-inline", "456:changed"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("saved config is missing %q", want)
+		}
+	}
+	for _, leaked := range []string{"sk-or-from-file", "brave-2"} {
+		if strings.Contains(string(data), leaked) {
+			t.Errorf("saved config contains the resolved secret %q", leaked)
+		}
+	}
+	if cfg.Providers.OpenRouter.APIKey != "sk-or-from-file" {
+		t.Error("SaveConfig() left the reference in the loaded config")
+	}
+}
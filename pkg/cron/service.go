@@ -29,6 +29,10 @@ type CronPayload struct {
 	Deliver bool   `json:"deliver"`
 	Channel string `json:"channel,omitempty"`
 	To      string `json:"to,omitempty"`
+	// Sender and Group are who created the job and where. Its runs get the
+	// same tool policies, and its approval requests go to the same person.
+	Sender string `json:"sender,omitempty"`
+	Group  string `json:"group,omitempty"`
 }
 
 type CronJobState struct {
@@ -380,6 +384,21 @@ func (cs *CronService) AddJob(name string, schedule CronSchedule, message string
 	return &job, nil
 }
 
+// ImportJob adds a job from an export, keeping its ID and state. It returns
+// false if a job with that ID already exists.
+func (cs *CronService) ImportJob(job CronJob) (bool, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	for _, existing := range cs.store.Jobs {
+		if existing.ID == job.ID {
+			return false, nil
+		}
+	}
+	cs.store.Jobs = append(cs.store.Jobs, job)
+	return true, cs.saveStoreUnsafe()
+}
+
 func (cs *CronService) UpdateJob(job *CronJob) error {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
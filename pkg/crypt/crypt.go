@@ -0,0 +1,430 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package crypt encrypts personal data at rest: session histories, memory
+// files and OAuth credentials. Files are sealed with AES-256-GCM under a key
+// read from a key file or derived from a passphrase. Plaintext files are
+// still read as they are, so a workspace can hold both while it is being
+// migrated with picoclaw encrypt or decrypt.
+package crypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// PassphraseEnv is the environment variable the passphrase is read from.
+const PassphraseEnv = "PICOCLAW_PASSPHRASE"
+
+var (
+	// ErrNoKey is returned when reading an encrypted file without a key.
+	ErrNoKey = errors.New("file is encrypted but no key is set (set " + PassphraseEnv + " or privacy.encryption.key_file)")
+	// ErrWrongKey is returned when an encrypted file doesn't open with the key.
+	ErrWrongKey = errors.New("cannot decrypt: wrong passphrase or key file")
+)
+
+// magic starts every encrypted file. The leading non-ASCII byte keeps it
+// from ever matching the start of a JSON or markdown file.
+var magic = []byte("\x89PCENC\x01")
+
+const (
+	saltSize    = 16
+	minKeyBytes = 16
+)
+
+// pbkdf2Iterations follows the OWASP recommendation for PBKDF2-HMAC-SHA256.
+// The key is derived once per process, so the cost is paid at startup.
+var pbkdf2Iterations = 600_000
+
+// Key seals and opens files. Encrypted files carry the salt their key was
+// derived with, so files written under an older salt still open.
+type Key struct {
+	derive func(salt []byte) ([]byte, error)
+	salt   []byte
+
+	mu    sync.Mutex
+	aeads map[string]cipher.AEAD
+}
+
+// LoadKey returns the key from keyFile if set, otherwise from passphrase.
+// The passphrase salt is kept in saltFile, which is created on first use;
+// it isn't secret, but losing it makes files sealed with it unreadable.
+func LoadKey(passphrase, keyFile, saltFile string) (*Key, error) {
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading key file: %w", err)
+		}
+		secret := bytes.TrimSpace(data)
+		if len(secret) < minKeyBytes {
+			return nil, fmt.Errorf("key file %s is too short (need at least %d bytes)", keyFile, minKeyBytes)
+		}
+		return &Key{
+			derive: func(salt []byte) ([]byte, error) {
+				return hkdf.Key(sha256.New, secret, salt, "picoclaw at-rest", 32)
+			},
+			salt:  make([]byte, saltSize), // full-entropy key, no salt needed
+			aeads: make(map[string]cipher.AEAD),
+		}, nil
+	}
+
+	if passphrase == "" {
+		return nil, errors.New("no passphrase or key file")
+	}
+	salt, err := loadSalt(saltFile)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{
+		derive: func(salt []byte) ([]byte, error) {
+			return pbkdf2.Key(sha256.New, passphrase, salt, pbkdf2Iterations, 32)
+		},
+		salt:  salt,
+		aeads: make(map[string]cipher.AEAD),
+	}, nil
+}
+
+func loadSalt(path string) ([]byte, error) {
+	if data, err := os.ReadFile(path); err == nil {
+		if len(data) != saltSize {
+			return nil, fmt.Errorf("salt file %s is corrupt", path)
+		}
+		return data, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, salt, 0600); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// checkPlaintext is what the key check file holds, sealed.
+var checkPlaintext = []byte("picoclaw key check")
+
+// CheckPath returns the key check file kept next to saltFile.
+func CheckPath(saltFile string) string {
+	return filepath.Join(filepath.Dir(saltFile), "encryption.check")
+}
+
+// Verify checks k against the check file at path, which holds a known text
+// sealed with the key in use when it was created. It is created on first
+// use. A different key, such as a mistyped passphrase, fails here with
+// ErrWrongKey, before anything is read or written with it.
+func (k *Key) Verify(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		sealed, err := k.Seal(checkPlaintext)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return err
+		}
+		return os.WriteFile(path, sealed, 0600)
+	}
+	if err != nil {
+		return err
+	}
+	plaintext, err := k.Open(data)
+	if err != nil || !IsEncrypted(data) || !bytes.Equal(plaintext, checkPlaintext) {
+		return fmt.Errorf("%w (checked against %s; to change the key, run picoclaw decrypt with the old one and delete that file)",
+			ErrWrongKey, path)
+	}
+	return nil
+}
+
+func (k *Key) aead(salt []byte) (cipher.AEAD, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if a, ok := k.aeads[string(salt)]; ok {
+		return a, nil
+	}
+
+	key, err := k.derive(salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	a, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	k.aeads[string(salt)] = a
+	return a, nil
+}
+
+// Seal encrypts plaintext as magic | salt | nonce | ciphertext.
+func (k *Key) Seal(plaintext []byte) ([]byte, error) {
+	a, err := k.aead(k.salt)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(magic)+saltSize+a.NonceSize()+len(plaintext)+a.Overhead())
+	out = append(out, magic...)
+	out = append(out, k.salt...)
+	nonce := make([]byte, a.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out = append(out, nonce...)
+	return a.Seal(out, nonce, plaintext, magic), nil
+}
+
+// Open decrypts data sealed by Seal. Plaintext is returned unchanged.
+func (k *Key) Open(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+
+	rest := data[len(magic):]
+	if len(rest) < saltSize {
+		return nil, ErrWrongKey
+	}
+	a, err := k.aead(rest[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	rest = rest[saltSize:]
+	if len(rest) < a.NonceSize() {
+		return nil, ErrWrongKey
+	}
+	plaintext, err := a.Open(nil, rest[:a.NonceSize()], rest[a.NonceSize():], magic)
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	return plaintext, nil
+}
+
+// IsEncrypted reports whether data was written by Seal.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+var (
+	mu        sync.RWMutex
+	current   *Key
+	encrypt   bool
+	protected []string
+)
+
+// Configure sets the process-wide key. With on, WriteFile encrypts; without
+// it, files are written as plaintext but encrypted ones still open if key is
+// set. key may be nil when on is false.
+func Configure(key *Key, on bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = key
+	encrypt = on && key != nil
+}
+
+// Setup configures encryption from the config values, taking the passphrase
+// from PICOCLAW_PASSPHRASE. Without a key source it leaves encryption off,
+// and it fails if encryption is enabled but no key is available, or if the
+// key doesn't match the check file next to saltFile (see Key.Verify).
+func Setup(enabled bool, keyFile, saltFile string) error {
+	passphrase := os.Getenv(PassphraseEnv)
+	if keyFile == "" && passphrase == "" {
+		Configure(nil, false)
+		if enabled {
+			return fmt.Errorf("privacy.encryption is enabled but no key is available: set %s or privacy.encryption.key_file", PassphraseEnv)
+		}
+		return nil
+	}
+
+	key, err := LoadKey(passphrase, keyFile, saltFile)
+	if err != nil {
+		return err
+	}
+	if err := key.Verify(CheckPath(saltFile)); err != nil {
+		return err
+	}
+	Configure(key, enabled)
+	return nil
+}
+
+// Enabled reports whether new writes are encrypted.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return encrypt
+}
+
+// HasKey reports whether a key is configured.
+func HasKey() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current != nil
+}
+
+// Protect marks files under paths as holding personal data. Tools that
+// write files use Protected to keep them encrypted.
+func Protect(paths ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, p := range paths {
+		if abs, err := filepath.Abs(p); err == nil {
+			protected = append(protected, abs)
+		}
+	}
+}
+
+// Protected reports whether path is, or is inside, a path passed to Protect.
+func Protected(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, p := range protected {
+		if abs == p || strings.HasPrefix(abs, p+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Seal encrypts data with the configured key when encryption is enabled and
+// returns it unchanged otherwise.
+func Seal(data []byte) ([]byte, error) {
+	mu.RLock()
+	key, on := current, encrypt
+	mu.RUnlock()
+	if !on {
+		return data, nil
+	}
+	return key.Seal(data)
+}
+
+// Open decrypts data if it is encrypted.
+func Open(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	mu.RLock()
+	key := current
+	mu.RUnlock()
+	if key == nil {
+		return nil, ErrNoKey
+	}
+	return key.Open(data)
+}
+
+// ReadFile is os.ReadFile for files that may be encrypted.
+func ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err = Open(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return data, nil
+}
+
+// WriteFile is os.WriteFile that encrypts when encryption is enabled.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	data, err := Seal(data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, perm)
+}
+
+// Migrate encrypts (or with encrypt false, decrypts) each file in place
+// with the configured key, skipping files already in that form. Each file
+// is replaced atomically. It returns how many files were rewritten.
+func Migrate(paths []string, encrypt bool) (int, error) {
+	mu.RLock()
+	key := current
+	mu.RUnlock()
+	if key == nil {
+		return 0, fmt.Errorf("no key: set %s or privacy.encryption.key_file", PassphraseEnv)
+	}
+
+	changed := 0
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return changed, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return changed, err
+		}
+		if IsEncrypted(data) == encrypt {
+			continue
+		}
+
+		if encrypt {
+			data, err = key.Seal(data)
+		} else {
+			data, err = key.Open(data)
+		}
+		if err != nil {
+			return changed, fmt.Errorf("%s: %w", path, err)
+		}
+		if err := replaceFile(path, data, info.Mode().Perm()); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+func replaceFile(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".crypt-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
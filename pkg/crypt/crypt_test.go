@@ -0,0 +1,181 @@
+package crypt
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func init() {
+	// Keep passphrase derivation fast in tests
+	pbkdf2Iterations = 1000
+}
+
+func TestKey_SealOpen(t *testing.T) {
+	dir := t.TempDir()
+	key, err := LoadKey("correct horse", "", filepath.Join(dir, "salt"))
+	if err != nil {
+		t.Fatalf("LoadKey() error: %v", err)
+	}
+
+	plaintext := []byte(`{"key":"telegram:1","messages":[]}`)
+	sealed, err := key.Seal(plaintext)
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+	if !IsEncrypted(sealed) || bytes.Contains(sealed, []byte("telegram")) {
+		t.Fatalf("sealed data looks like plaintext: %q", sealed)
+	}
+
+	got, err := key.Open(sealed)
+	if err != nil || !bytes.Equal(got, plaintext) {
+		t.Fatalf("Open() = %q, %v", got, err)
+	}
+	if got, _ := key.Open(plaintext); !bytes.Equal(got, plaintext) {
+		t.Errorf("Open(plaintext) = %q, want it unchanged", got)
+	}
+
+	// Same salt file, different passphrase
+	wrong, _ := LoadKey("wrong horse", "", filepath.Join(dir, "salt"))
+	if _, err := wrong.Open(sealed); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("Open() with wrong passphrase error = %v, want ErrWrongKey", err)
+	}
+
+	// A fresh process reuses the salt and derives the same key
+	again, _ := LoadKey("correct horse", "", filepath.Join(dir, "salt"))
+	if _, err := again.Open(sealed); err != nil {
+		t.Errorf("Open() after reloading the key: %v", err)
+	}
+}
+
+func TestSetup_WrongPassphrase(t *testing.T) {
+	dir := t.TempDir()
+	salt := filepath.Join(dir, "encryption.salt")
+	defer Configure(nil, false)
+
+	t.Setenv(PassphraseEnv, "correct horse")
+	if err := Setup(true, "", salt); err != nil {
+		t.Fatalf("Setup() error: %v", err)
+	}
+	if _, err := os.Stat(CheckPath(salt)); err != nil {
+		t.Fatalf("no key check file was written: %v", err)
+	}
+
+	// A mistyped passphrase fails at startup
+	t.Setenv(PassphraseEnv, "correct horze")
+	if err := Setup(true, "", salt); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("Setup() with the wrong passphrase = %v, want ErrWrongKey", err)
+	}
+
+	t.Setenv(PassphraseEnv, "correct horse")
+	if err := Setup(true, "", salt); err != nil {
+		t.Errorf("Setup() with the right passphrase again: %v", err)
+	}
+}
+
+func TestLoadKey_KeyFile(t *testing.T) {
+	dir := t.TempDir()
+	short := filepath.Join(dir, "short.key")
+	os.WriteFile(short, []byte("tiny\n"), 0600)
+	if _, err := LoadKey("", short, ""); err == nil {
+		t.Error("expected an error for a short key file")
+	}
+
+	keyFile := filepath.Join(dir, "at-rest.key")
+	os.WriteFile(keyFile, []byte("0123456789abcdef0123456789abcdef\n"), 0600)
+	key, err := LoadKey("ignored", keyFile, "")
+	if err != nil {
+		t.Fatalf("LoadKey() error: %v", err)
+	}
+	sealed, _ := key.Seal([]byte("note"))
+	if got, err := key.Open(sealed); err != nil || string(got) != "note" {
+		t.Errorf("Open() = %q, %v", got, err)
+	}
+}
+
+func TestReadWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	defer Configure(nil, false)
+
+	plainPath := filepath.Join(dir, "plain.md")
+	os.WriteFile(plainPath, []byte("written before encryption"), 0644)
+
+	key, _ := LoadKey("pass", "", filepath.Join(dir, "salt"))
+	Configure(key, true)
+
+	encPath := filepath.Join(dir, "enc.md")
+	if err := WriteFile(encPath, []byte("secret note"), 0644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	raw, _ := os.ReadFile(encPath)
+	if !IsEncrypted(raw) {
+		t.Fatal("WriteFile() wrote plaintext with encryption on")
+	}
+
+	// Mixed workspace: both files read back transparently
+	for path, want := range map[string]string{plainPath: "written before encryption", encPath: "secret note"} {
+		got, err := ReadFile(path)
+		if err != nil || string(got) != want {
+			t.Errorf("ReadFile(%s) = %q, %v; want %q", filepath.Base(path), got, err, want)
+		}
+	}
+
+	Configure(nil, false)
+	if _, err := ReadFile(encPath); !errors.Is(err, ErrNoKey) {
+		t.Errorf("ReadFile() without key error = %v, want ErrNoKey", err)
+	}
+	if got, err := ReadFile(plainPath); err != nil || string(got) != "written before encryption" {
+		t.Errorf("ReadFile(plain) without key = %q, %v", got, err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	defer Configure(nil, false)
+
+	key, _ := LoadKey("pass", "", filepath.Join(dir, "salt"))
+	Configure(key, true)
+
+	a := filepath.Join(dir, "a.json")
+	b := filepath.Join(dir, "b.json")
+	os.WriteFile(a, []byte(`{"a":1}`), 0600)
+	WriteFile(b, []byte(`{"b":2}`), 0600)
+
+	paths := []string{a, b, filepath.Join(dir, "missing.json")}
+	n, err := Migrate(paths, true)
+	if err != nil || n != 1 {
+		t.Fatalf("Migrate(encrypt) = %d, %v; want 1 file (the other is already encrypted)", n, err)
+	}
+	for _, p := range []string{a, b} {
+		raw, _ := os.ReadFile(p)
+		if !IsEncrypted(raw) {
+			t.Errorf("%s is still plaintext", filepath.Base(p))
+		}
+	}
+	if info, _ := os.Stat(a); info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600 kept", info.Mode().Perm())
+	}
+
+	n, err = Migrate(paths, false)
+	if err != nil || n != 2 {
+		t.Fatalf("Migrate(decrypt) = %d, %v; want 2", n, err)
+	}
+	if raw, _ := os.ReadFile(b); string(raw) != `{"b":2}` {
+		t.Errorf("decrypted b = %q", raw)
+	}
+}
+
+func TestProtected(t *testing.T) {
+	dir := t.TempDir()
+	memory := filepath.Join(dir, "memory")
+	Protect(memory)
+
+	if !Protected(filepath.Join(memory, "202601", "20260115.md")) {
+		t.Error("daily note should be protected")
+	}
+	if Protected(filepath.Join(dir, "memory-notes.md")) || Protected(filepath.Join(dir, "notes.md")) {
+		t.Error("files outside the memory directory should not be protected")
+	}
+}
@@ -0,0 +1,340 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package egress
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Entry is one line of the network audit log: an HTTP request, or a raw
+// connection such as a channel's WebSocket. Byte counts cover bodies for
+// requests and everything on the wire for connections.
+type Entry struct {
+	Time          time.Time `json:"ts"`
+	Kind          string    `json:"kind"` // "request" or "connection"
+	Component     string    `json:"component"`
+	Host          string    `json:"host"`
+	Method        string    `json:"method,omitempty"`
+	Status        int       `json:"status,omitempty"`
+	BytesSent     int64     `json:"bytes_sent"`
+	BytesReceived int64     `json:"bytes_received"`
+	SessionKey    string    `json:"session_key,omitempty"`
+	Blocked       bool      `json:"blocked,omitempty"`
+	Error         string    `json:"error,omitempty"`
+}
+
+var (
+	auditMu   sync.Mutex
+	auditPath string
+)
+
+// AuditPath returns the network audit log kept under workspace.
+func AuditPath(workspace string) string {
+	return filepath.Join(workspace, "audit", "network.jsonl")
+}
+
+func setAuditPath(path string) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	auditPath = path
+}
+
+func auditEnabled() bool {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	return auditPath != ""
+}
+
+// writeAudit appends e to the log. The file is opened per entry in append
+// mode, so lines from concurrent writers never interleave and nothing is
+// ever rewritten.
+func writeAudit(e Entry) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if auditPath == "" {
+		return
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(auditPath), 0700); err != nil {
+		return
+	}
+	f, err := os.OpenFile(auditPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Write(append(line, '\n'))
+}
+
+type sessionKeyType struct{}
+
+// WithSession tags ctx with the session the network traffic is made for,
+// so audit entries for requests made with it carry the session key.
+func WithSession(ctx context.Context, sessionKey string) context.Context {
+	return context.WithValue(ctx, sessionKeyType{}, sessionKey)
+}
+
+func sessionFrom(ctx context.Context) string {
+	key, _ := ctx.Value(sessionKeyType{}).(string)
+	return key
+}
+
+// Audit wraps rt so that every request through it is written to the audit
+// log once its response body is closed or read to the end.
+func Audit(component string, rt http.RoundTripper) http.RoundTripper {
+	return &auditTransport{component: component, base: rt}
+}
+
+type auditTransport struct {
+	component string
+	base      http.RoundTripper
+}
+
+func (t *auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !auditEnabled() {
+		return t.base.RoundTrip(req)
+	}
+
+	e := Entry{
+		Time:       time.Now().UTC(),
+		Kind:       "request",
+		Component:  t.component,
+		Host:       req.URL.Hostname(),
+		Method:     req.Method,
+		SessionKey: sessionFrom(req.Context()),
+	}
+
+	// The transport may still be writing the body after RoundTrip returns,
+	// so the count is read again when the entry is written.
+	sent := &countingReadCloser{}
+	if req.Body != nil && req.Body != http.NoBody {
+		sent.rc = req.Body
+		r2 := *req
+		r2.Body = sent
+		req = &r2
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		e.BytesSent = sent.n.Load()
+		e.Error = err.Error()
+		e.Blocked = errors.Is(err, ErrBlocked)
+		writeAudit(e)
+		return nil, err
+	}
+
+	e.Status = resp.StatusCode
+	resp.Body = &auditBody{countingReadCloser: countingReadCloser{rc: resp.Body}, entry: e, sent: sent}
+	return resp, nil
+}
+
+type countingReadCloser struct {
+	rc io.ReadCloser
+	n  atomic.Int64
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.rc.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+func (c *countingReadCloser) Close() error {
+	return c.rc.Close()
+}
+
+// auditBody writes the request's entry when the response is done with.
+type auditBody struct {
+	countingReadCloser
+	entry Entry
+	sent  *countingReadCloser
+	once  sync.Once
+}
+
+func (b *auditBody) Read(p []byte) (int, error) {
+	n, err := b.countingReadCloser.Read(p)
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *auditBody) Close() error {
+	err := b.countingReadCloser.Close()
+	b.finish()
+	return err
+}
+
+func (b *auditBody) finish() {
+	b.once.Do(func() {
+		b.entry.BytesSent = b.sent.n.Load()
+		b.entry.BytesReceived = b.n.Load()
+		writeAudit(b.entry)
+	})
+}
+
+// DialContext returns a dial function for clients that manage their own
+// connections, such as WebSocket dialers. It enforces local-only mode like
+// NewTransport and writes one audit entry per connection when it closes.
+func DialContext(component string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dial := guardedDial(component)
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, _ := net.SplitHostPort(addr)
+		e := Entry{
+			Time:       time.Now().UTC(),
+			Kind:       "connection",
+			Component:  component,
+			Host:       host,
+			SessionKey: sessionFrom(ctx),
+		}
+
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			if auditEnabled() {
+				e.Error = err.Error()
+				e.Blocked = errors.Is(err, ErrBlocked)
+				writeAudit(e)
+			}
+			return nil, err
+		}
+		return &auditConn{Conn: conn, entry: e}, nil
+	}
+}
+
+type auditConn struct {
+	net.Conn
+	entry    Entry
+	sent     atomic.Int64
+	received atomic.Int64
+	once     sync.Once
+}
+
+func (c *auditConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.received.Add(int64(n))
+	return n, err
+}
+
+func (c *auditConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.sent.Add(int64(n))
+	return n, err
+}
+
+func (c *auditConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() {
+		c.entry.BytesSent = c.sent.Load()
+		c.entry.BytesReceived = c.received.Load()
+		writeAudit(c.entry)
+	})
+	return err
+}
+
+// ReadAudit loads the entries of an audit log at or after since. Lines that
+// don't parse are skipped.
+func ReadAudit(path string, since time.Time) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if !e.Time.Before(since) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// HostSummary aggregates the audit entries for one destination host.
+type HostSummary struct {
+	Host          string
+	Requests      int
+	Blocked       int
+	Errors        int
+	BytesSent     int64
+	BytesReceived int64
+	Components    []string
+	Sessions      int
+	Last          time.Time
+}
+
+// Summarize groups entries by host, busiest first.
+func Summarize(entries []Entry) []HostSummary {
+	byHost := make(map[string]*HostSummary)
+	components := make(map[string]map[string]bool)
+	sessions := make(map[string]map[string]bool)
+
+	for _, e := range entries {
+		s, ok := byHost[e.Host]
+		if !ok {
+			s = &HostSummary{Host: e.Host}
+			byHost[e.Host] = s
+			components[e.Host] = make(map[string]bool)
+			sessions[e.Host] = make(map[string]bool)
+		}
+		s.Requests++
+		if e.Blocked {
+			s.Blocked++
+		} else if e.Error != "" {
+			s.Errors++
+		}
+		s.BytesSent += e.BytesSent
+		s.BytesReceived += e.BytesReceived
+		if e.Time.After(s.Last) {
+			s.Last = e.Time
+		}
+		components[e.Host][e.Component] = true
+		if e.SessionKey != "" {
+			sessions[e.Host][e.SessionKey] = true
+		}
+	}
+
+	out := make([]HostSummary, 0, len(byHost))
+	for host, s := range byHost {
+		for c := range components[host] {
+			s.Components = append(s.Components, c)
+		}
+		sort.Strings(s.Components)
+		s.Sessions = len(sessions[host])
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Requests != out[j].Requests {
+			return out[i].Requests > out[j].Requests
+		}
+		return out[i].Host < out[j].Host
+	})
+	return out
+}
@@ -0,0 +1,130 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAudit_RecordsRequests(t *testing.T) {
+	workspace := t.TempDir()
+	Configure(false, workspace)
+	defer Configure(false, "")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte("hello world"))
+	}))
+	defer server.Close()
+
+	ctx := WithSession(context.Background(), "telegram:42")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, strings.NewReader("ping"))
+	resp, err := NewClient("test", 5*time.Second).Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	entries, err := ReadAudit(AuditPath(workspace), time.Time{})
+	if err != nil {
+		t.Fatalf("ReadAudit() error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1 (reading to EOF and closing must not log twice)", len(entries))
+	}
+	e := entries[0]
+	if e.Kind != "request" || e.Component != "test" || e.Host != "127.0.0.1" || e.Method != http.MethodPost {
+		t.Errorf("entry = %+v", e)
+	}
+	if e.Status != http.StatusOK || e.BytesSent != 4 || e.BytesReceived != 11 {
+		t.Errorf("status/bytes = %d/%d/%d, want 200/4/11", e.Status, e.BytesSent, e.BytesReceived)
+	}
+	if e.SessionKey != "telegram:42" {
+		t.Errorf("SessionKey = %q, want telegram:42", e.SessionKey)
+	}
+}
+
+func TestAudit_RecordsBlocked(t *testing.T) {
+	workspace := t.TempDir()
+	Configure(true, workspace)
+	defer Configure(false, "")
+
+	if _, err := NewClient("web_search", 5*time.Second).Get("http://8.8.8.8/"); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("error = %v, want ErrBlocked", err)
+	}
+
+	entries, _ := ReadAudit(AuditPath(workspace), time.Time{})
+	if len(entries) != 1 || !entries[0].Blocked || entries[0].Host != "8.8.8.8" {
+		t.Fatalf("entries = %+v, want one blocked request to 8.8.8.8", entries)
+	}
+}
+
+func TestDialContext_RecordsConnection(t *testing.T) {
+	workspace := t.TempDir()
+	Configure(false, workspace)
+	defer Configure(false, "")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 5)
+		io.ReadFull(conn, buf)
+		conn.Write([]byte("pong!!"))
+	}()
+
+	conn, err := DialContext("onebot")(context.Background(), "tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	conn.Write([]byte("ping!"))
+	io.ReadFull(conn, make([]byte, 6))
+	conn.Close()
+
+	entries, _ := ReadAudit(AuditPath(workspace), time.Time{})
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	e := entries[0]
+	if e.Kind != "connection" || e.Component != "onebot" || e.BytesSent != 5 || e.BytesReceived != 6 {
+		t.Errorf("entry = %+v", e)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	now := time.Now()
+	entries := []Entry{
+		{Time: now, Component: "provider", Host: "api.example.com", BytesSent: 100, BytesReceived: 1000, SessionKey: "a"},
+		{Time: now, Component: "web_fetch", Host: "api.example.com", BytesSent: 10, BytesReceived: 50, SessionKey: "b"},
+		{Time: now, Component: "web_search", Host: "8.8.8.8", Blocked: true},
+	}
+
+	got := Summarize(entries)
+	if len(got) != 2 {
+		t.Fatalf("got %d hosts, want 2", len(got))
+	}
+	h := got[0]
+	if h.Host != "api.example.com" || h.Requests != 2 || h.BytesSent != 110 || h.BytesReceived != 1050 || h.Sessions != 2 {
+		t.Errorf("first host = %+v", h)
+	}
+	if strings.Join(h.Components, ",") != "provider,web_fetch" {
+		t.Errorf("Components = %v", h.Components)
+	}
+	if got[1].Blocked != 1 {
+		t.Errorf("second host = %+v, want one blocked", got[1])
+	}
+}
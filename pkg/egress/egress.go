@@ -0,0 +1,264 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package egress enforces privacy.local_only and keeps the outbound network
+// audit log. Outbound HTTP clients are built on NewTransport, whose dialer
+// refuses any destination that isn't loopback or a private (RFC 1918, or
+// fc00::/7 for IPv6) address while local-only mode is on, and wrapped in
+// Audit so each request is recorded.
+package egress
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// ErrBlocked is returned when local-only mode stops a connection.
+var ErrBlocked = errors.New("blocked by privacy.local_only")
+
+// maxRecent bounds the blocked attempts kept for picoclaw status.
+const maxRecent = 20
+
+var (
+	localOnly atomic.Bool
+
+	mu        sync.Mutex
+	statsPath string
+)
+
+// Configure switches local-only mode on or off. Blocked attempts are
+// counted in privacy/egress.json under workspace, where Stats reads them,
+// and every outbound connection is appended to the audit log at
+// AuditPath(workspace). An empty workspace keeps both in the log only.
+func Configure(on bool, workspace string) {
+	localOnly.Store(on)
+
+	audit := ""
+	if workspace != "" {
+		audit = AuditPath(workspace)
+	}
+	setAuditPath(audit)
+
+	mu.Lock()
+	defer mu.Unlock()
+	statsPath = ""
+	if workspace != "" {
+		statsPath = statsFile(workspace)
+	}
+}
+
+// LocalOnly reports whether local-only mode is on.
+func LocalOnly() bool {
+	return localOnly.Load()
+}
+
+// AllowedIP reports whether ip may be reached in local-only mode.
+func AllowedIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate()
+}
+
+// IsLocalHost reports whether every address host resolves to is allowed in
+// local-only mode. Hosts that don't resolve count as not local.
+func IsLocalHost(ctx context.Context, host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return AllowedIP(ip)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return false
+	}
+	for _, a := range addrs {
+		if !AllowedIP(a.IP) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsLocalURL is IsLocalHost for the host of a URL. A URL without a scheme
+// is read as host[:port].
+func IsLocalURL(ctx context.Context, raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		u, err = url.Parse("http://" + raw)
+		if err != nil {
+			return false
+		}
+	}
+	return IsLocalHost(ctx, u.Hostname())
+}
+
+// NewTransport returns a clone of http.DefaultTransport that enforces
+// local-only mode. component names the caller in logs, e.g. "web_fetch".
+// The check happens when connecting, after DNS resolution, so it also
+// covers redirects. Callers that set their own Proxy must wrap it in Proxy.
+func NewTransport(component string) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = guardedDial(component)
+	t.Proxy = Proxy(t.Proxy)
+	return t
+}
+
+// Proxy wraps a Transport or Dialer proxy function so that no proxy is used
+// while local-only mode is on. A proxy on the local network would otherwise
+// pass the connection check and forward the request anywhere.
+func Proxy(proxy func(*http.Request) (*url.URL, error)) func(*http.Request) (*url.URL, error) {
+	if proxy == nil {
+		return nil
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		if LocalOnly() {
+			return nil, nil
+		}
+		return proxy(req)
+	}
+}
+
+// NewClient returns an http.Client on NewTransport whose requests are
+// written to the audit log.
+func NewClient(component string, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: Audit(component, NewTransport(component)),
+	}
+}
+
+func guardedDial(component string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if !LocalOnly() {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		return dialLocal(ctx, dialer, component, network, addr)
+	}
+}
+
+// dialLocal resolves addr itself and connects to the first allowed address,
+// so a name can't resolve to a private address for the check and a public
+// one for the connection.
+func dialLocal(ctx context.Context, dialer *net.Dialer, component, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+
+	var lastErr error
+	for _, ip := range ips {
+		if !AllowedIP(ip) {
+			continue
+		}
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+
+	recordBlocked(component, host)
+	return nil, fmt.Errorf("%w: %s is not on this machine or the local network", ErrBlocked, host)
+}
+
+// Attempt is one blocked connection.
+type Attempt struct {
+	Time      time.Time `json:"time"`
+	Component string    `json:"component"`
+	Host      string    `json:"host"`
+}
+
+// Stats summarises blocked attempts for picoclaw status.
+type Stats struct {
+	Blocked int       `json:"blocked"`
+	Recent  []Attempt `json:"recent,omitempty"` // newest last
+}
+
+func statsFile(workspace string) string {
+	return filepath.Join(workspace, "privacy", "egress.json")
+}
+
+// LoadStats reads the blocked-attempt counters kept under workspace.
+func LoadStats(workspace string) (Stats, error) {
+	var s Stats
+	data, err := os.ReadFile(statsFile(workspace))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return s, err
+	}
+	err = json.Unmarshal(data, &s)
+	return s, err
+}
+
+func recordBlocked(component, host string) {
+	logger.WarnCF("egress", "Blocked outbound connection (privacy.local_only)",
+		map[string]interface{}{
+			"component": component,
+			"host":      host,
+		})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if statsPath == "" {
+		return
+	}
+
+	var s Stats
+	if data, err := os.ReadFile(statsPath); err == nil {
+		json.Unmarshal(data, &s)
+	}
+	s.Blocked++
+	s.Recent = append(s.Recent, Attempt{Time: time.Now(), Component: component, Host: host})
+	if len(s.Recent) > maxRecent {
+		s.Recent = s.Recent[len(s.Recent)-maxRecent:]
+	}
+
+	// Counters are informational; a failed write only loses the entry
+	os.MkdirAll(filepath.Dir(statsPath), 0755)
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return
+	}
+	tmp := statsPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, statsPath); err != nil {
+		os.Remove(tmp)
+	}
+}
@@ -0,0 +1,126 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestAllowedIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.10", true},
+		{"fd00::1", true},
+		{"8.8.8.8", false},
+		{"172.32.0.1", false},
+		{"169.254.169.254", false}, // link-local, e.g. cloud metadata
+		{"2001:4860:4860::8888", false},
+	}
+	for _, tt := range tests {
+		if got := AllowedIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("AllowedIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestIsLocalURL(t *testing.T) {
+	ctx := context.Background()
+	for raw, want := range map[string]bool{
+		"ws://127.0.0.1:3001":    true,
+		"http://localhost:8080":  true,
+		"192.168.1.5:8080":       true,
+		"https://8.8.8.8/bridge": false,
+	} {
+		if got := IsLocalURL(ctx, raw); got != want {
+			t.Errorf("IsLocalURL(%q) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestNewClient_LocalOnly(t *testing.T) {
+	workspace := t.TempDir()
+	Configure(true, workspace)
+	defer Configure(false, "")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := NewClient("test", 5*time.Second)
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("local request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	// A public address literal is refused before any packet is sent
+	_, err = client.Get("http://8.8.8.8/")
+	if !errors.Is(err, ErrBlocked) {
+		t.Fatalf("public request error = %v, want ErrBlocked", err)
+	}
+
+	stats, err := LoadStats(workspace)
+	if err != nil {
+		t.Fatalf("LoadStats() error: %v", err)
+	}
+	if stats.Blocked != 1 || len(stats.Recent) != 1 {
+		t.Fatalf("stats = %+v, want one blocked attempt", stats)
+	}
+	if stats.Recent[0].Component != "test" || stats.Recent[0].Host != "8.8.8.8" {
+		t.Errorf("attempt = %+v", stats.Recent[0])
+	}
+}
+
+func TestNewTransport_LocalOnlySkipsProxy(t *testing.T) {
+	Configure(true, "")
+	defer Configure(false, "")
+
+	// A proxy on this machine would pass the connection check and forward
+	// the request anywhere
+	proxied := false
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = true
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	transport := NewTransport("test")
+	transport.Proxy = Proxy(http.ProxyURL(proxyURL))
+	client := &http.Client{Transport: transport, Timeout: 5 * time.Second}
+
+	_, err := client.Get("http://8.8.8.8/")
+	if !errors.Is(err, ErrBlocked) {
+		t.Errorf("request through a local proxy error = %v, want ErrBlocked", err)
+	}
+	if proxied {
+		t.Error("the request went through the proxy")
+	}
+}
+
+func TestNewClient_Off(t *testing.T) {
+	Configure(false, "")
+	if LocalOnly() {
+		t.Fatal("LocalOnly() = true after Configure(false)")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	resp, err := NewClient("test", 5*time.Second).Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+}
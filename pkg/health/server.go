@@ -14,6 +14,7 @@ type Server struct {
 	mu        sync.RWMutex
 	ready     bool
 	checks    map[string]Check
+	metrics   map[string]func() interface{}
 	startTime time.Time
 }
 
@@ -35,11 +36,13 @@ func NewServer(host string, port int) *Server {
 	s := &Server{
 		ready:     false,
 		checks:    make(map[string]Check),
+		metrics:   make(map[string]func() interface{}),
 		startTime: time.Now(),
 	}
 
 	mux.HandleFunc("/health", s.healthHandler)
 	mux.HandleFunc("/ready", s.readyHandler)
+	mux.HandleFunc("/metrics", s.metricsHandler)
 
 	addr := fmt.Sprintf("%s:%d", host, port)
 	s.server = &http.Server{
@@ -103,6 +106,14 @@ func (s *Server) RegisterCheck(name string, checkFn func() (bool, string)) {
 	}
 }
 
+// RegisterMetric adds a value to /metrics. fn is called on every request,
+// so it reports the current value.
+func (s *Server) RegisterMetric(name string, fn func() interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metrics[name] = fn
+}
+
 func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -156,6 +167,20 @@ func (s *Server) readyHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (s *Server) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	s.mu.RLock()
+	metrics := make(map[string]interface{}, len(s.metrics))
+	for name, fn := range s.metrics {
+		metrics[name] = fn()
+	}
+	s.mu.RUnlock()
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(metrics)
+}
+
 func statusString(ok bool) string {
 	if ok {
 		return "ok"
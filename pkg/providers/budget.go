@@ -0,0 +1,152 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// ErrBudgetExceeded is returned instead of calling a cloud provider once a
+// daily usage cap has been reached.
+var ErrBudgetExceeded = errors.New("daily usage cap reached")
+
+// CloudBudget decides whether cloud providers may still be called and is told
+// about the tokens they consumed.
+type CloudBudget interface {
+	AllowCloud() error
+	RecordCloud(model string, usage *UsageInfo)
+}
+
+// WithCloudBudget gates every cloud backend of p behind budget. Local
+// providers are returned untouched; in a FallbackProvider only the cloud
+// backends are gated, so local ones keep answering once the cap is hit.
+// Recording and redacting wrappers are looked through, so that a fallback
+// chain inside them is gated backend by backend too.
+func WithCloudBudget(p LLMProvider, budget CloudBudget) LLMProvider {
+	if budget == nil {
+		return p
+	}
+	switch v := p.(type) {
+	case *FallbackProvider:
+		backends := make([]FallbackBackend, len(v.backends))
+		for i, b := range v.backends {
+			b.Provider = WithCloudBudget(b.Provider, budget)
+			backends[i] = b
+		}
+		return NewFallbackProvider(backends)
+	case *RecordingProvider:
+		return NewRecordingProvider(WithCloudBudget(v.inner, budget), v.path)
+	case *RedactingProvider:
+		return NewRedactingProvider(WithCloudBudget(v.inner, budget), v.redactor)
+	}
+	if IsLocal(p) {
+		return p
+	}
+	return &budgetProvider{inner: p, budget: budget}
+}
+
+// IsLocal reports whether p talks to a model on this machine or the local
+// network, meaning calls to it cost nothing and leave no third party.
+func IsLocal(p LLMProvider) bool {
+	switch v := p.(type) {
+	case *OllamaProvider:
+		return isLocalURL(v.config.BaseURL)
+	case *HTTPProvider:
+		return isLocalURL(v.apiBase)
+	case *FallbackProvider:
+		for _, b := range v.backends {
+			if !IsLocal(b.Provider) {
+				return false
+			}
+		}
+		return len(v.backends) > 0
+	case *budgetProvider:
+		return false
+	case *RecordingProvider:
+		return IsLocal(v.inner)
+	case *RedactingProvider:
+		return IsLocal(v.inner)
+	case *ReplayProvider:
+		return true
+	}
+	return false
+}
+
+func isLocalURL(raw string) bool {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" || strings.HasSuffix(host, ".local") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast())
+}
+
+// budgetProvider checks the budget before each call and records the usage
+// reported afterwards.
+type budgetProvider struct {
+	inner  LLMProvider
+	budget CloudBudget
+}
+
+func (p *budgetProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	if err := p.budget.AllowCloud(); err != nil {
+		return nil, err
+	}
+	resp, err := p.inner.Chat(ctx, messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+	p.budget.RecordCloud(model, resp.Usage)
+	return resp, nil
+}
+
+func (p *budgetProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	sp, ok := p.inner.(StreamingProvider)
+	if !ok {
+		resp, err := p.Chat(ctx, messages, tools, model, options)
+		if err != nil {
+			return nil, err
+		}
+		return responseAsStream(ctx, resp), nil
+	}
+
+	if err := p.budget.AllowCloud(); err != nil {
+		return nil, err
+	}
+	events, err := sp.ChatStream(ctx, messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan StreamEvent, 16)
+	go func() {
+		defer close(out)
+		for ev := range events {
+			if ev.Done && ev.Response != nil {
+				p.budget.RecordCloud(model, ev.Response.Usage)
+			}
+			if !sendStreamEvent(ctx, out, ev) {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (p *budgetProvider) GetDefaultModel() string {
+	return p.inner.GetDefaultModel()
+}
+
+func (p *budgetProvider) ModelInfo(ctx context.Context, model string) (*ModelInfo, error) {
+	info := GetModelInfo(ctx, p.inner, model)
+	return &info, nil
+}
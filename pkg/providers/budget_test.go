@@ -0,0 +1,128 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+type fixedBudget struct {
+	err      error
+	recorded int
+}
+
+func (b *fixedBudget) AllowCloud() error { return b.err }
+
+func (b *fixedBudget) RecordCloud(model string, usage *UsageInfo) {
+	if usage != nil {
+		b.recorded += usage.TotalTokens
+	}
+}
+
+func TestIsLocal(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider LLMProvider
+		want     bool
+	}{
+		{"ollama default", mustOllama(t, ""), true},
+		{"vllm on lan", NewHTTPProvider("", "http://192.168.1.20:8000/v1", ""), true},
+		{"openrouter", NewHTTPProvider("key", "https://openrouter.ai/api/v1", ""), false},
+		{"remote ollama", mustOllama(t, "https://ollama.example.com"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsLocal(tt.provider); got != tt.want {
+				t.Errorf("IsLocal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithCloudBudget_SkipsCloudFallbackOverCap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": map[string]interface{}{"role": "assistant", "content": "local"},
+			"done":    true,
+		})
+	}))
+	defer server.Close()
+
+	cloud := &scriptedProvider{reply: "cloud"}
+	local := mustOllama(t, server.URL)
+	budget := &fixedBudget{err: fmt.Errorf("%w: test", ErrBudgetExceeded)}
+
+	// A cloud-first chain with a local backup keeps working over the cap.
+	chain := NewFallbackProvider([]FallbackBackend{
+		{Name: "openrouter", Provider: cloud},
+		{Name: "ollama", Provider: local},
+	})
+	gated := WithCloudBudget(chain, budget)
+
+	resp, err := gated.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "m", nil)
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if resp.Content != "local" {
+		t.Errorf("Content = %q, want %q", resp.Content, "local")
+	}
+	if cloud.calls != 0 {
+		t.Errorf("cloud backend called %d times over cap, want 0", cloud.calls)
+	}
+
+	if WithCloudBudget(local, budget) != local {
+		t.Error("local provider should not be gated")
+	}
+
+	// The same chain being recorded for a cassette
+	recorded := WithCloudBudget(NewRecordingProvider(chain, filepath.Join(t.TempDir(), "cassette.json")), budget)
+	resp, err = recorded.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "m", nil)
+	if err != nil || resp.Content != "local" {
+		t.Errorf("recorded Chat() = %+v, %v; want the local backend to answer", resp, err)
+	}
+}
+
+func TestWithCloudBudget_RecordsUsage(t *testing.T) {
+	budget := &fixedBudget{}
+	inner := &usageProvider{usage: &UsageInfo{TotalTokens: 42}}
+	gated := WithCloudBudget(inner, budget)
+
+	if _, err := gated.Chat(context.Background(), nil, nil, "m", nil); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if budget.recorded != 42 {
+		t.Errorf("recorded = %d, want 42", budget.recorded)
+	}
+
+	budget.err = ErrBudgetExceeded
+	if _, err := gated.Chat(context.Background(), nil, nil, "m", nil); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Chat() over cap error = %v, want ErrBudgetExceeded", err)
+	}
+}
+
+type usageProvider struct {
+	usage *UsageInfo
+}
+
+func (p *usageProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	return &LLMResponse{Content: "ok", Usage: p.usage}, nil
+}
+
+func (p *usageProvider) GetDefaultModel() string {
+	return "m"
+}
+
+func mustOllama(t *testing.T, baseURL string) LLMProvider {
+	t.Helper()
+	p, err := CreateOllamaProvider(baseURL)
+	if err != nil {
+		t.Fatalf("CreateOllamaProvider() error: %v", err)
+	}
+	return p
+}
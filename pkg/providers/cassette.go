@@ -0,0 +1,263 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/crypt"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// ErrCassetteMiss is returned by ReplayProvider for a request that wasn't
+// recorded.
+var ErrCassetteMiss = errors.New("no recorded response for request")
+
+// Cassette is the on-disk form of a recorded conversation: every request a
+// RecordingProvider saw, in order, with the response it got back.
+type Cassette struct {
+	ModelInfo    *ModelInfo    `json:"model_info,omitempty"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded Chat call.
+type Interaction struct {
+	Hash     string           `json:"hash"`
+	Model    string           `json:"model"`
+	Messages []Message        `json:"messages"`
+	Tools    []ToolDefinition `json:"tools,omitempty"`
+	Response *LLMResponse     `json:"response"`
+}
+
+// RequestHash identifies a Chat request for replay. System messages are left
+// out because the agent's system prompt embeds the current time and the
+// workspace path, which differ between recording and replay; options are
+// left out because they don't change which answer is expected.
+func RequestHash(messages []Message, tools []ToolDefinition, model string) string {
+	filtered := make([]Message, 0, len(messages))
+	for _, m := range messages {
+		if m.Role != "system" {
+			filtered = append(filtered, m)
+		}
+	}
+
+	data, _ := json.Marshal(struct {
+		Model    string           `json:"model"`
+		Messages []Message        `json:"messages"`
+		Tools    []ToolDefinition `json:"tools"`
+	}{model, filtered, tools})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadCassette reads a cassette file.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := crypt.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette with a temp file + rename. It holds whole
+// conversations, so it is readable by the owner only and encrypted when
+// encryption at rest is on.
+func (c *Cassette) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := crypt.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to rename cassette: %w", err)
+	}
+	return nil
+}
+
+// RecordingProvider passes calls through to another provider and appends
+// each successful request/response pair to a cassette file, which is
+// rewritten after every call so that a crash loses nothing. Incognito
+// conversations are not recorded.
+type RecordingProvider struct {
+	inner LLMProvider
+	path  string
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecordingProvider starts a new cassette at path, replacing any existing
+// file once the first call is recorded.
+func NewRecordingProvider(inner LLMProvider, path string) *RecordingProvider {
+	return &RecordingProvider{inner: inner, path: path}
+}
+
+func (p *RecordingProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	resp, err := p.inner.Chat(ctx, messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+	if !IsIncognito(ctx) {
+		p.record(messages, tools, model, resp)
+	}
+	return resp, nil
+}
+
+func (p *RecordingProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	sp, ok := p.inner.(StreamingProvider)
+	if !ok {
+		resp, err := p.Chat(ctx, messages, tools, model, options)
+		if err != nil {
+			return nil, err
+		}
+		return responseAsStream(ctx, resp), nil
+	}
+
+	events, err := sp.ChatStream(ctx, messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan StreamEvent, 16)
+	go func() {
+		defer close(out)
+		for ev := range events {
+			if ev.Done && ev.Response != nil && !IsIncognito(ctx) {
+				p.record(messages, tools, model, ev.Response)
+			}
+			if !sendStreamEvent(ctx, out, ev) {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (p *RecordingProvider) GetDefaultModel() string {
+	return p.inner.GetDefaultModel()
+}
+
+// ModelInfo is recorded too, since it decides whether tools are offered
+// and so changes the requests that follow.
+func (p *RecordingProvider) ModelInfo(ctx context.Context, model string) (*ModelInfo, error) {
+	info := GetModelInfo(ctx, p.inner, model)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cassette.ModelInfo = &info
+	if err := p.cassette.Save(p.path); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (p *RecordingProvider) record(messages []Message, tools []ToolDefinition, model string, resp *LLMResponse) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.cassette.Interactions = append(p.cassette.Interactions, Interaction{
+		Hash:     RequestHash(messages, tools, model),
+		Model:    model,
+		Messages: messages,
+		Tools:    tools,
+		Response: resp,
+	})
+	if err := p.cassette.Save(p.path); err != nil {
+		// Recording is a debugging aid; don't fail the conversation over it
+		logger.WarnCF("provider", "Failed to save cassette",
+			map[string]interface{}{
+				"path":  p.path,
+				"error": err.Error(),
+			})
+	}
+}
+
+// ReplayProvider answers from a cassette instead of a backend. A request
+// recorded more than once gets its responses in recorded order, the last
+// one repeating.
+type ReplayProvider struct {
+	cassette *Cassette
+
+	mu     sync.Mutex
+	served map[string]int
+	byHash map[string][]*LLMResponse
+}
+
+// NewReplayProvider loads the cassette at path.
+func NewReplayProvider(path string) (*ReplayProvider, error) {
+	c, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &ReplayProvider{
+		cassette: c,
+		served:   make(map[string]int),
+		byHash:   make(map[string][]*LLMResponse),
+	}
+	for _, in := range c.Interactions {
+		p.byHash[in.Hash] = append(p.byHash[in.Hash], in.Response)
+	}
+	return p, nil
+}
+
+func (p *ReplayProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	hash := RequestHash(messages, tools, model)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	responses := p.byHash[hash]
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("%w (hash %s)", ErrCassetteMiss, hash)
+	}
+	idx := min(p.served[hash], len(responses)-1)
+	p.served[hash]++
+
+	// Hand out a copy so callers can't alter the recording. Empty argument
+	// maps don't survive the cassette's omitempty; providers never return
+	// nil arguments, so restore them.
+	resp := *responses[idx]
+	resp.ToolCalls = make([]ToolCall, len(responses[idx].ToolCalls))
+	for i, tc := range responses[idx].ToolCalls {
+		if tc.Arguments == nil {
+			tc.Arguments = map[string]interface{}{}
+		}
+		resp.ToolCalls[i] = tc
+	}
+	return &resp, nil
+}
+
+func (p *ReplayProvider) GetDefaultModel() string {
+	if len(p.cassette.Interactions) > 0 {
+		return p.cassette.Interactions[0].Model
+	}
+	return ""
+}
+
+func (p *ReplayProvider) ModelInfo(ctx context.Context, model string) (*ModelInfo, error) {
+	if p.cassette.ModelInfo != nil {
+		info := *p.cassette.ModelInfo
+		return &info, nil
+	}
+	info := builtinModelInfo(model)
+	return &info, nil
+}
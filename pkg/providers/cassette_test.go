@@ -0,0 +1,121 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/crypt"
+)
+
+func TestRecordingProvider_ReplayRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "run.json")
+	backend := &scriptedProvider{reply: "recorded answer"}
+	recorder := NewRecordingProvider(backend, path)
+
+	messages := []Message{
+		{Role: "system", Content: "It is 09:00"},
+		{Role: "user", Content: "What's up?"},
+	}
+	if _, err := recorder.Chat(context.Background(), messages, nil, "qwen2.5:7b", nil); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+
+	replay, err := NewReplayProvider(path)
+	if err != nil {
+		t.Fatalf("NewReplayProvider() error: %v", err)
+	}
+
+	// A different system prompt (e.g. a later time) still matches
+	messages[0].Content = "It is 17:30"
+	resp, err := replay.Chat(context.Background(), messages, nil, "qwen2.5:7b", nil)
+	if err != nil {
+		t.Fatalf("replay Chat() error: %v", err)
+	}
+	if resp.Content != "recorded answer" {
+		t.Errorf("Content = %q, want recorded answer", resp.Content)
+	}
+
+	messages[1].Content = "Something else"
+	_, err = replay.Chat(context.Background(), messages, nil, "qwen2.5:7b", nil)
+	if !errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("error = %v, want ErrCassetteMiss", err)
+	}
+}
+
+func TestCassette_SaveIsPrivate(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "at-rest.key")
+	os.WriteFile(keyFile, []byte("0123456789abcdef0123456789abcdef"), 0600)
+	key, err := crypt.LoadKey("", keyFile, "")
+	if err != nil {
+		t.Fatalf("LoadKey() error: %v", err)
+	}
+	crypt.Configure(key, true)
+	defer crypt.Configure(nil, false)
+
+	path := filepath.Join(dir, "cassettes", "run.json")
+	recorder := NewRecordingProvider(&scriptedProvider{reply: "answer"}, path)
+	if _, err := recorder.Chat(context.Background(), []Message{{Role: "user", Content: "hello"}}, nil, "m", nil); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("cassette not written: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("cassette mode = %v, want 0600", info.Mode().Perm())
+	}
+	raw, _ := os.ReadFile(path)
+	if !crypt.IsEncrypted(raw) {
+		t.Error("cassette was written as plaintext")
+	}
+	if _, err := NewReplayProvider(path); err != nil {
+		t.Errorf("NewReplayProvider() error: %v", err)
+	}
+}
+
+func TestRecordingProvider_SkipsIncognito(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+	recorder := NewRecordingProvider(&scriptedProvider{reply: "secret answer"}, path)
+
+	ctx := WithIncognito(context.Background())
+	if _, err := recorder.Chat(ctx, []Message{{Role: "user", Content: "my diagnosis"}}, nil, "m", nil); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("an incognito conversation was recorded to %s", path)
+	}
+}
+
+func TestReplayProvider_RepeatedRequest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+	backend := &scriptedProvider{}
+	recorder := NewRecordingProvider(backend, path)
+
+	messages := []Message{{Role: "user", Content: "roll a die"}}
+	for _, reply := range []string{"4", "2"} {
+		backend.reply = reply
+		recorder.Chat(context.Background(), messages, nil, "m", nil)
+	}
+
+	replay, err := NewReplayProvider(path)
+	if err != nil {
+		t.Fatalf("NewReplayProvider() error: %v", err)
+	}
+	var got []string
+	for i := 0; i < 3; i++ {
+		resp, err := replay.Chat(context.Background(), messages, nil, "m", nil)
+		if err != nil {
+			t.Fatalf("Chat() error: %v", err)
+		}
+		got = append(got, resp.Content)
+	}
+	if got[0] != "4" || got[1] != "2" || got[2] != "2" {
+		t.Errorf("replies = %v, want [4 2 2]", got)
+	}
+}
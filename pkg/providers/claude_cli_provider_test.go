@@ -433,6 +433,18 @@ func TestCreateProvider_ClaudeCli(t *testing.T) {
 	}
 }
 
+func TestCreateProvider_CliLocalOnly(t *testing.T) {
+	for _, name := range []string{"claude-cli", "codex-cli", "github_copilot"} {
+		cfg := config.DefaultConfig()
+		cfg.Agents.Defaults.Provider = name
+		cfg.Privacy.LocalOnly = true
+
+		if _, err := CreateProvider(cfg); err == nil {
+			t.Errorf("CreateProvider(%s) with local_only should fail", name)
+		}
+	}
+}
+
 func TestCreateProvider_ClaudeCode(t *testing.T) {
 	cfg := config.DefaultConfig()
 	cfg.Agents.Defaults.Provider = "claude-code"
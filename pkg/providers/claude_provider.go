@@ -3,11 +3,17 @@ package providers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/egress"
 )
 
 type ClaudeProvider struct {
@@ -19,6 +25,35 @@ func NewClaudeProvider(token string) *ClaudeProvider {
 	client := anthropic.NewClient(
 		option.WithAuthToken(token),
 		option.WithBaseURL("https://api.anthropic.com"),
+		option.WithHTTPClient(egress.NewClient("provider", 120*time.Second)),
+	)
+	return &ClaudeProvider{client: &client}
+}
+
+// NewClaudeProviderWithAPIKey talks to the Messages API with an
+// x-api-key credential. apiBase may be given with or without the /v1 suffix
+// that OpenAI-style configs carry; empty means api.anthropic.com.
+func NewClaudeProviderWithAPIKey(apiKey, apiBase, proxy string) *ClaudeProvider {
+	apiBase = strings.TrimSuffix(strings.TrimRight(apiBase, "/"), "/v1")
+	if apiBase == "" {
+		apiBase = "https://api.anthropic.com"
+	}
+
+	transport := egress.NewTransport("provider")
+	if proxy != "" {
+		if proxyURL, err := url.Parse(proxy); err == nil {
+			transport.Proxy = egress.Proxy(http.ProxyURL(proxyURL))
+		}
+	}
+	httpClient := &http.Client{
+		Timeout:   120 * time.Second,
+		Transport: egress.Audit("provider", transport),
+	}
+
+	client := anthropic.NewClient(
+		option.WithAPIKey(apiKey),
+		option.WithBaseURL(apiBase),
+		option.WithHTTPClient(httpClient),
 	)
 	return &ClaudeProvider{client: &client}
 }
@@ -46,12 +81,88 @@ func (p *ClaudeProvider) Chat(ctx context.Context, messages []Message, tools []T
 
 	resp, err := p.client.Messages.New(ctx, params, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("claude API call: %w", err)
+		return nil, classifyClaudeError(ctx, err)
 	}
 
 	return parseClaudeResponse(resp), nil
 }
 
+// ChatStream streams a response via the Messages API event stream.
+// Events are accumulated into an anthropic.Message so the final response is
+// parsed exactly like a non-streaming one.
+func (p *ClaudeProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	var opts []option.RequestOption
+	if p.tokenSource != nil {
+		tok, err := p.tokenSource()
+		if err != nil {
+			return nil, fmt.Errorf("refreshing token: %w", err)
+		}
+		opts = append(opts, option.WithAuthToken(tok))
+	}
+
+	params, err := buildClaudeParams(messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := p.client.Messages.NewStreaming(ctx, params, opts...)
+
+	events := make(chan StreamEvent, 16)
+	go func() {
+		defer stream.Close()
+		defer close(events)
+
+		message := anthropic.Message{}
+		for stream.Next() {
+			event := stream.Current()
+			if err := message.Accumulate(event); err != nil {
+				sendStreamEvent(ctx, events, StreamEvent{Err: fmt.Errorf("claude stream: %w", err)})
+				return
+			}
+
+			var ev StreamEvent
+			switch event.Type {
+			case "content_block_start":
+				if event.ContentBlock.Type == "tool_use" {
+					ev.ToolCall = &ToolCallDelta{
+						Index: int(event.Index),
+						ID:    event.ContentBlock.ID,
+						Name:  event.ContentBlock.Name,
+					}
+				}
+			case "content_block_delta":
+				switch event.Delta.Type {
+				case "text_delta":
+					ev.Content = event.Delta.Text
+				case "thinking_delta":
+					ev.Reasoning = event.Delta.Thinking
+				case "input_json_delta":
+					ev.ToolCall = &ToolCallDelta{
+						Index:     int(event.Index),
+						Arguments: event.Delta.PartialJSON,
+					}
+				}
+			}
+
+			if ev.Content == "" && ev.Reasoning == "" && ev.ToolCall == nil {
+				continue
+			}
+			if !sendStreamEvent(ctx, events, ev) {
+				return
+			}
+		}
+
+		if err := stream.Err(); err != nil {
+			sendStreamEvent(ctx, events, StreamEvent{Err: classifyClaudeError(ctx, err)})
+			return
+		}
+
+		sendStreamEvent(ctx, events, StreamEvent{Done: true, Response: parseClaudeResponse(&message)})
+	}()
+
+	return events, nil
+}
+
 func (p *ClaudeProvider) GetDefaultModel() string {
 	return "claude-sonnet-4-5-20250929"
 }
@@ -69,6 +180,10 @@ func buildClaudeParams(messages []Message, tools []ToolDefinition, model string,
 				anthropicMessages = append(anthropicMessages,
 					anthropic.NewUserMessage(anthropic.NewToolResultBlock(msg.ToolCallID, msg.Content, false)),
 				)
+			} else if len(msg.Parts) > 0 {
+				anthropicMessages = append(anthropicMessages,
+					anthropic.NewUserMessage(claudeContentBlocks(msg.Parts)...),
+				)
 			} else {
 				anthropicMessages = append(anthropicMessages,
 					anthropic.NewUserMessage(anthropic.NewTextBlock(msg.Content)),
@@ -81,7 +196,8 @@ func buildClaudeParams(messages []Message, tools []ToolDefinition, model string,
 					blocks = append(blocks, anthropic.NewTextBlock(msg.Content))
 				}
 				for _, tc := range msg.ToolCalls {
-					blocks = append(blocks, anthropic.NewToolUseBlock(tc.ID, tc.Arguments, tc.Name))
+					name, args := toolCallNameAndArgs(tc)
+					blocks = append(blocks, anthropic.NewToolUseBlock(tc.ID, args, name))
 				}
 				anthropicMessages = append(anthropicMessages, anthropic.NewAssistantMessage(blocks...))
 			} else {
@@ -102,7 +218,7 @@ func buildClaudeParams(messages []Message, tools []ToolDefinition, model string,
 	}
 
 	params := anthropic.MessageNewParams{
-		Model:     anthropic.Model(model),
+		Model:     anthropic.Model(strings.TrimPrefix(model, "anthropic/")),
 		Messages:  anthropicMessages,
 		MaxTokens: maxTokens,
 	}
@@ -122,6 +238,23 @@ func buildClaudeParams(messages []Message, tools []ToolDefinition, model string,
 	return params, nil
 }
 
+// claudeContentBlocks converts multimodal parts. Images go first, as the
+// Anthropic docs recommend placing them before the question about them.
+func claudeContentBlocks(parts []ContentPart) []anthropic.ContentBlockParamUnion {
+	var images, texts []anthropic.ContentBlockParamUnion
+	for _, part := range parts {
+		switch part.Type {
+		case "image":
+			images = append(images, anthropic.NewImageBlockBase64(part.MimeType, part.Base64()))
+		case "text":
+			if part.Text != "" {
+				texts = append(texts, anthropic.NewTextBlock(part.Text))
+			}
+		}
+	}
+	return append(images, texts...)
+}
+
 func translateToolsForClaude(tools []ToolDefinition) []anthropic.ToolUnionParam {
 	result := make([]anthropic.ToolUnionParam, 0, len(tools))
 	for _, t := range tools {
@@ -149,7 +282,7 @@ func translateToolsForClaude(tools []ToolDefinition) []anthropic.ToolUnionParam
 }
 
 func parseClaudeResponse(resp *anthropic.Message) *LLMResponse {
-	var content string
+	var content, reasoning string
 	var toolCalls []ToolCall
 
 	for _, block := range resp.Content {
@@ -157,6 +290,8 @@ func parseClaudeResponse(resp *anthropic.Message) *LLMResponse {
 		case "text":
 			tb := block.AsText()
 			content += tb.Text
+		case "thinking":
+			reasoning = joinReasoning(reasoning, block.AsThinking().Thinking)
 		case "tool_use":
 			tu := block.AsToolUse()
 			var args map[string]interface{}
@@ -183,6 +318,7 @@ func parseClaudeResponse(resp *anthropic.Message) *LLMResponse {
 
 	return &LLMResponse{
 		Content:      content,
+		Reasoning:    reasoning,
 		ToolCalls:    toolCalls,
 		FinishReason: finishReason,
 		Usage: &UsageInfo{
@@ -193,6 +329,21 @@ func parseClaudeResponse(resp *anthropic.Message) *LLMResponse {
 	}
 }
 
+// classifyClaudeError maps SDK errors onto the provider error kinds.
+func classifyClaudeError(ctx context.Context, err error) error {
+	wrapped := fmt.Errorf("claude API call: %w", err)
+
+	var apiErr *anthropic.Error
+	if errors.As(err, &apiErr) {
+		var header http.Header
+		if apiErr.Response != nil {
+			header = apiErr.Response.Header
+		}
+		return newStatusError(wrapped, apiErr.StatusCode, apiErr.RawJSON(), header)
+	}
+	return newTransportError(ctx, wrapped)
+}
+
 func createClaudeTokenSource() func() (string, error) {
 	return func() (string, error) {
 		cred, err := auth.GetCredential("anthropic")
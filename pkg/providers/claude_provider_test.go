@@ -194,6 +194,50 @@ func TestClaudeProvider_ChatRoundTrip(t *testing.T) {
 	}
 }
 
+func TestClaudeProvider_APIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("X-Api-Key") != "sk-ant-test" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var reqBody map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&reqBody)
+		if reqBody["model"] != "claude-sonnet-4-5-20250929" {
+			http.Error(w, "unexpected model", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":          "msg_test",
+			"type":        "message",
+			"role":        "assistant",
+			"model":       reqBody["model"],
+			"stop_reason": "end_turn",
+			"content":     []map[string]interface{}{{"type": "text", "text": "pong"}},
+			"usage":       map[string]interface{}{"input_tokens": 3, "output_tokens": 1},
+		})
+	}))
+	defer server.Close()
+
+	// api_base values written for the OpenAI-style provider end in /v1
+	provider := NewClaudeProviderWithAPIKey("sk-ant-test", server.URL+"/v1", "")
+
+	messages := []Message{{Role: "user", Content: "ping"}}
+	resp, err := provider.Chat(t.Context(), messages, nil, "anthropic/claude-sonnet-4-5-20250929", nil)
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if resp.Content != "pong" {
+		t.Errorf("Content = %q, want %q", resp.Content, "pong")
+	}
+}
+
 func TestClaudeProvider_GetDefaultModel(t *testing.T) {
 	p := NewClaudeProvider("test-token")
 	if got := p.GetDefaultModel(); got != "claude-sonnet-4-5-20250929" {
@@ -5,12 +5,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/option"
 	"github.com/openai/openai-go/v3/responses"
 	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/egress"
 	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
@@ -31,6 +34,7 @@ func NewCodexProvider(token, accountID string) *CodexProvider {
 		option.WithAPIKey(token),
 		option.WithHeader("originator", "codex_cli_rs"),
 		option.WithHeader("OpenAI-Beta", "responses=experimental"),
+		option.WithHTTPClient(egress.NewClient("provider", 120*time.Second)),
 	}
 	if accountID != "" {
 		opts = append(opts, option.WithHeader("Chatgpt-Account-Id", accountID))
@@ -119,7 +123,7 @@ func (p *CodexProvider) Chat(ctx context.Context, messages []Message, tools []To
 			}
 		}
 		logger.ErrorCF("provider.codex", "Codex API call failed", fields)
-		return nil, fmt.Errorf("codex API call: %w", err)
+		return nil, classifyCodexError(ctx, err)
 	}
 	if resp == nil {
 		fields := map[string]interface{}{
@@ -337,6 +341,21 @@ func parseCodexResponse(resp *responses.Response) *LLMResponse {
 	}
 }
 
+// classifyCodexError maps SDK errors onto the provider error kinds.
+func classifyCodexError(ctx context.Context, err error) error {
+	wrapped := fmt.Errorf("codex API call: %w", err)
+
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		var header http.Header
+		if apiErr.Response != nil {
+			header = apiErr.Response.Header
+		}
+		return newStatusError(wrapped, apiErr.StatusCode, apiErr.RawJSON(), header)
+	}
+	return newTransportError(ctx, wrapped)
+}
+
 func createCodexTokenSource() func() (string, string, error) {
 	return func() (string, string, error) {
 		cred, err := auth.GetCredential("openai")
@@ -0,0 +1,71 @@
+package providers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// MaxImageBytes caps the size of an image attached to a message. Most
+// vision APIs reject anything larger.
+const MaxImageBytes = 20 << 20
+
+// TextPart returns a text content part.
+func TextPart(text string) ContentPart {
+	return ContentPart{Type: "text", Text: text}
+}
+
+// ImagePartFromFile reads an image from disk. The MIME type is sniffed from
+// the content, and files that aren't images are rejected.
+func ImagePartFromFile(path string) (ContentPart, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ContentPart{}, err
+	}
+	if info.Size() > MaxImageBytes {
+		return ContentPart{}, fmt.Errorf("image %s is too large (%d bytes)", path, info.Size())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ContentPart{}, err
+	}
+	return imagePart(data)
+}
+
+// ImagePartFromDataURL decodes a "data:image/...;base64," URL as sent by
+// browsers.
+func ImagePartFromDataURL(dataURL string) (ContentPart, error) {
+	header, payload, ok := strings.Cut(dataURL, ",")
+	if !ok || !strings.HasPrefix(header, "data:") || !strings.HasSuffix(header, ";base64") {
+		return ContentPart{}, fmt.Errorf("not a base64 data URL")
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return ContentPart{}, fmt.Errorf("decoding data URL: %w", err)
+	}
+	if len(data) > MaxImageBytes {
+		return ContentPart{}, fmt.Errorf("image is too large (%d bytes)", len(data))
+	}
+	return imagePart(data)
+}
+
+func imagePart(data []byte) (ContentPart, error) {
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return ContentPart{}, fmt.Errorf("unsupported image type %s", mimeType)
+	}
+	return ContentPart{Type: "image", MimeType: mimeType, Data: data}, nil
+}
+
+// Base64 returns the image data base64-encoded.
+func (p ContentPart) Base64() string {
+	return base64.StdEncoding.EncodeToString(p.Data)
+}
+
+// DataURL returns the image as a data URL.
+func (p ContentPart) DataURL() string {
+	return "data:" + p.MimeType + ";base64," + p.Base64()
+}
@@ -0,0 +1,101 @@
+package providers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A 1x1 transparent PNG
+const testPNGBase64 = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAQAAAC1HAwCAAAAC0lEQVR42mNkYAAAAAYAAjCB0C8AAAAASUVORK5CYII="
+
+func testImageMessage(t *testing.T) Message {
+	t.Helper()
+	part, err := ImagePartFromDataURL("data:image/png;base64," + testPNGBase64)
+	if err != nil {
+		t.Fatalf("ImagePartFromDataURL() error: %v", err)
+	}
+	return Message{
+		Role:    "user",
+		Content: "What is in this picture?",
+		Parts:   []ContentPart{TextPart("What is in this picture?"), part},
+	}
+}
+
+func TestImagePartFromFile(t *testing.T) {
+	dir := t.TempDir()
+	data, _ := base64.StdEncoding.DecodeString(testPNGBase64)
+
+	imgPath := filepath.Join(dir, "photo.jpg") // extension deliberately wrong
+	os.WriteFile(imgPath, data, 0644)
+	part, err := ImagePartFromFile(imgPath)
+	if err != nil {
+		t.Fatalf("ImagePartFromFile() error: %v", err)
+	}
+	if part.MimeType != "image/png" {
+		t.Errorf("MimeType = %q, want sniffed image/png", part.MimeType)
+	}
+
+	txtPath := filepath.Join(dir, "notes.txt")
+	os.WriteFile(txtPath, []byte("hello"), 0644)
+	if _, err := ImagePartFromFile(txtPath); err == nil {
+		t.Error("expected error for non-image file")
+	}
+}
+
+func TestToOpenAIMessages_ImageParts(t *testing.T) {
+	out := toOpenAIMessages([]Message{{Role: "system", Content: "sys"}, testImageMessage(t)})
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	var decoded []map[string]interface{}
+	json.Unmarshal(data, &decoded)
+
+	if decoded[0]["content"] != "sys" {
+		t.Errorf("plain message content = %v, want string", decoded[0]["content"])
+	}
+	parts, ok := decoded[1]["content"].([]interface{})
+	if !ok || len(parts) != 2 {
+		t.Fatalf("multimodal content = %v, want 2 parts", decoded[1]["content"])
+	}
+	img := parts[1].(map[string]interface{})
+	if img["type"] != "image_url" {
+		t.Errorf("part type = %v, want image_url", img["type"])
+	}
+	url := img["image_url"].(map[string]interface{})["url"].(string)
+	if !strings.HasPrefix(url, "data:image/png;base64,") {
+		t.Errorf("image url = %q, want data URL", url)
+	}
+}
+
+func TestToOllamaMessages_Images(t *testing.T) {
+	out := toOllamaMessages([]Message{testImageMessage(t)})
+	if len(out[0].Images) != 1 || out[0].Images[0] != testPNGBase64 {
+		t.Errorf("Images = %v, want the raw base64 payload", out[0].Images)
+	}
+	if out[0].Content != "What is in this picture?" {
+		t.Errorf("Content = %q", out[0].Content)
+	}
+}
+
+func TestBuildClaudeParams_ImageBlock(t *testing.T) {
+	params, err := buildClaudeParams([]Message{testImageMessage(t)}, nil, "claude-sonnet-4-5", nil)
+	if err != nil {
+		t.Fatalf("buildClaudeParams() error: %v", err)
+	}
+	blocks := params.Messages[0].Content
+	if len(blocks) != 2 {
+		t.Fatalf("len(blocks) = %d, want 2", len(blocks))
+	}
+	if blocks[0].OfImage == nil {
+		t.Error("first block should be the image")
+	}
+	if blocks[1].OfText == nil || blocks[1].OfText.Text != "What is in this picture?" {
+		t.Error("second block should be the question text")
+	}
+}
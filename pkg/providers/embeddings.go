@@ -0,0 +1,220 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/crypt"
+)
+
+const (
+	DefaultOllamaEmbeddingModel = "nomic-embed-text"
+	DefaultOpenAIEmbeddingModel = "text-embedding-3-small"
+
+	// DefaultEmbeddingBatchSize bounds how many texts go into one request.
+	DefaultEmbeddingBatchSize = 32
+)
+
+// EmbeddingProvider turns text into vectors for semantic search. The
+// returned slice has one embedding per input text, in the same order. An
+// empty model selects the provider's default embedding model.
+type EmbeddingProvider interface {
+	Embed(ctx context.Context, texts []string, model string) ([][]float32, error)
+}
+
+// CreateEmbeddingProvider builds the embedding backend configured in
+// embeddings, wrapped in a CachingEmbedder that batches requests and keeps
+// vectors under workspace/embeddings.
+func CreateEmbeddingProvider(cfg *config.Config) (*CachingEmbedder, error) {
+	var inner EmbeddingProvider
+
+	switch strings.ToLower(cfg.Embeddings.Provider) {
+	case "", "ollama":
+		apiBase := strings.TrimSuffix(strings.TrimRight(cfg.Providers.Ollama.APIBase, "/"), "/v1")
+		p, err := NewOllamaProvider(OllamaConfig{
+			BaseURL: apiBase,
+			APIKey:  cfg.Providers.Ollama.APIKey,
+			Proxy:   cfg.Providers.Ollama.Proxy,
+		})
+		if err != nil {
+			return nil, err
+		}
+		inner = p
+	case "openai":
+		if cfg.Providers.OpenAI.APIKey == "" {
+			return nil, fmt.Errorf("embeddings: no API key configured for openai")
+		}
+		apiBase := cfg.Providers.OpenAI.APIBase
+		if apiBase == "" {
+			apiBase = "https://api.openai.com/v1"
+		}
+		inner = NewHTTPProvider(cfg.Providers.OpenAI.APIKey, apiBase, cfg.Providers.OpenAI.Proxy)
+	case "vllm":
+		if cfg.Providers.VLLM.APIBase == "" {
+			return nil, fmt.Errorf("embeddings: no API base configured for vllm")
+		}
+		inner = NewHTTPProvider(cfg.Providers.VLLM.APIKey, cfg.Providers.VLLM.APIBase, cfg.Providers.VLLM.Proxy)
+	default:
+		return nil, fmt.Errorf("embeddings: unsupported provider %q", cfg.Embeddings.Provider)
+	}
+
+	cacheDir := filepath.Join(cfg.WorkspacePath(), "embeddings")
+	return NewCachingEmbedder(inner, cfg.Embeddings.Model, cacheDir, cfg.Embeddings.BatchSize), nil
+}
+
+// CachingEmbedder sends texts to an EmbeddingProvider in batches and stores
+// each vector on disk under the SHA-256 of model and text, so unchanged
+// memory notes or session messages are never embedded twice.
+type CachingEmbedder struct {
+	inner     EmbeddingProvider
+	model     string
+	dir       string
+	batchSize int
+}
+
+// NewCachingEmbedder wraps inner. model is used when Embed is called with an
+// empty model, and defaults to the one inner uses; cacheDir may be empty to
+// disable the disk cache.
+func NewCachingEmbedder(inner EmbeddingProvider, model, cacheDir string, batchSize int) *CachingEmbedder {
+	if batchSize <= 0 {
+		batchSize = DefaultEmbeddingBatchSize
+	}
+	if model == "" {
+		// Cache under the name of the model that actually embeds, so that
+		// configuring it explicitly later doesn't miss the cache
+		model = defaultEmbeddingModel(inner)
+	}
+	return &CachingEmbedder{
+		inner:     inner,
+		model:     model,
+		dir:       cacheDir,
+		batchSize: batchSize,
+	}
+}
+
+func (e *CachingEmbedder) Embed(ctx context.Context, texts []string, model string) ([][]float32, error) {
+	if model == "" {
+		model = e.model
+	}
+
+	out := make([][]float32, len(texts))
+
+	// Texts that aren't cached, deduplicated, with the positions they fill
+	var missing []string
+	positions := make(map[string][]int)
+	for i, text := range texts {
+		if vec := e.load(model, text); vec != nil {
+			out[i] = vec
+			continue
+		}
+		if _, seen := positions[text]; !seen {
+			missing = append(missing, text)
+		}
+		positions[text] = append(positions[text], i)
+	}
+
+	for start := 0; start < len(missing); start += e.batchSize {
+		end := min(start+e.batchSize, len(missing))
+		batch := missing[start:end]
+
+		vectors, err := e.inner.Embed(ctx, batch, model)
+		if err != nil {
+			return nil, err
+		}
+		if len(vectors) != len(batch) {
+			return nil, fmt.Errorf("embeddings: got %d vectors for %d texts", len(vectors), len(batch))
+		}
+
+		for j, text := range batch {
+			for _, i := range positions[text] {
+				out[i] = vectors[j]
+			}
+			e.store(model, text, vectors[j])
+		}
+	}
+
+	return out, nil
+}
+
+// defaultEmbeddingModel is the model p embeds with when given none.
+func defaultEmbeddingModel(p EmbeddingProvider) string {
+	switch p.(type) {
+	case *OllamaProvider:
+		return DefaultOllamaEmbeddingModel
+	case *HTTPProvider:
+		return DefaultOpenAIEmbeddingModel
+	}
+	return ""
+}
+
+func (e *CachingEmbedder) cachePath(model, text string) string {
+	sum := sha256.Sum256([]byte(model + "\x00" + text))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(e.dir, key[:2], key)
+}
+
+// load returns the cached vector, or nil on a miss.
+func (e *CachingEmbedder) load(model, text string) []float32 {
+	if e.dir == "" {
+		return nil
+	}
+	data, err := crypt.ReadFile(e.cachePath(model, text))
+	if err != nil || len(data) == 0 || len(data)%4 != 0 {
+		return nil
+	}
+	vec := make([]float32, len(data)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return vec
+}
+
+// store writes a vector as little-endian float32s, readable only by the
+// owner and encrypted when encryption at rest is on, as the vectors give
+// away the text. Failures only cost a re-embed later, so they are ignored.
+func (e *CachingEmbedder) store(model, text string, vec []float32) {
+	if e.dir == "" || len(vec) == 0 {
+		return
+	}
+	path := e.cachePath(model, text)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+	data := make([]byte, len(vec)*4)
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(v))
+	}
+	tmp := path + ".tmp"
+	if err := crypt.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+	}
+}
+
+// CosineSimilarity compares two embeddings, returning a value in [-1, 1].
+// Vectors of different length, or zero vectors, score 0.
+func CosineSimilarity(a, b []float32) float32 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(na) * math.Sqrt(nb)))
+}
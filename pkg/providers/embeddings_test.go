@@ -0,0 +1,189 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/crypt"
+)
+
+// countingEmbedder returns [len(text), batch index] for each text and
+// records the batches it was asked for.
+type countingEmbedder struct {
+	batches [][]string
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, texts []string, model string) ([][]float32, error) {
+	e.batches = append(e.batches, texts)
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		out[i] = []float32{float32(len(text)), float32(len(e.batches))}
+	}
+	return out, nil
+}
+
+func TestCachingEmbedder_BatchesAndCaches(t *testing.T) {
+	inner := &countingEmbedder{}
+	dir := t.TempDir()
+	e := NewCachingEmbedder(inner, "nomic-embed-text", dir, 2)
+
+	texts := []string{"a", "bb", "a", "ccc", "dddd"}
+	vectors, err := e.Embed(context.Background(), texts, "")
+	if err != nil {
+		t.Fatalf("Embed() error: %v", err)
+	}
+	if len(vectors) != len(texts) {
+		t.Fatalf("len(vectors) = %d, want %d", len(vectors), len(texts))
+	}
+	// "a" appears twice but is embedded once: 4 unique texts in batches of 2
+	if len(inner.batches) != 2 {
+		t.Errorf("batches = %v, want 2 batches", inner.batches)
+	}
+	if vectors[0][0] != 1 || vectors[2][0] != 1 || vectors[4][0] != 4 {
+		t.Errorf("vectors out of order: %v", vectors)
+	}
+
+	// A fresh embedder over the same directory serves everything from disk
+	inner2 := &countingEmbedder{}
+	e2 := NewCachingEmbedder(inner2, "nomic-embed-text", dir, 2)
+	cached, err := e2.Embed(context.Background(), []string{"ccc", "a"}, "")
+	if err != nil {
+		t.Fatalf("Embed() error: %v", err)
+	}
+	if len(inner2.batches) != 0 {
+		t.Errorf("expected cache hits, inner called with %v", inner2.batches)
+	}
+	if cached[0][0] != 3 || cached[1][0] != 1 {
+		t.Errorf("cached vectors = %v", cached)
+	}
+
+	// A different model is a different cache key
+	if _, err := e2.Embed(context.Background(), []string{"a"}, "mxbai-embed-large"); err != nil {
+		t.Fatalf("Embed() error: %v", err)
+	}
+	if len(inner2.batches) != 1 {
+		t.Errorf("expected a miss for another model, batches = %v", inner2.batches)
+	}
+}
+
+func TestCachingEmbedder_CacheIsPrivate(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "at-rest.key")
+	os.WriteFile(keyFile, []byte("0123456789abcdef0123456789abcdef"), 0600)
+	key, err := crypt.LoadKey("", keyFile, "")
+	if err != nil {
+		t.Fatalf("LoadKey() error: %v", err)
+	}
+	crypt.Configure(key, true)
+	defer crypt.Configure(nil, false)
+
+	cacheDir := filepath.Join(dir, "embeddings")
+	e := NewCachingEmbedder(&countingEmbedder{}, "nomic-embed-text", cacheDir, 0)
+	if _, err := e.Embed(context.Background(), []string{"my secret note"}, ""); err != nil {
+		t.Fatalf("Embed() error: %v", err)
+	}
+
+	path := e.cachePath("nomic-embed-text", "my secret note")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("vector not cached: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("cache file mode = %v, want 0600", info.Mode().Perm())
+	}
+	if raw, _ := os.ReadFile(path); !crypt.IsEncrypted(raw) {
+		t.Error("vector was cached as plaintext")
+	}
+
+	inner := &countingEmbedder{}
+	if _, err := NewCachingEmbedder(inner, "nomic-embed-text", cacheDir, 0).Embed(context.Background(), []string{"my secret note"}, ""); err != nil || len(inner.batches) != 0 {
+		t.Errorf("encrypted cache entry not read back: batches %v, error %v", inner.batches, err)
+	}
+}
+
+func TestNewCachingEmbedder_DefaultModel(t *testing.T) {
+	ollama, _ := CreateOllamaProvider("")
+	if got := NewCachingEmbedder(ollama.(EmbeddingProvider), "", "", 0).model; got != DefaultOllamaEmbeddingModel {
+		t.Errorf("model = %q, want the one Ollama embeds with", got)
+	}
+	if got := NewCachingEmbedder(NewHTTPProvider("", "http://vllm:8000/v1", ""), "", "", 0).model; got != DefaultOpenAIEmbeddingModel {
+		t.Errorf("model = %q, want the OpenAI default", got)
+	}
+}
+
+func TestOllamaProvider_Embed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != DefaultOllamaEmbeddingModel {
+			http.Error(w, "unexpected model "+req.Model, http.StatusBadRequest)
+			return
+		}
+		embeddings := make([][]float32, len(req.Input))
+		for i := range req.Input {
+			embeddings[i] = []float32{float32(i), 1}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": embeddings})
+	}))
+	defer server.Close()
+
+	provider, _ := CreateOllamaProvider(server.URL)
+	vectors, err := provider.(EmbeddingProvider).Embed(t.Context(), []string{"x", "y"}, "")
+	if err != nil {
+		t.Fatalf("Embed() error: %v", err)
+	}
+	if len(vectors) != 2 || vectors[1][0] != 1 {
+		t.Errorf("vectors = %v", vectors)
+	}
+}
+
+func TestHTTPProvider_Embed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		// Deliberately out of order
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]interface{}{
+				{"index": 1, "embedding": []float32{0, 1}},
+				{"index": 0, "embedding": []float32{1, 0}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	provider := NewHTTPProvider("key", server.URL, "")
+	vectors, err := provider.Embed(t.Context(), []string{"first", "second"}, "")
+	if err != nil {
+		t.Fatalf("Embed() error: %v", err)
+	}
+	if vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("vectors = %v, want ordered by index", vectors)
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	if got := CosineSimilarity([]float32{1, 0}, []float32{2, 0}); got < 0.999 {
+		t.Errorf("parallel = %v, want 1", got)
+	}
+	if got := CosineSimilarity([]float32{1, 0}, []float32{0, 1}); got != 0 {
+		t.Errorf("orthogonal = %v, want 0", got)
+	}
+	if got := CosineSimilarity([]float32{1}, []float32{1, 0}); got != 0 {
+		t.Errorf("mismatched length = %v, want 0", got)
+	}
+}
@@ -0,0 +1,155 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error kinds returned by providers. Test for them with errors.Is; the
+// concrete error is a *ProviderError carrying the status code and body.
+var (
+	// ErrContextOverflow means the request exceeded the model's context
+	// window. Compressing history and retrying can help.
+	ErrContextOverflow = errors.New("context window exceeded")
+	// ErrRateLimited means the backend throttled the request. Retrying after
+	// a delay, or on another backend, can help.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrAuth means the credentials were missing, invalid or expired.
+	// Retrying won't help.
+	ErrAuth = errors.New("authentication failed")
+	// ErrTransient covers network failures, timeouts and 5xx responses.
+	ErrTransient = errors.New("transient provider error")
+)
+
+// ProviderError is a classified failure from an LLM backend.
+type ProviderError struct {
+	Kind       error // one of the Err* kinds above, or nil when unclassified
+	StatusCode int   // 0 for transport errors
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *ProviderError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ProviderError) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// RetryAfter returns the delay a backend asked for before retrying, or zero.
+func RetryAfter(err error) time.Duration {
+	var pe *ProviderError
+	if errors.As(err, &pe) {
+		return pe.RetryAfter
+	}
+	return 0
+}
+
+// overflowMarkers are fragments of the error bodies backends send when the
+// prompt doesn't fit the context window. For HTTP backends they are only
+// consulted for 400/413/422 responses so that e.g. "invalid token" auth
+// failures aren't mistaken for overflows.
+var overflowMarkers = []string{
+	"context_length_exceeded",
+	"maximum context length",
+	"context length",
+	"context window",
+	"prompt is too long",
+	"input is too long",
+	"too many tokens",
+	"exceed max message tokens",
+	"exceeds the maximum number of tokens",
+	"reduce the length",
+	"request too large",
+}
+
+// Classify returns err as an ErrContextOverflow when it is unclassified but
+// its message reads like an overflow. Providers that only have an error
+// message to go by, such as the CLI wrappers, report overflows this way.
+// Any other error is returned unchanged.
+func Classify(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	var pe *ProviderError
+	if errors.As(err, &pe) && pe.Kind != nil {
+		return err
+	}
+	lower := strings.ToLower(err.Error())
+	for _, marker := range overflowMarkers {
+		if strings.Contains(lower, marker) {
+			return &ProviderError{Kind: ErrContextOverflow, Err: err}
+		}
+	}
+	return err
+}
+
+// newStatusError classifies a non-2xx response. err is the descriptive error
+// callers would have returned before classification.
+func newStatusError(err error, statusCode int, body string, header http.Header) *ProviderError {
+	pe := &ProviderError{
+		Kind:       classifyStatus(statusCode, body),
+		StatusCode: statusCode,
+		Err:        err,
+	}
+	if header != nil {
+		pe.RetryAfter = parseRetryAfter(header.Get("Retry-After"))
+	}
+	return pe
+}
+
+func classifyStatus(statusCode int, body string) error {
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return ErrAuth
+	case statusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case statusCode == http.StatusBadRequest ||
+		statusCode == http.StatusRequestEntityTooLarge ||
+		statusCode == http.StatusUnprocessableEntity:
+		lower := strings.ToLower(body)
+		for _, marker := range overflowMarkers {
+			if strings.Contains(lower, marker) {
+				return ErrContextOverflow
+			}
+		}
+		return nil
+	case statusCode == http.StatusRequestTimeout || statusCode >= 500:
+		// Includes Anthropic's 529 "overloaded"
+		return ErrTransient
+	}
+	return nil
+}
+
+// newTransportError classifies a failure to get any response at all. A
+// cancelled or expired caller context is passed through untouched since
+// retrying can't help.
+func newTransportError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	return &ProviderError{Kind: ErrTransient, Err: err}
+}
+
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,84 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClassifyStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{"openai overflow", 400, `{"error":{"code":"context_length_exceeded","message":"This model's maximum context length is 128000 tokens"}}`, ErrContextOverflow},
+		{"anthropic overflow", 400, `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`, ErrContextOverflow},
+		{"volcengine overflow", 400, `{"error":{"code":"InvalidParameter","message":"Total tokens of image and text exceed max message tokens"}}`, ErrContextOverflow},
+		{"invalid token is auth", 401, `{"error":{"message":"Invalid token"}}`, ErrAuth},
+		{"bad request", 400, `{"error":{"message":"unknown field: foo"}}`, nil},
+		{"rate limited", 429, `{"error":{"message":"Rate limit reached"}}`, ErrRateLimited},
+		{"overloaded", 529, `{"type":"error","error":{"type":"overloaded_error"}}`, ErrTransient},
+		{"bad gateway", 502, `<html>Bad Gateway</html>`, ErrTransient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyStatus(tt.status, tt.body); got != tt.want {
+				t.Errorf("classifyStatus(%d) = %v, want %v", tt.status, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPProvider_TypedErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		http.Error(w, `{"error":{"message":"Rate limit reached"}}`, http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	provider := NewHTTPProvider("key", server.URL, "")
+	_, err := provider.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "m", nil)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("error = %v, want ErrRateLimited", err)
+	}
+	if got := RetryAfter(err); got != 7*time.Second {
+		t.Errorf("RetryAfter = %v, want 7s", got)
+	}
+}
+
+func TestNewTransportError(t *testing.T) {
+	cause := fmt.Errorf("connection refused")
+	if err := newTransportError(context.Background(), cause); !errors.Is(err, ErrTransient) || !errors.Is(err, cause) {
+		t.Errorf("error = %v, want ErrTransient wrapping cause", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := newTransportError(ctx, cause); errors.Is(err, ErrTransient) {
+		t.Error("cancelled request should not be classified as transient")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	cliErr := fmt.Errorf("claude cli returned error: Prompt is too long")
+	if err := Classify(cliErr); !errors.Is(err, ErrContextOverflow) || !errors.Is(err, cliErr) {
+		t.Errorf("Classify() = %v, want ErrContextOverflow wrapping the CLI error", err)
+	}
+
+	authErr := newStatusError(fmt.Errorf("invalid token"), 401, "context length", nil)
+	if err := Classify(authErr); !errors.Is(err, ErrAuth) || errors.Is(err, ErrContextOverflow) {
+		t.Errorf("Classify() = %v, want a classified error left alone", err)
+	}
+	for _, err := range []error{fmt.Errorf("codex cli error: exit status 1"), context.Canceled} {
+		if got := Classify(err); got != err {
+			t.Errorf("Classify(%v) = %v, want it unchanged", err, got)
+		}
+	}
+}
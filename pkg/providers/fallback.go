@@ -0,0 +1,258 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// DefaultFallbackCooldown is how long a backend is skipped after a failure
+// that triggered failover.
+const DefaultFallbackCooldown = 60 * time.Second
+
+// FallbackBackend is one entry in a FallbackProvider chain.
+type FallbackBackend struct {
+	Name     string
+	Provider LLMProvider
+	// Model overrides the model requested by the caller; empty keeps it.
+	Model string
+	// Cooldown overrides DefaultFallbackCooldown for this backend.
+	Cooldown time.Duration
+}
+
+// FallbackProvider tries each backend in order and moves on to the next one
+// on transport errors, 5xx responses and rate limits. Backends that failed
+// are skipped until their cooldown expires, unless every backend is cooling
+// down, in which case all of them are tried again.
+type FallbackProvider struct {
+	backends []FallbackBackend
+	now      func() time.Time
+
+	mu            sync.Mutex
+	cooldownUntil map[int]time.Time
+}
+
+func NewFallbackProvider(backends []FallbackBackend) *FallbackProvider {
+	return &FallbackProvider{
+		backends:      backends,
+		now:           time.Now,
+		cooldownUntil: make(map[int]time.Time),
+	}
+}
+
+func (p *FallbackProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	var lastErr error
+	for _, idx := range p.candidates() {
+		b := p.backends[idx]
+		backendModel := p.modelFor(b, model)
+
+		resp, err := b.Provider.Chat(ctx, messages, tools, backendModel, options)
+		if err == nil {
+			p.markHealthy(idx)
+			p.logAnswered(idx, backendModel)
+			return p.tag(resp, idx, backendModel), nil
+		}
+		if errors.Is(err, ErrBudgetExceeded) {
+			// Over the cap isn't a fault of the backend, so no cooldown.
+			lastErr = err
+			continue
+		}
+		if !shouldFailover(ctx, err) {
+			return nil, err
+		}
+		p.markFailed(idx, backendModel, err)
+		lastErr = err
+	}
+	return nil, fmt.Errorf("all providers failed: %w", lastErr)
+}
+
+// ChatStream fails over only while opening the stream; once a backend has
+// started emitting events it is committed to. Backends that can't stream
+// answer through Chat and are replayed as a single content event.
+func (p *FallbackProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	var lastErr error
+	for _, idx := range p.candidates() {
+		b := p.backends[idx]
+		backendModel := p.modelFor(b, model)
+
+		var events <-chan StreamEvent
+		var err error
+		if sp, ok := b.Provider.(StreamingProvider); ok {
+			events, err = sp.ChatStream(ctx, messages, tools, backendModel, options)
+		} else {
+			var resp *LLMResponse
+			resp, err = b.Provider.Chat(ctx, messages, tools, backendModel, options)
+			if err == nil {
+				events = responseAsStream(ctx, resp)
+			}
+		}
+		if err == nil {
+			p.markHealthy(idx)
+			p.logAnswered(idx, backendModel)
+			return p.tagStream(ctx, events, idx, backendModel), nil
+		}
+		if errors.Is(err, ErrBudgetExceeded) {
+			// Over the cap isn't a fault of the backend, so no cooldown.
+			lastErr = err
+			continue
+		}
+		if !shouldFailover(ctx, err) {
+			return nil, err
+		}
+		p.markFailed(idx, backendModel, err)
+		lastErr = err
+	}
+	return nil, fmt.Errorf("all providers failed: %w", lastErr)
+}
+
+func (p *FallbackProvider) GetDefaultModel() string {
+	if len(p.backends) == 0 {
+		return ""
+	}
+	return p.backends[0].Provider.GetDefaultModel()
+}
+
+// ModelInfo reports what every backend can handle: the smallest context
+// window, and tools or vision only if all backends support them, so that a
+// failover doesn't send a request the next backend would reject. The
+// result is Guessed if any backend couldn't be asked.
+func (p *FallbackProvider) ModelInfo(ctx context.Context, model string) (*ModelInfo, error) {
+	var merged *ModelInfo
+	for _, b := range p.backends {
+		info := GetModelInfo(ctx, b.Provider, p.modelFor(b, model))
+		if merged == nil {
+			merged = &info
+			continue
+		}
+		merged.ContextWindow = min(merged.ContextWindow, info.ContextWindow)
+		merged.Tools = merged.Tools && info.Tools
+		merged.Vision = merged.Vision && info.Vision
+		// A guess about any backend makes the whole answer one
+		merged.Guessed = merged.Guessed || info.Guessed
+	}
+	if merged == nil {
+		return nil, fmt.Errorf("no backends configured")
+	}
+	return merged, nil
+}
+
+// candidates returns backend indexes in order, leaving out those cooling down.
+func (p *FallbackProvider) candidates() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	available := make([]int, 0, len(p.backends))
+	for i := range p.backends {
+		if until, ok := p.cooldownUntil[i]; ok && now.Before(until) {
+			continue
+		}
+		available = append(available, i)
+	}
+	if len(available) > 0 {
+		return available
+	}
+
+	all := make([]int, len(p.backends))
+	for i := range all {
+		all[i] = i
+	}
+	return all
+}
+
+func (p *FallbackProvider) modelFor(b FallbackBackend, requested string) string {
+	if b.Model != "" {
+		return b.Model
+	}
+	return requested
+}
+
+func (p *FallbackProvider) markHealthy(idx int) {
+	p.mu.Lock()
+	delete(p.cooldownUntil, idx)
+	p.mu.Unlock()
+}
+
+func (p *FallbackProvider) markFailed(idx int, model string, err error) {
+	cooldown := p.backends[idx].Cooldown
+	if cooldown <= 0 {
+		cooldown = DefaultFallbackCooldown
+	}
+
+	p.mu.Lock()
+	p.cooldownUntil[idx] = p.now().Add(cooldown)
+	p.mu.Unlock()
+
+	logger.WarnCF("provider", "Provider failed, trying next fallback",
+		map[string]interface{}{
+			"backend":  p.backends[idx].Name,
+			"model":    model,
+			"cooldown": cooldown.String(),
+			"error":    err.Error(),
+		})
+}
+
+func (p *FallbackProvider) logAnswered(idx int, model string) {
+	logger.InfoCF("provider", "LLM response served",
+		map[string]interface{}{
+			"backend":  p.backends[idx].Name,
+			"model":    model,
+			"fallback": idx > 0,
+		})
+}
+
+// tag records on resp which backend and model answered, so that usage is
+// counted against them rather than the configured model.
+func (p *FallbackProvider) tag(resp *LLMResponse, idx int, model string) *LLMResponse {
+	if resp != nil {
+		resp.Provider = p.backends[idx].Name
+		resp.Model = model
+	}
+	return resp
+}
+
+// tagStream passes events on, tagging the final response.
+func (p *FallbackProvider) tagStream(ctx context.Context, events <-chan StreamEvent, idx int, model string) <-chan StreamEvent {
+	out := make(chan StreamEvent)
+	go func() {
+		defer close(out)
+		for ev := range events {
+			if ev.Done {
+				p.tag(ev.Response, idx, model)
+			}
+			if !sendStreamEvent(ctx, out, ev) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// responseAsStream wraps a complete response in a stream channel.
+func responseAsStream(ctx context.Context, resp *LLMResponse) <-chan StreamEvent {
+	events := make(chan StreamEvent, 2)
+	go func() {
+		defer close(events)
+		if resp.Content != "" {
+			if !sendStreamEvent(ctx, events, StreamEvent{Content: resp.Content}) {
+				return
+			}
+		}
+		sendStreamEvent(ctx, events, StreamEvent{Done: true, Response: resp})
+	}()
+	return events
+}
+
+// shouldFailover reports whether err is worth retrying on another backend:
+// transient failures and rate limits. Request errors such as a bad model
+// name or an oversized context would fail the same way elsewhere.
+func shouldFailover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return errors.Is(err, ErrTransient) || errors.Is(err, ErrRateLimited)
+}
@@ -0,0 +1,147 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type scriptedProvider struct {
+	err    error
+	reply  string
+	calls  int
+	models []string
+}
+
+func (p *scriptedProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	p.calls++
+	p.models = append(p.models, model)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &LLMResponse{Content: p.reply, FinishReason: "stop"}, nil
+}
+
+func (p *scriptedProvider) GetDefaultModel() string {
+	return "scripted"
+}
+
+func TestFallbackProvider_FailsOverOnServerError(t *testing.T) {
+	local := &scriptedProvider{err: newStatusError(fmt.Errorf("ollama returned status 503: loading model"), 503, "loading model", nil)}
+	lan := &scriptedProvider{err: newTransportError(context.Background(), fmt.Errorf("dial tcp 192.168.1.20:8000: i/o timeout"))}
+	cloud := &scriptedProvider{reply: "from cloud"}
+
+	p := NewFallbackProvider([]FallbackBackend{
+		{Name: "ollama", Provider: local},
+		{Name: "vllm", Provider: lan},
+		{Name: "openrouter", Provider: cloud, Model: "openai/gpt-4o-mini"},
+	})
+
+	resp, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "qwen2.5:7b", nil)
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if resp.Content != "from cloud" {
+		t.Errorf("Content = %q, want %q", resp.Content, "from cloud")
+	}
+	if local.models[0] != "qwen2.5:7b" {
+		t.Errorf("primary model = %q, want requested model", local.models[0])
+	}
+	if cloud.models[0] != "openai/gpt-4o-mini" {
+		t.Errorf("fallback model = %q, want override", cloud.models[0])
+	}
+	if resp.Provider != "openrouter" || resp.Model != "openai/gpt-4o-mini" {
+		t.Errorf("response from %s/%s, want the backend that answered", resp.Provider, resp.Model)
+	}
+
+	// Streams report it on the final response
+	events, err := p.ChatStream(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "qwen2.5:7b", nil)
+	if err != nil {
+		t.Fatalf("ChatStream() error: %v", err)
+	}
+	for ev := range events {
+		if ev.Done && (ev.Response.Provider != "openrouter" || ev.Response.Model != "openai/gpt-4o-mini") {
+			t.Errorf("streamed response from %s/%s, want the backend that answered", ev.Response.Provider, ev.Response.Model)
+		}
+	}
+}
+
+func TestFallbackProvider_DoesNotFailOverOnClientError(t *testing.T) {
+	primary := &scriptedProvider{err: newStatusError(fmt.Errorf("API request failed"), 400, "bad request", nil)}
+	backup := &scriptedProvider{reply: "unused"}
+
+	p := NewFallbackProvider([]FallbackBackend{
+		{Name: "primary", Provider: primary},
+		{Name: "backup", Provider: backup},
+	})
+
+	if _, err := p.Chat(context.Background(), nil, nil, "m", nil); err == nil {
+		t.Fatal("expected error to be returned")
+	}
+	if backup.calls != 0 {
+		t.Errorf("backup called %d times, want 0", backup.calls)
+	}
+}
+
+func TestFallbackProvider_Cooldown(t *testing.T) {
+	primary := &scriptedProvider{err: newStatusError(fmt.Errorf("API request failed"), 429, "slow down", nil)}
+	backup := &scriptedProvider{reply: "ok"}
+
+	now := time.Unix(1000, 0)
+	p := NewFallbackProvider([]FallbackBackend{
+		{Name: "primary", Provider: primary, Cooldown: time.Minute},
+		{Name: "backup", Provider: backup},
+	})
+	p.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.Chat(context.Background(), nil, nil, "m", nil); err != nil {
+			t.Fatalf("Chat() error: %v", err)
+		}
+	}
+	if primary.calls != 1 {
+		t.Errorf("primary called %d times during cooldown, want 1", primary.calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	primary.err = nil
+	primary.reply = "back"
+	resp, err := p.Chat(context.Background(), nil, nil, "m", nil)
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if resp.Content != "back" {
+		t.Errorf("Content = %q, want primary to answer after cooldown", resp.Content)
+	}
+}
+
+func TestFallbackProvider_AllFailed(t *testing.T) {
+	cause := newStatusError(fmt.Errorf("ollama returned status 500: boom"), 500, "boom", nil)
+	p := NewFallbackProvider([]FallbackBackend{
+		{Name: "a", Provider: &scriptedProvider{err: cause}},
+		{Name: "b", Provider: &scriptedProvider{err: cause}},
+	})
+
+	_, err := p.Chat(context.Background(), nil, nil, "m", nil)
+	if !errors.Is(err, cause) {
+		t.Errorf("error = %v, want wrapped cause", err)
+	}
+}
+
+func TestFallbackProvider_ModelInfoGuessedByAnyBackend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close() // Ollama not up yet
+	ollama, _ := CreateOllamaProvider(server.URL)
+
+	p := NewFallbackProvider([]FallbackBackend{
+		{Name: "openrouter", Provider: &scriptedProvider{}, Model: "openai/gpt-4o-mini"},
+		{Name: "ollama", Provider: ollama, Model: "llama3.2"},
+	})
+	if info := GetModelInfo(t.Context(), p, "openai/gpt-4o-mini"); !info.Guessed {
+		t.Errorf("ModelInfo() = %+v, want guessed when a fallback couldn't be asked", info)
+	}
+}
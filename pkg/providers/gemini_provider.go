@@ -0,0 +1,415 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// Gemini provider using the native generateContent API
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/egress"
+)
+
+const (
+	DefaultGeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	DefaultGeminiModel   = "gemini-2.5-flash"
+)
+
+// GeminiProvider implements LLMProvider for the Gemini API. Unlike the
+// OpenAI-compatible providers it speaks generateContent directly, which is
+// what the API key issued by Google AI Studio is for.
+type GeminiProvider struct {
+	apiKey     string
+	apiBase    string
+	httpClient *http.Client
+}
+
+type geminiRequest struct {
+	Contents          []geminiContent         `json:"contents"`
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	Tools             []geminiTool            `json:"tools,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiPart holds exactly one of its fields.
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	InlineData       *geminiInlineData       `json:"inlineData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"` // Text is a thought summary
+}
+
+type geminiInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"` // base64
+}
+
+type geminiFunctionCall struct {
+	ID   string                 `json:"id,omitempty"`
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args"`
+}
+
+type geminiFunctionResponse struct {
+	ID       string                 `json:"id,omitempty"`
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+type geminiGenerationConfig struct {
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback,omitempty"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata,omitempty"`
+}
+
+func NewGeminiProvider(apiKey, apiBase, proxy string) *GeminiProvider {
+	transport := egress.NewTransport("provider")
+
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err == nil {
+			transport.Proxy = egress.Proxy(http.ProxyURL(proxyURL))
+		}
+	}
+
+	client := &http.Client{
+		Timeout:   120 * time.Second,
+		Transport: egress.Audit("provider", transport),
+	}
+
+	if apiBase == "" {
+		apiBase = DefaultGeminiBaseURL
+	}
+
+	return &GeminiProvider{
+		apiKey:     apiKey,
+		apiBase:    strings.TrimRight(apiBase, "/"),
+		httpClient: client,
+	}
+}
+
+func (p *GeminiProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	model = geminiModelName(model)
+	if model == "" {
+		model = DefaultGeminiModel
+	}
+
+	reqBody, err := json.Marshal(buildGeminiRequest(messages, tools, options))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/models/%s:generateContent", p.apiBase, url.PathEscape(model))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", p.apiKey)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, newTransportError(ctx, fmt.Errorf("failed to send request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, newTransportError(ctx, fmt.Errorf("failed to read response: %w", err))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newStatusError(fmt.Errorf("gemini returned status %d: %s", resp.StatusCode, string(body)),
+			resp.StatusCode, string(body), resp.Header)
+	}
+
+	var geminiResp geminiResponse
+	if err := json.Unmarshal(body, &geminiResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return parseGeminiResponse(&geminiResp)
+}
+
+func (p *GeminiProvider) GetDefaultModel() string {
+	return DefaultGeminiModel
+}
+
+// geminiModelName drops the routing prefixes used in config model names.
+func geminiModelName(model string) string {
+	for _, prefix := range []string{"google/", "gemini/", "models/"} {
+		model = strings.TrimPrefix(model, prefix)
+	}
+	return model
+}
+
+// buildGeminiRequest converts provider messages to generateContent's format.
+// System messages become systemInstruction, assistant turns use the "model"
+// role, and tool results are sent as functionResponse parts named after the
+// call they answer. Consecutive turns of the same role are merged since the
+// API expects all responses to one round of function calls in one turn.
+func buildGeminiRequest(messages []Message, tools []ToolDefinition, options map[string]interface{}) geminiRequest {
+	var req geminiRequest
+	var system []geminiPart
+	toolNames := make(map[string]string)
+
+	appendContent := func(role string, parts ...geminiPart) {
+		if len(parts) == 0 {
+			return
+		}
+		if n := len(req.Contents); n > 0 && req.Contents[n-1].Role == role {
+			req.Contents[n-1].Parts = append(req.Contents[n-1].Parts, parts...)
+			return
+		}
+		req.Contents = append(req.Contents, geminiContent{Role: role, Parts: parts})
+	}
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case "system":
+			if msg.Content != "" {
+				system = append(system, geminiPart{Text: msg.Content})
+			}
+		case "user":
+			if len(msg.Parts) > 0 {
+				appendContent("user", geminiContentParts(msg.Parts)...)
+			} else if msg.Content != "" {
+				appendContent("user", geminiPart{Text: msg.Content})
+			}
+		case "assistant":
+			var parts []geminiPart
+			if msg.Content != "" {
+				parts = append(parts, geminiPart{Text: msg.Content})
+			}
+			for _, tc := range msg.ToolCalls {
+				name, args := toolCallNameAndArgs(tc)
+				if tc.ID != "" {
+					toolNames[tc.ID] = name
+				}
+				parts = append(parts, geminiPart{FunctionCall: &geminiFunctionCall{Name: name, Args: args}})
+			}
+			appendContent("model", parts...)
+		case "tool":
+			appendContent("user", geminiPart{FunctionResponse: &geminiFunctionResponse{
+				Name:     toolNames[msg.ToolCallID],
+				Response: map[string]interface{}{"content": msg.Content},
+			}})
+		}
+	}
+
+	if len(system) > 0 {
+		req.SystemInstruction = &geminiContent{Parts: system}
+	}
+
+	if len(tools) > 0 {
+		decls := make([]geminiFunctionDeclaration, 0, len(tools))
+		for _, t := range tools {
+			decl := geminiFunctionDeclaration{
+				Name:        t.Function.Name,
+				Description: t.Function.Description,
+			}
+			// An object schema without properties is rejected, so leave
+			// parameters out for tools that take no arguments.
+			if props, ok := t.Function.Parameters["properties"].(map[string]interface{}); ok && len(props) > 0 {
+				decl.Parameters = geminiSchema(t.Function.Parameters)
+			}
+			decls = append(decls, decl)
+		}
+		req.Tools = []geminiTool{{FunctionDeclarations: decls}}
+	}
+
+	var genCfg geminiGenerationConfig
+	if mt, ok := options["max_tokens"].(int); ok {
+		genCfg.MaxOutputTokens = mt
+	}
+	if temp, ok := options["temperature"].(float64); ok {
+		genCfg.Temperature = &temp
+	}
+	if genCfg != (geminiGenerationConfig{}) {
+		req.GenerationConfig = &genCfg
+	}
+
+	return req
+}
+
+func geminiContentParts(parts []ContentPart) []geminiPart {
+	out := make([]geminiPart, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case "image":
+			out = append(out, geminiPart{InlineData: &geminiInlineData{MimeType: part.MimeType, Data: part.Base64()}})
+		case "text":
+			if part.Text != "" {
+				out = append(out, geminiPart{Text: part.Text})
+			}
+		}
+	}
+	return out
+}
+
+// geminiSchema copies a JSON schema, dropping the keywords the Gemini
+// function declaration schema doesn't accept.
+func geminiSchema(v interface{}) map[string]interface{} {
+	schema, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	out := make(map[string]interface{}, len(schema))
+	for k, val := range schema {
+		switch k {
+		case "$schema", "additionalProperties":
+			continue
+		case "properties":
+			if props, ok := val.(map[string]interface{}); ok {
+				converted := make(map[string]interface{}, len(props))
+				for name, prop := range props {
+					converted[name] = geminiSchema(prop)
+				}
+				val = converted
+			}
+		case "items":
+			val = geminiSchema(val)
+		}
+		out[k] = val
+	}
+	return out
+}
+
+func parseGeminiResponse(resp *geminiResponse) (*LLMResponse, error) {
+	if len(resp.Candidates) == 0 {
+		if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+			return nil, fmt.Errorf("gemini blocked the prompt: %s", resp.PromptFeedback.BlockReason)
+		}
+		return &LLMResponse{Content: "", FinishReason: "stop"}, nil
+	}
+
+	candidate := resp.Candidates[0]
+	var content, reasoning strings.Builder
+	var toolCalls []ToolCall
+	for i, part := range candidate.Content.Parts {
+		if part.FunctionCall != nil {
+			id := part.FunctionCall.ID
+			if id == "" {
+				// Gemini pairs results with calls by name; the agent loop
+				// still needs an ID to match them.
+				id = fmt.Sprintf("call_%d_%d", time.Now().UnixNano(), i)
+			}
+			args := part.FunctionCall.Args
+			if args == nil {
+				args = map[string]interface{}{}
+			}
+			toolCalls = append(toolCalls, ToolCall{
+				ID:        id,
+				Name:      part.FunctionCall.Name,
+				Arguments: args,
+			})
+			continue
+		}
+		if part.Thought {
+			reasoning.WriteString(part.Text)
+			continue
+		}
+		content.WriteString(part.Text)
+	}
+
+	finishReason := "stop"
+	if len(toolCalls) > 0 {
+		finishReason = "tool_calls"
+	} else if candidate.FinishReason == "MAX_TOKENS" {
+		finishReason = "length"
+	}
+
+	var usage *UsageInfo
+	if resp.UsageMetadata != nil {
+		usage = &UsageInfo{
+			PromptTokens:     resp.UsageMetadata.PromptTokenCount,
+			CompletionTokens: resp.UsageMetadata.CandidatesTokenCount,
+			TotalTokens:      resp.UsageMetadata.TotalTokenCount,
+		}
+	}
+
+	return &LLMResponse{
+		Content:      content.String(),
+		Reasoning:    strings.TrimSpace(reasoning.String()),
+		ToolCalls:    toolCalls,
+		FinishReason: finishReason,
+		Usage:        usage,
+	}, nil
+}
+
+// ModelInfo reads the model's input token limit from models.get. Gemini
+// models all take tools and images, which the built-in table reflects.
+func (p *GeminiProvider) ModelInfo(ctx context.Context, model string) (*ModelInfo, error) {
+	model = geminiModelName(model)
+	if model == "" {
+		model = DefaultGeminiModel
+	}
+
+	endpoint := fmt.Sprintf("%s/models/%s", p.apiBase, url.PathEscape(model))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("x-goog-api-key", p.apiKey)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gemini returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		InputTokenLimit int `json:"inputTokenLimit"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	info := builtinModelInfo(model)
+	if result.InputTokenLimit > 0 {
+		info.ContextWindow = result.InputTokenLimit
+	}
+	return &info, nil
+}
@@ -0,0 +1,155 @@
+package providers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBuildGeminiRequest_ToolRoundTrip(t *testing.T) {
+	messages := []Message{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: "What's in the workspace?"},
+		{
+			Role: "assistant",
+			ToolCalls: []ToolCall{
+				{ID: "call_1", Type: "function", Function: &FunctionCall{Name: "list_dir", Arguments: `{"path":"."}`}},
+				{ID: "call_2", Type: "function", Function: &FunctionCall{Name: "read_file", Arguments: `{"path":"README.md"}`}},
+			},
+		},
+		{Role: "tool", Content: "memory/", ToolCallID: "call_1"},
+		{Role: "tool", Content: "# Hello", ToolCallID: "call_2"},
+	}
+
+	req := buildGeminiRequest(messages, nil, map[string]interface{}{"max_tokens": 512})
+
+	if req.SystemInstruction == nil || req.SystemInstruction.Parts[0].Text != "You are helpful." {
+		t.Fatalf("SystemInstruction = %+v", req.SystemInstruction)
+	}
+	if len(req.Contents) != 3 {
+		t.Fatalf("len(Contents) = %d, want 3 (user, model, merged tool results)", len(req.Contents))
+	}
+
+	model := req.Contents[1]
+	if model.Role != "model" || len(model.Parts) != 2 {
+		t.Fatalf("model turn = %+v", model)
+	}
+	if fc := model.Parts[0].FunctionCall; fc == nil || fc.Name != "list_dir" || fc.Args["path"] != "." {
+		t.Errorf("FunctionCall = %+v", model.Parts[0].FunctionCall)
+	}
+
+	results := req.Contents[2]
+	if results.Role != "user" || len(results.Parts) != 2 {
+		t.Fatalf("tool results turn = %+v", results)
+	}
+	if fr := results.Parts[1].FunctionResponse; fr == nil || fr.Name != "read_file" || fr.Response["content"] != "# Hello" {
+		t.Errorf("FunctionResponse = %+v", results.Parts[1].FunctionResponse)
+	}
+
+	if req.GenerationConfig == nil || req.GenerationConfig.MaxOutputTokens != 512 {
+		t.Errorf("GenerationConfig = %+v", req.GenerationConfig)
+	}
+}
+
+func TestGeminiProvider_ChatWithTools(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-2.5-flash:generateContent" {
+			http.Error(w, "not found: "+r.URL.Path, http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("x-goog-api-key") != "test-key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req geminiRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(req.Tools) != 1 || req.Tools[0].FunctionDeclarations[0].Name != "exec" {
+			http.Error(w, "tools not forwarded", http.StatusBadRequest)
+			return
+		}
+		if _, ok := req.Tools[0].FunctionDeclarations[0].Parameters["additionalProperties"]; ok {
+			http.Error(w, "unsupported schema keyword", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"candidates": []map[string]interface{}{
+				{
+					"content": map[string]interface{}{
+						"role": "model",
+						"parts": []map[string]interface{}{
+							{"functionCall": map[string]interface{}{"name": "exec", "args": map[string]interface{}{"command": "uptime"}}},
+						},
+					},
+					"finishReason": "STOP",
+				},
+			},
+			"usageMetadata": map[string]interface{}{
+				"promptTokenCount":     20,
+				"candidatesTokenCount": 5,
+				"totalTokenCount":      25,
+			},
+		})
+	}))
+	defer server.Close()
+
+	provider := NewGeminiProvider("test-key", server.URL, "")
+
+	tools := []ToolDefinition{
+		{
+			Type: "function",
+			Function: ToolFunctionDefinition{
+				Name:        "exec",
+				Description: "Run a shell command",
+				Parameters: map[string]interface{}{
+					"type":                 "object",
+					"additionalProperties": false,
+					"properties": map[string]interface{}{
+						"command": map[string]interface{}{"type": "string"},
+					},
+				},
+			},
+		},
+	}
+
+	resp, err := provider.Chat(t.Context(), []Message{{Role: "user", Content: "uptime?"}}, tools, "google/gemini-2.5-flash", nil)
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if resp.FinishReason != "tool_calls" {
+		t.Errorf("FinishReason = %q, want %q", resp.FinishReason, "tool_calls")
+	}
+	if len(resp.ToolCalls) != 1 {
+		t.Fatalf("len(ToolCalls) = %d, want 1", len(resp.ToolCalls))
+	}
+	tc := resp.ToolCalls[0]
+	if tc.ID == "" {
+		t.Error("expected generated tool call ID")
+	}
+	if tc.Name != "exec" || tc.Arguments["command"] != "uptime" {
+		t.Errorf("ToolCall = %+v", tc)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 25 {
+		t.Errorf("Usage = %+v, want 25 total tokens", resp.Usage)
+	}
+}
+
+func TestGeminiProvider_ErrorKinds(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"code":429,"status":"RESOURCE_EXHAUSTED"}}`, http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	provider := NewGeminiProvider("test-key", server.URL, "")
+	_, err := provider.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "gemini-2.5-flash", nil)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("error = %v, want ErrRateLimited", err)
+	}
+}
@@ -7,6 +7,7 @@
 package providers
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -19,6 +20,7 @@ import (
 
 	"github.com/sipeed/picoclaw/pkg/auth"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/egress"
 )
 
 type HTTPProvider struct {
@@ -28,19 +30,20 @@ type HTTPProvider struct {
 }
 
 func NewHTTPProvider(apiKey, apiBase, proxy string) *HTTPProvider {
-	client := &http.Client{
-		Timeout: 120 * time.Second,
-	}
+	transport := egress.NewTransport("provider")
 
 	if proxy != "" {
 		proxyURL, err := url.Parse(proxy)
 		if err == nil {
-			client.Transport = &http.Transport{
-				Proxy: http.ProxyURL(proxyURL),
-			}
+			transport.Proxy = egress.Proxy(http.ProxyURL(proxyURL))
 		}
 	}
 
+	client := &http.Client{
+		Timeout:   120 * time.Second,
+		Transport: egress.Audit("provider", transport),
+	}
+
 	return &HTTPProvider{
 		apiKey:     apiKey,
 		apiBase:    strings.TrimRight(apiBase, "/"),
@@ -49,21 +52,168 @@ func NewHTTPProvider(apiKey, apiBase, proxy string) *HTTPProvider {
 }
 
 func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
-	if p.apiBase == "" {
-		return nil, fmt.Errorf("API base not configured")
+	req, err := p.newChatRequest(ctx, messages, tools, model, options, false)
+	if err != nil {
+		return nil, err
 	}
 
-	// Strip provider prefix from model name (e.g., moonshot/kimi-k2.5 -> kimi-k2.5, groq/openai/gpt-oss-120b -> openai/gpt-oss-120b, ollama/qwen2.5:14b -> qwen2.5:14b)
-	if idx := strings.Index(model, "/"); idx != -1 {
-		prefix := model[:idx]
-		if prefix == "moonshot" || prefix == "nvidia" || prefix == "groq" || prefix == "ollama" {
-			model = model[idx+1:]
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, newTransportError(ctx, fmt.Errorf("failed to send request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newStatusError(fmt.Errorf("API request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(body)),
+			resp.StatusCode, string(body), resp.Header)
+	}
+
+	return p.parseResponse(body)
+}
+
+// ChatStream sends the request with "stream": true and relays the
+// server-sent events of the OpenAI chat completions API as StreamEvents.
+func (p *HTTPProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	req, err := p.newChatRequest(ctx, messages, tools, model, options, true)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, newTransportError(ctx, fmt.Errorf("failed to send request: %w", err))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, newStatusError(fmt.Errorf("API request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(body)),
+			resp.StatusCode, string(body), resp.Header)
+	}
+
+	events := make(chan StreamEvent, 16)
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+
+		acc := newStreamAccumulator()
+		var finishReason string
+		var usage *UsageInfo
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if data == "[DONE]" {
+				break
+			}
+
+			var chunk struct {
+				Choices []struct {
+					Delta struct {
+						Content          string `json:"content"`
+						ReasoningContent string `json:"reasoning_content"` // DeepSeek, vLLM
+						Reasoning        string `json:"reasoning"`         // OpenRouter
+						ToolCalls        []struct {
+							Index    int    `json:"index"`
+							ID       string `json:"id"`
+							Function struct {
+								Name      string `json:"name"`
+								Arguments string `json:"arguments"`
+							} `json:"function"`
+						} `json:"tool_calls"`
+					} `json:"delta"`
+					FinishReason string `json:"finish_reason"`
+				} `json:"choices"`
+				Usage *UsageInfo `json:"usage"`
+			}
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				sendStreamEvent(ctx, events, StreamEvent{Err: fmt.Errorf("failed to decode stream chunk: %w", err)})
+				return
+			}
+
+			if chunk.Usage != nil {
+				usage = chunk.Usage
+			}
+			if len(chunk.Choices) == 0 {
+				continue
+			}
+
+			choice := chunk.Choices[0]
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+			if r := choice.Delta.ReasoningContent + choice.Delta.Reasoning; r != "" {
+				acc.addReasoning(r)
+				if !sendStreamEvent(ctx, events, StreamEvent{Reasoning: r}) {
+					return
+				}
+			}
+			if choice.Delta.Content != "" {
+				content, reasoning := acc.addContent(choice.Delta.Content)
+				if content != "" || reasoning != "" {
+					if !sendStreamEvent(ctx, events, StreamEvent{Content: content, Reasoning: reasoning}) {
+						return
+					}
+				}
+			}
+			for _, tc := range choice.Delta.ToolCalls {
+				delta := ToolCallDelta{
+					Index:     tc.Index,
+					ID:        tc.ID,
+					Name:      tc.Function.Name,
+					Arguments: tc.Function.Arguments,
+				}
+				acc.addToolCall(delta)
+				if !sendStreamEvent(ctx, events, StreamEvent{ToolCall: &delta}) {
+					return
+				}
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			sendStreamEvent(ctx, events, StreamEvent{Err: fmt.Errorf("failed to read stream: %w", err)})
+			return
 		}
+
+		if content, reasoning := acc.flushContent(); content != "" || reasoning != "" {
+			if !sendStreamEvent(ctx, events, StreamEvent{Content: content, Reasoning: reasoning}) {
+				return
+			}
+		}
+		sendStreamEvent(ctx, events, StreamEvent{Done: true, Response: acc.response(finishReason, usage)})
+	}()
+
+	return events, nil
+}
+
+// newChatRequest builds the POST /chat/completions request shared by Chat and ChatStream.
+func (p *HTTPProvider) newChatRequest(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, stream bool) (*http.Request, error) {
+	if p.apiBase == "" {
+		return nil, fmt.Errorf("API base not configured")
 	}
 
+	model = openAIModelName(model)
+
 	requestBody := map[string]interface{}{
 		"model":    model,
-		"messages": messages,
+		"messages": toOpenAIMessages(messages),
+	}
+
+	if stream {
+		requestBody["stream"] = true
+		// Without this the token counts are dropped from streamed responses.
+		requestBody["stream_options"] = map[string]interface{}{"include_usage": true}
 	}
 
 	if len(tools) > 0 {
@@ -105,30 +255,55 @@ func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []Too
 		req.Header.Set("Authorization", "Bearer "+p.apiKey)
 	}
 
-	resp, err := p.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
+	return req, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
+// openAIMessage is the wire form of a chat message. Content is either a
+// string or, for multimodal messages, a list of typed parts.
+type openAIMessage struct {
+	Role       string      `json:"role"`
+	Content    interface{} `json:"content"`
+	ToolCalls  []ToolCall  `json:"tool_calls,omitempty"`
+	ToolCallID string      `json:"tool_call_id,omitempty"`
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(body))
+func toOpenAIMessages(messages []Message) []openAIMessage {
+	out := make([]openAIMessage, 0, len(messages))
+	for _, msg := range messages {
+		om := openAIMessage{
+			Role:       msg.Role,
+			Content:    msg.Content,
+			ToolCalls:  msg.ToolCalls,
+			ToolCallID: msg.ToolCallID,
+		}
+		if len(msg.Parts) > 0 {
+			parts := make([]map[string]interface{}, 0, len(msg.Parts))
+			for _, part := range msg.Parts {
+				switch part.Type {
+				case "text":
+					parts = append(parts, map[string]interface{}{"type": "text", "text": part.Text})
+				case "image":
+					parts = append(parts, map[string]interface{}{
+						"type":      "image_url",
+						"image_url": map[string]interface{}{"url": part.DataURL()},
+					})
+				}
+			}
+			om.Content = parts
+		}
+		out = append(out, om)
 	}
-
-	return p.parseResponse(body)
+	return out
 }
 
 func (p *HTTPProvider) parseResponse(body []byte) (*LLMResponse, error) {
 	var apiResponse struct {
 		Choices []struct {
 			Message struct {
-				Content   string `json:"content"`
-				ToolCalls []struct {
+				Content          string `json:"content"`
+				ReasoningContent string `json:"reasoning_content"` // DeepSeek, vLLM
+				Reasoning        string `json:"reasoning"`         // OpenRouter
+				ToolCalls        []struct {
 					ID       string `json:"id"`
 					Type     string `json:"type"`
 					Function *struct {
@@ -185,11 +360,14 @@ func (p *HTTPProvider) parseResponse(body []byte) (*LLMResponse, error) {
 		})
 	}
 
+	content, inlineReasoning := SplitThinking(choice.Message.Content)
+
 	return &LLMResponse{
-		Content:      choice.Message.Content,
+		Content:      content,
 		ToolCalls:    toolCalls,
 		FinishReason: choice.FinishReason,
 		Usage:        apiResponse.Usage,
+		Reasoning:    joinReasoning(choice.Message.ReasoningContent, choice.Message.Reasoning, inlineReasoning),
 	}, nil
 }
 
@@ -197,6 +375,97 @@ func (p *HTTPProvider) GetDefaultModel() string {
 	return ""
 }
 
+// openAIModelName strips the routing prefix from a model name (e.g.,
+// moonshot/kimi-k2.5 -> kimi-k2.5, groq/openai/gpt-oss-120b ->
+// openai/gpt-oss-120b, ollama/qwen2.5:14b -> qwen2.5:14b).
+func openAIModelName(model string) string {
+	if idx := strings.Index(model, "/"); idx != -1 {
+		prefix := model[:idx]
+		if prefix == "moonshot" || prefix == "nvidia" || prefix == "groq" || prefix == "ollama" {
+			return model[idx+1:]
+		}
+	}
+	return model
+}
+
+// openAIModelEntry is one item of a /models listing. Besides the standard
+// id, backends add their own metadata: OpenRouter sends context_length,
+// architecture and supported_parameters, vLLM max_model_len and Groq
+// context_window.
+type openAIModelEntry struct {
+	ID            string `json:"id"`
+	ContextLength int    `json:"context_length"`
+	MaxModelLen   int    `json:"max_model_len"`
+	ContextWindow int    `json:"context_window"`
+	Architecture  *struct {
+		InputModalities []string `json:"input_modalities"`
+	} `json:"architecture"`
+	SupportedParameters []string `json:"supported_parameters"`
+}
+
+// ModelInfo looks the model up in the backend's /models listing. Fields the
+// listing doesn't carry come from the built-in table.
+func (p *HTTPProvider) ModelInfo(ctx context.Context, model string) (*ModelInfo, error) {
+	model = openAIModelName(model)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.apiBase+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("model listing returned status %d", resp.StatusCode)
+	}
+
+	var listing struct {
+		Data []openAIModelEntry `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&listing); err != nil {
+		return nil, fmt.Errorf("failed to decode model listing: %w", err)
+	}
+
+	info := builtinModelInfo(model)
+	for _, m := range listing.Data {
+		if m.ID != model {
+			continue
+		}
+		for _, n := range []int{m.ContextLength, m.MaxModelLen, m.ContextWindow} {
+			if n > 0 {
+				info.ContextWindow = n
+				break
+			}
+		}
+		if m.Architecture != nil && len(m.Architecture.InputModalities) > 0 {
+			info.Vision = false
+			for _, modality := range m.Architecture.InputModalities {
+				if modality == "image" {
+					info.Vision = true
+				}
+			}
+		}
+		if len(m.SupportedParameters) > 0 {
+			info.Tools = false
+			for _, param := range m.SupportedParameters {
+				if param == "tools" {
+					info.Tools = true
+				}
+			}
+		}
+		break
+	}
+
+	return &info, nil
+}
+
 func createClaudeAuthProvider() (LLMProvider, error) {
 	cred, err := auth.GetCredential("anthropic")
 	if err != nil {
@@ -219,14 +488,95 @@ func createCodexAuthProvider() (LLMProvider, error) {
 	return NewCodexProviderWithTokenSource(cred.AccessToken, cred.AccountID, createCodexTokenSource()), nil
 }
 
+// CreateProvider builds the provider configured in agents.defaults. When
+// fallbacks are listed, the result is a FallbackProvider that tries the
+// primary first and then each fallback in order. A cassette in record mode
+// wraps the result in a RecordingProvider; in replay mode no backend is
+// created at all.
 func CreateProvider(cfg *config.Config) (LLMProvider, error) {
-	model := cfg.Agents.Defaults.Model
-	providerName := strings.ToLower(cfg.Agents.Defaults.Provider)
+	switch cfg.Cassette.Mode {
+	case "":
+		return createConfiguredProvider(cfg)
+	case "replay":
+		// No backend is needed, nor any credentials for one
+		return NewReplayProvider(cfg.CassettePath())
+	case "record":
+		p, err := createConfiguredProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewRecordingProvider(p, cfg.CassettePath()), nil
+	}
+	return nil, fmt.Errorf("unknown cassette mode %q (want record or replay)", cfg.Cassette.Mode)
+}
+
+func createConfiguredProvider(cfg *config.Config) (LLMProvider, error) {
+	redactor, err := NewRedactor(cfg.Privacy.RedactPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	primary, section, err := createProviderFor(cfg, cfg.Agents.Defaults.Provider, cfg.Agents.Defaults.Model)
+	if err != nil {
+		return nil, err
+	}
+	primary = withRedaction(section, primary, redactor)
+	if len(cfg.Agents.Defaults.Fallbacks) == 0 {
+		return primary, nil
+	}
+
+	primaryName := cfg.Agents.Defaults.Provider
+	if primaryName == "" {
+		primaryName = "primary"
+	}
+	backends := []FallbackBackend{{Name: primaryName, Provider: primary}}
+
+	for _, fb := range cfg.Agents.Defaults.Fallbacks {
+		model := fb.Model
+		if model == "" {
+			model = cfg.Agents.Defaults.Model
+		}
+		p, section, err := createProviderFor(cfg, fb.Provider, model)
+		if err != nil {
+			return nil, fmt.Errorf("fallback provider %q: %w", fb.Provider, err)
+		}
+		p = withRedaction(section, p, redactor)
+		backends = append(backends, FallbackBackend{
+			Name:     fb.Provider,
+			Provider: p,
+			Model:    fb.Model,
+			Cooldown: time.Duration(fb.CooldownSeconds) * time.Second,
+		})
+	}
+
+	return NewFallbackProvider(backends), nil
+}
+
+// runsExternally reports whether the provider hands requests to a CLI or
+// sidecar that talks to its cloud service itself.
+func runsExternally(providerName string) bool {
+	switch providerName {
+	case "claude-cli", "claudecode", "claude-code", "codex-cli", "codex-code", "github_copilot", "copilot":
+		return true
+	}
+	return false
+}
+
+func createProviderFor(cfg *config.Config, providerName, model string) (LLMProvider, *config.ProviderConfig, error) {
+	providerName = strings.ToLower(providerName)
 
 	var apiKey, apiBase, proxy string
+	// The config section of the provider picked, which holds its redact
+	// setting
+	var section *config.ProviderConfig
 
 	lowerModel := strings.ToLower(model)
 
+	if cfg.Privacy.LocalOnly && (runsExternally(providerName) ||
+		(providerName == "openai" || providerName == "gpt") && cfg.Providers.OpenAI.AuthMethod == "codex-cli") {
+		return nil, nil, fmt.Errorf("provider %q can't be used with privacy.local_only: it reaches a cloud service through another program, whose connections aren't checked", providerName)
+	}
+
 	// First, try to use explicitly configured provider
 	if providerName != "" {
 		switch providerName {
@@ -241,10 +591,11 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 		case "openai", "gpt":
 			if cfg.Providers.OpenAI.APIKey != "" || cfg.Providers.OpenAI.AuthMethod != "" {
 				if cfg.Providers.OpenAI.AuthMethod == "codex-cli" {
-					return NewCodexProviderWithTokenSource("", "", CreateCodexCliTokenSource()), nil
+					return NewCodexProviderWithTokenSource("", "", CreateCodexCliTokenSource()), &cfg.Providers.OpenAI, nil
 				}
 				if cfg.Providers.OpenAI.AuthMethod == "oauth" || cfg.Providers.OpenAI.AuthMethod == "token" {
-					return createCodexAuthProvider()
+					p, err := createCodexAuthProvider()
+					return p, &cfg.Providers.OpenAI, err
 				}
 				apiKey = cfg.Providers.OpenAI.APIKey
 				apiBase = cfg.Providers.OpenAI.APIBase
@@ -255,12 +606,11 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 		case "anthropic", "claude":
 			if cfg.Providers.Anthropic.APIKey != "" || cfg.Providers.Anthropic.AuthMethod != "" {
 				if cfg.Providers.Anthropic.AuthMethod == "oauth" || cfg.Providers.Anthropic.AuthMethod == "token" {
-					return createClaudeAuthProvider()
+					p, err := createClaudeAuthProvider()
+					return p, &cfg.Providers.Anthropic, err
 				}
-				apiKey = cfg.Providers.Anthropic.APIKey
-				apiBase = cfg.Providers.Anthropic.APIBase
-				if apiBase == "" {
-					apiBase = "https://api.anthropic.com/v1"
+				if cfg.Providers.Anthropic.APIKey != "" {
+					return NewClaudeProviderWithAPIKey(cfg.Providers.Anthropic.APIKey, cfg.Providers.Anthropic.APIBase, cfg.Providers.Anthropic.Proxy), &cfg.Providers.Anthropic, nil
 				}
 			}
 		case "openrouter":
@@ -282,11 +632,7 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 			}
 		case "gemini", "google":
 			if cfg.Providers.Gemini.APIKey != "" {
-				apiKey = cfg.Providers.Gemini.APIKey
-				apiBase = cfg.Providers.Gemini.APIBase
-				if apiBase == "" {
-					apiBase = "https://generativelanguage.googleapis.com/v1beta"
-				}
+				return NewGeminiProvider(cfg.Providers.Gemini.APIKey, cfg.Providers.Gemini.APIBase, cfg.Providers.Gemini.Proxy), &cfg.Providers.Gemini, nil
 			}
 		case "vllm":
 			if cfg.Providers.VLLM.APIBase != "" {
@@ -301,18 +647,31 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 					apiBase = "https://router.shengsuanyun.com/api/v1"
 				}
 			}
+		case "ollama":
+			// Native /api/chat supports tool calling; an api_base configured
+			// for the OpenAI-compatible endpoint ends in /v1, so drop that.
+			apiBase = strings.TrimSuffix(strings.TrimRight(cfg.Providers.Ollama.APIBase, "/"), "/v1")
+			p, err := NewOllamaProvider(OllamaConfig{
+				BaseURL: apiBase,
+				APIKey:  cfg.Providers.Ollama.APIKey,
+				Proxy:   cfg.Providers.Ollama.Proxy,
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+			return p, &cfg.Providers.Ollama, nil
 		case "claude-cli", "claudecode", "claude-code":
 			workspace := cfg.WorkspacePath()
 			if workspace == "" {
 				workspace = "."
 			}
-			return NewClaudeCliProvider(workspace), nil
+			return NewClaudeCliProvider(workspace), nil, nil
 		case "codex-cli", "codex-code":
 			workspace := cfg.WorkspacePath()
 			if workspace == "" {
 				workspace = "."
 			}
-			return NewCodexCliProvider(workspace), nil
+			return NewCodexCliProvider(workspace), nil, nil
 		case "deepseek":
 			if cfg.Providers.DeepSeek.APIKey != "" {
 				apiKey = cfg.Providers.DeepSeek.APIKey
@@ -330,10 +689,16 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 			} else {
 				apiBase = "localhost:4321"
 			}
-			return NewGitHubCopilotProvider(apiBase, cfg.Providers.GitHubCopilot.ConnectMode, model)
+			p, err := NewGitHubCopilotProvider(apiBase, cfg.Providers.GitHubCopilot.ConnectMode, model)
+			if err != nil {
+				return nil, nil, err
+			}
+			return p, &cfg.Providers.GitHubCopilot, nil
 
 		}
-
+		if apiKey != "" || apiBase != "" {
+			section = providerConfigByName(cfg, providerName)
+		}
 	}
 
 	// Fallback: detect provider from model name
@@ -343,6 +708,7 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 			apiKey = cfg.Providers.Moonshot.APIKey
 			apiBase = cfg.Providers.Moonshot.APIBase
 			proxy = cfg.Providers.Moonshot.Proxy
+			section = &cfg.Providers.Moonshot
 			if apiBase == "" {
 				apiBase = "https://api.moonshot.cn/v1"
 			}
@@ -350,6 +716,7 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 		case strings.HasPrefix(model, "openrouter/") || strings.HasPrefix(model, "anthropic/") || strings.HasPrefix(model, "openai/") || strings.HasPrefix(model, "meta-llama/") || strings.HasPrefix(model, "deepseek/") || strings.HasPrefix(model, "google/"):
 			apiKey = cfg.Providers.OpenRouter.APIKey
 			proxy = cfg.Providers.OpenRouter.Proxy
+			section = &cfg.Providers.OpenRouter
 			if cfg.Providers.OpenRouter.APIBase != "" {
 				apiBase = cfg.Providers.OpenRouter.APIBase
 			} else {
@@ -358,38 +725,32 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 
 		case (strings.Contains(lowerModel, "claude") || strings.HasPrefix(model, "anthropic/")) && (cfg.Providers.Anthropic.APIKey != "" || cfg.Providers.Anthropic.AuthMethod != ""):
 			if cfg.Providers.Anthropic.AuthMethod == "oauth" || cfg.Providers.Anthropic.AuthMethod == "token" {
-				return createClaudeAuthProvider()
-			}
-			apiKey = cfg.Providers.Anthropic.APIKey
-			apiBase = cfg.Providers.Anthropic.APIBase
-			proxy = cfg.Providers.Anthropic.Proxy
-			if apiBase == "" {
-				apiBase = "https://api.anthropic.com/v1"
+				p, err := createClaudeAuthProvider()
+				return p, &cfg.Providers.Anthropic, err
 			}
+			return NewClaudeProviderWithAPIKey(cfg.Providers.Anthropic.APIKey, cfg.Providers.Anthropic.APIBase, cfg.Providers.Anthropic.Proxy), &cfg.Providers.Anthropic, nil
 
 		case (strings.Contains(lowerModel, "gpt") || strings.HasPrefix(model, "openai/")) && (cfg.Providers.OpenAI.APIKey != "" || cfg.Providers.OpenAI.AuthMethod != ""):
 			if cfg.Providers.OpenAI.AuthMethod == "oauth" || cfg.Providers.OpenAI.AuthMethod == "token" {
-				return createCodexAuthProvider()
+				p, err := createCodexAuthProvider()
+				return p, &cfg.Providers.OpenAI, err
 			}
 			apiKey = cfg.Providers.OpenAI.APIKey
 			apiBase = cfg.Providers.OpenAI.APIBase
 			proxy = cfg.Providers.OpenAI.Proxy
+			section = &cfg.Providers.OpenAI
 			if apiBase == "" {
 				apiBase = "https://api.openai.com/v1"
 			}
 
 		case (strings.Contains(lowerModel, "gemini") || strings.HasPrefix(model, "google/")) && cfg.Providers.Gemini.APIKey != "":
-			apiKey = cfg.Providers.Gemini.APIKey
-			apiBase = cfg.Providers.Gemini.APIBase
-			proxy = cfg.Providers.Gemini.Proxy
-			if apiBase == "" {
-				apiBase = "https://generativelanguage.googleapis.com/v1beta"
-			}
+			return NewGeminiProvider(cfg.Providers.Gemini.APIKey, cfg.Providers.Gemini.APIBase, cfg.Providers.Gemini.Proxy), &cfg.Providers.Gemini, nil
 
 		case (strings.Contains(lowerModel, "glm") || strings.Contains(lowerModel, "zhipu") || strings.Contains(lowerModel, "zai")) && cfg.Providers.Zhipu.APIKey != "":
 			apiKey = cfg.Providers.Zhipu.APIKey
 			apiBase = cfg.Providers.Zhipu.APIBase
 			proxy = cfg.Providers.Zhipu.Proxy
+			section = &cfg.Providers.Zhipu
 			if apiBase == "" {
 				apiBase = "https://open.bigmodel.cn/api/paas/v4"
 			}
@@ -398,6 +759,7 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 			apiKey = cfg.Providers.Groq.APIKey
 			apiBase = cfg.Providers.Groq.APIBase
 			proxy = cfg.Providers.Groq.Proxy
+			section = &cfg.Providers.Groq
 			if apiBase == "" {
 				apiBase = "https://api.groq.com/openai/v1"
 			}
@@ -406,6 +768,7 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 			apiKey = cfg.Providers.Nvidia.APIKey
 			apiBase = cfg.Providers.Nvidia.APIBase
 			proxy = cfg.Providers.Nvidia.Proxy
+			section = &cfg.Providers.Nvidia
 			if apiBase == "" {
 				apiBase = "https://integrate.api.nvidia.com/v1"
 			}
@@ -414,6 +777,7 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 			apiKey = cfg.Providers.Ollama.APIKey
 			apiBase = cfg.Providers.Ollama.APIBase
 			proxy = cfg.Providers.Ollama.Proxy
+			section = &cfg.Providers.Ollama
 			if apiBase == "" {
 				apiBase = "http://localhost:11434/v1"
 			}
@@ -422,29 +786,93 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 			apiKey = cfg.Providers.VLLM.APIKey
 			apiBase = cfg.Providers.VLLM.APIBase
 			proxy = cfg.Providers.VLLM.Proxy
+			section = &cfg.Providers.VLLM
 
 		default:
 			if cfg.Providers.OpenRouter.APIKey != "" {
 				apiKey = cfg.Providers.OpenRouter.APIKey
 				proxy = cfg.Providers.OpenRouter.Proxy
+				section = &cfg.Providers.OpenRouter
 				if cfg.Providers.OpenRouter.APIBase != "" {
 					apiBase = cfg.Providers.OpenRouter.APIBase
 				} else {
 					apiBase = "https://openrouter.ai/api/v1"
 				}
 			} else {
-				return nil, fmt.Errorf("no API key configured for model: %s", model)
+				return nil, nil, fmt.Errorf("no API key configured for model: %s", model)
 			}
 		}
 	}
 
 	if apiKey == "" && !strings.HasPrefix(model, "bedrock/") {
-		return nil, fmt.Errorf("no API key configured for provider (model: %s)", model)
+		return nil, nil, fmt.Errorf("no API key configured for provider (model: %s)", model)
 	}
 
 	if apiBase == "" {
-		return nil, fmt.Errorf("no API base configured for provider (model: %s)", model)
+		return nil, nil, fmt.Errorf("no API base configured for provider (model: %s)", model)
+	}
+
+	return NewHTTPProvider(apiKey, apiBase, proxy), section, nil
+}
+
+// Embed implements EmbeddingProvider via the OpenAI-compatible /embeddings
+// endpoint.
+func (p *HTTPProvider) Embed(ctx context.Context, texts []string, model string) ([][]float32, error) {
+	model = openAIModelName(model)
+	if model == "" {
+		model = DefaultOpenAIEmbeddingModel
 	}
 
-	return NewHTTPProvider(apiKey, apiBase, proxy), nil
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"model": model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.apiBase+"/embeddings", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, newTransportError(ctx, fmt.Errorf("failed to send request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, newTransportError(ctx, fmt.Errorf("failed to read response: %w", err))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newStatusError(fmt.Errorf("API request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(body)),
+			resp.StatusCode, string(body), resp.Header)
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	// Entries carry their input position; don't rely on response order
+	vectors := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
 }
@@ -0,0 +1,79 @@
+package providers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPProvider_ChatStream(t *testing.T) {
+	chunks := []string{
+		`{"choices":[{"delta":{"content":"Checking"}}]}`,
+		`{"choices":[{"delta":{"content":" now"}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"web_fetch","arguments":""}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"url\":"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"https://example.com\"}"}}]}}]}`,
+		`{"choices":[{"delta":{},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":12,"completion_tokens":7,"total_tokens":19}}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, c := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	provider := NewHTTPProvider("test-key", server.URL, "")
+	events, err := provider.ChatStream(t.Context(), []Message{{Role: "user", Content: "fetch it"}}, nil, "test-model", nil)
+	if err != nil {
+		t.Fatalf("ChatStream() error: %v", err)
+	}
+
+	var content string
+	var fragments int
+	var final *LLMResponse
+	for ev := range events {
+		if ev.Err != nil {
+			t.Fatalf("stream error: %v", ev.Err)
+		}
+		content += ev.Content
+		if ev.ToolCall != nil {
+			fragments++
+		}
+		if ev.Done {
+			final = ev.Response
+		}
+	}
+
+	if content != "Checking now" {
+		t.Errorf("streamed content = %q, want %q", content, "Checking now")
+	}
+	if fragments != 3 {
+		t.Errorf("tool call fragments = %d, want 3", fragments)
+	}
+	if final == nil {
+		t.Fatal("expected a final Done event")
+	}
+	if final.FinishReason != "tool_calls" {
+		t.Errorf("FinishReason = %q, want %q", final.FinishReason, "tool_calls")
+	}
+	if len(final.ToolCalls) != 1 {
+		t.Fatalf("len(ToolCalls) = %d, want 1", len(final.ToolCalls))
+	}
+	if final.ToolCalls[0].ID != "call_1" || final.ToolCalls[0].Name != "web_fetch" {
+		t.Errorf("ToolCall = %+v, want call_1/web_fetch", final.ToolCalls[0])
+	}
+	if final.ToolCalls[0].Arguments["url"] != "https://example.com" {
+		t.Errorf("Arguments[url] = %v, want %q", final.ToolCalls[0].Arguments["url"], "https://example.com")
+	}
+	if final.Usage == nil || final.Usage.TotalTokens != 19 {
+		t.Errorf("Usage = %+v, want total 19", final.Usage)
+	}
+}
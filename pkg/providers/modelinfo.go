@@ -0,0 +1,138 @@
+package providers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// DefaultContextWindow is assumed for models that neither the backend nor
+// the built-in table know about.
+const DefaultContextWindow = 32768
+
+// ModelInfo describes what a model can do.
+type ModelInfo struct {
+	ContextWindow int  // Prompt + completion tokens the model accepts
+	Tools         bool // Supports function calling
+	Vision        bool // Accepts image content parts
+
+	// Guessed is set when the backend couldn't be asked, e.g. because the
+	// server is still starting, and the values come from the built-in table.
+	Guessed bool `json:"-"`
+}
+
+// ModelInfoProvider is implemented by providers that can ask their backend
+// about a model, e.g. Ollama's /api/show or an OpenAI-compatible /models
+// listing.
+type ModelInfoProvider interface {
+	ModelInfo(ctx context.Context, model string) (*ModelInfo, error)
+}
+
+// GetModelInfo returns the capabilities of model as served by p. The
+// backend is asked first when it supports that, then the built-in table is
+// consulted, and unknown models get DefaultContextWindow with tools enabled
+// and vision disabled.
+func GetModelInfo(ctx context.Context, p LLMProvider, model string) ModelInfo {
+	if mp, ok := p.(ModelInfoProvider); ok {
+		info, err := mp.ModelInfo(ctx, model)
+		if err == nil && info != nil {
+			if info.ContextWindow <= 0 {
+				info.ContextWindow = DefaultContextWindow
+			}
+			return *info
+		}
+		if err != nil {
+			logger.WarnCF("provider", "Model info query failed, using built-in table",
+				map[string]interface{}{
+					"model": model,
+					"error": err.Error(),
+				})
+		}
+		guess := builtinModelInfo(model)
+		guess.Guessed = true
+		return guess
+	}
+
+	return builtinModelInfo(model)
+}
+
+// builtinModelInfo looks model up in the table, falling back to the
+// defaults for unknown models.
+func builtinModelInfo(model string) ModelInfo {
+	if info, ok := LookupModelInfo(model); ok {
+		return info
+	}
+	return ModelInfo{ContextWindow: DefaultContextWindow, Tools: true}
+}
+
+// knownModels maps model name prefixes to their capabilities. The longest
+// matching prefix wins, so "qwen2.5vl" is looked up before "qwen2.5".
+var knownModels = map[string]ModelInfo{
+	// Anthropic
+	"claude": {ContextWindow: 200000, Tools: true, Vision: true},
+
+	// OpenAI
+	"gpt-5":         {ContextWindow: 400000, Tools: true, Vision: true},
+	"gpt-4.1":       {ContextWindow: 1047576, Tools: true, Vision: true},
+	"gpt-4o":        {ContextWindow: 128000, Tools: true, Vision: true},
+	"gpt-4-turbo":   {ContextWindow: 128000, Tools: true, Vision: true},
+	"gpt-4":         {ContextWindow: 8192, Tools: true},
+	"gpt-3.5-turbo": {ContextWindow: 16385, Tools: true},
+	"o1":            {ContextWindow: 200000, Tools: true, Vision: true},
+	"o3":            {ContextWindow: 200000, Tools: true, Vision: true},
+	"o4-mini":       {ContextWindow: 200000, Tools: true, Vision: true},
+
+	// Google
+	"gemini":         {ContextWindow: 1048576, Tools: true, Vision: true},
+	"gemini-1.5-pro": {ContextWindow: 2097152, Tools: true, Vision: true},
+	"gemma3":         {ContextWindow: 131072, Vision: true},
+
+	// DeepSeek
+	"deepseek-chat":     {ContextWindow: 128000, Tools: true},
+	"deepseek-reasoner": {ContextWindow: 128000, Tools: true},
+	"deepseek-r1":       {ContextWindow: 131072},
+
+	// Zhipu
+	"glm-4":  {ContextWindow: 128000, Tools: true},
+	"glm-4v": {ContextWindow: 8192, Vision: true},
+
+	// Moonshot
+	"moonshot-v1-8k":   {ContextWindow: 8192, Tools: true},
+	"moonshot-v1-32k":  {ContextWindow: 32768, Tools: true},
+	"moonshot-v1-128k": {ContextWindow: 131072, Tools: true},
+	"kimi":             {ContextWindow: 131072, Tools: true},
+
+	// Open-weight models, as named by Ollama and vLLM
+	"llama3":          {ContextWindow: 8192},
+	"llama3.1":        {ContextWindow: 131072, Tools: true},
+	"llama3.2":        {ContextWindow: 131072, Tools: true},
+	"llama3.2-vision": {ContextWindow: 131072, Vision: true},
+	"llama3.3":        {ContextWindow: 131072, Tools: true},
+	"qwen2.5":         {ContextWindow: 32768, Tools: true},
+	"qwen2.5vl":       {ContextWindow: 128000, Vision: true},
+	"qwen3":           {ContextWindow: 40960, Tools: true},
+	"mistral":         {ContextWindow: 32768, Tools: true},
+	"llava":           {ContextWindow: 4096, Vision: true},
+	"phi4":            {ContextWindow: 16384},
+}
+
+// LookupModelInfo finds model in the built-in capability table. Routing
+// prefixes such as "openrouter/" or "ollama/" are ignored.
+func LookupModelInfo(model string) (ModelInfo, bool) {
+	name := strings.ToLower(model)
+	if idx := strings.LastIndex(name, "/"); idx != -1 {
+		name = name[idx+1:]
+	}
+
+	var best string
+	for prefix := range knownModels {
+		if strings.HasPrefix(name, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return ModelInfo{}, false
+	}
+	return knownModels[best], true
+}
@@ -0,0 +1,134 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLookupModelInfo(t *testing.T) {
+	tests := []struct {
+		model     string
+		wantCtx   int
+		wantTools bool
+		wantOK    bool
+	}{
+		{"claude-sonnet-4-5-20250929", 200000, true, true},
+		{"openrouter/openai/gpt-4o-mini", 128000, true, true},
+		{"ollama/qwen2.5:7b", 32768, true, true},
+		{"qwen2.5vl:7b", 128000, false, true},
+		{"my-finetune", 0, false, false},
+	}
+	for _, tt := range tests {
+		info, ok := LookupModelInfo(tt.model)
+		if ok != tt.wantOK || info.ContextWindow != tt.wantCtx || info.Tools != tt.wantTools {
+			t.Errorf("LookupModelInfo(%q) = %+v, %v; want ctx %d tools %v ok %v",
+				tt.model, info, ok, tt.wantCtx, tt.wantTools, tt.wantOK)
+		}
+	}
+}
+
+func TestGetModelInfo_UnknownModelDefaults(t *testing.T) {
+	info := GetModelInfo(context.Background(), &scriptedProvider{}, "my-finetune")
+	if info.ContextWindow != DefaultContextWindow || !info.Tools || info.Vision {
+		t.Errorf("GetModelInfo() = %+v, want defaults", info)
+	}
+}
+
+func TestOllamaProvider_ModelInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/show" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["model"] != "gemma3:4b" {
+			http.Error(w, "unexpected model", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"parameters":   "stop \"<end_of_turn>\"\nnum_ctx 8192",
+			"model_info":   map[string]interface{}{"gemma3.context_length": 131072},
+			"capabilities": []string{"completion", "vision"},
+		})
+	}))
+	defer server.Close()
+
+	provider, _ := CreateOllamaProvider(server.URL)
+	info := GetModelInfo(t.Context(), provider, "ollama/gemma3:4b")
+	if info.ContextWindow != 8192 {
+		t.Errorf("ContextWindow = %d, want num_ctx 8192", info.ContextWindow)
+	}
+	if info.Tools || !info.Vision {
+		t.Errorf("capabilities = %+v, want vision only", info)
+	}
+}
+
+func TestOllamaProvider_ModelInfoDefaultNumCtx(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"model_info":   map[string]interface{}{"llama.context_length": 131072},
+			"capabilities": []string{"completion", "tools"},
+		})
+	}))
+	defer server.Close()
+
+	provider, _ := CreateOllamaProvider(server.URL)
+	info := GetModelInfo(t.Context(), provider, "llama3.2")
+	if info.ContextWindow != ollamaDefaultNumCtx {
+		t.Errorf("ContextWindow = %d, want the %d Ollama loads the model with", info.ContextWindow, ollamaDefaultNumCtx)
+	}
+	if info.Guessed {
+		t.Error("an answered query should not be marked as guessed")
+	}
+
+	server.Close()
+	if info := GetModelInfo(t.Context(), provider, "llama3.2"); !info.Guessed {
+		t.Error("expected the built-in values to be marked as guessed when the server is down")
+	}
+}
+
+func TestHTTPProvider_ModelInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]interface{}{
+				{"id": "other/model", "context_length": 1000},
+				{
+					"id":                   "meta-llama/llama-3.3-70b-instruct",
+					"context_length":       65536,
+					"architecture":         map[string]interface{}{"input_modalities": []string{"text"}},
+					"supported_parameters": []string{"temperature", "tools"},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	provider := NewHTTPProvider("key", server.URL, "")
+	info := GetModelInfo(t.Context(), provider, "meta-llama/llama-3.3-70b-instruct")
+	if info.ContextWindow != 65536 || !info.Tools || info.Vision {
+		t.Errorf("GetModelInfo() = %+v, want 65536 ctx with tools and no vision", info)
+	}
+}
+
+func TestFallbackProvider_ModelInfo(t *testing.T) {
+	p := NewFallbackProvider([]FallbackBackend{
+		{Name: "cloud", Provider: &scriptedProvider{}, Model: "gpt-4o"},
+		{Name: "local", Provider: &scriptedProvider{}, Model: "qwen2.5:7b"},
+	})
+
+	info := GetModelInfo(context.Background(), p, "")
+	if info.ContextWindow != 32768 {
+		t.Errorf("ContextWindow = %d, want the smallest backend window", info.ContextWindow)
+	}
+	if !info.Tools || info.Vision {
+		t.Errorf("capabilities = %+v, want tools only since qwen2.5 has no vision", info)
+	}
+}
@@ -11,12 +11,21 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/egress"
 )
 
 const (
 	DefaultOllamaBaseURL = "http://localhost:11434"
 	DefaultOllamaModel   = "llama3.2"
+
+	// ollamaDefaultNumCtx is the context Ollama loads a model with when
+	// neither the request nor the Modelfile sets num_ctx.
+	ollamaDefaultNumCtx = 4096
 )
 
 // OllamaConfig holds the Ollama provider configuration
@@ -24,6 +33,10 @@ type OllamaConfig struct {
 	BaseURL string
 	Model   string
 	Timeout time.Duration
+	// APIKey and Proxy are for an Ollama behind a reverse proxy that wants
+	// a bearer token, or reached through an HTTP proxy.
+	APIKey string
+	Proxy  string
 }
 
 // OllamaProvider implements LLMProvider for Ollama
@@ -34,109 +47,398 @@ type OllamaProvider struct {
 
 // OllamaMessage represents a message in Ollama format
 type OllamaMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []OllamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"` // Set on role "tool" results
+	Images    []string         `json:"images,omitempty"`    // Base64-encoded, for vision models
+	Thinking  string           `json:"thinking,omitempty"`  // Reasoning of thinking models, response only
+}
+
+// OllamaToolCall represents a tool call in Ollama format.
+// Unlike OpenAI, Ollama sends arguments as a JSON object rather than a string.
+type OllamaToolCall struct {
+	ID       string                 `json:"id,omitempty"`
+	Function OllamaToolCallFunction `json:"function"`
+}
+
+// OllamaToolCallFunction holds the name and arguments of an Ollama tool call
+type OllamaToolCallFunction struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
 }
 
 // OllamaRequest represents a chat request to Ollama
 type OllamaRequest struct {
-	Model    string          `json:"model"`
-	Messages []OllamaMessage `json:"messages"`
-	Stream   bool            `json:"stream"`
+	Model    string                 `json:"model"`
+	Messages []OllamaMessage        `json:"messages"`
+	Tools    []ToolDefinition       `json:"tools,omitempty"`
+	Stream   bool                   `json:"stream"`
+	Options  map[string]interface{} `json:"options,omitempty"`
 }
 
 // OllamaResponse represents a response from Ollama
 type OllamaResponse struct {
-	Model     string       `json:"model"`
-	CreatedAt time.Time    `json:"created_at"`
-	Message   OllamaMessage `json:"message"`
-	Done      bool         `json:"done"`
+	Model      string        `json:"model"`
+	CreatedAt  time.Time     `json:"created_at"`
+	Message    OllamaMessage `json:"message"`
+	Done       bool          `json:"done"`
+	DoneReason string        `json:"done_reason,omitempty"`
+
+	// Token counts, present on the final (done) response
+	PromptEvalCount int `json:"prompt_eval_count,omitempty"`
+	EvalCount       int `json:"eval_count,omitempty"`
 }
 
 // CreateOllamaProvider creates a new Ollama provider
 func CreateOllamaProvider(baseURL string) (LLMProvider, error) {
-	config := OllamaConfig{
-		BaseURL: baseURL,
-		Model:   DefaultOllamaModel,
-		Timeout: 120 * time.Second,
-	}
-	
+	return NewOllamaProvider(OllamaConfig{BaseURL: baseURL})
+}
+
+// NewOllamaProvider creates an Ollama provider from config, filling in the
+// defaults for the fields left empty.
+func NewOllamaProvider(config OllamaConfig) (*OllamaProvider, error) {
 	if config.BaseURL == "" {
 		config.BaseURL = DefaultOllamaBaseURL
 	}
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	if config.Model == "" {
+		config.Model = DefaultOllamaModel
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 120 * time.Second
+	}
+
+	transport := egress.NewTransport("provider")
+	if config.Proxy != "" {
+		proxyURL, err := url.Parse(config.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ollama proxy %q: %w", config.Proxy, err)
+		}
+		transport.Proxy = egress.Proxy(http.ProxyURL(proxyURL))
+	}
 
 	return &OllamaProvider{
 		config: config,
 		httpClient: &http.Client{
-			Timeout: config.Timeout,
+			Timeout:   config.Timeout,
+			Transport: egress.Audit("provider", transport),
 		},
 	}, nil
 }
 
+// newRequest builds a request to the Ollama API at path.
+func (p *OllamaProvider) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.config.BaseURL+path, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if p.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+	}
+	return req, nil
+}
+
 // Chat completes a chat conversation with Ollama
 func (p *OllamaProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
 	if len(messages) == 0 {
 		return nil, fmt.Errorf("no messages provided")
 	}
 
-	// Convert to Ollama format
-	ollamaMessages := make([]OllamaMessage, len(messages))
-	for i, msg := range messages {
-		ollamaMessages[i] = OllamaMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		}
+	// Use provided model or default, dropping the "ollama/" routing prefix
+	model = strings.TrimPrefix(model, "ollama/")
+	if model == "" {
+		model = p.config.Model
+	}
+
+	resp, err := p.postChat(ctx, OllamaRequest{
+		Model:    model,
+		Messages: toOllamaMessages(messages),
+		Tools:    tools,
+		Stream:   false,
+		Options:  ollamaOptions(options),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var ollamaResp OllamaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return parseOllamaResponse(&ollamaResp), nil
+}
+
+// ChatStream streams a chat response from Ollama. With "stream": true the
+// server replies with newline-delimited JSON objects, one per token batch;
+// tool calls arrive whole in a single chunk.
+func (p *OllamaProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no messages provided")
 	}
 
-	// Use provided model or default
+	model = strings.TrimPrefix(model, "ollama/")
 	if model == "" {
 		model = p.config.Model
 	}
 
-	ollamaReq := OllamaRequest{
+	resp, err := p.postChat(ctx, OllamaRequest{
 		Model:    model,
-		Messages: ollamaMessages,
-		Stream:   false,
+		Messages: toOllamaMessages(messages),
+		Tools:    tools,
+		Stream:   true,
+		Options:  ollamaOptions(options),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan StreamEvent, 16)
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+
+		// Accumulate into a synthetic non-streaming response so the final
+		// event goes through the same parsing as Chat.
+		var final OllamaResponse
+		var content, thinking strings.Builder
+		var think thinkFilter
+		toolIndex := 0
+
+		decoder := json.NewDecoder(resp.Body)
+		for {
+			var chunk OllamaResponse
+			if err := decoder.Decode(&chunk); err != nil {
+				if err == io.EOF {
+					break
+				}
+				sendStreamEvent(ctx, events, StreamEvent{Err: fmt.Errorf("decode error: %w", err)})
+				return
+			}
+
+			if chunk.Message.Thinking != "" {
+				thinking.WriteString(chunk.Message.Thinking)
+				if !sendStreamEvent(ctx, events, StreamEvent{Reasoning: chunk.Message.Thinking}) {
+					return
+				}
+			}
+			if chunk.Message.Content != "" {
+				content.WriteString(chunk.Message.Content)
+				visible, reasoning := think.push(chunk.Message.Content)
+				if visible != "" || reasoning != "" {
+					if !sendStreamEvent(ctx, events, StreamEvent{Content: visible, Reasoning: reasoning}) {
+						return
+					}
+				}
+			}
+
+			for _, tc := range chunk.Message.ToolCalls {
+				final.Message.ToolCalls = append(final.Message.ToolCalls, tc)
+				args, _ := json.Marshal(tc.Function.Arguments)
+				delta := ToolCallDelta{
+					Index:     toolIndex,
+					ID:        tc.ID,
+					Name:      tc.Function.Name,
+					Arguments: string(args),
+				}
+				toolIndex++
+				if !sendStreamEvent(ctx, events, StreamEvent{ToolCall: &delta}) {
+					return
+				}
+			}
+
+			if chunk.Done {
+				final.Model = chunk.Model
+				final.CreatedAt = chunk.CreatedAt
+				final.Done = true
+				final.DoneReason = chunk.DoneReason
+				final.PromptEvalCount = chunk.PromptEvalCount
+				final.EvalCount = chunk.EvalCount
+				break
+			}
+		}
+
+		if visible, reasoning := think.flush(); visible != "" || reasoning != "" {
+			if !sendStreamEvent(ctx, events, StreamEvent{Content: visible, Reasoning: reasoning}) {
+				return
+			}
+		}
+
+		final.Message.Role = "assistant"
+		final.Message.Content = content.String()
+		final.Message.Thinking = thinking.String()
+		sendStreamEvent(ctx, events, StreamEvent{Done: true, Response: parseOllamaResponse(&final)})
+	}()
+
+	return events, nil
+}
+
+// ollamaOptions maps the request options onto Ollama's model options:
+// max_tokens to num_predict and context_window to num_ctx.
+func ollamaOptions(options map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	if n, ok := options["max_tokens"].(int); ok && n > 0 {
+		out["num_predict"] = n
+	}
+	if t, ok := options["temperature"].(float64); ok {
+		out["temperature"] = t
+	}
+	if n, ok := options["context_window"].(int); ok && n > 0 {
+		out["num_ctx"] = n
+	}
+	if len(out) == 0 {
+		return nil
 	}
+	return out
+}
 
+// postChat sends a request to /api/chat and returns the response once the
+// status has been checked. The caller owns the body.
+func (p *OllamaProvider) postChat(ctx context.Context, ollamaReq OllamaRequest) (*http.Response, error) {
 	reqBody, err := json.Marshal(ollamaReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/api/chat", p.config.BaseURL)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(reqBody))
+	req, err := p.newRequest(ctx, http.MethodPost, "/api/chat", reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, newTransportError(ctx, fmt.Errorf("failed to send request: %w", err))
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("ollama returned status %d: %s", resp.StatusCode, string(body))
+		resp.Body.Close()
+		return nil, newStatusError(fmt.Errorf("ollama returned status %d: %s", resp.StatusCode, string(body)),
+			resp.StatusCode, string(body), resp.Header)
 	}
 
-	var ollamaResp OllamaResponse
-	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	return resp, nil
+}
+
+// toOllamaMessages converts provider messages to Ollama's chat format.
+// Assistant tool calls carry their arguments as objects, and tool results
+// are matched back to the originating call by name since Ollama has no
+// notion of tool call IDs.
+func toOllamaMessages(messages []Message) []OllamaMessage {
+	toolNames := make(map[string]string)
+	out := make([]OllamaMessage, 0, len(messages))
+
+	for _, msg := range messages {
+		om := OllamaMessage{
+			Role:    msg.Role,
+			Content: msg.Content,
+		}
+
+		for _, part := range msg.Parts {
+			if part.Type == "image" {
+				om.Images = append(om.Images, part.Base64())
+			}
+		}
+
+		switch msg.Role {
+		case "assistant":
+			for _, tc := range msg.ToolCalls {
+				name, args := toolCallNameAndArgs(tc)
+				if tc.ID != "" {
+					toolNames[tc.ID] = name
+				}
+				om.ToolCalls = append(om.ToolCalls, OllamaToolCall{
+					ID: tc.ID,
+					Function: OllamaToolCallFunction{
+						Name:      name,
+						Arguments: args,
+					},
+				})
+			}
+		case "tool":
+			om.ToolName = toolNames[msg.ToolCallID]
+		}
+
+		out = append(out, om)
 	}
 
+	return out
+}
+
+// toolCallNameAndArgs returns the name and decoded arguments of a tool call,
+// accepting both the flat form produced by providers and the OpenAI form
+// (nested function with string arguments) stored in session history.
+func toolCallNameAndArgs(tc ToolCall) (string, map[string]interface{}) {
+	name := tc.Name
+	args := tc.Arguments
+
+	if tc.Function != nil {
+		if name == "" {
+			name = tc.Function.Name
+		}
+		if args == nil && tc.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+				args = map[string]interface{}{"raw": tc.Function.Arguments}
+			}
+		}
+	}
+
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	return name, args
+}
+
+// parseOllamaResponse converts a non-streaming Ollama chat response.
+func parseOllamaResponse(resp *OllamaResponse) *LLMResponse {
+	toolCalls := make([]ToolCall, 0, len(resp.Message.ToolCalls))
+	for i, tc := range resp.Message.ToolCalls {
+		id := tc.ID
+		if id == "" {
+			// Older Ollama releases don't assign IDs; the agent loop needs one
+			// to pair the tool result with its call.
+			id = fmt.Sprintf("call_%d_%d", resp.CreatedAt.UnixNano(), i)
+		}
+		args := tc.Function.Arguments
+		if args == nil {
+			args = map[string]interface{}{}
+		}
+		toolCalls = append(toolCalls, ToolCall{
+			ID:        id,
+			Name:      tc.Function.Name,
+			Arguments: args,
+		})
+	}
+
+	finishReason := "stop"
+	if len(toolCalls) > 0 {
+		finishReason = "tool_calls"
+	} else if resp.DoneReason == "length" {
+		finishReason = "length"
+	}
+
+	content, inlineReasoning := SplitThinking(resp.Message.Content)
+
 	return &LLMResponse{
-		Content:      ollamaResp.Message.Content,
-		FinishReason: "stop",
+		Content:      content,
+		Reasoning:    joinReasoning(resp.Message.Thinking, inlineReasoning),
+		ToolCalls:    toolCalls,
+		FinishReason: finishReason,
 		Usage: &UsageInfo{
-			PromptTokens:     0, // Ollama doesn't provide token counts
-			CompletionTokens: 0,
-			TotalTokens:      0,
+			PromptTokens:     resp.PromptEvalCount,
+			CompletionTokens: resp.EvalCount,
+			TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
 		},
-	}, nil
+	}
 }
 
 // GetDefaultModel returns the default model for Ollama
@@ -146,8 +448,7 @@ func (p *OllamaProvider) GetDefaultModel() string {
 
 // ListModels returns available models from Ollama
 func (p *OllamaProvider) ListModels(ctx context.Context) ([]string, error) {
-	url := fmt.Sprintf("%s/api/tags", p.config.BaseURL)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	req, err := p.newRequest(ctx, http.MethodGet, "/api/tags", nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -179,3 +480,121 @@ func (p *OllamaProvider) ListModels(ctx context.Context) ([]string, error) {
 
 	return models, nil
 }
+
+// ModelInfo asks /api/show for the model's context length and capabilities.
+// The context window reported is the one the server loads the model with:
+// the Modelfile's num_ctx, or else Ollama's default, capped at the
+// architecture's maximum. Releases that predate the capabilities list fall
+// back to the built-in table for tool and vision support.
+func (p *OllamaProvider) ModelInfo(ctx context.Context, model string) (*ModelInfo, error) {
+	model = strings.TrimPrefix(model, "ollama/")
+	if model == "" {
+		model = p.config.Model
+	}
+
+	reqBody, err := json.Marshal(map[string]string{"model": model})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := p.newRequest(ctx, http.MethodPost, "/api/show", reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ollama returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Parameters   string                 `json:"parameters"`
+		ModelInfo    map[string]interface{} `json:"model_info"`
+		Capabilities []string               `json:"capabilities"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	info := builtinModelInfo(model)
+
+	info.ContextWindow = ollamaDefaultNumCtx
+	for key, v := range result.ModelInfo {
+		if strings.HasSuffix(key, ".context_length") {
+			if n, ok := v.(float64); ok && n > 0 && int(n) < info.ContextWindow {
+				info.ContextWindow = int(n)
+			}
+		}
+	}
+	for _, line := range strings.Split(result.Parameters, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "num_ctx" {
+			if n, err := strconv.Atoi(fields[1]); err == nil && n > 0 {
+				info.ContextWindow = n
+			}
+		}
+	}
+
+	if len(result.Capabilities) > 0 {
+		info.Tools = false
+		info.Vision = false
+		for _, c := range result.Capabilities {
+			switch c {
+			case "tools":
+				info.Tools = true
+			case "vision":
+				info.Vision = true
+			}
+		}
+	}
+
+	return &info, nil
+}
+
+// Embed implements EmbeddingProvider via /api/embed, which takes the whole
+// batch in one request.
+func (p *OllamaProvider) Embed(ctx context.Context, texts []string, model string) ([][]float32, error) {
+	model = strings.TrimPrefix(model, "ollama/")
+	if model == "" {
+		model = DefaultOllamaEmbeddingModel
+	}
+
+	reqBody, err := json.Marshal(map[string]interface{}{
+		"model": model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := p.newRequest(ctx, http.MethodPost, "/api/embed", reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, newTransportError(ctx, fmt.Errorf("failed to send request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newStatusError(fmt.Errorf("ollama returned status %d: %s", resp.StatusCode, string(body)),
+			resp.StatusCode, string(body), resp.Header)
+	}
+
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return result.Embeddings, nil
+}
@@ -0,0 +1,251 @@
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestToOllamaMessages_ToolRoundTrip(t *testing.T) {
+	messages := []Message{
+		{Role: "user", Content: "List the workspace"},
+		{
+			Role: "assistant",
+			ToolCalls: []ToolCall{
+				{
+					ID:   "call_1",
+					Type: "function",
+					Function: &FunctionCall{
+						Name:      "list_dir",
+						Arguments: `{"path":"."}`,
+					},
+				},
+			},
+		},
+		{Role: "tool", Content: "memory/\nskills/", ToolCallID: "call_1"},
+	}
+
+	got := toOllamaMessages(messages)
+	if len(got) != 3 {
+		t.Fatalf("len(messages) = %d, want 3", len(got))
+	}
+
+	assistant := got[1]
+	if len(assistant.ToolCalls) != 1 {
+		t.Fatalf("len(ToolCalls) = %d, want 1", len(assistant.ToolCalls))
+	}
+	if assistant.ToolCalls[0].Function.Name != "list_dir" {
+		t.Errorf("Function.Name = %q, want %q", assistant.ToolCalls[0].Function.Name, "list_dir")
+	}
+	if assistant.ToolCalls[0].Function.Arguments["path"] != "." {
+		t.Errorf("Function.Arguments[path] = %v, want %q", assistant.ToolCalls[0].Function.Arguments["path"], ".")
+	}
+
+	if got[2].ToolName != "list_dir" {
+		t.Errorf("ToolName = %q, want %q", got[2].ToolName, "list_dir")
+	}
+}
+
+func TestOllamaProvider_ChatWithTools(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+
+		var req OllamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Model != "qwen2.5:7b" {
+			http.Error(w, "unexpected model "+req.Model, http.StatusBadRequest)
+			return
+		}
+		if len(req.Tools) != 1 || req.Tools[0].Function.Name != "exec" {
+			http.Error(w, "tools not forwarded", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"model": req.Model,
+			"message": map[string]interface{}{
+				"role":    "assistant",
+				"content": "",
+				"tool_calls": []map[string]interface{}{
+					{
+						"function": map[string]interface{}{
+							"name":      "exec",
+							"arguments": map[string]interface{}{"command": "uptime"},
+						},
+					},
+				},
+			},
+			"done":        true,
+			"done_reason": "stop",
+		})
+	}))
+	defer server.Close()
+
+	provider, err := CreateOllamaProvider(server.URL)
+	if err != nil {
+		t.Fatalf("CreateOllamaProvider() error: %v", err)
+	}
+
+	tools := []ToolDefinition{
+		{
+			Type: "function",
+			Function: ToolFunctionDefinition{
+				Name:        "exec",
+				Description: "Run a shell command",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"command": map[string]interface{}{"type": "string"},
+					},
+				},
+			},
+		},
+	}
+
+	resp, err := provider.Chat(t.Context(), []Message{{Role: "user", Content: "uptime?"}}, tools, "ollama/qwen2.5:7b", nil)
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if resp.FinishReason != "tool_calls" {
+		t.Errorf("FinishReason = %q, want %q", resp.FinishReason, "tool_calls")
+	}
+	if len(resp.ToolCalls) != 1 {
+		t.Fatalf("len(ToolCalls) = %d, want 1", len(resp.ToolCalls))
+	}
+	tc := resp.ToolCalls[0]
+	if tc.ID == "" {
+		t.Error("expected generated tool call ID")
+	}
+	if tc.Name != "exec" {
+		t.Errorf("Name = %q, want %q", tc.Name, "exec")
+	}
+	if tc.Arguments["command"] != "uptime" {
+		t.Errorf("Arguments[command] = %v, want %q", tc.Arguments["command"], "uptime")
+	}
+}
+
+func TestOllamaProvider_ChatOptions(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OllamaRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		got = req.Options
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": map[string]interface{}{"role": "assistant", "content": "ok"},
+			"done":    true,
+		})
+	}))
+	defer server.Close()
+
+	provider, _ := CreateOllamaProvider(server.URL)
+	_, err := provider.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "llama3.2", map[string]interface{}{
+		"max_tokens":     1024,
+		"temperature":    0.3,
+		"context_window": 16384,
+	})
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if got["num_predict"] != float64(1024) || got["temperature"] != 0.3 || got["num_ctx"] != float64(16384) {
+		t.Errorf("options = %v, want num_predict 1024, temperature 0.3 and num_ctx 16384", got)
+	}
+}
+
+func TestOllamaProvider_ChatStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OllamaRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream {
+			http.Error(w, "expected stream", http.StatusBadRequest)
+			return
+		}
+
+		enc := json.NewEncoder(w)
+		enc.Encode(map[string]interface{}{"message": map[string]interface{}{"role": "assistant", "content": "Hel"}, "done": false})
+		enc.Encode(map[string]interface{}{"message": map[string]interface{}{"role": "assistant", "content": "lo"}, "done": false})
+		enc.Encode(map[string]interface{}{"message": map[string]interface{}{"role": "assistant", "content": ""}, "done": true, "done_reason": "stop"})
+	}))
+	defer server.Close()
+
+	provider, _ := CreateOllamaProvider(server.URL)
+	sp, ok := provider.(StreamingProvider)
+	if !ok {
+		t.Fatal("OllamaProvider should implement StreamingProvider")
+	}
+
+	events, err := sp.ChatStream(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "llama3.2", nil)
+	if err != nil {
+		t.Fatalf("ChatStream() error: %v", err)
+	}
+
+	var deltas []string
+	var final *LLMResponse
+	for ev := range events {
+		if ev.Err != nil {
+			t.Fatalf("stream error: %v", ev.Err)
+		}
+		if ev.Content != "" {
+			deltas = append(deltas, ev.Content)
+		}
+		if ev.Done {
+			final = ev.Response
+		}
+	}
+
+	// The start is held back in case it is reasoning whose <think> was in
+	// the prompt, so short answers arrive in one piece
+	if got := strings.Join(deltas, ""); got != "Hello" {
+		t.Errorf("streamed content = %q, want %q", got, "Hello")
+	}
+	if final == nil || final.Content != "Hello" {
+		t.Fatalf("final response = %+v, want content %q", final, "Hello")
+	}
+	if final.FinishReason != "stop" {
+		t.Errorf("FinishReason = %q, want %q", final.FinishReason, "stop")
+	}
+}
+
+func TestCreateProvider_OllamaAPIKeyAndProxy(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": map[string]interface{}{"role": "assistant", "content": "ok"},
+			"done":    true,
+		})
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Provider = "ollama"
+	cfg.Agents.Defaults.Model = "llama3.2"
+	cfg.Providers.Ollama.APIBase = server.URL + "/v1"
+	cfg.Providers.Ollama.APIKey = "behind-proxy"
+
+	p, err := CreateProvider(cfg)
+	if err != nil {
+		t.Fatalf("CreateProvider() error: %v", err)
+	}
+	if _, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "llama3.2", nil); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if auth != "Bearer behind-proxy" {
+		t.Errorf("Authorization = %q, want the configured key", auth)
+	}
+
+	cfg.Providers.Ollama.Proxy = "http://[::1"
+	if _, err := CreateProvider(cfg); err == nil {
+		t.Error("expected an invalid proxy to be reported")
+	}
+}
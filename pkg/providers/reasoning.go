@@ -0,0 +1,154 @@
+package providers
+
+import "strings"
+
+const (
+	thinkOpenTag  = "<think>"
+	thinkCloseTag = "</think>"
+)
+
+// SplitThinking separates the <think>...</think> blocks that reasoning
+// models such as qwen3 and deepseek-r1 emit inline from the answer. Some
+// chat templates open the block in the prompt, so a closing tag without an
+// opening one marks everything before it as reasoning. An unterminated
+// block is treated as reasoning to the end.
+func SplitThinking(content string) (answer, reasoning string) {
+	if !strings.Contains(content, thinkOpenTag) && !strings.Contains(content, thinkCloseTag) {
+		return content, ""
+	}
+
+	var ans, thought []string
+	rest := content
+
+	if open, end := strings.Index(rest, thinkOpenTag), strings.Index(rest, thinkCloseTag); end != -1 && (open == -1 || end < open) {
+		thought = append(thought, rest[:end])
+		rest = rest[end+len(thinkCloseTag):]
+	}
+
+	for {
+		open := strings.Index(rest, thinkOpenTag)
+		if open == -1 {
+			ans = append(ans, rest)
+			break
+		}
+		ans = append(ans, rest[:open])
+		rest = rest[open+len(thinkOpenTag):]
+
+		end := strings.Index(rest, thinkCloseTag)
+		if end == -1 {
+			thought = append(thought, rest)
+			break
+		}
+		thought = append(thought, rest[:end])
+		rest = rest[end+len(thinkCloseTag):]
+	}
+
+	return strings.TrimSpace(strings.Join(ans, "")), joinReasoning(thought...)
+}
+
+// joinReasoning concatenates non-empty reasoning fragments.
+func joinReasoning(parts ...string) string {
+	var kept []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, "\n\n")
+}
+
+// thinkHoldLimit is how much of the start of a stream thinkFilter holds
+// back while waiting for a closing tag whose opening one was in the prompt.
+const thinkHoldLimit = 2048
+
+// thinkFilter routes streamed content deltas to answer or reasoning as
+// <think> tags open and close. A tag may be split across deltas, so a
+// trailing fragment that could start one is held back until the next delta.
+// Like SplitThinking, it treats a closing tag that comes before any opening
+// one as the end of reasoning, so the start of the stream is held back until
+// the first tag or thinkHoldLimit bytes.
+type thinkFilter struct {
+	started     bool   // the start of the stream has been routed
+	head        string // start of the stream held back until started
+	inThink     bool
+	pending     string
+	trimLeading bool // drop whitespace between </think> and the answer
+}
+
+func (f *thinkFilter) push(delta string) (content, reasoning string) {
+	if !f.started {
+		f.head += delta
+		open, end := strings.Index(f.head, thinkOpenTag), strings.Index(f.head, thinkCloseTag)
+		switch {
+		case end != -1 && (open == -1 || end < open):
+			// The chat template opened the block in the prompt
+			f.inThink = true
+		case open == -1 && len(f.head) < thinkHoldLimit:
+			return "", ""
+		}
+		f.started = true
+		delta, f.head = f.head, ""
+	}
+
+	buf := f.pending + delta
+	f.pending = ""
+
+	var ans, thought strings.Builder
+	for buf != "" {
+		tag := thinkOpenTag
+		if f.inThink {
+			tag = thinkCloseTag
+		}
+
+		idx := strings.Index(buf, tag)
+		if idx == -1 {
+			keep := partialTagSuffix(buf, tag)
+			f.emit(&ans, &thought, buf[:len(buf)-keep])
+			f.pending = buf[len(buf)-keep:]
+			break
+		}
+
+		f.emit(&ans, &thought, buf[:idx])
+		buf = buf[idx+len(tag):]
+		f.inThink = !f.inThink
+		if !f.inThink {
+			f.trimLeading = true
+		}
+	}
+
+	return ans.String(), thought.String()
+}
+
+// flush returns whatever was held back once the stream has ended.
+func (f *thinkFilter) flush() (content, reasoning string) {
+	var ans, thought strings.Builder
+	f.emit(&ans, &thought, f.head+f.pending)
+	f.head, f.pending = "", ""
+	return ans.String(), thought.String()
+}
+
+func (f *thinkFilter) emit(ans, thought *strings.Builder, s string) {
+	if f.inThink {
+		thought.WriteString(s)
+		return
+	}
+	if f.trimLeading {
+		s = strings.TrimLeft(s, " \t\r\n")
+		if s == "" {
+			return
+		}
+		f.trimLeading = false
+	}
+	ans.WriteString(s)
+}
+
+// partialTagSuffix returns the length of the longest suffix of s that is a
+// proper prefix of tag.
+func partialTagSuffix(s, tag string) int {
+	for n := min(len(tag)-1, len(s)); n > 0; n-- {
+		if strings.HasSuffix(s, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,197 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSplitThinking(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		wantAnswer    string
+		wantReasoning string
+	}{
+		{"no tags", "  plain answer ", "  plain answer ", ""},
+		{"leading block", "<think>\nadd them up\n</think>\n\n4", "4", "add them up"},
+		{"opened by the template", "add them up</think>4", "4", "add them up"},
+		{"unterminated", "<think>still going", "", "still going"},
+		{"two blocks", "<think>a</think>one <think>b</think>two", "one two", "a\n\nb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answer, reasoning := SplitThinking(tt.content)
+			if answer != tt.wantAnswer || reasoning != tt.wantReasoning {
+				t.Errorf("SplitThinking(%q) = (%q, %q), want (%q, %q)",
+					tt.content, answer, reasoning, tt.wantAnswer, tt.wantReasoning)
+			}
+		})
+	}
+}
+
+func TestThinkFilter_TagsSplitAcrossDeltas(t *testing.T) {
+	var f thinkFilter
+	var content, reasoning string
+	for _, delta := range []string{"<th", "ink>plan", " it</thi", "nk>\n\nDone", " <", "b>"} {
+		c, r := f.push(delta)
+		content += c
+		reasoning += r
+	}
+	c, r := f.flush()
+	content += c
+	reasoning += r
+
+	if content != "Done <b>" {
+		t.Errorf("content = %q, want %q", content, "Done <b>")
+	}
+	if reasoning != "plan it" {
+		t.Errorf("reasoning = %q, want %q", reasoning, "plan it")
+	}
+}
+
+func TestThinkFilter_OpenedInPrompt(t *testing.T) {
+	tests := []struct {
+		name          string
+		deltas        []string
+		wantContent   string
+		wantReasoning string
+	}{
+		{"closing tag only", []string{"pondering", " more</th", "ink>Answer"}, "Answer", "pondering more"},
+		{"no tags", []string{"Just", " an answer"}, "Just an answer", ""},
+		{"long answer without tags", []string{strings.Repeat("a", thinkHoldLimit), "</think>b"}, strings.Repeat("a", thinkHoldLimit) + "</think>b", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f thinkFilter
+			var content, reasoning string
+			for _, delta := range tt.deltas {
+				c, r := f.push(delta)
+				content += c
+				reasoning += r
+			}
+			c, r := f.flush()
+			content += c
+			reasoning += r
+
+			if content != tt.wantContent || reasoning != tt.wantReasoning {
+				t.Errorf("content, reasoning = %q, %q; want %q, %q", content, reasoning, tt.wantContent, tt.wantReasoning)
+			}
+		})
+	}
+}
+
+func TestHTTPProvider_ChatStreamReasoning(t *testing.T) {
+	chunks := []string{
+		`{"choices":[{"delta":{"reasoning_content":"The user "}}]}`,
+		`{"choices":[{"delta":{"reasoning_content":"says hi."}}]}`,
+		`{"choices":[{"delta":{"content":"<think>and greets</think>"}}]}`,
+		`{"choices":[{"delta":{"content":"Hello!"}}]}`,
+		`{"choices":[{"delta":{},"finish_reason":"stop"}]}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, c := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	provider := NewHTTPProvider("test-key", server.URL, "")
+	events, err := provider.ChatStream(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "deepseek-reasoner", nil)
+	if err != nil {
+		t.Fatalf("ChatStream() error: %v", err)
+	}
+
+	var content, reasoning string
+	var final *LLMResponse
+	for ev := range events {
+		if ev.Err != nil {
+			t.Fatalf("stream error: %v", ev.Err)
+		}
+		content += ev.Content
+		reasoning += ev.Reasoning
+		if ev.Done {
+			final = ev.Response
+		}
+	}
+
+	if content != "Hello!" {
+		t.Errorf("streamed content = %q, want %q", content, "Hello!")
+	}
+	if reasoning != "The user says hi.and greets" {
+		t.Errorf("streamed reasoning = %q", reasoning)
+	}
+	if final == nil || final.Content != "Hello!" {
+		t.Fatalf("final response = %+v, want content %q", final, "Hello!")
+	}
+	if final.Reasoning != "The user says hi.\n\nand greets" {
+		t.Errorf("final Reasoning = %q", final.Reasoning)
+	}
+}
+
+func TestHTTPProvider_ChatReasoningContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{
+				"message": map[string]interface{}{
+					"role":              "assistant",
+					"content":           "42",
+					"reasoning_content": "Six times seven.",
+				},
+				"finish_reason": "stop",
+			}},
+		})
+	}))
+	defer server.Close()
+
+	provider := NewHTTPProvider("test-key", server.URL, "")
+	resp, err := provider.Chat(t.Context(), []Message{{Role: "user", Content: "6*7?"}}, nil, "deepseek-reasoner", nil)
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if resp.Content != "42" || resp.Reasoning != "Six times seven." {
+		t.Errorf("response = (%q, %q), want (%q, %q)", resp.Content, resp.Reasoning, "42", "Six times seven.")
+	}
+}
+
+func TestOllamaProvider_ChatStreamThinking(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enc := json.NewEncoder(w)
+		enc.Encode(map[string]interface{}{"message": map[string]interface{}{"role": "assistant", "content": "<think>short"}, "done": false})
+		enc.Encode(map[string]interface{}{"message": map[string]interface{}{"role": "assistant", "content": "</think>Sure."}, "done": false})
+		enc.Encode(map[string]interface{}{"message": map[string]interface{}{"role": "assistant", "content": ""}, "done": true, "done_reason": "stop"})
+	}))
+	defer server.Close()
+
+	provider, _ := CreateOllamaProvider(server.URL)
+	events, err := provider.(StreamingProvider).ChatStream(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "qwen3", nil)
+	if err != nil {
+		t.Fatalf("ChatStream() error: %v", err)
+	}
+
+	var content string
+	var final *LLMResponse
+	for ev := range events {
+		if ev.Err != nil {
+			t.Fatalf("stream error: %v", ev.Err)
+		}
+		content += ev.Content
+		if ev.Done {
+			final = ev.Response
+		}
+	}
+
+	if content != "Sure." {
+		t.Errorf("streamed content = %q, want %q", content, "Sure.")
+	}
+	if final == nil || final.Content != "Sure." || final.Reasoning != "short" {
+		t.Fatalf("final response = %+v, want content %q and reasoning %q", final, "Sure.", "short")
+	}
+}
@@ -0,0 +1,465 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// redactRule finds one kind of sensitive value. valid, when set, rejects
+// matches that only look the part, such as IBANs with a bad checksum.
+type redactRule struct {
+	label string
+	re    *regexp.Regexp
+	valid func(string) bool
+}
+
+var builtinRedactRules = []redactRule{
+	{
+		label: "API_KEY",
+		re: regexp.MustCompile(`\b(?:sk-[A-Za-z0-9_-]{20,}|gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,}|` +
+			`xox[abprs]-[A-Za-z0-9-]{10,}|AKIA[0-9A-Z]{16}|AIza[0-9A-Za-z_-]{35})`),
+	},
+	{
+		label: "EMAIL",
+		re:    regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+	},
+	{
+		label: "IBAN",
+		re:    regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`),
+		valid: validIBAN,
+	},
+	{
+		// International numbers need the leading +; without it only the
+		// North American (555) 123-4567 and 555-123-4567 forms are
+		// recognised, so dates and IDs are left alone.
+		label: "PHONE",
+		re:    regexp.MustCompile(`\+\d{1,3}[ .-]?(?:\(\d{1,4}\)[ .-]?)?\d{1,4}(?:[ .-]?\d{2,4}){1,4}|\(\d{3}\) ?\d{3}[ .-]\d{4}\b|\b\d{3}[.-]\d{3}[.-]\d{4}\b`),
+		valid: func(s string) bool {
+			n := countDigits(s)
+			return n >= 7 && n <= 15
+		},
+	},
+}
+
+func countDigits(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			n++
+		}
+	}
+	return n
+}
+
+// validIBAN checks the ISO 13616 mod-97 checksum.
+func validIBAN(s string) bool {
+	s = strings.ReplaceAll(s, " ", "")
+	if len(s) < 15 || len(s) > 34 {
+		return false
+	}
+	var digits strings.Builder
+	for _, r := range s[4:] + s[:4] {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			fmt.Fprintf(&digits, "%d", r-'A'+10)
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	return ok && n.Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+var placeholderPattern = regexp.MustCompile(`\[[A-Z_]+_\d+\]`)
+
+// maxPlaceholderLen bounds how much streamed text is held back while a
+// placeholder might still be arriving.
+const maxPlaceholderLen = 32
+
+// Redactor holds the rules that find sensitive values. The values are
+// swapped for placeholders by a Redaction, one per request, so a
+// placeholder never stands for a value from another request or chat.
+type Redactor struct {
+	rules []redactRule
+}
+
+// NewRedactor builds a Redactor from the built-in rules plus patterns,
+// extra regular expressions whose matches become [REDACTED_n].
+func NewRedactor(patterns []string) (*Redactor, error) {
+	rules := append([]redactRule(nil), builtinRedactRules...)
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", p, err)
+		}
+		rules = append(rules, redactRule{label: "REDACTED", re: re})
+	}
+	return &Redactor{rules: rules}, nil
+}
+
+// NewRedaction starts the redaction of one request.
+func (r *Redactor) NewRedaction() *Redaction {
+	return &Redaction{
+		rules:         r.rules,
+		byValue:       make(map[string]string),
+		byPlaceholder: make(map[string]string),
+		counts:        make(map[string]int),
+		taken:         make(map[string]bool),
+	}
+}
+
+// Redaction swaps sensitive values for placeholders such as [EMAIL_1] in a
+// request and back in its response. Within it a value keeps its
+// placeholder, and the whole conversation is redacted again for every
+// request, so the model sees the same token for the same address each
+// turn. Only placeholders it issued are restored.
+type Redaction struct {
+	rules []redactRule
+
+	mu            sync.Mutex
+	byValue       map[string]string
+	byPlaceholder map[string]string
+	counts        map[string]int
+	taken         map[string]bool // Placeholder-shaped text already in the request
+}
+
+// Reserve keeps the placeholder-shaped text in s, such as a user typing
+// "[EMAIL_1]", from being issued as a placeholder. The model's echo of it
+// is then left alone instead of being restored to a redacted value. Call
+// it on every text of the request before redacting any.
+func (x *Redaction) Reserve(s string) {
+	if !strings.Contains(s, "[") {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, ph := range placeholderPattern.FindAllString(s, -1) {
+		x.taken[ph] = true
+	}
+}
+
+// Redact replaces every sensitive value in s with its placeholder.
+func (x *Redaction) Redact(s string) string {
+	if s == "" {
+		return s
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	for _, rule := range x.rules {
+		s = outsidePlaceholders(s, func(text string) string {
+			return rule.re.ReplaceAllStringFunc(text, func(match string) string {
+				if rule.valid != nil && !rule.valid(match) {
+					return match
+				}
+				return x.placeholderLocked(rule.label, match)
+			})
+		})
+	}
+	return s
+}
+
+// outsidePlaceholders applies fn to the text between placeholders, so a
+// later rule can't match inside one an earlier rule produced.
+func outsidePlaceholders(s string, fn func(string) string) string {
+	locs := placeholderPattern.FindAllStringIndex(s, -1)
+	if len(locs) == 0 {
+		return fn(s)
+	}
+	var b strings.Builder
+	prev := 0
+	for _, loc := range locs {
+		b.WriteString(fn(s[prev:loc[0]]))
+		b.WriteString(s[loc[0]:loc[1]])
+		prev = loc[1]
+	}
+	b.WriteString(fn(s[prev:]))
+	return b.String()
+}
+
+func (x *Redaction) placeholderLocked(label, value string) string {
+	if ph, ok := x.byValue[value]; ok {
+		return ph
+	}
+	var ph string
+	for {
+		x.counts[label]++
+		ph = fmt.Sprintf("[%s_%d]", label, x.counts[label])
+		if !x.taken[ph] {
+			break
+		}
+	}
+	x.byValue[value] = ph
+	x.byPlaceholder[ph] = value
+	return ph
+}
+
+// Restore puts the original values back in place of the placeholders this
+// Redaction issued. Anything else that looks like a placeholder is left as
+// is.
+func (x *Redaction) Restore(s string) string {
+	if !strings.Contains(s, "[") {
+		return s
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	return placeholderPattern.ReplaceAllStringFunc(s, func(ph string) string {
+		if v, ok := x.byPlaceholder[ph]; ok {
+			return v
+		}
+		return ph
+	})
+}
+
+// rewriteValue walks decoded JSON, such as tool-call arguments, applying
+// fn to every string in it.
+func rewriteValue(v interface{}, fn func(string) string) interface{} {
+	switch t := v.(type) {
+	case string:
+		return fn(t)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			out[k] = rewriteValue(val, fn)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, val := range t {
+			out[i] = rewriteValue(val, fn)
+		}
+		return out
+	}
+	return v
+}
+
+func rewriteArguments(args map[string]interface{}, fn func(string) string) map[string]interface{} {
+	if args == nil {
+		return nil
+	}
+	return rewriteValue(args, fn).(map[string]interface{})
+}
+
+// RedactingProvider scrubs outgoing messages with a Redactor and restores
+// the placeholders in what comes back, so the provider never sees the
+// values while the agent and its tools work with the real ones.
+type RedactingProvider struct {
+	inner    LLMProvider
+	redactor *Redactor
+}
+
+func NewRedactingProvider(inner LLMProvider, redactor *Redactor) *RedactingProvider {
+	return &RedactingProvider{inner: inner, redactor: redactor}
+}
+
+func (p *RedactingProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	x := p.redactor.NewRedaction()
+	resp, err := p.inner.Chat(ctx, redactMessages(x, messages), tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+	return restoreResponse(x, resp), nil
+}
+
+func (p *RedactingProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	sp, ok := p.inner.(StreamingProvider)
+	if !ok {
+		resp, err := p.Chat(ctx, messages, tools, model, options)
+		if err != nil {
+			return nil, err
+		}
+		return responseAsStream(ctx, resp), nil
+	}
+
+	x := p.redactor.NewRedaction()
+	events, err := sp.ChatStream(ctx, redactMessages(x, messages), tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan StreamEvent, 16)
+	go func() {
+		defer close(out)
+		var content, reasoning placeholderBuffer
+		for ev := range events {
+			if ev.Done {
+				ev.Content = x.Restore(content.pending + ev.Content)
+				ev.Reasoning = x.Restore(reasoning.pending + ev.Reasoning)
+				if ev.Response != nil {
+					ev.Response = restoreResponse(x, ev.Response)
+				}
+			} else {
+				ev.Content = x.Restore(content.push(ev.Content))
+				ev.Reasoning = x.Restore(reasoning.push(ev.Reasoning))
+				if ev.ToolCall != nil {
+					tc := *ev.ToolCall
+					tc.Arguments = x.Restore(tc.Arguments)
+					ev.ToolCall = &tc
+				}
+				if ev.Content == "" && ev.Reasoning == "" && ev.ToolCall == nil && ev.Err == nil {
+					continue
+				}
+			}
+			if !sendStreamEvent(ctx, out, ev) {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (p *RedactingProvider) GetDefaultModel() string {
+	return p.inner.GetDefaultModel()
+}
+
+func (p *RedactingProvider) ModelInfo(ctx context.Context, model string) (*ModelInfo, error) {
+	info := GetModelInfo(ctx, p.inner, model)
+	return &info, nil
+}
+
+// redactMessages returns a redacted copy of messages. Placeholder-shaped
+// text already in them is reserved first, wherever it appears.
+func redactMessages(x *Redaction, messages []Message) []Message {
+	rewriteMessages(messages, func(s string) string {
+		x.Reserve(s)
+		return s
+	})
+	out := rewriteMessages(messages, x.Redact)
+
+	redacted := 0
+	for i := range out {
+		if out[i].Content != messages[i].Content {
+			redacted++
+		}
+	}
+
+	if redacted > 0 {
+		logger.DebugCF("provider", "Redacted sensitive values from prompt",
+			map[string]interface{}{
+				"messages": redacted,
+			})
+	}
+	return out
+}
+
+// rewriteMessages returns a copy of messages with fn applied to every text
+// in them, including tool-call arguments.
+func rewriteMessages(messages []Message, fn func(string) string) []Message {
+	out := make([]Message, len(messages))
+	for i, m := range messages {
+		m.Content = fn(m.Content)
+		if len(m.Parts) > 0 {
+			parts := make([]ContentPart, len(m.Parts))
+			for j, part := range m.Parts {
+				part.Text = fn(part.Text)
+				parts[j] = part
+			}
+			m.Parts = parts
+		}
+		if len(m.ToolCalls) > 0 {
+			calls := make([]ToolCall, len(m.ToolCalls))
+			for j, tc := range m.ToolCalls {
+				tc.Arguments = rewriteArguments(tc.Arguments, fn)
+				if tc.Function != nil {
+					f := *tc.Function
+					f.Arguments = fn(f.Arguments)
+					tc.Function = &f
+				}
+				calls[j] = tc
+			}
+			m.ToolCalls = calls
+		}
+		out[i] = m
+	}
+	return out
+}
+
+func restoreResponse(x *Redaction, resp *LLMResponse) *LLMResponse {
+	restored := *resp
+	restored.Content = x.Restore(resp.Content)
+	restored.Reasoning = x.Restore(resp.Reasoning)
+	if len(resp.ToolCalls) > 0 {
+		restored.ToolCalls = make([]ToolCall, len(resp.ToolCalls))
+		for i, tc := range resp.ToolCalls {
+			tc.Arguments = rewriteArguments(tc.Arguments, x.Restore)
+			if tc.Function != nil {
+				fn := *tc.Function
+				fn.Arguments = x.Restore(fn.Arguments)
+				tc.Function = &fn
+			}
+			restored.ToolCalls[i] = tc
+		}
+	}
+	return &restored
+}
+
+// placeholderBuffer holds back the tail of a streamed text while it could
+// be the start of a placeholder split across deltas.
+type placeholderBuffer struct {
+	pending string
+}
+
+func (b *placeholderBuffer) push(delta string) string {
+	buf := b.pending + delta
+	b.pending = ""
+
+	open := strings.LastIndex(buf, "[")
+	if open == -1 || strings.Contains(buf[open:], "]") || len(buf)-open >= maxPlaceholderLen {
+		return buf
+	}
+	b.pending = buf[open:]
+	return buf[:open]
+}
+
+// withRedaction wraps p when section, the config of the provider p was
+// made from (see createProviderFor), has redact set.
+func withRedaction(section *config.ProviderConfig, p LLMProvider, redactor *Redactor) LLMProvider {
+	if section == nil || !section.Redact {
+		return p
+	}
+	return NewRedactingProvider(p, redactor)
+}
+
+// providerConfigByName maps a provider name, including the aliases
+// createProviderFor accepts, to its config section.
+func providerConfigByName(cfg *config.Config, name string) *config.ProviderConfig {
+	switch strings.ToLower(name) {
+	case "anthropic", "claude":
+		return &cfg.Providers.Anthropic
+	case "openai", "gpt":
+		return &cfg.Providers.OpenAI
+	case "openrouter":
+		return &cfg.Providers.OpenRouter
+	case "groq":
+		return &cfg.Providers.Groq
+	case "zhipu", "glm":
+		return &cfg.Providers.Zhipu
+	case "vllm":
+		return &cfg.Providers.VLLM
+	case "gemini", "google":
+		return &cfg.Providers.Gemini
+	case "nvidia":
+		return &cfg.Providers.Nvidia
+	case "ollama":
+		return &cfg.Providers.Ollama
+	case "moonshot":
+		return &cfg.Providers.Moonshot
+	case "shengsuanyun":
+		return &cfg.Providers.ShengSuanYun
+	case "deepseek":
+		return &cfg.Providers.DeepSeek
+	case "github_copilot", "copilot":
+		return &cfg.Providers.GitHubCopilot
+	}
+	return nil
+}
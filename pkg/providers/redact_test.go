@@ -0,0 +1,271 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestRedactor_RedactAndRestore(t *testing.T) {
+	r, err := NewRedactor([]string{`ACME-\d{6}`})
+	if err != nil {
+		t.Fatalf("NewRedactor() error: %v", err)
+	}
+
+	input := "Mail jane.doe@example.com or call +49 30 1234 5678 / (555) 123-4567. " +
+		"Pay to DE89 3704 0044 0532 0130 00 with key sk-abcdefghijklmnopqrstuvwxyz123456, ticket ACME-123456. " +
+		"Again: jane.doe@example.com"
+	x := r.NewRedaction()
+	redacted := x.Redact(input)
+
+	for _, secret := range []string{"jane.doe@example.com", "+49 30 1234 5678", "(555) 123-4567",
+		"DE89 3704 0044 0532 0130 00", "sk-abcdefghij", "ACME-123456"} {
+		if strings.Contains(redacted, secret) {
+			t.Errorf("redacted text still contains %q: %s", secret, redacted)
+		}
+	}
+	if strings.Count(redacted, "[EMAIL_1]") != 2 {
+		t.Errorf("the same address should get the same placeholder: %s", redacted)
+	}
+	for _, ph := range []string{"[PHONE_1]", "[PHONE_2]", "[IBAN_1]", "[API_KEY_1]", "[REDACTED_1]"} {
+		if !strings.Contains(redacted, ph) {
+			t.Errorf("missing placeholder %s in %s", ph, redacted)
+		}
+	}
+
+	if got := x.Restore(redacted); got != input {
+		t.Errorf("Restore() = %q, want the original", got)
+	}
+	// Placeholders stay stable within a request
+	if got := x.Redact("jane.doe@example.com"); got != "[EMAIL_1]" {
+		t.Errorf("second Redact() = %q, want [EMAIL_1]", got)
+	}
+	// and mean nothing to another one
+	if got := r.NewRedaction().Restore("[EMAIL_1]"); got != "[EMAIL_1]" {
+		t.Errorf("Restore() in another request = %q, want the placeholder left alone", got)
+	}
+}
+
+func TestRedaction_ReservesTypedPlaceholders(t *testing.T) {
+	r, _ := NewRedactor(nil)
+	x := r.NewRedaction()
+
+	typed := "What does [EMAIL_1] mean?"
+	x.Reserve(typed)
+	redacted := x.Redact("Write to bob@example.org")
+	if redacted != "Write to [EMAIL_2]" {
+		t.Errorf("Redact() = %q, want the typed placeholder skipped", redacted)
+	}
+	if got := x.Restore("[EMAIL_1] is a placeholder; I wrote to [EMAIL_2]"); got != "[EMAIL_1] is a placeholder; I wrote to bob@example.org" {
+		t.Errorf("Restore() = %q, want only the issued placeholder restored", got)
+	}
+}
+
+func TestRedactor_LeavesLookalikesAlone(t *testing.T) {
+	r, _ := NewRedactor(nil)
+	x := r.NewRedaction()
+	for _, s := range []string{
+		"Meeting on 2024-01-15 at 10:30",
+		"Order 1234567890 shipped",
+		"DE00 3704 0044 0532 0130 00", // bad IBAN checksum
+		"See [NOTE_1] above",
+	} {
+		if got := x.Redact(s); got != s {
+			t.Errorf("Redact(%q) = %q, want unchanged", s, got)
+		}
+		if got := x.Restore(s); got != s {
+			t.Errorf("Restore(%q) = %q, want unchanged", s, got)
+		}
+	}
+}
+
+func TestNewRedactor_InvalidPattern(t *testing.T) {
+	if _, err := NewRedactor([]string{"("}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+// echoProvider records what it was sent and answers with a fixed response.
+type echoProvider struct {
+	seen []Message
+	resp *LLMResponse
+}
+
+func (p *echoProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	p.seen = messages
+	return p.resp, nil
+}
+
+func (p *echoProvider) GetDefaultModel() string {
+	return "echo"
+}
+
+func TestRedactingProvider_Chat(t *testing.T) {
+	r, _ := NewRedactor(nil)
+	inner := &echoProvider{resp: &LLMResponse{
+		Content: "Sending to [EMAIL_1] now.",
+		ToolCalls: []ToolCall{{
+			ID:        "call_1",
+			Name:      "message",
+			Arguments: map[string]interface{}{"to": []interface{}{"[EMAIL_1]"}, "note": "hi"},
+		}},
+	}}
+	p := NewRedactingProvider(inner, r)
+
+	messages := []Message{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: "Email bob@example.org please"},
+		{Role: "assistant", ToolCalls: []ToolCall{{
+			ID:       "call_0",
+			Type:     "function",
+			Function: &FunctionCall{Name: "lookup", Arguments: `{"q":"bob@example.org"}`},
+		}}},
+	}
+	resp, err := p.Chat(t.Context(), messages, nil, "model", nil)
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+
+	for _, m := range inner.seen {
+		if strings.Contains(m.Content, "bob@example.org") {
+			t.Errorf("provider saw the address in %s content: %q", m.Role, m.Content)
+		}
+		for _, tc := range m.ToolCalls {
+			if strings.Contains(tc.Function.Arguments, "bob@example.org") {
+				t.Errorf("provider saw the address in tool call arguments: %s", tc.Function.Arguments)
+			}
+		}
+	}
+	if messages[1].Content != "Email bob@example.org please" {
+		t.Error("caller's messages were modified")
+	}
+
+	if resp.Content != "Sending to bob@example.org now." {
+		t.Errorf("Content = %q, want the address restored", resp.Content)
+	}
+	to := resp.ToolCalls[0].Arguments["to"].([]interface{})
+	if to[0] != "bob@example.org" {
+		t.Errorf("tool call argument = %v, want the address restored", to[0])
+	}
+}
+
+func TestRedactingProvider_ChatTypedPlaceholder(t *testing.T) {
+	r, _ := NewRedactor(nil)
+	inner := &echoProvider{resp: &LLMResponse{Content: "[EMAIL_1] is not an address; [EMAIL_2] is."}}
+	p := NewRedactingProvider(inner, r)
+
+	// An earlier request issued [EMAIL_1] for another address
+	if _, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "alice@example.com"}}, nil, "model", nil); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+
+	resp, err := p.Chat(t.Context(), []Message{
+		{Role: "user", Content: "Mail bob@example.org"},
+		{Role: "user", Content: "What is [EMAIL_1]?"},
+	}, nil, "model", nil)
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if want := "[EMAIL_1] is not an address; bob@example.org is."; resp.Content != want {
+		t.Errorf("Content = %q, want %q", resp.Content, want)
+	}
+}
+
+func TestRedactingProvider_ChatStreamSplitPlaceholder(t *testing.T) {
+	r, _ := NewRedactor(nil)
+
+	inner := &streamingMock{events: []StreamEvent{
+		{Content: "Write to [EMA"},
+		{Content: "IL_1] today ["},
+		{Content: "or not]"},
+		{Done: true, Response: &LLMResponse{Content: "Write to [EMAIL_1] today [or not]"}},
+	}}
+	events, err := NewRedactingProvider(inner, r).ChatStream(t.Context(),
+		[]Message{{Role: "user", Content: "Write to bob@example.org"}}, nil, "model", nil)
+	if err != nil {
+		t.Fatalf("ChatStream() error: %v", err)
+	}
+
+	var content string
+	var final *LLMResponse
+	for ev := range events {
+		content += ev.Content
+		if ev.Done {
+			final = ev.Response
+		}
+	}
+	want := "Write to bob@example.org today [or not]"
+	if content != want {
+		t.Errorf("streamed content = %q, want %q", content, want)
+	}
+	if final == nil || final.Content != want {
+		t.Errorf("final response = %+v, want %q", final, want)
+	}
+}
+
+// streamingMock replays fixed stream events.
+type streamingMock struct {
+	events []StreamEvent
+}
+
+func (p *streamingMock) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	return p.events[len(p.events)-1].Response, nil
+}
+
+func (p *streamingMock) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error) {
+	ch := make(chan StreamEvent, len(p.events))
+	for _, ev := range p.events {
+		ch <- ev
+	}
+	close(ch)
+	return ch, nil
+}
+
+func (p *streamingMock) GetDefaultModel() string {
+	return "stream"
+}
+
+func TestCreateProvider_RedactPerProvider(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Provider = "openrouter"
+	cfg.Agents.Defaults.Model = "openai/gpt-4o"
+	cfg.Agents.Defaults.Fallbacks = []config.FallbackConfig{{Provider: "ollama", Model: "llama3.2"}}
+	cfg.Providers.OpenRouter.APIKey = "sk-or-test"
+	cfg.Providers.OpenRouter.Redact = true
+
+	p, err := CreateProvider(cfg)
+	if err != nil {
+		t.Fatalf("CreateProvider() error: %v", err)
+	}
+	fp, ok := p.(*FallbackProvider)
+	if !ok {
+		t.Fatalf("CreateProvider() = %T, want *FallbackProvider", p)
+	}
+	if _, ok := fp.backends[0].Provider.(*RedactingProvider); !ok {
+		t.Errorf("openrouter backend = %T, want *RedactingProvider", fp.backends[0].Provider)
+	}
+	if _, ok := fp.backends[1].Provider.(*OllamaProvider); !ok {
+		t.Errorf("ollama backend = %T, want it untouched", fp.backends[1].Provider)
+	}
+	if IsLocal(fp.backends[0].Provider) {
+		t.Error("a redacted cloud provider is still a cloud provider")
+	}
+}
+
+func TestCreateProvider_RedactProviderFromModel(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Provider = ""
+	cfg.Agents.Defaults.Model = "openai/gpt-4o"
+	cfg.Providers.OpenRouter.APIKey = "sk-or-test"
+	cfg.Providers.OpenRouter.Redact = true
+
+	p, err := CreateProvider(cfg)
+	if err != nil {
+		t.Fatalf("CreateProvider() error: %v", err)
+	}
+	if _, ok := p.(*RedactingProvider); !ok {
+		t.Errorf("provider picked from the model name = %T, want *RedactingProvider", p)
+	}
+}
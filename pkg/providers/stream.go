@@ -0,0 +1,143 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// StreamEvent is a single incremental update emitted by a StreamingProvider.
+// Content, Reasoning and ToolCall carry deltas; the last event on the channel
+// has either Done set (with the assembled Response) or Err set. Reasoning is
+// never part of Content, so Content can be shown to users as it arrives.
+type StreamEvent struct {
+	Content   string         `json:"content,omitempty"`
+	Reasoning string         `json:"reasoning,omitempty"`
+	ToolCall  *ToolCallDelta `json:"tool_call,omitempty"`
+	Done      bool           `json:"done,omitempty"`
+	Response  *LLMResponse   `json:"response,omitempty"`
+	Err       error          `json:"-"`
+}
+
+// ToolCallDelta is a fragment of a tool call being generated.
+// Fragments sharing an Index belong to the same call. ID and Name usually
+// arrive with the first fragment; Arguments is raw JSON text to concatenate.
+type ToolCallDelta struct {
+	Index     int    `json:"index"`
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+// StreamingProvider is implemented by providers that can deliver tokens as
+// they are generated. Callers should type-assert an LLMProvider and fall
+// back to Chat when streaming isn't supported.
+type StreamingProvider interface {
+	LLMProvider
+	ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (<-chan StreamEvent, error)
+}
+
+// sendStreamEvent delivers an event unless the caller has gone away.
+func sendStreamEvent(ctx context.Context, ch chan<- StreamEvent, ev StreamEvent) bool {
+	select {
+	case ch <- ev:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// streamAccumulator assembles content and tool call deltas into the final
+// LLMResponse delivered with the Done event.
+type streamAccumulator struct {
+	content   strings.Builder // raw, including any <think> blocks
+	reasoning strings.Builder // from a separate reasoning field
+	think     thinkFilter
+	toolCalls map[int]*streamToolCall
+}
+
+type streamToolCall struct {
+	id        string
+	name      string
+	arguments strings.Builder
+}
+
+func newStreamAccumulator() *streamAccumulator {
+	return &streamAccumulator{
+		toolCalls: make(map[int]*streamToolCall),
+	}
+}
+
+// addContent records a content delta and returns its visible and
+// reasoning parts, with inline <think> blocks moved to the latter.
+func (a *streamAccumulator) addContent(delta string) (content, reasoning string) {
+	a.content.WriteString(delta)
+	return a.think.push(delta)
+}
+
+// flushContent returns content held back by addContent while waiting to see
+// whether it starts a tag. Call it once the stream has ended.
+func (a *streamAccumulator) flushContent() (content, reasoning string) {
+	return a.think.flush()
+}
+
+func (a *streamAccumulator) addReasoning(delta string) {
+	a.reasoning.WriteString(delta)
+}
+
+func (a *streamAccumulator) addToolCall(delta ToolCallDelta) {
+	tc, ok := a.toolCalls[delta.Index]
+	if !ok {
+		tc = &streamToolCall{}
+		a.toolCalls[delta.Index] = tc
+	}
+	if delta.ID != "" {
+		tc.id = delta.ID
+	}
+	if delta.Name != "" {
+		tc.name = delta.Name
+	}
+	tc.arguments.WriteString(delta.Arguments)
+}
+
+func (a *streamAccumulator) response(finishReason string, usage *UsageInfo) *LLMResponse {
+	indexes := make([]int, 0, len(a.toolCalls))
+	for idx := range a.toolCalls {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+
+	toolCalls := make([]ToolCall, 0, len(indexes))
+	for _, idx := range indexes {
+		tc := a.toolCalls[idx]
+		arguments := make(map[string]interface{})
+		if raw := tc.arguments.String(); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &arguments); err != nil {
+				arguments["raw"] = raw
+			}
+		}
+		toolCalls = append(toolCalls, ToolCall{
+			ID:        tc.id,
+			Name:      tc.name,
+			Arguments: arguments,
+		})
+	}
+
+	if len(toolCalls) > 0 && (finishReason == "" || finishReason == "stop") {
+		finishReason = "tool_calls"
+	}
+	if finishReason == "" {
+		finishReason = "stop"
+	}
+
+	content, inlineReasoning := SplitThinking(a.content.String())
+
+	return &LLMResponse{
+		Content:      content,
+		Reasoning:    joinReasoning(a.reasoning.String(), inlineReasoning),
+		ToolCalls:    toolCalls,
+		FinishReason: finishReason,
+		Usage:        usage,
+	}
+}
@@ -20,6 +20,14 @@ type LLMResponse struct {
 	ToolCalls    []ToolCall `json:"tool_calls,omitempty"`
 	FinishReason string     `json:"finish_reason"`
 	Usage        *UsageInfo `json:"usage,omitempty"`
+	// Reasoning is the model's thinking, kept out of Content: <think>
+	// blocks, DeepSeek's reasoning_content, Claude thinking blocks and the
+	// like. Empty for models that don't reason visibly.
+	Reasoning string `json:"reasoning,omitempty"`
+	// Provider and Model name the backend and model that answered when a
+	// fallback chain picked one. Both are empty for a single provider.
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
 }
 
 type UsageInfo struct {
@@ -33,6 +41,20 @@ type Message struct {
 	Content    string     `json:"content"`
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string     `json:"tool_call_id,omitempty"`
+	// Parts carries multimodal content. When set it supersedes Content,
+	// which should hold the same text for providers that can't see images.
+	Parts []ContentPart `json:"parts,omitempty"`
+	// Reasoning keeps an assistant turn's thinking in session history. It
+	// is never sent back to the model.
+	Reasoning string `json:"reasoning,omitempty"`
+}
+
+// ContentPart is one piece of a multimodal message: text or an image.
+type ContentPart struct {
+	Type     string `json:"type"` // "text" or "image"
+	Text     string `json:"text,omitempty"`
+	MimeType string `json:"mime_type,omitempty"` // images only, e.g. "image/jpeg"
+	Data     []byte `json:"data,omitempty"`      // raw image bytes
 }
 
 type LLMProvider interface {
@@ -50,3 +72,17 @@ type ToolFunctionDefinition struct {
 	Description string                 `json:"description"`
 	Parameters  map[string]interface{} `json:"parameters"`
 }
+
+type incognitoKey struct{}
+
+// WithIncognito marks ctx as belonging to an incognito conversation, which
+// providers must not record.
+func WithIncognito(ctx context.Context) context.Context {
+	return context.WithValue(ctx, incognitoKey{}, true)
+}
+
+// IsIncognito reports whether ctx belongs to an incognito conversation.
+func IsIncognito(ctx context.Context) bool {
+	on, _ := ctx.Value(incognitoKey{}).(bool)
+	return on
+}
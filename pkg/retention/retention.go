@@ -0,0 +1,338 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package retention deletes personal data once it is older than the ages
+// set in privacy.retention. The gateway runs a Janitor in the background;
+// picoclaw privacy purge runs the same plan once, or only prints it.
+package retention
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/session"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// Data classes, as named in privacy.retention and purge reports.
+const (
+	ClassSessions   = "sessions"
+	ClassMedia      = "media"
+	ClassDailyNotes = "daily_notes"
+	ClassCron       = "cron"
+)
+
+// cronSessionPrefix starts the keys of the sessions cron jobs run in.
+const cronSessionPrefix = "cron-"
+
+// janitorInterval is how often the gateway's janitor looks for expired data.
+const janitorInterval = time.Hour
+
+// Policy holds the maximum age for each data class; 0 keeps data forever.
+type Policy struct {
+	Sessions   time.Duration
+	Media      time.Duration
+	DailyNotes time.Duration
+	Cron       time.Duration
+}
+
+// PolicyFromConfig parses privacy.retention.
+func PolicyFromConfig(c config.RetentionConfig) (Policy, error) {
+	var p Policy
+	for _, f := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{ClassSessions, c.Sessions, &p.Sessions},
+		{ClassMedia, c.Media, &p.Media},
+		{ClassDailyNotes, c.DailyNotes, &p.DailyNotes},
+		{ClassCron, c.Cron, &p.Cron},
+	} {
+		d, err := ParseAge(f.value)
+		if err != nil {
+			return Policy{}, fmt.Errorf("privacy.retention.%s: %w", f.name, err)
+		}
+		*f.dst = d
+	}
+	return p, nil
+}
+
+// Empty reports whether the policy keeps everything.
+func (p Policy) Empty() bool {
+	return p == Policy{}
+}
+
+// ParseAge parses a retention age: a Go duration such as "24h", or a number
+// of days such as "30d". An empty string is 0.
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, fmt.Errorf("invalid age %q (use e.g. 24h or 30d)", s)
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid age %q: must be positive", s)
+	}
+	return d, nil
+}
+
+// Item is one piece of data a purge deletes.
+type Item struct {
+	Class string
+	Name  string    // session key, file name or cron job
+	Path  string    // file deleted; empty for cron jobs
+	Time  time.Time // last change
+	jobID string
+	store *session.SessionManager // Session store the item is in
+}
+
+// Janitor finds and deletes expired data. Sessions and cron jobs go through
+// the live stores so a running gateway doesn't write them back.
+type Janitor struct {
+	policy    Policy
+	workspace string
+	mediaDir  string
+	sessions  []*session.SessionManager
+	cron      *cron.CronService
+
+	mu       sync.Mutex
+	stopChan chan struct{}
+}
+
+// NewJanitor returns a janitor for workspace. sessions and cronService may
+// be nil to leave those classes alone.
+func NewJanitor(policy Policy, workspace string, sessions *session.SessionManager, cronService *cron.CronService) *Janitor {
+	j := &Janitor{
+		policy:    policy,
+		workspace: workspace,
+		mediaDir:  utils.MediaDir(),
+		cron:      cronService,
+	}
+	if sessions != nil {
+		j.AddSessions(sessions)
+	}
+	return j
+}
+
+// AddSessions expires the sessions of another store too, such as the web
+// UI's.
+func (j *Janitor) AddSessions(sessions *session.SessionManager) {
+	j.sessions = append(j.sessions, sessions)
+}
+
+// Plan lists what a purge at now would delete, oldest first.
+func (j *Janitor) Plan(now time.Time) ([]Item, error) {
+	var items []Item
+
+	if j.policy.Sessions > 0 || j.policy.Cron > 0 {
+		for _, store := range j.sessions {
+			for key, updated := range store.UpdatedAt() {
+				class, maxAge := ClassSessions, j.policy.Sessions
+				if strings.HasPrefix(key, cronSessionPrefix) {
+					class, maxAge = ClassCron, j.policy.Cron
+				}
+				if maxAge > 0 && now.Sub(updated) > maxAge {
+					items = append(items, Item{Class: class, Name: key, Path: store.Path(key), Time: updated, store: store})
+				}
+			}
+		}
+	}
+
+	if j.cron != nil && j.policy.Cron > 0 {
+		for _, job := range j.cron.ListJobs(true) {
+			// Only one-time jobs that have run; recurring ones are still wanted
+			if job.Schedule.Kind != "at" || job.Enabled || job.State.LastRunAtMS == nil {
+				continue
+			}
+			ran := time.UnixMilli(*job.State.LastRunAtMS)
+			if now.Sub(ran) > j.policy.Cron {
+				items = append(items, Item{Class: ClassCron, Name: fmt.Sprintf("job %s (%s)", job.ID, job.Name), Time: ran, jobID: job.ID})
+			}
+		}
+	}
+
+	if j.policy.Media > 0 {
+		media, err := expiredFiles(j.mediaDir, now.Add(-j.policy.Media))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range media {
+			f.Class = ClassMedia
+			items = append(items, f)
+		}
+	}
+
+	if j.policy.DailyNotes > 0 {
+		notes, err := expiredNotes(filepath.Join(j.workspace, "memory"), now.Add(-j.policy.DailyNotes))
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, notes...)
+	}
+
+	sort.Slice(items, func(a, b int) bool {
+		return items[a].Time.Before(items[b].Time)
+	})
+	return items, nil
+}
+
+// expiredFiles lists files under dir last modified before cutoff.
+func expiredFiles(dir string, cutoff time.Time) ([]Item, error) {
+	var items []Item
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if info.ModTime().Before(cutoff) {
+			items = append(items, Item{Name: d.Name(), Path: path, Time: info.ModTime()})
+		}
+		return nil
+	})
+	return items, err
+}
+
+// expiredNotes lists memory/YYYYMM/YYYYMMDD.md notes for days that ended
+// before cutoff. MEMORY.md is never touched.
+func expiredNotes(memoryDir string, cutoff time.Time) ([]Item, error) {
+	matches, err := filepath.Glob(filepath.Join(memoryDir, "[0-9][0-9][0-9][0-9][0-9][0-9]", "*.md"))
+	if err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	for _, path := range matches {
+		day, err := time.ParseInLocation("20060102", strings.TrimSuffix(filepath.Base(path), ".md"), time.Local)
+		if err != nil {
+			continue
+		}
+		if end := day.AddDate(0, 0, 1); end.Before(cutoff) {
+			items = append(items, Item{Class: ClassDailyNotes, Name: filepath.Base(path), Path: path, Time: end})
+		}
+	}
+	return items, nil
+}
+
+// Purge deletes items and returns the ones it removed. It carries on past
+// failures and returns the first one.
+func (j *Janitor) Purge(items []Item) ([]Item, error) {
+	var removed []Item
+	var firstErr error
+
+	for _, item := range items {
+		switch {
+		case item.jobID != "":
+			if !j.cron.RemoveJob(item.jobID) {
+				continue
+			}
+		case item.store != nil:
+			item.store.Delete(item.Name)
+		default:
+			if err := os.Remove(item.Path); err != nil && !os.IsNotExist(err) {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			if item.Class == ClassDailyNotes {
+				// Drop the month directory once its last note is gone
+				os.Remove(filepath.Dir(item.Path))
+			}
+		}
+		removed = append(removed, item)
+	}
+	return removed, firstErr
+}
+
+// Start runs the janitor now and then every hour until Stop.
+func (j *Janitor) Start() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.stopChan != nil || j.policy.Empty() {
+		return
+	}
+	j.stopChan = make(chan struct{})
+	go j.runLoop(j.stopChan)
+}
+
+// Stop ends the background loop.
+func (j *Janitor) Stop() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.stopChan != nil {
+		close(j.stopChan)
+		j.stopChan = nil
+	}
+}
+
+func (j *Janitor) runLoop(stopChan chan struct{}) {
+	ticker := time.NewTicker(janitorInterval)
+	defer ticker.Stop()
+
+	for {
+		j.sweep()
+		select {
+		case <-stopChan:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *Janitor) sweep() {
+	items, err := j.Plan(time.Now())
+	if err != nil {
+		logger.WarnCF("retention", "Failed to plan purge", map[string]interface{}{"error": err.Error()})
+		return
+	}
+	if len(items) == 0 {
+		return
+	}
+
+	removed, err := j.Purge(items)
+	counts := make(map[string]interface{})
+	for _, item := range removed {
+		c, _ := counts[item.Class].(int)
+		counts[item.Class] = c + 1
+	}
+	if err != nil {
+		counts["error"] = err.Error()
+		logger.WarnCF("retention", "Purged expired data with errors", counts)
+		return
+	}
+	logger.InfoCF("retention", "Purged expired data", counts)
+}
@@ -0,0 +1,189 @@
+package retention
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/session"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"24h", 24 * time.Hour, false},
+		{"30d", 30 * 24 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"d", 0, true},
+		{"-1d", 0, true},
+		{"a week", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseAge(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseAge(%q) = %v, %v; want %v, err=%v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPolicyFromConfig(t *testing.T) {
+	p, err := PolicyFromConfig(config.RetentionConfig{Sessions: "30d", Media: "24h"})
+	if err != nil {
+		t.Fatalf("PolicyFromConfig() error: %v", err)
+	}
+	if p.Sessions != 30*24*time.Hour || p.Media != 24*time.Hour || p.DailyNotes != 0 || p.Empty() {
+		t.Errorf("policy = %+v", p)
+	}
+	if _, err := PolicyFromConfig(config.RetentionConfig{Cron: "soon"}); err == nil {
+		t.Error("expected an error for an invalid age")
+	}
+	if p, _ := PolicyFromConfig(config.RetentionConfig{}); !p.Empty() {
+		t.Error("an empty config should keep everything")
+	}
+}
+
+func TestJanitor_PlanAndPurge(t *testing.T) {
+	workspace := t.TempDir()
+	now := time.Now()
+	old := now.Add(-40 * 24 * time.Hour)
+
+	// Sessions: one stale chat, one fresh chat, one stale cron run
+	sessions := session.NewSessionManager(filepath.Join(workspace, "sessions"))
+	for _, key := range []string{"telegram:1", "telegram:2", "cron-abc"} {
+		sessions.AddMessage(key, "user", "hi")
+		sessions.Save(key)
+	}
+	backdate(t, sessions, "telegram:1", old)
+	backdate(t, sessions, "cron-abc", old)
+	sessions = session.NewSessionManager(filepath.Join(workspace, "sessions"))
+
+	// Cron: a finished one-time job and a recurring one
+	cs := cron.NewCronService(filepath.Join(workspace, "cron", "jobs.json"), nil)
+	at := old.UnixMilli()
+	done, _ := cs.AddJob("reminder", cron.CronSchedule{Kind: "at", AtMS: &at}, "call mom", false, "", "")
+	done.Enabled = false
+	done.State.LastRunAtMS = &at
+	cs.UpdateJob(done)
+	every := int64(time.Hour / time.Millisecond)
+	cs.AddJob("hourly", cron.CronSchedule{Kind: "every", EveryMS: &every}, "check", false, "", "")
+
+	// Media: an old and a new download
+	mediaDir := filepath.Join(workspace, "media")
+	os.MkdirAll(mediaDir, 0755)
+	oldMedia := filepath.Join(mediaDir, "old.jpg")
+	os.WriteFile(oldMedia, []byte("x"), 0644)
+	os.Chtimes(oldMedia, old, old)
+	os.WriteFile(filepath.Join(mediaDir, "new.jpg"), []byte("x"), 0644)
+
+	// Daily notes: one from 40 days ago, one from today, and MEMORY.md
+	oldNote := filepath.Join(workspace, "memory", old.Format("200601"), old.Format("20060102")+".md")
+	newNote := filepath.Join(workspace, "memory", now.Format("200601"), now.Format("20060102")+".md")
+	for _, p := range []string{oldNote, newNote, filepath.Join(workspace, "memory", "MEMORY.md")} {
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte("note"), 0644)
+	}
+
+	j := NewJanitor(Policy{
+		Sessions:   30 * 24 * time.Hour,
+		Media:      24 * time.Hour,
+		DailyNotes: 30 * 24 * time.Hour,
+		Cron:       7 * 24 * time.Hour,
+	}, workspace, sessions, cs)
+	j.mediaDir = mediaDir
+
+	items, err := j.Plan(now)
+	if err != nil {
+		t.Fatalf("Plan() error: %v", err)
+	}
+	got := make(map[string]string)
+	for _, item := range items {
+		got[item.Name] = item.Class
+	}
+	want := map[string]string{
+		"telegram:1":                     ClassSessions,
+		"cron-abc":                       ClassCron,
+		"job " + done.ID + " (reminder)": ClassCron,
+		"old.jpg":                        ClassMedia,
+		filepath.Base(oldNote):           ClassDailyNotes,
+	}
+	if len(got) != len(want) {
+		t.Errorf("planned %v, want %v", got, want)
+	}
+	for name, class := range want {
+		if got[name] != class {
+			t.Errorf("item %q class = %q, want %q", name, got[name], class)
+		}
+	}
+
+	// A dry run changes nothing
+	if _, err := os.Stat(oldMedia); err != nil {
+		t.Fatal("Plan() deleted a file")
+	}
+
+	removed, err := j.Purge(items)
+	if err != nil || len(removed) != len(items) {
+		t.Fatalf("Purge() = %d items, %v; want %d", len(removed), err, len(items))
+	}
+	for _, p := range []string{oldMedia, oldNote, filepath.Dir(oldNote), filepath.Join(workspace, "sessions", "telegram_1.json")} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s still exists", p)
+		}
+	}
+	if h := sessions.GetHistory("telegram:2"); len(h) != 1 {
+		t.Error("fresh session was purged")
+	}
+	if jobs := cs.ListJobs(true); len(jobs) != 1 || jobs[0].Name != "hourly" {
+		t.Errorf("jobs after purge = %+v, want only the recurring job", jobs)
+	}
+	if _, err := os.Stat(newNote); err != nil {
+		t.Error("today's note was purged")
+	}
+
+	if items, _ := j.Plan(now); len(items) != 0 {
+		t.Errorf("second Plan() = %+v, want nothing left", items)
+	}
+}
+
+func TestJanitor_AddSessions(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-40 * 24 * time.Hour)
+
+	gateway := session.NewSessionManager(filepath.Join(dir, "workspace", "sessions"))
+	webui := session.NewSessionManager(filepath.Join(dir, "sessions"))
+	for _, sm := range []*session.SessionManager{gateway, webui} {
+		sm.AddMessage("web:1", "user", "hi")
+		backdate(t, sm, "web:1", old)
+	}
+
+	j := NewJanitor(Policy{Sessions: 30 * 24 * time.Hour}, dir, gateway, nil)
+	j.AddSessions(webui)
+	items, err := j.Plan(time.Now())
+	if err != nil || len(items) != 2 {
+		t.Fatalf("Plan() = %+v, %v; want the stale session of each store", items, err)
+	}
+	if _, err := j.Purge(items); err != nil {
+		t.Fatalf("Purge() error: %v", err)
+	}
+	for _, sm := range []*session.SessionManager{gateway, webui} {
+		if _, err := os.Stat(sm.Path("web:1")); !os.IsNotExist(err) {
+			t.Errorf("%s still exists", sm.Path("web:1"))
+		}
+	}
+}
+
+// backdate rewrites a saved session as last updated at the given time.
+func backdate(t *testing.T, sm *session.SessionManager, key string, at time.Time) {
+	t.Helper()
+	s := sm.GetOrCreate(key)
+	s.Updated = at
+	if err := sm.Save(key); err != nil {
+		t.Fatalf("Save(%q) error: %v", key, err)
+	}
+}
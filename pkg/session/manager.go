@@ -2,12 +2,15 @@ package session
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/sipeed/picoclaw/pkg/crypt"
+	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/providers"
 )
 
@@ -23,12 +26,16 @@ type SessionManager struct {
 	sessions map[string]*Session
 	mu       sync.RWMutex
 	storage  string
+	// Files that failed to load, for example with the wrong key. They are
+	// never saved over, so their history isn't lost.
+	unreadable map[string]bool
 }
 
 func NewSessionManager(storage string) *SessionManager {
 	sm := &SessionManager{
-		sessions: make(map[string]*Session),
-		storage:  storage,
+		sessions:   make(map[string]*Session),
+		storage:    storage,
+		unreadable: make(map[string]bool),
 	}
 
 	if storage != "" {
@@ -170,6 +177,127 @@ func (sm *SessionManager) Delete(key string) bool {
 	return true
 }
 
+// ImportResult says what Import did with a session.
+type ImportResult int
+
+const (
+	ImportSkipped ImportResult = iota // the history was already there
+	ImportAdded                       // the key was free
+	ImportMerged                      // the history was merged into the local one
+)
+
+// Import adds a session read from an export. A session whose key is free is
+// stored as is. Otherwise the two histories are merged under that key: when
+// one continues the other the longer one is kept, and when they differ the
+// messages of the session updated first come first. Summaries are joined
+// the same way. Importing the same session again changes nothing.
+func (sm *SessionManager) Import(s Session) ImportResult {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if s.Messages == nil {
+		s.Messages = []providers.Message{}
+	}
+	existing, ok := sm.sessions[s.Key]
+	if !ok {
+		sm.sessions[s.Key] = &s
+		return ImportAdded
+	}
+
+	local, imported := encodeMessages(existing.Messages), encodeMessages(s.Messages)
+	switch {
+	case containsRun(local, imported) && strings.Contains(existing.Summary, s.Summary):
+		return ImportSkipped
+	case containsRun(imported, local) && strings.Contains(s.Summary, existing.Summary):
+		existing.Messages = s.Messages
+		existing.Summary = s.Summary
+	case hasPrefix(imported, local):
+		existing.Messages = s.Messages
+		existing.Summary = joinSummaries(existing.Summary, s.Summary)
+	case hasPrefix(local, imported):
+		existing.Summary = joinSummaries(s.Summary, existing.Summary)
+	case s.Updated.Before(existing.Updated):
+		existing.Messages = append(append([]providers.Message{}, s.Messages...), existing.Messages...)
+		existing.Summary = joinSummaries(s.Summary, existing.Summary)
+	default:
+		existing.Messages = append(existing.Messages, s.Messages...)
+		existing.Summary = joinSummaries(existing.Summary, s.Summary)
+	}
+	if !s.Created.IsZero() && s.Created.Before(existing.Created) {
+		existing.Created = s.Created
+	}
+	if s.Updated.After(existing.Updated) {
+		existing.Updated = s.Updated
+	}
+	return ImportMerged
+}
+
+// encodeMessages returns the JSON of each message, for comparing histories.
+func encodeMessages(messages []providers.Message) []string {
+	out := make([]string, len(messages))
+	for i, m := range messages {
+		data, _ := json.Marshal(m)
+		out[i] = string(data)
+	}
+	return out
+}
+
+// hasPrefix reports whether a starts with all of prefix.
+func hasPrefix(a, prefix []string) bool {
+	if len(prefix) > len(a) {
+		return false
+	}
+	for i := range prefix {
+		if a[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// containsRun reports whether run appears in a as consecutive elements.
+func containsRun(a, run []string) bool {
+	for i := 0; i+len(run) <= len(a); i++ {
+		if hasPrefix(a[i:], run) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinSummaries joins two summaries, first before second, unless one already
+// holds the other.
+func joinSummaries(first, second string) string {
+	switch {
+	case strings.Contains(first, second):
+		return first
+	case strings.Contains(second, first):
+		return second
+	}
+	return first + "\n\n" + second
+}
+
+// UpdatedAt returns every session key with the time the session last
+// changed.
+func (sm *SessionManager) UpdatedAt() map[string]time.Time {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	times := make(map[string]time.Time, len(sm.sessions))
+	for key, session := range sm.sessions {
+		times[key] = session.Updated
+	}
+	return times
+}
+
+// Path returns the file a session is saved to, or "" without storage.
+func (sm *SessionManager) Path(key string) string {
+	if sm.storage == "" {
+		return ""
+	}
+	return filepath.Join(sm.storage, sanitizeFilename(key)+".json")
+}
+
 // sanitizeFilename converts a session key into a cross-platform safe filename.
 // Session keys use "channel:chatID" (e.g. "telegram:123456") but ':' is the
 // volume separator on Windows, so filepath.Base would misinterpret the key.
@@ -193,6 +321,9 @@ func (sm *SessionManager) Save(key string) error {
 	if filename == "." || !filepath.IsLocal(filename) || strings.ContainsAny(filename, `/\\`) {
 		return os.ErrInvalid
 	}
+	if sm.unreadable[filename+".json"] {
+		return fmt.Errorf("session file %s.json could not be read at startup and is not overwritten", filename)
+	}
 
 	// Snapshot under read lock, then perform slow file I/O after unlock.
 	sm.mu.RLock()
@@ -220,6 +351,9 @@ func (sm *SessionManager) Save(key string) error {
 	if err != nil {
 		return err
 	}
+	if data, err = crypt.Seal(data); err != nil {
+		return err
+	}
 
 	sessionPath := filepath.Join(sm.storage, filename+".json")
 	tmpFile, err := os.CreateTemp(sm.storage, "session-*.tmp")
@@ -274,13 +408,20 @@ func (sm *SessionManager) loadSessions() error {
 		}
 
 		sessionPath := filepath.Join(sm.storage, file.Name())
-		data, err := os.ReadFile(sessionPath)
+		data, err := crypt.ReadFile(sessionPath)
 		if err != nil {
+			logger.WarnCF("session", "Skipping unreadable session file",
+				map[string]interface{}{
+					"file":  file.Name(),
+					"error": err.Error(),
+				})
+			sm.unreadable[file.Name()] = true
 			continue
 		}
 
 		var session Session
 		if err := json.Unmarshal(data, &session); err != nil {
+			sm.unreadable[file.Name()] = true
 			continue
 		}
 
@@ -1,9 +1,15 @@
 package session
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/crypt"
+	"github.com/sipeed/picoclaw/pkg/providers"
 )
 
 func TestSanitizeFilename(t *testing.T) {
@@ -72,3 +78,112 @@ func TestSave_RejectsPathTraversal(t *testing.T) {
 		}
 	}
 }
+
+func TestLoadSessions_MixedEncryption(t *testing.T) {
+	tmpDir := t.TempDir()
+	keyFile := filepath.Join(t.TempDir(), "at-rest.key")
+	os.WriteFile(keyFile, []byte("0123456789abcdef0123456789abcdef"), 0600)
+	key, err := crypt.LoadKey("", keyFile, "")
+	if err != nil {
+		t.Fatalf("LoadKey() error: %v", err)
+	}
+	defer crypt.Configure(nil, false)
+
+	// One session saved before encryption was turned on, one after
+	sm := NewSessionManager(tmpDir)
+	sm.AddMessage("cli:old", "user", "plaintext history")
+	sm.Save("cli:old")
+
+	crypt.Configure(key, true)
+	sm.AddMessage("telegram:42", "user", "my IBAN is DE89 3704 0044 0532 0130 00")
+	if err := sm.Save("telegram:42"); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	raw, _ := os.ReadFile(filepath.Join(tmpDir, "telegram_42.json"))
+	if !crypt.IsEncrypted(raw) {
+		t.Fatal("session saved with encryption on is plaintext on disk")
+	}
+
+	sm2 := NewSessionManager(tmpDir)
+	if h := sm2.GetHistory("cli:old"); len(h) != 1 || h[0].Content != "plaintext history" {
+		t.Errorf("plaintext session = %+v", h)
+	}
+	if h := sm2.GetHistory("telegram:42"); len(h) != 1 || h[0].Content != "my IBAN is DE89 3704 0044 0532 0130 00" {
+		t.Errorf("encrypted session = %+v", h)
+	}
+
+	// Without the key the encrypted session is skipped, not loaded as garbage
+	crypt.Configure(nil, false)
+	sm3 := NewSessionManager(tmpDir)
+	if h := sm3.GetHistory("telegram:42"); len(h) != 0 {
+		t.Errorf("encrypted session loaded without a key: %+v", h)
+	}
+	if h := sm3.GetHistory("cli:old"); len(h) != 1 {
+		t.Errorf("plaintext session not loaded without a key: %+v", h)
+	}
+
+	// and never saved over
+	sm3.AddMessage("telegram:42", "user", "new message")
+	if err := sm3.Save("telegram:42"); err == nil {
+		t.Error("Save() overwrote a session file that could not be read")
+	}
+	if after, _ := os.ReadFile(filepath.Join(tmpDir, "telegram_42.json")); !bytes.Equal(after, raw) {
+		t.Error("the unreadable session file was changed")
+	}
+}
+
+func TestImport_MergesUnderKey(t *testing.T) {
+	msg := func(content string) providers.Message { return providers.Message{Role: "user", Content: content} }
+	earlier := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name     string
+		imported Session
+		want     []string
+		result   ImportResult
+	}{
+		{"identical", Session{Messages: []providers.Message{msg("a"), msg("b")}}, []string{"a", "b"}, ImportSkipped},
+		{"part of the local one", Session{Messages: []providers.Message{msg("b")}}, []string{"a", "b"}, ImportSkipped},
+		{"continues the local one", Session{Messages: []providers.Message{msg("a"), msg("b"), msg("c")}}, []string{"a", "b", "c"}, ImportMerged},
+		{"older and different", Session{Messages: []providers.Message{msg("x")}, Updated: earlier}, []string{"x", "a", "b"}, ImportMerged},
+		{"newer and different", Session{Messages: []providers.Message{msg("x")}, Updated: time.Now().Add(time.Hour)}, []string{"a", "b", "x"}, ImportMerged},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := NewSessionManager("")
+			sm.AddMessage("chat", "user", "a")
+			sm.AddMessage("chat", "user", "b")
+
+			tt.imported.Key = "chat"
+			if got := sm.Import(tt.imported); got != tt.result {
+				t.Errorf("Import() = %v, want %v", got, tt.result)
+			}
+			var got []string
+			for _, m := range sm.GetHistory("chat") {
+				got = append(got, m.Content)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("history = %v, want %v", got, tt.want)
+			}
+			// A second import of the same session adds nothing
+			if again := sm.Import(tt.imported); again != ImportSkipped {
+				t.Errorf("second Import() = %v, want ImportSkipped", again)
+			}
+		})
+	}
+}
+
+func TestImport_JoinsSummaries(t *testing.T) {
+	sm := NewSessionManager("")
+	sm.AddMessage("chat", "user", "a")
+	sm.SetSummary("chat", "local summary")
+
+	sm.Import(Session{Key: "chat", Summary: "imported summary", Messages: []providers.Message{{Role: "user", Content: "x"}}, Updated: time.Now().Add(time.Hour)})
+	if got := sm.GetSummary("chat"); got != "local summary\n\nimported summary" {
+		t.Errorf("summary = %q", got)
+	}
+	if got := sm.Import(Session{Key: "other", Summary: "s"}); got != ImportAdded {
+		t.Errorf("Import() of a free key = %v, want ImportAdded", got)
+	}
+}
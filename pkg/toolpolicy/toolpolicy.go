@@ -0,0 +1,195 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package toolpolicy limits the tools each channel, group and sender may
+// use, as set in tools.policies. The first policy that matches a message
+// applies. Messages no policy matches may use no tool on a channel some
+// policy names, and every tool on other channels.
+package toolpolicy
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// ErrNotAllowed is returned, wrapped, for calls a policy refuses.
+var ErrNotAllowed = errors.New("not allowed here")
+
+// Policies are the policies of the config in order. They implement
+// tools.Policy.
+type Policies struct {
+	rules    []rule
+	channels map[string]bool // Channels some policy names
+}
+
+type rule struct {
+	channel string
+	senders []string
+	groups  []string
+	allow   map[string]bool // nil allows all
+	deny    map[string]bool
+	args    map[string]map[string]*regexp.Regexp
+}
+
+// New compiles the policies of cfg. An argument pattern that doesn't compile
+// is logged and the tool it belongs to is denied by its policy, rather than
+// allowed without the check.
+func New(cfg []config.ToolPolicy) *Policies {
+	p := &Policies{channels: make(map[string]bool)}
+	for i, pc := range cfg {
+		if pc.Channel != "" {
+			p.channels[pc.Channel] = true
+		}
+		r := rule{
+			channel: pc.Channel,
+			senders: pc.Senders,
+			groups:  pc.Groups,
+			allow:   toSet(pc.Allow),
+			deny:    toSet(pc.Deny),
+			args:    make(map[string]map[string]*regexp.Regexp),
+		}
+		if r.allow["*"] {
+			r.allow = nil
+		}
+		if r.deny == nil {
+			r.deny = make(map[string]bool)
+		}
+
+		for tool, patterns := range pc.Args {
+			r.args[tool] = make(map[string]*regexp.Regexp)
+			for arg, expr := range patterns {
+				// The whole value must match, not just a part of it
+				re, err := regexp.Compile(`^(?:` + expr + `)$`)
+				if err != nil {
+					logger.ErrorCF("toolpolicy", "Invalid argument pattern, denying the tool",
+						map[string]interface{}{
+							"policy":  i,
+							"tool":    tool,
+							"arg":     arg,
+							"pattern": expr,
+							"error":   err.Error(),
+						})
+					r.deny[tool] = true
+					continue
+				}
+				r.args[tool][arg] = re
+			}
+		}
+		p.rules = append(p.rules, r)
+	}
+	return p
+}
+
+func toSet(names []string) map[string]bool {
+	if len(names) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[strings.TrimSpace(name)] = true
+	}
+	return set
+}
+
+// denyAll applies to callers no policy matches on a channel that has
+// policies.
+var denyAll = rule{deny: map[string]bool{"*": true}}
+
+// ruleFor returns the first policy that matches caller. It returns nil when
+// none does and no policy names the caller's channel.
+func (p *Policies) ruleFor(caller tools.Caller) *rule {
+	for i := range p.rules {
+		if p.rules[i].matches(caller) {
+			return &p.rules[i]
+		}
+	}
+	// A channel with policies is limited to the callers they list
+	if p.channels[caller.Channel] {
+		return &denyAll
+	}
+	return nil
+}
+
+func (r *rule) matches(caller tools.Caller) bool {
+	if r.channel != "" && r.channel != caller.Channel {
+		return false
+	}
+	if len(r.senders) > 0 && !matchSender(caller.SenderID, r.senders) {
+		return false
+	}
+	if len(r.groups) > 0 && !contains(r.groups, caller.Group) && !contains(r.groups, caller.ChatID) {
+		return false
+	}
+	return true
+}
+
+// matchSender matches the sender against IDs and usernames. Sender IDs may
+// be in the "id|username" form some channels use, and usernames may be
+// written with a leading "@".
+func matchSender(senderID string, entries []string) bool {
+	if senderID == "" {
+		return false
+	}
+	id, user, _ := strings.Cut(senderID, "|")
+	for _, entry := range entries {
+		entry = strings.TrimPrefix(strings.TrimSpace(entry), "@")
+		if entry == senderID || entry == id || (user != "" && entry == user) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, item := range list {
+		if strings.TrimSpace(item) == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *rule) allows(tool string) bool {
+	if r.deny["*"] || r.deny[tool] {
+		return false
+	}
+	return r.allow == nil || r.allow[tool]
+}
+
+// Allows reports whether caller may use tool at all.
+func (p *Policies) Allows(tool string, caller tools.Caller) bool {
+	r := p.ruleFor(caller)
+	return r == nil || r.allows(tool)
+}
+
+// Check returns an error when caller may not use tool, or not with args.
+func (p *Policies) Check(tool string, args map[string]interface{}, caller tools.Caller) error {
+	r := p.ruleFor(caller)
+	if r == nil {
+		return nil
+	}
+	if !r.allows(tool) {
+		return fmt.Errorf("tool %s is %w", tool, ErrNotAllowed)
+	}
+	for arg, re := range r.args[tool] {
+		var value string
+		if v, ok := args[arg]; ok && v != nil {
+			value = fmt.Sprint(v)
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("%s was not run: this %s argument is %w", tool, arg, ErrNotAllowed)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package toolpolicy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+func testPolicies() *Policies {
+	return New([]config.ToolPolicy{
+		{
+			Channel: "discord",
+			Senders: config.FlexibleStringSlice{"@owner"},
+		},
+		{
+			Channel: "discord",
+			Groups:  config.FlexibleStringSlice{"guild-1"},
+			Allow:   config.FlexibleStringSlice{"web_search", "web_fetch"},
+		},
+		{
+			Channel: "telegram",
+			Deny:    config.FlexibleStringSlice{"i2c", "spi"},
+			Args: map[string]map[string]string{
+				"exec":      {"command": `(ls|cat|df -h)( [\w./-]+)*`},
+				"read_file": {"path": `(`},
+			},
+		},
+		{
+			Channel: "discord",
+			Deny:    config.FlexibleStringSlice{"*"},
+		},
+	})
+}
+
+func TestPolicies_Allows(t *testing.T) {
+	p := testPolicies()
+
+	tests := []struct {
+		name   string
+		tool   string
+		caller tools.Caller
+		want   bool
+	}{
+		{"owner may use anything", "exec", tools.Caller{Channel: "discord", SenderID: "1|owner", ChatID: "c", Group: "guild-1"}, true},
+		{"guild allows listed tool", "web_search", tools.Caller{Channel: "discord", SenderID: "2|friend", ChatID: "c", Group: "guild-1"}, true},
+		{"guild hides other tools", "exec", tools.Caller{Channel: "discord", SenderID: "2|friend", ChatID: "c", Group: "guild-1"}, false},
+		{"group matches chat ID", "web_fetch", tools.Caller{Channel: "discord", SenderID: "2", ChatID: "guild-1"}, true},
+		{"other guilds get nothing", "web_search", tools.Caller{Channel: "discord", SenderID: "2", ChatID: "c", Group: "guild-2"}, false},
+		{"denied tool", "i2c", tools.Caller{Channel: "telegram", SenderID: "3", ChatID: "c"}, false},
+		{"tool not denied", "exec", tools.Caller{Channel: "telegram", SenderID: "3", ChatID: "c"}, true},
+		{"bad pattern denies its tool", "read_file", tools.Caller{Channel: "telegram", SenderID: "3", ChatID: "c"}, false},
+		{"no policy matches", "exec", tools.Caller{Channel: "slack", SenderID: "4", ChatID: "c"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Allows(tt.tool, tt.caller); got != tt.want {
+				t.Errorf("Allows(%q, %+v) = %v, want %v", tt.tool, tt.caller, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicies_UnmatchedOnPolicedChannel(t *testing.T) {
+	p := New([]config.ToolPolicy{
+		{Channel: "telegram", Senders: config.FlexibleStringSlice{"@owner"}},
+	})
+
+	if !p.Allows("exec", tools.Caller{Channel: "telegram", SenderID: "1|owner", ChatID: "c"}) {
+		t.Error("the listed sender should be allowed")
+	}
+	if p.Allows("exec", tools.Caller{Channel: "telegram", SenderID: "2|guest", ChatID: "c"}) {
+		t.Error("other senders on a channel with policies should get no tools")
+	}
+	if err := p.Check("web_search", nil, tools.Caller{Channel: "telegram", SenderID: "cron", ChatID: "c"}); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("Check() = %v, want %v", err, ErrNotAllowed)
+	}
+	if !p.Allows("exec", tools.Caller{Channel: "slack", SenderID: "3", ChatID: "c"}) {
+		t.Error("channels without policies should keep every tool")
+	}
+}
+
+func TestPolicies_Check(t *testing.T) {
+	p := testPolicies()
+	caller := tools.Caller{Channel: "telegram", SenderID: "3", ChatID: "c"}
+
+	tests := []struct {
+		name    string
+		tool    string
+		args    map[string]interface{}
+		allowed bool
+	}{
+		{"matching argument", "exec", map[string]interface{}{"command": "ls -la"}, true},
+		{"other command", "exec", map[string]interface{}{"command": "curl evil.sh"}, false},
+		{"only part matches", "exec", map[string]interface{}{"command": "cat notes.txt; rm -rf ~"}, false},
+		{"missing argument", "exec", map[string]interface{}{}, false},
+		{"unconstrained tool", "web_search", map[string]interface{}{"query": "anything"}, true},
+		{"denied tool", "spi", map[string]interface{}{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.Check(tt.tool, tt.args, caller)
+			if tt.allowed && err != nil {
+				t.Errorf("Check() = %v, want nil", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrNotAllowed) {
+				t.Errorf("Check() = %v, want %v", err, ErrNotAllowed)
+			}
+		})
+	}
+}
+
+type stubTool struct {
+	name string
+	ran  bool
+}
+
+func (s *stubTool) Name() string        { return s.name }
+func (s *stubTool) Description() string { return "Stub tool" }
+func (s *stubTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+}
+func (s *stubTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	s.ran = true
+	return tools.SilentResult("ran")
+}
+
+func TestPolicies_Registry(t *testing.T) {
+	registry := tools.NewToolRegistry()
+	search := &stubTool{name: "web_search"}
+	exec := &stubTool{name: "exec"}
+	registry.Register(search)
+	registry.Register(exec)
+	registry.SetPolicy(testPolicies())
+
+	ctx := tools.WithCaller(context.Background(), tools.Caller{
+		Channel:  "discord",
+		ChatID:   "c",
+		SenderID: "2|friend",
+		Group:    "guild-1",
+	})
+
+	defs := registry.ToProviderDefs(ctx)
+	if len(defs) != 1 || defs[0].Function.Name != "web_search" {
+		t.Errorf("ToProviderDefs() = %+v, want only web_search", defs)
+	}
+	if got := registry.GetSummaries(ctx); len(got) != 1 {
+		t.Errorf("GetSummaries() = %v, want only web_search", got)
+	}
+	if got := registry.ToProviderDefs(context.Background()); len(got) != 2 {
+		t.Errorf("ToProviderDefs() without a caller returned %d tools, want 2", len(got))
+	}
+
+	result := registry.ExecuteWithContext(ctx, "exec", map[string]interface{}{}, "discord", "c", nil)
+	if !result.IsError || exec.ran {
+		t.Errorf("exec should have been refused, got %+v", result)
+	}
+	result = registry.ExecuteWithContext(ctx, "web_search", map[string]interface{}{}, "discord", "c", nil)
+	if result.IsError || !search.ran {
+		t.Errorf("web_search should have run, got %+v", result)
+	}
+}
@@ -10,13 +10,6 @@ type Tool interface {
 	Execute(ctx context.Context, args map[string]interface{}) *ToolResult
 }
 
-// ContextualTool is an optional interface that tools can implement
-// to receive the current message context (channel, chatID)
-type ContextualTool interface {
-	Tool
-	SetContext(channel, chatID string)
-}
-
 // AsyncCallback is a function type that async tools use to notify completion.
 // When an async tool finishes its work, it calls this callback with the result.
 //
@@ -66,9 +59,35 @@ type AsyncTool interface {
 	Tool
 	// SetCallback registers a callback function to be invoked when the async operation completes.
 	// The callback will be called from a goroutine and should handle thread-safety if needed.
+	// A callback passed to ToolRegistry.ExecuteWithContext takes precedence for that call.
 	SetCallback(cb AsyncCallback)
 }
 
+// SerialTool is an optional interface for tools whose calls must not run at
+// the same time. When the model asks for several tool calls at once they run
+// in parallel, except that calls with the same serial key run one after
+// another, in the order they were requested.
+//
+// An empty key means the call can run alongside any other. Tools that act on
+// one resource use a key per resource, so that only calls for the same
+// resource wait for each other:
+//
+//	func (t *EditFileTool) SerialKey(args map[string]interface{}) string {
+//	    return fileSerialKey(args, t.allowedDir)
+//	}
+type SerialTool interface {
+	Tool
+	SerialKey(args map[string]interface{}) string
+}
+
+// Serial keys the agent knows about. A command may read or change any file,
+// so when one response holds both, calls keyed with ExecSerialKey and with
+// FileSerialPrefix all run one after another.
+const (
+	ExecSerialKey    = "exec"
+	FileSerialPrefix = "file:"
+)
+
 func ToolToSchema(tool Tool) map[string]interface{} {
 	return map[string]interface{}{
 		"type": "function",
@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// The agent handles several conversations at once with the same tool
+// instances, so everything specific to one call travels in its context
+// rather than in fields set on the tool.
+
+type toolContextKey struct{}
+
+type toolContext struct {
+	channel string
+	chatID  string
+}
+
+// WithToolContext returns ctx carrying the channel and chat a tool call is
+// made for. ToolRegistry.ExecuteWithContext sets it on every call.
+func WithToolContext(ctx context.Context, channel, chatID string) context.Context {
+	return context.WithValue(ctx, toolContextKey{}, toolContext{channel: channel, chatID: chatID})
+}
+
+// ToolContext returns the channel and chat set by WithToolContext, or empty
+// strings when there are none.
+func ToolContext(ctx context.Context) (channel, chatID string) {
+	tc, _ := ctx.Value(toolContextKey{}).(toolContext)
+	return tc.channel, tc.chatID
+}
+
+type callerKey struct{}
+
+// Caller is who the tools are used for: the sender of the message being
+// handled and where it was sent. Group is the server or group the chat
+// belongs to, on channels that have them.
+type Caller struct {
+	Channel  string
+	ChatID   string
+	SenderID string
+	Group    string
+}
+
+// WithCaller returns ctx carrying the caller of the message being handled
+// with it, which tool policies are checked against (see Policy).
+func WithCaller(ctx context.Context, c Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, c)
+}
+
+// CallerFrom returns the caller set by WithCaller, if any.
+func CallerFrom(ctx context.Context) Caller {
+	c, _ := ctx.Value(callerKey{}).(Caller)
+	return c
+}
+
+type asyncCallbackKey struct{}
+
+// withAsyncCallback returns ctx carrying the callback an async tool calls
+// when it completes.
+func withAsyncCallback(ctx context.Context, cb AsyncCallback) context.Context {
+	return context.WithValue(ctx, asyncCallbackKey{}, cb)
+}
+
+func asyncCallbackFrom(ctx context.Context) AsyncCallback {
+	cb, _ := ctx.Value(asyncCallbackKey{}).(AsyncCallback)
+	return cb
+}
+
+type roundKey struct{}
+
+// Round records what tools did while the agent handled one message.
+type Round struct {
+	messageSent atomic.Bool
+}
+
+// WithRound starts a round for the message being handled with ctx.
+func WithRound(ctx context.Context) (context.Context, *Round) {
+	r := &Round{}
+	return context.WithValue(ctx, roundKey{}, r), r
+}
+
+// MessageSent reports whether the message tool sent something during the
+// round, in which case the agent's final reply would be a duplicate.
+func (r *Round) MessageSent() bool {
+	return r.messageSent.Load()
+}
+
+func roundFrom(ctx context.Context) *Round {
+	r, _ := ctx.Value(roundKey{}).(*Round)
+	return r
+}
@@ -3,7 +3,6 @@ package tools
 import (
 	"context"
 	"fmt"
-	"sync"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
@@ -14,6 +13,9 @@ import (
 // JobExecutor is the interface for executing cron jobs through the agent
 type JobExecutor interface {
 	ProcessDirectWithChannel(ctx context.Context, content, sessionKey, channel, chatID string) (string, error)
+	// ExecuteTool runs a tool for the chat a job reports to, with the
+	// approvals and policies that apply to the model's own calls.
+	ExecuteTool(ctx context.Context, name string, args map[string]interface{}, channel, chatID string) *ToolResult
 }
 
 // CronTool provides scheduling capabilities for the agent
@@ -21,19 +23,15 @@ type CronTool struct {
 	cronService *cron.CronService
 	executor    JobExecutor
 	msgBus      *bus.MessageBus
-	execTool    *ExecTool
-	channel     string
-	chatID      string
-	mu          sync.RWMutex
 }
 
-// NewCronTool creates a new CronTool
-func NewCronTool(cronService *cron.CronService, executor JobExecutor, msgBus *bus.MessageBus, workspace string, restrict bool) *CronTool {
+// NewCronTool creates a new CronTool. Scheduled commands run through the
+// executor's exec tool.
+func NewCronTool(cronService *cron.CronService, executor JobExecutor, msgBus *bus.MessageBus) *CronTool {
 	return &CronTool{
 		cronService: cronService,
 		executor:    executor,
 		msgBus:      msgBus,
-		execTool:    NewExecTool(workspace, restrict),
 	}
 }
 
@@ -90,14 +88,6 @@ func (t *CronTool) Parameters() map[string]interface{} {
 	}
 }
 
-// SetContext sets the current session context for job creation
-func (t *CronTool) SetContext(channel, chatID string) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	t.channel = channel
-	t.chatID = chatID
-}
-
 // Execute runs the tool with the given arguments
 func (t *CronTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
 	action, ok := args["action"].(string)
@@ -107,7 +97,7 @@ func (t *CronTool) Execute(ctx context.Context, args map[string]interface{}) *To
 
 	switch action {
 	case "add":
-		return t.addJob(args)
+		return t.addJob(ctx, args)
 	case "list":
 		return t.listJobs()
 	case "remove":
@@ -121,11 +111,9 @@ func (t *CronTool) Execute(ctx context.Context, args map[string]interface{}) *To
 	}
 }
 
-func (t *CronTool) addJob(args map[string]interface{}) *ToolResult {
-	t.mu.RLock()
-	channel := t.channel
-	chatID := t.chatID
-	t.mu.RUnlock()
+func (t *CronTool) addJob(ctx context.Context, args map[string]interface{}) *ToolResult {
+	// Jobs report back to the conversation that created them
+	channel, chatID := ToolContext(ctx)
 
 	if channel == "" || chatID == "" {
 		return ErrorResult("no session context (channel/chat_id not set). Use this tool in an active conversation.")
@@ -195,11 +183,13 @@ func (t *CronTool) addJob(args map[string]interface{}) *ToolResult {
 		return ErrorResult(fmt.Sprintf("Error adding job: %v", err))
 	}
 
-	if command != "" {
-		job.Payload.Command = command
-		// Need to save the updated payload
-		t.cronService.UpdateJob(job)
-	}
+	// Runs act for the user who created the job (see ExecuteJob)
+	caller := CallerFrom(ctx)
+	job.Payload.Command = command
+	job.Payload.Sender = caller.SenderID
+	job.Payload.Group = caller.Group
+	// Need to save the updated payload
+	t.cronService.UpdateJob(job)
 
 	return SilentResult(fmt.Sprintf("Cron job added: %s (id: %s)", job.Name, job.ID))
 }
@@ -273,13 +263,29 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 		chatID = "direct"
 	}
 
+	// The job acts for the user who created it, so their tool policies
+	// apply and they are the one asked for approvals. Jobs saved without a
+	// sender act for "cron", which no policy names and nobody can answer for.
+	sender := job.Payload.Sender
+	if sender == "" {
+		sender = "cron"
+	}
+	ctx = WithCaller(ctx, Caller{
+		Channel:  channel,
+		ChatID:   chatID,
+		SenderID: sender,
+		Group:    job.Payload.Group,
+	})
+
 	// Execute command if present
 	if job.Payload.Command != "" {
 		args := map[string]interface{}{
 			"command": job.Payload.Command,
 		}
 
-		result := t.execTool.Execute(ctx, args)
+		// Through the registry, so a command that needs approval is asked
+		// about in the chat the job reports to each time it runs
+		result := t.executor.ExecuteTool(ctx, "exec", args, channel, chatID)
 		var output string
 		if result.IsError {
 			output = fmt.Sprintf("Error executing scheduled command: %s", result.ForLLM)
@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/cron"
+)
+
+// fakeJobExecutor records the tool calls of scheduled jobs.
+type fakeJobExecutor struct {
+	tool            string
+	args            map[string]interface{}
+	channel, chatID string
+	caller          Caller
+	result          *ToolResult
+}
+
+func (e *fakeJobExecutor) ProcessDirectWithChannel(ctx context.Context, content, sessionKey, channel, chatID string) (string, error) {
+	return "", nil
+}
+
+func (e *fakeJobExecutor) ExecuteTool(ctx context.Context, name string, args map[string]interface{}, channel, chatID string) *ToolResult {
+	e.tool, e.args, e.channel, e.chatID = name, args, channel, chatID
+	e.caller = CallerFrom(ctx)
+	return e.result
+}
+
+func TestCronTool_ExecuteJob_CommandGoesThroughExecutor(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	executor := &fakeJobExecutor{result: ErrorResult("not approved")}
+	tool := NewCronTool(nil, executor, msgBus)
+
+	job := &cron.CronJob{ID: "j1", Payload: cron.CronPayload{
+		Command: "make deploy",
+		Channel: "telegram",
+		To:      "42",
+		Sender:  "7",
+	}}
+	tool.ExecuteJob(context.Background(), job)
+
+	if executor.tool != "exec" || executor.args["command"] != "make deploy" {
+		t.Errorf("executor got %s %v, want exec of the job's command", executor.tool, executor.args)
+	}
+	if executor.channel != "telegram" || executor.chatID != "42" {
+		t.Errorf("executor got %s:%s, want the job's chat telegram:42", executor.channel, executor.chatID)
+	}
+	if executor.caller.SenderID != "7" {
+		t.Errorf("command ran for %+v, want the job's creator", executor.caller)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	out, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok || !strings.Contains(out.Content, "not approved") {
+		t.Errorf("expected the refusal to be reported, got %q", out.Content)
+	}
+}
+
+func TestCronTool_AddJob_StoresCaller(t *testing.T) {
+	cs := cron.NewCronService(filepath.Join(t.TempDir(), "jobs.json"), nil)
+	executor := &fakeJobExecutor{result: SilentResult("ok")}
+	tool := NewCronTool(cs, executor, bus.NewMessageBus())
+
+	caller := Caller{Channel: "discord", ChatID: "chan-1", SenderID: "friend", Group: "guild-9"}
+	ctx := WithCaller(WithToolContext(context.Background(), "discord", "chan-1"), caller)
+	result := tool.Execute(ctx, map[string]interface{}{
+		"action":        "add",
+		"message":       "disk space",
+		"command":       "df -h",
+		"every_seconds": float64(3600),
+	})
+	if result.IsError {
+		t.Fatalf("add failed: %s", result.ForLLM)
+	}
+
+	jobs := cs.ListJobs(true)
+	if len(jobs) != 1 || jobs[0].Payload.Sender != "friend" || jobs[0].Payload.Group != "guild-9" {
+		t.Fatalf("jobs = %+v, want the creator stored", jobs)
+	}
+	tool.ExecuteJob(context.Background(), &jobs[0])
+	if executor.caller != caller {
+		t.Errorf("job ran for %+v, want %+v", executor.caller, caller)
+	}
+}
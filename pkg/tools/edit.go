@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/crypt"
 )
 
 // EditFileTool edits a file by replacing old_text with new_text.
@@ -30,6 +32,10 @@ func (t *EditFileTool) Description() string {
 	return "Edit a file by replacing old_text with new_text. The old_text must exist exactly in the file."
 }
 
+func (t *EditFileTool) SerialKey(args map[string]interface{}) string {
+	return fileSerialKey(args, t.allowedDir)
+}
+
 func (t *EditFileTool) Parameters() map[string]interface{} {
 	return map[string]interface{}{
 		"type": "object",
@@ -71,12 +77,15 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]interface{})
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
+	if err := checkMemoryWrite(ctx, resolvedPath); err != nil {
+		return ErrorResult(err.Error())
+	}
 
 	if _, err := os.Stat(resolvedPath); os.IsNotExist(err) {
 		return ErrorResult(fmt.Sprintf("file not found: %s", path))
 	}
 
-	content, err := os.ReadFile(resolvedPath)
+	content, err := readFile(resolvedPath)
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
 	}
@@ -94,7 +103,7 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]interface{})
 
 	newContent := strings.Replace(contentStr, oldText, newText, 1)
 
-	if err := os.WriteFile(resolvedPath, []byte(newContent), 0644); err != nil {
+	if err := writeFile(resolvedPath, []byte(newContent)); err != nil {
 		return ErrorResult(fmt.Sprintf("failed to write file: %v", err))
 	}
 
@@ -118,6 +127,10 @@ func (t *AppendFileTool) Description() string {
 	return "Append content to the end of a file"
 }
 
+func (t *AppendFileTool) SerialKey(args map[string]interface{}) string {
+	return fileSerialKey(args, t.workspace)
+}
+
 func (t *AppendFileTool) Parameters() map[string]interface{} {
 	return map[string]interface{}{
 		"type": "object",
@@ -150,6 +163,21 @@ func (t *AppendFileTool) Execute(ctx context.Context, args map[string]interface{
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
+	if err := checkMemoryWrite(ctx, resolvedPath); err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	if crypt.Protected(resolvedPath) {
+		// Encrypted files can't be appended to in place
+		existing, err := crypt.ReadFile(resolvedPath)
+		if err != nil && !os.IsNotExist(err) {
+			return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
+		}
+		if err := writeFile(resolvedPath, append(existing, content...)); err != nil {
+			return ErrorResult(fmt.Sprintf("failed to append to file: %v", err))
+		}
+		return SilentResult(fmt.Sprintf("Appended to %s", path))
+	}
 
 	f, err := os.OpenFile(resolvedPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
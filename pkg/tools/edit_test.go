@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/crypt"
 )
 
 // TestEditTool_EditFile_Success verifies successful file editing
@@ -287,3 +289,135 @@ func TestEditTool_AppendFile_MissingContent(t *testing.T) {
 		t.Errorf("Expected error when content is missing")
 	}
 }
+
+// TestEditTool_ProtectedFileStaysEncrypted verifies that memory files edited
+// by the agent are still encrypted at rest afterwards
+func TestEditTool_ProtectedFileStaysEncrypted(t *testing.T) {
+	tmpDir := t.TempDir()
+	memoryDir := filepath.Join(tmpDir, "memory")
+	os.MkdirAll(memoryDir, 0755)
+	keyFile := filepath.Join(tmpDir, "at-rest.key")
+	os.WriteFile(keyFile, []byte("0123456789abcdef0123456789abcdef"), 0600)
+
+	key, err := crypt.LoadKey("", keyFile, "")
+	if err != nil {
+		t.Fatalf("LoadKey() error: %v", err)
+	}
+	crypt.Configure(key, true)
+	defer crypt.Configure(nil, false)
+	crypt.Protect(memoryDir)
+
+	ctx := context.Background()
+	memoryFile := filepath.Join(memoryDir, "MEMORY.md")
+	steps := []struct {
+		tool Tool
+		args map[string]interface{}
+	}{
+		{NewWriteFileTool(tmpDir, true), map[string]interface{}{"path": memoryFile, "content": "Likes tea."}},
+		{NewEditFileTool(tmpDir, true), map[string]interface{}{"path": memoryFile, "old_text": "tea", "new_text": "green tea"}},
+		{NewAppendFileTool(tmpDir, true), map[string]interface{}{"path": memoryFile, "content": "\nLives in Berlin."}},
+	}
+	for _, step := range steps {
+		if result := step.tool.Execute(ctx, step.args); result.IsError {
+			t.Fatalf("%s failed: %s", step.tool.Name(), result.ForLLM)
+		}
+		raw, _ := os.ReadFile(memoryFile)
+		if !crypt.IsEncrypted(raw) {
+			t.Fatalf("%s left MEMORY.md as plaintext", step.tool.Name())
+		}
+	}
+
+	result := NewReadFileTool(tmpDir, true).Execute(ctx, map[string]interface{}{"path": memoryFile})
+	if result.ForLLM != "Likes green tea.\nLives in Berlin." {
+		t.Errorf("read_file = %q", result.ForLLM)
+	}
+}
+
+// TestReadFileTool_DecryptsOnlyProtectedPaths verifies that encrypted files
+// outside the protected paths, such as session histories, are not decrypted
+func TestReadFileTool_DecryptsOnlyProtectedPaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	keyFile := filepath.Join(tmpDir, "at-rest.key")
+	os.WriteFile(keyFile, []byte("0123456789abcdef0123456789abcdef"), 0600)
+
+	key, err := crypt.LoadKey("", keyFile, "")
+	if err != nil {
+		t.Fatalf("LoadKey() error: %v", err)
+	}
+	crypt.Configure(key, true)
+	defer crypt.Configure(nil, false)
+	crypt.Protect(filepath.Join(tmpDir, "memory"))
+
+	sessionFile := filepath.Join(tmpDir, "sessions", "chat.json")
+	os.MkdirAll(filepath.Dir(sessionFile), 0755)
+	if err := crypt.WriteFile(sessionFile, []byte("private conversation"), 0600); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+
+	ctx := context.Background()
+	result := NewReadFileTool(tmpDir, false).Execute(ctx, map[string]interface{}{"path": sessionFile})
+	if strings.Contains(result.ForLLM, "private conversation") {
+		t.Error("read_file decrypted a file outside the protected paths")
+	}
+	result = NewEditFileTool(tmpDir, false).Execute(ctx, map[string]interface{}{
+		"path": sessionFile, "old_text": "private", "new_text": "public",
+	})
+	if !result.IsError {
+		t.Error("edit_file should not find text in a file it can't decrypt")
+	}
+}
+
+// TestFileTools_RefuseCredentialFiles verifies that the auth store and the
+// secret vault can't be read or changed, even through a symlink
+func TestFileTools_RefuseCredentialFiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	workspace := t.TempDir()
+
+	secrets := filepath.Join(home, ".picoclaw", "secrets.json")
+	os.MkdirAll(filepath.Dir(secrets), 0700)
+	os.WriteFile(secrets, []byte(`{"secrets":{"GITHUB_TOKEN":"ghp_x"}}`), 0600)
+	link := filepath.Join(workspace, "notes.json")
+	if err := os.Symlink(secrets, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	ctx := context.Background()
+	for _, path := range []string{secrets, link, filepath.Join(home, ".picoclaw", "auth.json")} {
+		steps := []struct {
+			tool Tool
+			args map[string]interface{}
+		}{
+			{NewReadFileTool(workspace, false), map[string]interface{}{"path": path}},
+			{NewWriteFileTool(workspace, false), map[string]interface{}{"path": path, "content": "{}"}},
+			{NewEditFileTool(workspace, false), map[string]interface{}{"path": path, "old_text": "ghp_x", "new_text": "y"}},
+			{NewAppendFileTool(workspace, false), map[string]interface{}{"path": path, "content": "x"}},
+		}
+		for _, step := range steps {
+			result := step.tool.Execute(ctx, step.args)
+			if !result.IsError || !strings.Contains(result.ForLLM, "access denied") {
+				t.Errorf("%s on %s = %q, want access denied", step.tool.Name(), path, result.ForLLM)
+			}
+		}
+	}
+	if data, _ := os.ReadFile(secrets); string(data) != `{"secrets":{"GITHUB_TOKEN":"ghp_x"}}` {
+		t.Errorf("secrets.json was changed: %s", data)
+	}
+}
+
+// TestEditFileTool_SerialKey verifies calls for the same file share a key
+func TestEditFileTool_SerialKey(t *testing.T) {
+	workspace := t.TempDir()
+	tool := NewEditFileTool(workspace, true)
+
+	relative := tool.SerialKey(map[string]interface{}{"path": "notes.md"})
+	absolute := tool.SerialKey(map[string]interface{}{"path": filepath.Join(workspace, "notes.md")})
+	other := tool.SerialKey(map[string]interface{}{"path": "other.md"})
+
+	if relative != absolute {
+		t.Errorf("Expected same key for relative and absolute path, got %q and %q", relative, absolute)
+	}
+	if relative == other {
+		t.Errorf("Expected different keys for different files, got %q", other)
+	}
+}
@@ -6,11 +6,53 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/crypt"
+	"github.com/sipeed/picoclaw/pkg/vault"
 )
 
+// writeFile writes a file for the file tools. Files under a path protected
+// by crypt, such as the memory directory, stay encrypted at rest.
+func writeFile(path string, data []byte) error {
+	if crypt.Protected(path) {
+		return crypt.WriteFile(path, data, 0644)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// readFile reads a file for the file tools. Only files under a path
+// protected by crypt are decrypted. Other encrypted files, such as session
+// histories, are returned as the bytes on disk.
+func readFile(path string) ([]byte, error) {
+	if crypt.Protected(path) {
+		return crypt.ReadFile(path)
+	}
+	return os.ReadFile(path)
+}
+
+// isCredentialFile reports whether path is the OAuth credential store or the
+// secret vault, which no file tool may touch, restricted or not.
+func isCredentialFile(path string) bool {
+	info, statErr := os.Stat(path)
+	for _, f := range []string{auth.StorePath(), vault.DefaultPath()} {
+		if filepath.Clean(path) == filepath.Clean(f) {
+			return true
+		}
+		// Also through symlinks and hard links
+		if fi, err := os.Stat(f); statErr == nil && err == nil && os.SameFile(info, fi) {
+			return true
+		}
+	}
+	return false
+}
+
 // validatePath ensures the given path is within the workspace if restrict is true.
 func validatePath(path, workspace string, restrict bool) (string, error) {
 	if workspace == "" {
+		if isCredentialFile(path) {
+			return "", fmt.Errorf("access denied: %s holds credentials", path)
+		}
 		return path, nil
 	}
 
@@ -56,6 +98,9 @@ func validatePath(path, workspace string, restrict bool) (string, error) {
 		}
 	}
 
+	if isCredentialFile(absPath) {
+		return "", fmt.Errorf("access denied: %s holds credentials", path)
+	}
 	return absPath, nil
 }
 
@@ -77,6 +122,16 @@ func isWithinWorkspace(candidate, workspace string) bool {
 	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
 }
 
+// fileSerialKey keeps calls for the same file from running at once, so a
+// read doesn't see a write half done.
+func fileSerialKey(args map[string]interface{}, workspace string) string {
+	path, _ := args["path"].(string)
+	if resolved, err := validatePath(path, workspace, false); err == nil {
+		path = resolved
+	}
+	return FileSerialPrefix + path
+}
+
 type ReadFileTool struct {
 	workspace string
 	restrict  bool
@@ -94,6 +149,10 @@ func (t *ReadFileTool) Description() string {
 	return "Read the contents of a file"
 }
 
+func (t *ReadFileTool) SerialKey(args map[string]interface{}) string {
+	return fileSerialKey(args, t.workspace)
+}
+
 func (t *ReadFileTool) Parameters() map[string]interface{} {
 	return map[string]interface{}{
 		"type": "object",
@@ -118,7 +177,7 @@ func (t *ReadFileTool) Execute(ctx context.Context, args map[string]interface{})
 		return ErrorResult(err.Error())
 	}
 
-	content, err := os.ReadFile(resolvedPath)
+	content, err := readFile(resolvedPath)
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
 	}
@@ -143,6 +202,10 @@ func (t *WriteFileTool) Description() string {
 	return "Write content to a file"
 }
 
+func (t *WriteFileTool) SerialKey(args map[string]interface{}) string {
+	return fileSerialKey(args, t.workspace)
+}
+
 func (t *WriteFileTool) Parameters() map[string]interface{} {
 	return map[string]interface{}{
 		"type": "object",
@@ -175,13 +238,16 @@ func (t *WriteFileTool) Execute(ctx context.Context, args map[string]interface{}
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
+	if err := checkMemoryWrite(ctx, resolvedPath); err != nil {
+		return ErrorResult(err.Error())
+	}
 
 	dir := filepath.Dir(resolvedPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return ErrorResult(fmt.Sprintf("failed to create directory: %v", err))
 	}
 
-	if err := os.WriteFile(resolvedPath, []byte(content), 0644); err != nil {
+	if err := writeFile(resolvedPath, []byte(content)); err != nil {
 		return ErrorResult(fmt.Sprintf("failed to write file: %v", err))
 	}
 
@@ -63,6 +63,11 @@ func (t *I2CTool) Parameters() map[string]interface{} {
 	}
 }
 
+// SerialKey keeps transactions on the bus from interleaving.
+func (t *I2CTool) SerialKey(args map[string]interface{}) string {
+	return "i2c"
+}
+
 func (t *I2CTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
 	if runtime.GOOS != "linux" {
 		return ErrorResult("I2C is only supported on Linux. This tool requires /dev/i2c-* device files.")
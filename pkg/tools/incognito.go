@@ -0,0 +1,34 @@
+package tools
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sipeed/picoclaw/pkg/crypt"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// ErrIncognito is returned by tools that would persist personal data while
+// the conversation is incognito.
+var ErrIncognito = errors.New("this conversation is incognito: memory is read-only (turn it off with /incognito off)")
+
+// WithIncognito marks ctx as belonging to an incognito conversation. Tools
+// called with it refuse to write memory and keep their arguments out of the
+// logs, and providers don't record it.
+func WithIncognito(ctx context.Context) context.Context {
+	return providers.WithIncognito(ctx)
+}
+
+// IsIncognito reports whether ctx belongs to an incognito conversation.
+func IsIncognito(ctx context.Context) bool {
+	return providers.IsIncognito(ctx)
+}
+
+// checkMemoryWrite refuses writes to memory files, the paths protected by
+// crypt, from an incognito conversation.
+func checkMemoryWrite(ctx context.Context, path string) error {
+	if IsIncognito(ctx) && crypt.Protected(path) {
+		return ErrIncognito
+	}
+	return nil
+}
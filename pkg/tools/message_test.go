@@ -3,12 +3,13 @@ package tools
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
 )
 
 func TestMessageTool_Execute_Success(t *testing.T) {
 	tool := NewMessageTool()
-	tool.SetContext("test-channel", "test-chat-id")
 
 	var sentChannel, sentChatID, sentContent string
 	tool.SetSendCallback(func(channel, chatID, content string) error {
@@ -18,7 +19,7 @@ func TestMessageTool_Execute_Success(t *testing.T) {
 		return nil
 	})
 
-	ctx := context.Background()
+	ctx := WithToolContext(context.Background(), "test-channel", "test-chat-id")
 	args := map[string]interface{}{
 		"content": "Hello, world!",
 	}
@@ -60,7 +61,6 @@ func TestMessageTool_Execute_Success(t *testing.T) {
 
 func TestMessageTool_Execute_WithCustomChannel(t *testing.T) {
 	tool := NewMessageTool()
-	tool.SetContext("default-channel", "default-chat-id")
 
 	var sentChannel, sentChatID string
 	tool.SetSendCallback(func(channel, chatID, content string) error {
@@ -69,7 +69,7 @@ func TestMessageTool_Execute_WithCustomChannel(t *testing.T) {
 		return nil
 	})
 
-	ctx := context.Background()
+	ctx := WithToolContext(context.Background(), "default-channel", "default-chat-id")
 	args := map[string]interface{}{
 		"content": "Test message",
 		"channel": "custom-channel",
@@ -96,14 +96,13 @@ func TestMessageTool_Execute_WithCustomChannel(t *testing.T) {
 
 func TestMessageTool_Execute_SendFailure(t *testing.T) {
 	tool := NewMessageTool()
-	tool.SetContext("test-channel", "test-chat-id")
 
 	sendErr := errors.New("network error")
 	tool.SetSendCallback(func(channel, chatID, content string) error {
 		return sendErr
 	})
 
-	ctx := context.Background()
+	ctx := WithToolContext(context.Background(), "test-channel", "test-chat-id")
 	args := map[string]interface{}{
 		"content": "Test message",
 	}
@@ -133,9 +132,8 @@ func TestMessageTool_Execute_SendFailure(t *testing.T) {
 
 func TestMessageTool_Execute_MissingContent(t *testing.T) {
 	tool := NewMessageTool()
-	tool.SetContext("test-channel", "test-chat-id")
 
-	ctx := context.Background()
+	ctx := WithToolContext(context.Background(), "test-channel", "test-chat-id")
 	args := map[string]interface{}{} // content missing
 
 	result := tool.Execute(ctx, args)
@@ -151,7 +149,7 @@ func TestMessageTool_Execute_MissingContent(t *testing.T) {
 
 func TestMessageTool_Execute_NoTargetChannel(t *testing.T) {
 	tool := NewMessageTool()
-	// No SetContext called, so defaultChannel and defaultChatID are empty
+	// No tool context, so there is no default channel or chat ID
 
 	tool.SetSendCallback(func(channel, chatID, content string) error {
 		return nil
@@ -175,10 +173,9 @@ func TestMessageTool_Execute_NoTargetChannel(t *testing.T) {
 
 func TestMessageTool_Execute_NotConfigured(t *testing.T) {
 	tool := NewMessageTool()
-	tool.SetContext("test-channel", "test-chat-id")
 	// No SetSendCallback called
 
-	ctx := context.Background()
+	ctx := WithToolContext(context.Background(), "test-channel", "test-chat-id")
 	args := map[string]interface{}{
 		"content": "Test message",
 	}
@@ -257,3 +254,40 @@ func TestMessageTool_Parameters(t *testing.T) {
 		t.Error("Expected chat_id type to be 'string'")
 	}
 }
+
+func TestMessageTool_Execute_ConcurrentContexts(t *testing.T) {
+	tool := NewMessageTool()
+	var mu sync.Mutex
+	sent := make(map[string]string)
+	tool.SetSendCallback(func(channel, chatID, content string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent[content] = channel + ":" + chatID
+		return nil
+	})
+
+	// Calls for different chats don't see each other's context or round
+	var wg sync.WaitGroup
+	rounds := make([]*Round, 20)
+	for i := range rounds {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx, round := WithRound(WithToolContext(context.Background(), "telegram", fmt.Sprint(i)))
+			rounds[i] = round
+			if i%2 == 0 {
+				tool.Execute(ctx, map[string]interface{}{"content": fmt.Sprint("msg-", i)})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i, round := range rounds {
+		if round.MessageSent() != (i%2 == 0) {
+			t.Errorf("round %d: MessageSent() = %v", i, round.MessageSent())
+		}
+		if i%2 == 0 && sent[fmt.Sprint("msg-", i)] != fmt.Sprint("telegram:", i) {
+			t.Errorf("msg-%d went to %q", i, sent[fmt.Sprint("msg-", i)])
+		}
+	}
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -11,8 +12,26 @@ import (
 )
 
 type ToolRegistry struct {
-	tools map[string]Tool
-	mu    sync.RWMutex
+	tools    map[string]Tool
+	mu       sync.RWMutex
+	approver Approver
+	policy   Policy
+}
+
+// Approver decides whether a tool call may run, for example by asking the
+// user. Approve returns nil to let the call run; otherwise the call is
+// refused with the error.
+type Approver interface {
+	Approve(ctx context.Context, tool string, args map[string]interface{}, channel, chatID string) error
+}
+
+// Policy limits which tools a caller may use and how.
+type Policy interface {
+	// Allows reports whether caller may use tool at all. Tools it doesn't
+	// allow are left out of what the model is shown.
+	Allows(tool string, caller Caller) bool
+	// Check returns an error when caller may not make the call.
+	Check(tool string, args map[string]interface{}, caller Caller) error
 }
 
 func NewToolRegistry() *ToolRegistry {
@@ -27,6 +46,29 @@ func (r *ToolRegistry) Register(tool Tool) {
 	r.tools[tool.Name()] = tool
 }
 
+// SetApprover makes every call through ExecuteWithContext wait for a's
+// decision first.
+func (r *ToolRegistry) SetApprover(a Approver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.approver = a
+}
+
+// SetPolicy makes ExecuteWithContext refuse the calls p doesn't allow for
+// the caller in ctx (see WithCaller), and hides the tools it doesn't allow
+// them at all.
+func (r *ToolRegistry) SetPolicy(p Policy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policy = p
+}
+
+// allows reports whether the caller in ctx may use the tool. The caller
+// must hold r.mu.
+func (r *ToolRegistry) allows(ctx context.Context, name string) bool {
+	return r.policy == nil || r.policy.Allows(name, CallerFrom(ctx))
+}
+
 func (r *ToolRegistry) Get(name string) (Tool, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -39,14 +81,16 @@ func (r *ToolRegistry) Execute(ctx context.Context, name string, args map[string
 }
 
 // ExecuteWithContext executes a tool with channel/chatID context and optional async callback.
-// If the tool implements AsyncTool and a non-nil callback is provided,
-// the callback will be set on the tool before execution.
+// Both are passed to the tool in ctx (see ToolContext), so the same tool can
+// run for several conversations at once.
 func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args map[string]interface{}, channel, chatID string, asyncCallback AsyncCallback) *ToolResult {
-	logger.InfoCF("tool", "Tool execution started",
-		map[string]interface{}{
-			"tool": name,
-			"args": args,
-		})
+	// Incognito conversations keep arguments and results out of the logs
+	incognito := IsIncognito(ctx)
+	startFields := map[string]interface{}{"tool": name}
+	if !incognito {
+		startFields["args"] = args
+	}
+	logger.InfoCF("tool", "Tool execution started", startFields)
 
 	tool, ok := r.Get(name)
 	if !ok {
@@ -57,18 +101,41 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 		return ErrorResult(fmt.Sprintf("tool %q not found", name)).WithError(fmt.Errorf("tool not found"))
 	}
 
-	// If tool implements ContextualTool, set context
-	if contextualTool, ok := tool.(ContextualTool); ok && channel != "" && chatID != "" {
-		contextualTool.SetContext(channel, chatID)
+	r.mu.RLock()
+	approver, policy := r.approver, r.policy
+	r.mu.RUnlock()
+	if policy != nil {
+		caller := CallerFrom(ctx)
+		if caller.Channel == "" {
+			caller.Channel, caller.ChatID = channel, chatID
+		}
+		if err := policy.Check(name, args, caller); err != nil {
+			logger.WarnCF("tool", "Tool call not allowed",
+				map[string]interface{}{
+					"tool":      name,
+					"channel":   caller.Channel,
+					"sender_id": caller.SenderID,
+					"error":     err.Error(),
+				})
+			return ErrorResult(err.Error()).WithError(err)
+		}
+	}
+	if approver != nil {
+		if err := approver.Approve(ctx, name, args, channel, chatID); err != nil {
+			logger.WarnCF("tool", "Tool call not approved",
+				map[string]interface{}{
+					"tool":  name,
+					"error": err.Error(),
+				})
+			return ErrorResult(err.Error()).WithError(err)
+		}
 	}
 
-	// If tool implements AsyncTool and callback is provided, set callback
-	if asyncTool, ok := tool.(AsyncTool); ok && asyncCallback != nil {
-		asyncTool.SetCallback(asyncCallback)
-		logger.DebugCF("tool", "Async callback injected",
-			map[string]interface{}{
-				"tool": name,
-			})
+	if channel != "" && chatID != "" {
+		ctx = WithToolContext(ctx, channel, chatID)
+	}
+	if asyncCallback != nil {
+		ctx = withAsyncCallback(ctx, asyncCallback)
 	}
 
 	start := time.Now()
@@ -77,12 +144,14 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 
 	// Log based on result type
 	if result.IsError {
-		logger.ErrorCF("tool", "Tool execution failed",
-			map[string]interface{}{
-				"tool":     name,
-				"duration": duration.Milliseconds(),
-				"error":    result.ForLLM,
-			})
+		fields := map[string]interface{}{
+			"tool":     name,
+			"duration": duration.Milliseconds(),
+		}
+		if !incognito {
+			fields["error"] = result.ForLLM
+		}
+		logger.ErrorCF("tool", "Tool execution failed", fields)
 	} else if result.Async {
 		logger.InfoCF("tool", "Tool started (async)",
 			map[string]interface{}{
@@ -101,6 +170,19 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 	return result
 }
 
+// SerialKey returns the serial key of a call (see SerialTool), or "" when
+// the call may run in parallel with others.
+func (r *ToolRegistry) SerialKey(name string, args map[string]interface{}) string {
+	tool, ok := r.Get(name)
+	if !ok {
+		return ""
+	}
+	if st, ok := tool.(SerialTool); ok {
+		return st.SerialKey(args)
+	}
+	return ""
+}
+
 func (r *ToolRegistry) GetDefinitions() []map[string]interface{} {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -113,14 +195,24 @@ func (r *ToolRegistry) GetDefinitions() []map[string]interface{} {
 }
 
 // ToProviderDefs converts tool definitions to provider-compatible format.
-// This is the format expected by LLM provider APIs.
-func (r *ToolRegistry) ToProviderDefs() []providers.ToolDefinition {
+// This is the format expected by LLM provider APIs. Tools are sorted by name
+// so identical conversations produce identical requests. Tools the policy
+// doesn't allow the caller in ctx are left out.
+func (r *ToolRegistry) ToProviderDefs(ctx context.Context) []providers.ToolDefinition {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	definitions := make([]providers.ToolDefinition, 0, len(r.tools))
-	for _, tool := range r.tools {
-		schema := ToolToSchema(tool)
+	names := make([]string, 0, len(r.tools))
+	for name := range r.tools {
+		if r.allows(ctx, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	definitions := make([]providers.ToolDefinition, 0, len(names))
+	for _, name := range names {
+		schema := ToolToSchema(r.tools[name])
 
 		// Safely extract nested values with type checks
 		fn, ok := schema["function"].(map[string]interface{})
@@ -163,14 +255,17 @@ func (r *ToolRegistry) Count() int {
 	return len(r.tools)
 }
 
-// GetSummaries returns human-readable summaries of all registered tools.
-// Returns a slice of "name - description" strings.
-func (r *ToolRegistry) GetSummaries() []string {
+// GetSummaries returns human-readable summaries of the registered tools the
+// caller in ctx may use. Returns a slice of "name - description" strings.
+func (r *ToolRegistry) GetSummaries(ctx context.Context) []string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	summaries := make([]string, 0, len(r.tools))
-	for _, tool := range r.tools {
+	for name, tool := range r.tools {
+		if !r.allows(ctx, name) {
+			continue
+		}
 		summaries = append(summaries, fmt.Sprintf("- `%s` - %s", tool.Name(), tool.Description()))
 	}
 	return summaries
@@ -0,0 +1,22 @@
+package tools
+
+import (
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/vault"
+)
+
+// secretHint tells the model which vault secrets a tool accepts, or returns
+// "" when there are none.
+func secretHint(v *vault.Vault) string {
+	names, _ := v.Names()
+	if len(names) == 0 {
+		return ""
+	}
+	placeholders := make([]string, len(names))
+	for i, name := range names {
+		placeholders[i] = vault.Placeholder(name)
+	}
+	return " Stored secrets can be used by writing their placeholder, which is replaced when the tool runs: " +
+		strings.Join(placeholders, ", ") + ". Their values are never shown to you."
+}
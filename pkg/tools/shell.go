@@ -11,6 +11,8 @@ import (
 	"runtime"
 	"strings"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/vault"
 )
 
 type ExecTool struct {
@@ -19,6 +21,7 @@ type ExecTool struct {
 	denyPatterns        []*regexp.Regexp
 	allowPatterns       []*regexp.Regexp
 	restrictToWorkspace bool
+	vault               *vault.Vault
 }
 
 func NewExecTool(workingDir string, restrict bool) *ExecTool {
@@ -47,7 +50,11 @@ func (t *ExecTool) Name() string {
 }
 
 func (t *ExecTool) Description() string {
-	return "Execute a shell command and return its output. Use with caution."
+	hint := secretHint(t.vault)
+	if hint != "" {
+		hint += " In commands a placeholder becomes an environment variable, so it works unquoted or in double quotes but not in single quotes."
+	}
+	return "Execute a shell command and return its output. Use with caution." + hint
 }
 
 func (t *ExecTool) Parameters() map[string]interface{} {
@@ -67,6 +74,12 @@ func (t *ExecTool) Parameters() map[string]interface{} {
 	}
 }
 
+// SerialKey makes commands run one at a time: they share the working
+// directory and may depend on each other's effects.
+func (t *ExecTool) SerialKey(args map[string]interface{}) string {
+	return ExecSerialKey
+}
+
 func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
 	command, ok := args["command"].(string)
 	if !ok {
@@ -89,30 +102,52 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 		return ErrorResult(guardError)
 	}
 
+	// Secrets reach the command as environment variables the placeholders
+	// are replaced with, after the guard has seen it, and are masked out of
+	// everything it prints
+	ref := func(envVar string) string { return "${" + envVar + "}" }
+	if runtime.GOOS == "windows" {
+		ref = func(envVar string) string { return "${env:" + envVar + "}" }
+	}
+	expanded, secretEnv, err := t.vault.ExpandEnv(command, ref)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
 	cmdCtx, cancel := context.WithTimeout(ctx, t.timeout)
 	defer cancel()
 
 	var cmd *exec.Cmd
 	if runtime.GOOS == "windows" {
-		cmd = exec.CommandContext(cmdCtx, "powershell", "-NoProfile", "-NonInteractive", "-Command", command)
+		cmd = exec.CommandContext(cmdCtx, "powershell", "-NoProfile", "-NonInteractive", "-Command", expanded)
 	} else {
-		cmd = exec.CommandContext(cmdCtx, "sh", "-c", command)
+		cmd = exec.CommandContext(cmdCtx, "sh", "-c", expanded)
 	}
 	if cwd != "" {
 		cmd.Dir = cwd
 	}
+	if len(secretEnv) > 0 {
+		cmd.Env = append(os.Environ(), secretEnv...)
+	}
+	// Stopping the run or hitting the timeout kills everything the command
+	// started, not just the shell
+	killGroupOnCancel(cmd)
+	cmd.WaitDelay = time.Second
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
+	err = cmd.Run()
 	output := stdout.String()
 	if stderr.Len() > 0 {
 		output += "\nSTDERR:\n" + stderr.String()
 	}
 
 	if err != nil {
+		if ctx.Err() != nil {
+			return ErrorResult("Command cancelled")
+		}
 		if cmdCtx.Err() == context.DeadlineExceeded {
 			msg := fmt.Sprintf("Command timed out after %v", t.timeout)
 			return &ToolResult{
@@ -124,6 +159,7 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 		output += fmt.Sprintf("\nExit code: %v", err)
 	}
 
+	output = t.vault.Mask(output)
 	if output == "" {
 		output = "(no output)"
 	}
@@ -208,6 +244,12 @@ func (t *ExecTool) SetTimeout(timeout time.Duration) {
 	t.timeout = timeout
 }
 
+// SetVault lets commands use {{secret:NAME}} placeholders from v, where
+// each secret's scope allows the command.
+func (t *ExecTool) SetVault(v *vault.Vault) {
+	t.vault = v
+}
+
 func (t *ExecTool) SetRestrictToWorkspace(restrict bool) {
 	t.restrictToWorkspace = restrict
 }
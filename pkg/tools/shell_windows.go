@@ -0,0 +1,7 @@
+package tools
+
+import "os/exec"
+
+// killGroupOnCancel is a no-op on Windows, where cancelling kills only the
+// shell; WaitDelay keeps its children from holding up the tool.
+func killGroupOnCancel(cmd *exec.Cmd) {}
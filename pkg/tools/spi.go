@@ -67,6 +67,11 @@ func (t *SPITool) Parameters() map[string]interface{} {
 	}
 }
 
+// SerialKey keeps transfers on the bus from interleaving.
+func (t *SPITool) SerialKey(args map[string]interface{}) string {
+	return "spi"
+}
+
 func (t *SPITool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
 	if runtime.GOOS != "linux" {
 		return ErrorResult("SPI is only supported on Linux. This tool requires /dev/spidev* device files.")
@@ -30,6 +30,7 @@ type SubagentManager struct {
 	workspace     string
 	tools         *ToolRegistry
 	maxIterations int
+	contextWindow int // Passed to the provider with each request; 0 = its own
 	nextID        int
 }
 
@@ -54,6 +55,28 @@ func (sm *SubagentManager) SetTools(tools *ToolRegistry) {
 	sm.tools = tools
 }
 
+// SetContextWindow sets the context window requested from the provider,
+// the same as the main agent's so local servers don't reload the model.
+func (sm *SubagentManager) SetContextWindow(n int) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.contextWindow = n
+}
+
+// llmOptions are the options of a subagent's requests to the model.
+func (sm *SubagentManager) llmOptions() map[string]any {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	options := map[string]any{
+		"max_tokens":  4096,
+		"temperature": 0.7,
+	}
+	if sm.contextWindow > 0 {
+		options["context_window"] = sm.contextWindow
+	}
+	return options
+}
+
 // RegisterTool registers a tool for subagent execution.
 func (sm *SubagentManager) RegisterTool(tool Tool) {
 	sm.mu.Lock()
@@ -130,10 +153,7 @@ After completing the task, provide a clear summary of what was done.`
 		Model:         sm.defaultModel,
 		Tools:         tools,
 		MaxIterations: maxIter,
-		LLMOptions: map[string]any{
-			"max_tokens":  4096,
-			"temperature": 0.7,
-		},
+		LLMOptions:    sm.llmOptions(),
 	}, messages, task.OriginChannel, task.OriginChatID)
 
 	sm.mu.Lock()
@@ -209,17 +229,23 @@ func (sm *SubagentManager) ListTasks() []*SubagentTask {
 // Unlike SpawnTool which runs tasks asynchronously, SubagentTool waits for completion
 // and returns the result directly in the ToolResult.
 type SubagentTool struct {
-	manager       *SubagentManager
-	originChannel string
-	originChatID  string
+	manager *SubagentManager
 }
 
 func NewSubagentTool(manager *SubagentManager) *SubagentTool {
 	return &SubagentTool{
-		manager:       manager,
-		originChannel: "cli",
-		originChatID:  "direct",
+		manager: manager,
+	}
+}
+
+// originOf returns the conversation a subagent reports back to, the CLI
+// when the call has none.
+func originOf(ctx context.Context) (channel, chatID string) {
+	channel, chatID = ToolContext(ctx)
+	if channel == "" || chatID == "" {
+		return "cli", "direct"
 	}
+	return channel, chatID
 }
 
 func (t *SubagentTool) Name() string {
@@ -247,11 +273,6 @@ func (t *SubagentTool) Parameters() map[string]interface{} {
 	}
 }
 
-func (t *SubagentTool) SetContext(channel, chatID string) {
-	t.originChannel = channel
-	t.originChatID = chatID
-}
-
 func (t *SubagentTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
 	task, ok := args["task"].(string)
 	if !ok {
@@ -277,6 +298,7 @@ func (t *SubagentTool) Execute(ctx context.Context, args map[string]interface{})
 	}
 
 	// Use RunToolLoop to execute with tools (same as async SpawnTool)
+	originChannel, originChatID := originOf(ctx)
 	sm := t.manager
 	sm.mu.RLock()
 	tools := sm.tools
@@ -288,11 +310,8 @@ func (t *SubagentTool) Execute(ctx context.Context, args map[string]interface{})
 		Model:         sm.defaultModel,
 		Tools:         tools,
 		MaxIterations: maxIter,
-		LLMOptions: map[string]any{
-			"max_tokens":  4096,
-			"temperature": 0.7,
-		},
-	}, messages, t.originChannel, t.originChatID)
+		LLMOptions:    sm.llmOptions(),
+	}, messages, originChannel, originChatID)
 
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("Subagent execution failed: %v", err)).WithError(err)
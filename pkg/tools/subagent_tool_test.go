@@ -4,6 +4,7 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/providers"
@@ -112,17 +113,17 @@ func TestSubagentTool_Parameters(t *testing.T) {
 	}
 }
 
-// TestSubagentTool_SetContext verifies context setting
-func TestSubagentTool_SetContext(t *testing.T) {
-	provider := &MockLLMProvider{}
-	manager := NewSubagentManager(provider, "test-model", "/tmp/test", nil)
-	tool := NewSubagentTool(manager)
-
-	tool.SetContext("test-channel", "test-chat")
+// TestSubagentTool_OriginOf verifies the origin comes from the call context
+func TestSubagentTool_OriginOf(t *testing.T) {
+	channel, chatID := originOf(context.Background())
+	if channel != "cli" || chatID != "direct" {
+		t.Errorf("Expected cli/direct without tool context, got %s/%s", channel, chatID)
+	}
 
-	// Verify context is set (we can't directly access private fields,
-	// but we can verify it doesn't crash)
-	// The actual context usage is tested in Execute tests
+	channel, chatID = originOf(WithToolContext(context.Background(), "test-channel", "test-chat"))
+	if channel != "test-channel" || chatID != "test-chat" {
+		t.Errorf("Expected test-channel/test-chat, got %s/%s", channel, chatID)
+	}
 }
 
 // TestSubagentTool_Execute_Success tests successful execution
@@ -131,9 +132,8 @@ func TestSubagentTool_Execute_Success(t *testing.T) {
 	msgBus := bus.NewMessageBus()
 	manager := NewSubagentManager(provider, "test-model", "/tmp/test", msgBus)
 	tool := NewSubagentTool(manager)
-	tool.SetContext("telegram", "chat-123")
 
-	ctx := context.Background()
+	ctx := WithToolContext(context.Background(), "telegram", "chat-123")
 	args := map[string]interface{}{
 		"task":  "Write a haiku about coding",
 		"label": "haiku-task",
@@ -265,9 +265,7 @@ func TestSubagentTool_Execute_ContextPassing(t *testing.T) {
 	// Set context
 	channel := "test-channel"
 	chatID := "test-chat"
-	tool.SetContext(channel, chatID)
-
-	ctx := context.Background()
+	ctx := WithToolContext(context.Background(), channel, chatID)
 	args := map[string]interface{}{
 		"task": "Test context passing",
 	}
@@ -313,3 +311,54 @@ func TestSubagentTool_ForUserTruncation(t *testing.T) {
 		t.Error("ForLLM should contain reference to original task")
 	}
 }
+
+// releasedProvider answers once release is closed, or fails when its
+// context is cancelled first.
+type releasedProvider struct {
+	release chan struct{}
+}
+
+func (m *releasedProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	select {
+	case <-m.release:
+		return &providers.LLMResponse{Content: "Background work done"}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (m *releasedProvider) GetDefaultModel() string {
+	return "test-model"
+}
+
+// TestSpawnTool_OutlivesTurn verifies a spawned task keeps running after the
+// turn that spawned it ends and cancels its context
+func TestSpawnTool_OutlivesTurn(t *testing.T) {
+	provider := &releasedProvider{release: make(chan struct{})}
+	manager := NewSubagentManager(provider, "test-model", "/tmp/test", nil)
+	tool := NewSpawnTool(manager)
+
+	results := make(chan *ToolResult, 1)
+	turnCtx, endTurn := context.WithCancel(WithIncognito(context.Background()))
+	ctx := withAsyncCallback(turnCtx, func(ctx context.Context, result *ToolResult) {
+		if !IsIncognito(ctx) {
+			t.Error("spawned task lost the values of the turn's context")
+		}
+		results <- result
+	})
+
+	if result := tool.Execute(ctx, map[string]interface{}{"task": "work"}); !result.Async {
+		t.Fatalf("expected an async result, got %+v", result)
+	}
+	endTurn()
+	close(provider.release)
+
+	select {
+	case result := <-results:
+		if result.IsError {
+			t.Errorf("spawned task failed after the turn ended: %s", result.ForLLM)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("spawned task did not report back")
+	}
+}
@@ -34,6 +34,10 @@ type ToolLoopResult struct {
 // RunToolLoop executes the LLM + tool call iteration loop.
 // This is the core agent logic that can be reused by both main agent and subagents.
 func RunToolLoop(ctx context.Context, config ToolLoopConfig, messages []providers.Message, channel, chatID string) (*ToolLoopResult, error) {
+	// Subagents have their own message tool; what they send isn't the
+	// reply of the round that started them
+	ctx = context.WithValue(ctx, roundKey{}, (*Round)(nil))
+
 	iteration := 0
 	var finalContent string
 
@@ -49,7 +53,7 @@ func RunToolLoop(ctx context.Context, config ToolLoopConfig, messages []provider
 		// 1. Build tool definitions
 		var providerToolDefs []providers.ToolDefinition
 		if config.Tools != nil {
-			providerToolDefs = config.Tools.ToProviderDefs()
+			providerToolDefs = config.Tools.ToProviderDefs(ctx)
 		}
 
 		// 2. Set default LLM options
@@ -115,8 +119,11 @@ func RunToolLoop(ctx context.Context, config ToolLoopConfig, messages []provider
 
 		// 7. Execute tool calls
 		for _, tc := range response.ToolCalls {
-			argsJSON, _ := json.Marshal(tc.Arguments)
-			argsPreview := utils.Truncate(string(argsJSON), 200)
+			argsPreview := "…"
+			if !IsIncognito(ctx) {
+				argsJSON, _ := json.Marshal(tc.Arguments)
+				argsPreview = utils.Truncate(string(argsJSON), 200)
+			}
 			logger.InfoCF("toolloop", fmt.Sprintf("Tool call: %s(%s)", tc.Name, argsPreview),
 				map[string]any{
 					"tool":      tc.Name,
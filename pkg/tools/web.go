@@ -10,6 +10,9 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/egress"
+	"github.com/sipeed/picoclaw/pkg/vault"
 )
 
 const (
@@ -36,7 +39,7 @@ func (p *BraveSearchProvider) Search(ctx context.Context, query string, count in
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("X-Subscription-Token", p.apiKey)
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := egress.NewClient("web_search", 10*time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("request failed: %w", err)
@@ -96,7 +99,7 @@ func (p *DuckDuckGoSearchProvider) Search(ctx context.Context, query string, cou
 
 	req.Header.Set("User-Agent", userAgent)
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := egress.NewClient("web_search", 10*time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("request failed: %w", err)
@@ -267,6 +270,7 @@ func (t *WebSearchTool) Execute(ctx context.Context, args map[string]interface{}
 
 type WebFetchTool struct {
 	maxChars int
+	vault    *vault.Vault
 }
 
 func NewWebFetchTool(maxChars int) *WebFetchTool {
@@ -283,7 +287,7 @@ func (t *WebFetchTool) Name() string {
 }
 
 func (t *WebFetchTool) Description() string {
-	return "Fetch a URL and extract readable content (HTML to text). Use this to get weather info, news, articles, or any web content."
+	return "Fetch a URL and extract readable content (HTML to text). Use this to get weather info, news, articles, or any web content." + secretHint(t.vault)
 }
 
 func (t *WebFetchTool) Parameters() map[string]interface{} {
@@ -299,6 +303,11 @@ func (t *WebFetchTool) Parameters() map[string]interface{} {
 				"description": "Maximum characters to extract",
 				"minimum":     100.0,
 			},
+			"headers": map[string]interface{}{
+				"type":                 "object",
+				"description":          "Optional request headers, e.g. {\"Authorization\": \"Bearer ...\"}",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+			},
 		},
 		"required": []string{"url"},
 	}
@@ -310,9 +319,30 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 		return ErrorResult("url is required")
 	}
 
-	parsedURL, err := url.Parse(urlStr)
+	// Secrets are substituted into the request only, for hosts their scope
+	// lists; urlStr keeps the placeholders and everything returned is masked
+	fetchURL, err := t.vault.ExpandURL(urlStr)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	parsedURL, err := url.Parse(fetchURL)
 	if err != nil {
-		return ErrorResult(fmt.Sprintf("invalid URL: %v", err))
+		return ErrorResult(t.vault.Mask(fmt.Sprintf("invalid URL: %v", err)))
+	}
+
+	withSecrets := fetchURL != urlStr
+	headers := make(map[string]string)
+	if h, ok := args["headers"].(map[string]interface{}); ok {
+		for name, raw := range h {
+			value, ok := raw.(string)
+			if !ok {
+				return ErrorResult(fmt.Sprintf("header %s must be a string", name))
+			}
+			if headers[name], err = t.vault.ExpandForHost(value, parsedURL.Hostname()); err != nil {
+				return ErrorResult(err.Error())
+			}
+			withSecrets = withSecrets || headers[name] != value
+		}
 	}
 
 	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
@@ -330,32 +360,40 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 		}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fetchURL, nil)
 	if err != nil {
-		return ErrorResult(fmt.Sprintf("failed to create request: %v", err))
+		return ErrorResult(t.vault.Mask(fmt.Sprintf("failed to create request: %v", err)))
 	}
 
 	req.Header.Set("User-Agent", userAgent)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	transport := egress.NewTransport("web_fetch")
+	transport.MaxIdleConns = 10
+	transport.IdleConnTimeout = 30 * time.Second
+	transport.TLSHandshakeTimeout = 15 * time.Second
 
 	client := &http.Client{
-		Timeout: 60 * time.Second,
-		Transport: &http.Transport{
-			MaxIdleConns:        10,
-			IdleConnTimeout:     30 * time.Second,
-			DisableCompression:  false,
-			TLSHandshakeTimeout: 15 * time.Second,
-		},
+		Timeout:   60 * time.Second,
+		Transport: egress.Audit("web_fetch", transport),
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			if len(via) >= 5 {
 				return fmt.Errorf("stopped after 5 redirects")
 			}
+			// Headers go along on redirects, so a request carrying secrets
+			// stays on the host they were allowed for
+			if withSecrets && req.URL.Hostname() != parsedURL.Hostname() {
+				return fmt.Errorf("not following a redirect to %s with secrets in the request", req.URL.Hostname())
+			}
 			return nil
 		},
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return ErrorResult(fmt.Sprintf("request failed: %v", err))
+		return ErrorResult(t.vault.Mask(fmt.Sprintf("request failed: %v", err)))
 	}
 	defer resp.Body.Close()
 
@@ -365,6 +403,7 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 	}
 
 	contentType := resp.Header.Get("Content-Type")
+	body = []byte(t.vault.Mask(string(body)))
 
 	var text, extractor string
 
@@ -409,6 +448,12 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 	}
 }
 
+// SetVault lets the URL and headers use {{secret:NAME}} placeholders from v,
+// for the hosts each secret's scope lists.
+func (t *WebFetchTool) SetVault(v *vault.Vault) {
+	t.vault = v
+}
+
 func (t *WebFetchTool) extractText(htmlContent string) string {
 	re := regexp.MustCompile(`<script[\s\S]*?</script>`)
 	result := re.ReplaceAllLiteralString(htmlContent, "")
@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/vault"
 )
 
 // TestWebTool_WebFetch_Success verifies successful URL fetching
@@ -254,3 +257,76 @@ func TestWebTool_WebFetch_MissingDomain(t *testing.T) {
 		t.Errorf("Expected domain error message, got ForLLM: %s", result.ForLLM)
 	}
 }
+
+// TestWebTool_WebFetch_Secrets verifies secrets reach the request only
+func TestWebTool_WebFetch_Secrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"auth": r.Header.Get("Authorization"),
+			"key":  r.URL.Query().Get("key"),
+		})
+	}))
+	defer server.Close()
+
+	v := vault.New(filepath.Join(t.TempDir(), "secrets.json"))
+	local := vault.Scope{Hosts: []string{"127.0.0.1"}}
+	v.Set("API_TOKEN", "tok-12345", local)
+	v.Set("WEATHER_KEY", "wk/67+89", local)
+	tool := NewWebFetchTool(50000)
+	tool.SetVault(v)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"url":     server.URL + "?key={{secret:WEATHER_KEY}}",
+		"headers": map[string]interface{}{"Authorization": "Bearer {{secret:API_TOKEN}}"},
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	for _, leaked := range []string{"tok-12345", "wk/67+89", "wk%2F67%2B89"} {
+		if strings.Contains(result.ForUser, leaked) || strings.Contains(result.ForLLM, leaked) {
+			t.Errorf("secret %q leaked into the result: %s", leaked, result.ForUser)
+		}
+	}
+	if !strings.Contains(result.ForUser, "Bearer {{secret:API_TOKEN}}") || !strings.Contains(result.ForUser, `\"key\": \"{{secret:WEATHER_KEY}}\"`) {
+		t.Errorf("Expected the echoed secrets masked, got: %s", result.ForUser)
+	}
+}
+
+// TestWebTool_WebFetch_SecretScope verifies secrets only go to their hosts
+func TestWebTool_WebFetch_SecretScope(t *testing.T) {
+	hits := 0
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer other.Close()
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, strings.Replace(other.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+	}))
+	defer redirect.Close()
+
+	v := vault.New(filepath.Join(t.TempDir(), "secrets.json"))
+	v.Set("GITHUB_TOKEN", "ghp-12345", vault.Scope{Hosts: []string{"api.github.com"}})
+	v.Set("LOCAL_TOKEN", "loc-12345", vault.Scope{Hosts: []string{"127.0.0.1"}})
+	tool := NewWebFetchTool(50000)
+	tool.SetVault(v)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"url":     other.URL,
+		"headers": map[string]interface{}{"Authorization": "Bearer {{secret:GITHUB_TOKEN}}"},
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "GITHUB_TOKEN") {
+		t.Errorf("Expected the secret refused for another host, got: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{
+		"url":     redirect.URL,
+		"headers": map[string]interface{}{"Authorization": "Bearer {{secret:LOCAL_TOKEN}}"},
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "redirect") {
+		t.Errorf("Expected the redirect to another host refused, got: %s", result.ForLLM)
+	}
+	if hits != 0 {
+		t.Errorf("the secrets reached a host outside their scope %d times", hits)
+	}
+}
@@ -0,0 +1,293 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// Totals accumulates token counts and estimated cost.
+type Totals struct {
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+func (t Totals) String() string {
+	s := fmt.Sprintf("%d tokens (%d in / %d out) over %d requests",
+		t.TotalTokens, t.PromptTokens, t.CompletionTokens, t.Requests)
+	if t.CostUSD > 0 {
+		s += fmt.Sprintf(", ~$%.4f", t.CostUSD)
+	}
+	return s
+}
+
+func (t *Totals) add(u *providers.UsageInfo, cost float64) {
+	t.Requests++
+	t.PromptTokens += u.PromptTokens
+	t.CompletionTokens += u.CompletionTokens
+	t.TotalTokens += u.TotalTokens
+	t.CostUSD += cost
+}
+
+// Day holds one calendar day of usage. Cloud is the subset counted against
+// the daily caps.
+type Day struct {
+	All   Totals `json:"all"`
+	Cloud Totals `json:"cloud"`
+}
+
+type ledger struct {
+	Sessions  map[string]*Totals `json:"sessions"`
+	Channels  map[string]*Totals `json:"channels"`
+	Providers map[string]*Totals `json:"providers"`
+	Models    map[string]*Totals `json:"models"`
+	Days      map[string]*Day    `json:"days"` // keyed by local date, YYYY-MM-DD
+}
+
+// Tracker records LLM token usage per session, channel, provider and model and
+// enforces the daily cloud caps from config.UsageConfig. It persists to
+// usage/usage.json in the workspace.
+type Tracker struct {
+	path   string
+	limits config.UsageConfig
+	now    func() time.Time
+
+	mu   sync.Mutex
+	data *ledger
+}
+
+// NewTracker loads the usage ledger from the workspace, starting empty if
+// there is none yet.
+func NewTracker(workspace string, cfg config.UsageConfig) *Tracker {
+	dir := filepath.Join(workspace, "usage")
+	os.MkdirAll(dir, 0755)
+
+	t := &Tracker{
+		path:   filepath.Join(dir, "usage.json"),
+		limits: cfg,
+		now:    time.Now,
+		data:   newLedger(),
+	}
+
+	if data, err := os.ReadFile(t.path); err == nil {
+		var l ledger
+		if err := json.Unmarshal(data, &l); err == nil {
+			t.data = &l
+			t.data.fill()
+		}
+	}
+
+	return t
+}
+
+func newLedger() *ledger {
+	l := &ledger{}
+	l.fill()
+	return l
+}
+
+func (l *ledger) fill() {
+	if l.Sessions == nil {
+		l.Sessions = make(map[string]*Totals)
+	}
+	if l.Channels == nil {
+		l.Channels = make(map[string]*Totals)
+	}
+	if l.Providers == nil {
+		l.Providers = make(map[string]*Totals)
+	}
+	if l.Models == nil {
+		l.Models = make(map[string]*Totals)
+	}
+	if l.Days == nil {
+		l.Days = make(map[string]*Day)
+	}
+}
+
+// Record adds the usage of one LLM call made on behalf of a session.
+// provider and model are those that answered; an empty provider is only
+// counted per model.
+func (t *Tracker) Record(sessionKey, channel, provider, model string, u *providers.UsageInfo) error {
+	if u == nil {
+		return nil
+	}
+	cost := t.Cost(model, u)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	totalsFor(t.data.Sessions, sessionKey).add(u, cost)
+	totalsFor(t.data.Channels, channel).add(u, cost)
+	if provider != "" {
+		totalsFor(t.data.Providers, provider).add(u, cost)
+	}
+	totalsFor(t.data.Models, model).add(u, cost)
+	t.today().All.add(u, cost)
+
+	return t.save()
+}
+
+// RecordCloud counts a cloud call towards today's caps. It implements
+// providers.CloudBudget.
+func (t *Tracker) RecordCloud(model string, u *providers.UsageInfo) {
+	if u == nil {
+		return
+	}
+	cost := t.Cost(model, u)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.today().Cloud.add(u, cost)
+	t.save()
+}
+
+// AllowCloud returns an error wrapping providers.ErrBudgetExceeded once
+// today's cloud usage has reached a configured cap.
+func (t *Tracker) AllowCloud() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	day, ok := t.data.Days[t.dateKey()]
+	if !ok {
+		return nil
+	}
+	if limit := t.limits.DailyTokenLimit; limit > 0 && day.Cloud.TotalTokens >= limit {
+		return fmt.Errorf("%w: %d/%d tokens today", providers.ErrBudgetExceeded, day.Cloud.TotalTokens, limit)
+	}
+	if limit := t.limits.DailyCostLimit; limit > 0 && day.Cloud.CostUSD >= limit {
+		return fmt.Errorf("%w: $%.2f/$%.2f today", providers.ErrBudgetExceeded, day.Cloud.CostUSD, limit)
+	}
+	return nil
+}
+
+// Cost estimates the USD cost of a call from the configured prices. Models
+// without a price cost nothing. A price keyed by "model" also matches
+// "provider/model".
+func (t *Tracker) Cost(model string, u *providers.UsageInfo) float64 {
+	price, ok := t.limits.Prices[model]
+	if !ok {
+		if idx := strings.LastIndex(model, "/"); idx != -1 {
+			price, ok = t.limits.Prices[model[idx+1:]]
+		}
+	}
+	if !ok {
+		return 0
+	}
+	return (float64(u.PromptTokens)*price.Input + float64(u.CompletionTokens)*price.Output) / 1_000_000
+}
+
+// Session returns the accumulated usage of a session.
+func (t *Tracker) Session(sessionKey string) Totals {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s, ok := t.data.Sessions[sessionKey]; ok {
+		return *s
+	}
+	return Totals{}
+}
+
+// Today returns today's usage.
+func (t *Tracker) Today() Day {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if d, ok := t.data.Days[t.dateKey()]; ok {
+		return *d
+	}
+	return Day{}
+}
+
+// Models returns the accumulated usage per model.
+func (t *Tracker) Models() map[string]Totals {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return copyTotals(t.data.Models)
+}
+
+// Providers returns the accumulated usage per provider.
+func (t *Tracker) Providers() map[string]Totals {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return copyTotals(t.data.Providers)
+}
+
+// Channels returns the accumulated usage per channel.
+func (t *Tracker) Channels() map[string]Totals {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return copyTotals(t.data.Channels)
+}
+
+// Limits returns the configured daily caps.
+func (t *Tracker) Limits() config.UsageConfig {
+	return t.limits
+}
+
+func (t *Tracker) dateKey() string {
+	return t.now().Format("2006-01-02")
+}
+
+// today must be called with the lock held.
+func (t *Tracker) today() *Day {
+	key := t.dateKey()
+	d, ok := t.data.Days[key]
+	if !ok {
+		d = &Day{}
+		t.data.Days[key] = d
+	}
+	return d
+}
+
+// save writes the ledger with a temp file + rename. Must be called with the
+// lock held.
+func (t *Tracker) save() error {
+	data, err := json.MarshalIndent(t.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage: %w", err)
+	}
+
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write usage: %w", err)
+	}
+	if err := os.Rename(tmp, t.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to rename usage file: %w", err)
+	}
+	return nil
+}
+
+func totalsFor(m map[string]*Totals, key string) *Totals {
+	if key == "" {
+		key = "unknown"
+	}
+	t, ok := m[key]
+	if !ok {
+		t = &Totals{}
+		m[key] = t
+	}
+	return t
+}
+
+func copyTotals(m map[string]*Totals) map[string]Totals {
+	out := make(map[string]Totals, len(m))
+	for k, v := range m {
+		out[k] = *v
+	}
+	return out
+}
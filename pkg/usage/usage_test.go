@@ -0,0 +1,72 @@
+package usage
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestTracker_RecordAndReload(t *testing.T) {
+	workspace := t.TempDir()
+	cfg := config.UsageConfig{
+		Prices: map[string]config.ModelPrice{
+			"gpt-4o-mini": {Input: 0.15, Output: 0.60},
+		},
+	}
+
+	tracker := NewTracker(workspace, cfg)
+	u := &providers.UsageInfo{PromptTokens: 1000, CompletionTokens: 500, TotalTokens: 1500}
+	if err := tracker.Record("telegram:42", "telegram", "openrouter", "openai/gpt-4o-mini", u); err != nil {
+		t.Fatalf("Record() error: %v", err)
+	}
+	if err := tracker.Record("telegram:42", "telegram", "openrouter", "openai/gpt-4o-mini", u); err != nil {
+		t.Fatalf("Record() error: %v", err)
+	}
+
+	reloaded := NewTracker(workspace, cfg)
+	session := reloaded.Session("telegram:42")
+	if session.Requests != 2 || session.TotalTokens != 3000 {
+		t.Errorf("session totals = %+v, want 2 requests / 3000 tokens", session)
+	}
+	wantCost := 2 * (1000*0.15 + 500*0.60) / 1_000_000
+	if diff := session.CostUSD - wantCost; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("CostUSD = %v, want %v", session.CostUSD, wantCost)
+	}
+	if got := reloaded.Channels()["telegram"].TotalTokens; got != 3000 {
+		t.Errorf("channel tokens = %d, want 3000", got)
+	}
+	if got := reloaded.Providers()["openrouter"].TotalTokens; got != 3000 {
+		t.Errorf("provider tokens = %d, want 3000", got)
+	}
+	if got := reloaded.Today().All.Requests; got != 2 {
+		t.Errorf("today requests = %d, want 2", got)
+	}
+}
+
+func TestTracker_DailyTokenCap(t *testing.T) {
+	tracker := NewTracker(t.TempDir(), config.UsageConfig{DailyTokenLimit: 1000})
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local)
+	tracker.now = func() time.Time { return now }
+
+	if err := tracker.AllowCloud(); err != nil {
+		t.Fatalf("AllowCloud() before usage = %v, want nil", err)
+	}
+
+	tracker.RecordCloud("gpt-4o", &providers.UsageInfo{TotalTokens: 1200})
+	if err := tracker.AllowCloud(); !errors.Is(err, providers.ErrBudgetExceeded) {
+		t.Fatalf("AllowCloud() over cap = %v, want ErrBudgetExceeded", err)
+	}
+
+	// RecordCloud only feeds the caps; Record keeps the reporting totals
+	if tracker.Today().All.TotalTokens != 0 {
+		t.Errorf("RecordCloud changed the reporting totals")
+	}
+
+	now = now.Add(24 * time.Hour)
+	if err := tracker.AllowCloud(); err != nil {
+		t.Errorf("AllowCloud() next day = %v, want nil", err)
+	}
+}
@@ -0,0 +1,372 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+// Package vault keeps secrets that tools use on the agent's behalf without
+// the model ever seeing them. The model writes a placeholder such as
+// {{secret:GITHUB_TOKEN}}; the tool substitutes the value when it runs and
+// masks the value back out of whatever it returns. Each secret has a scope,
+// the hosts and commands it may be used with, and is refused everywhere
+// else.
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/crypt"
+)
+
+// minValueLen is the shortest value accepted. Shorter values would be
+// masked out of ordinary output where they happen to appear.
+const minValueLen = 4
+
+var (
+	placeholderRe = regexp.MustCompile(`\{\{secret:([^}]*)\}\}`)
+	nameRe        = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Placeholder returns the text the model uses to refer to a secret.
+func Placeholder(name string) string {
+	return "{{secret:" + name + "}}"
+}
+
+// EnvVar returns the environment variable that carries a secret into a
+// command.
+func EnvVar(name string) string {
+	return "PICOCLAW_SECRET_" + name
+}
+
+// DefaultPath returns the vault file, ~/.picoclaw/secrets.json. It is kept
+// outside the workspace so the file tools can't read it.
+func DefaultPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".picoclaw", "secrets.json")
+}
+
+type vaultFile struct {
+	Secrets map[string]string `json:"secrets"`
+	Scopes  map[string]Scope  `json:"scopes,omitempty"`
+}
+
+// Scope is where a secret may be used. A secret with an empty scope can't
+// be used at all.
+type Scope struct {
+	// Hosts web_fetch may send the secret to: names such as api.github.com,
+	// or *.example.com for any subdomain of example.com
+	Hosts []string `json:"hosts,omitempty"`
+	// Commands are regular expressions. An exec command may use the secret
+	// when the whole command, as written with its placeholders, matches one.
+	Commands []string `json:"commands,omitempty"`
+}
+
+// Empty reports whether the scope allows nothing.
+func (s Scope) Empty() bool {
+	return len(s.Hosts) == 0 && len(s.Commands) == 0
+}
+
+func (s Scope) allowsHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, h := range s.Hosts {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == host || (strings.HasPrefix(h, "*.") && strings.HasSuffix(host, h[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Scope) allowsCommand(command string) bool {
+	for _, expr := range s.Commands {
+		re, err := regexp.Compile(`^(?:` + expr + `)$`)
+		if err == nil && re.MatchString(command) {
+			return true
+		}
+	}
+	return false
+}
+
+// Vault is a set of named secrets stored in one file, encrypted when
+// encryption at rest is enabled. The file is reread when it changes, so
+// secrets set from the CLI reach a running gateway.
+type Vault struct {
+	path string
+
+	mu      sync.RWMutex
+	secrets map[string]string
+	scopes  map[string]Scope
+	modTime time.Time
+	size    int64
+}
+
+// New returns the vault stored at path. Nothing is read until it is used.
+func New(path string) *Vault {
+	return &Vault{path: path, secrets: make(map[string]string), scopes: make(map[string]Scope)}
+}
+
+// Names returns the names of the stored secrets, sorted.
+func (v *Vault) Names() ([]string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if err := v.refresh(); err != nil {
+		return nil, err
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	names := make([]string, 0, len(v.secrets))
+	for name := range v.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Set stores a secret and where it may be used, replacing any previous
+// value and scope.
+func (v *Vault) Set(name, value string, scope Scope) error {
+	if !nameRe.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q: use letters, digits and underscores", name)
+	}
+	if len(value) < minValueLen {
+		return fmt.Errorf("secret value must be at least %d characters", minValueLen)
+	}
+	for _, expr := range scope.Commands {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("invalid command pattern %q: %w", expr, err)
+		}
+	}
+	if err := v.refresh(); err != nil {
+		return err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.secrets[name] = value
+	v.scopes[name] = scope
+	return v.saveLocked()
+}
+
+// Scope returns where the named secret may be used.
+func (v *Vault) Scope(name string) Scope {
+	if v == nil {
+		return Scope{}
+	}
+	v.refresh()
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.scopes[name]
+}
+
+// Delete removes a secret and reports whether it existed.
+func (v *Vault) Delete(name string) (bool, error) {
+	if err := v.refresh(); err != nil {
+		return false, err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if _, ok := v.secrets[name]; !ok {
+		return false, nil
+	}
+	delete(v.secrets, name)
+	delete(v.scopes, name)
+	return true, v.saveLocked()
+}
+
+// ExpandForHost replaces every placeholder in s, a header of a request to
+// host, with its secret. It fails if a placeholder names a secret that
+// doesn't exist, so a request is never sent with the placeholder text in
+// place of a credential, or one whose scope doesn't list host.
+func (v *Vault) ExpandForHost(s, host string) (string, error) {
+	return v.expand(s,
+		func(scope Scope) bool { return scope.allowsHost(host) },
+		"be sent to "+host,
+		func(name, value string) string { return value })
+}
+
+// ExpandURL is ExpandForHost for a URL and its own host: each value is
+// query-escaped, so characters such as + and & arrive as part of the
+// secret.
+func (v *Vault) ExpandURL(s string) (string, error) {
+	expanded, err := v.expand(s, nil, "",
+		func(name, value string) string { return url.QueryEscape(value) })
+	if err != nil || expanded == s {
+		return expanded, err
+	}
+	// The host is checked as it will be connected to, with values filled in
+	u, err := url.Parse(expanded)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %s", v.Mask(err.Error()))
+	}
+	host := u.Hostname()
+	return v.expand(s,
+		func(scope Scope) bool { return scope.allowsHost(host) },
+		"be sent to "+v.Mask(host),
+		func(name, value string) string { return url.QueryEscape(value) })
+}
+
+// ExpandEnv is for a shell command. Each placeholder is replaced with
+// ref(EnvVar(name)), a reference to the variable in the shell's syntax, and
+// the values are returned as VAR=value for the command's environment. The
+// shell never parses a value, so quotes or a ; in a secret can't change the
+// command. It fails like ExpandForHost, for a secret whose scope doesn't
+// allow the command.
+func (v *Vault) ExpandEnv(s string, ref func(envVar string) string) (string, []string, error) {
+	var env []string
+	seen := make(map[string]bool)
+	out, err := v.expand(s,
+		func(scope Scope) bool { return scope.allowsCommand(s) },
+		"be used in this command",
+		func(name, value string) string {
+			if !seen[name] {
+				seen[name] = true
+				env = append(env, EnvVar(name)+"="+value)
+			}
+			return ref(EnvVar(name))
+		})
+	if err != nil {
+		return "", nil, err
+	}
+	return out, env, nil
+}
+
+// expand replaces the placeholders in s with replace. A secret whose scope
+// allowed doesn't accept is refused, with an error saying it may not
+// action; nil allowed skips the check.
+func (v *Vault) expand(s string, allowed func(Scope) bool, action string, replace func(name, value string) string) (string, error) {
+	if !strings.Contains(s, "{{secret:") {
+		return s, nil
+	}
+	if v == nil {
+		return "", fmt.Errorf("no secret vault is configured")
+	}
+	if err := v.refresh(); err != nil {
+		return "", err
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	var missing, refused []string
+	out := placeholderRe.ReplaceAllStringFunc(s, func(m string) string {
+		name := placeholderRe.FindStringSubmatch(m)[1]
+		value, ok := v.secrets[name]
+		if !ok {
+			missing = append(missing, name)
+			return m
+		}
+		if allowed != nil && !allowed(v.scopes[name]) {
+			refused = append(refused, name)
+			return m
+		}
+		return replace(name, value)
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("unknown secret %q (add it with: picoclaw secret set %s)", missing[0], missing[0])
+	}
+	if len(refused) > 0 {
+		return "", fmt.Errorf("secret %q may not %s (its scope is set with: picoclaw secret set %s --host ... --command ...)",
+			refused[0], action, refused[0])
+	}
+	return out, nil
+}
+
+// Mask replaces every secret value in s, plain or URL-encoded, with its
+// placeholder.
+func (v *Vault) Mask(s string) string {
+	if v == nil || s == "" {
+		return s
+	}
+	// Mask with what was last loaded even if the file can't be read now
+	v.refresh()
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if len(v.secrets) == 0 {
+		return s
+	}
+
+	type form struct{ value, name string }
+	var forms []form
+	for name, value := range v.secrets {
+		forms = append(forms, form{value, name})
+		if escaped := url.QueryEscape(value); escaped != value {
+			forms = append(forms, form{escaped, name})
+		}
+	}
+	// Longest first, so a secret containing another is masked whole
+	sort.Slice(forms, func(i, j int) bool { return len(forms[i].value) > len(forms[j].value) })
+	for _, f := range forms {
+		s = strings.ReplaceAll(s, f.value, Placeholder(f.name))
+	}
+	return s
+}
+
+// refresh rereads the file if it changed since it was last read.
+func (v *Vault) refresh() error {
+	info, err := os.Stat(v.path)
+	if os.IsNotExist(err) {
+		v.mu.Lock()
+		v.secrets = make(map[string]string)
+		v.scopes = make(map[string]Scope)
+		v.modTime, v.size = time.Time{}, 0
+		v.mu.Unlock()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	v.mu.RLock()
+	fresh := info.ModTime().Equal(v.modTime) && info.Size() == v.size
+	v.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	data, err := crypt.ReadFile(v.path)
+	if err != nil {
+		return fmt.Errorf("reading secret vault: %w", err)
+	}
+	var f vaultFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("reading secret vault: %w", err)
+	}
+	if f.Secrets == nil {
+		f.Secrets = make(map[string]string)
+	}
+	if f.Scopes == nil {
+		f.Scopes = make(map[string]Scope)
+	}
+
+	v.mu.Lock()
+	v.secrets = f.Secrets
+	v.scopes = f.Scopes
+	v.modTime, v.size = info.ModTime(), info.Size()
+	v.mu.Unlock()
+	return nil
+}
+
+func (v *Vault) saveLocked() error {
+	data, err := json.MarshalIndent(vaultFile{Secrets: v.secrets, Scopes: v.scopes}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(v.path), 0755); err != nil {
+		return err
+	}
+	if err := crypt.WriteFile(v.path, data, 0600); err != nil {
+		return err
+	}
+	if info, err := os.Stat(v.path); err == nil {
+		v.modTime, v.size = info.ModTime(), info.Size()
+	}
+	return nil
+}
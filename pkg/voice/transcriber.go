@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/sipeed/picoclaw/pkg/egress"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/utils"
 )
@@ -33,11 +34,9 @@ func NewGroqTranscriber(apiKey string) *GroqTranscriber {
 
 	apiBase := "https://api.groq.com/openai/v1"
 	return &GroqTranscriber{
-		apiKey:  apiKey,
-		apiBase: apiBase,
-		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
-		},
+		apiKey:     apiKey,
+		apiBase:    apiBase,
+		httpClient: egress.NewClient("voice", 60*time.Second),
 	}
 }
 